@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	utils "hephaestus/internal/utils"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newConfigCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	var asJSON bool
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration after env overrides and defaults, secrets masked",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			effective := utils.RedactedConfig(a.cfg)
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(effective)
+			}
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			defer enc.Close()
+			return enc.Encode(effective)
+		},
+	}
+	show.Flags().BoolVar(&asJSON, "json", false, "print JSON instead of YAML")
+
+	cmd.AddCommand(show)
+	return cmd
+}
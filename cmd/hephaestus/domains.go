@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newIssueCmd(a *app) *cobra.Command {
+	var req models.CreateDomainReq
+
+	cmd := &cobra.Command{
+		Use:   "issue <domain>",
+		Short: "Create a domain and issue its certificate, or start monitoring it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, closeRepo, err := a.newService()
+			if err != nil {
+				return err
+			}
+			defer closeRepo()
+
+			req.Domain = args[0]
+			req.CreatedBy = a.userID
+			req.TenantID = a.tenantID
+			domainID, err := service.CreateDomain(req)
+			if err != nil {
+				return err
+			}
+			fmt.Println("domain created:", domainID)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&req.DNSProvider, "provider", "", "DNS provider used for the challenge, default_provider when empty")
+	flags.StringSliceVar(&req.AltDomains, "alt", nil, "alternative domains of the certificate")
+	flags.StringVar(&req.NginxContainerName, "nginx-container", "", "nginx container reloaded after renewals")
+	flags.StringVar(&req.VerificationMethod, "verification-method", "dns-01", "challenge type")
+	flags.BoolVar(&req.AutoRenew, "auto-renew", true, "renew the certificate before it expires")
+	flags.StringSliceVar(&req.Tags, "tags", nil, "tags of the domain, e.g. env=prod")
+	flags.StringVar(&req.Notes, "notes", "", "notes of the domain")
+	flags.BoolVar(&req.Revive, "revive", false, "re-create a previously deleted domain")
+	flags.StringVar(&req.KeyType, "key-type", "", "certificate key: rsa2048, rsa3072, rsa4096, rsa8192, ec256 or ec384, certs.key_type when empty")
+	flags.StringVar(&req.Profile, "profile", "", "ACME profile, e.g. tlsserver or shortlived, certs.profile when empty")
+	flags.BoolVar(&req.ReuseKey, "reuse-key", false, "renewals keep the private key of the current certificate")
+	flags.StringSliceVar(&req.OutputFormats, "output-formats", nil, "encodings written besides PEM, e.g. der, certs.output_formats when empty")
+	flags.BoolVar(&req.DeferIssuance, "defer", false, "only store the domain, the next renewal cycle issues the certificate")
+	flags.StringVar(&req.Kind, "kind", models.DomainKindManaged, "managed, or monitored to only watch a certificate issued elsewhere")
+	flags.StringVar(&req.MonitorAddress, "monitor-address", "", "host:port a monitored domain is checked on, <domain>:443 when empty")
+	return cmd
+}
+
+func newIssueCSRCmd(a *app) *cobra.Command {
+	var req models.IssueFromCSRReq
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "issue-csr <csr.pem>",
+		Short: "Issue a certificate for a CSR, the key stays with the requester and no domain is created",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			service, closeRepo, err := a.newService()
+			if err != nil {
+				return err
+			}
+			defer closeRepo()
+
+			req.CSR = string(data)
+			req.UserID = a.userID
+			req.TenantID = a.tenantID
+			issued, err := service.IssueFromCSR(req)
+			if err != nil {
+				return err
+			}
+			if out == "" {
+				fmt.Print(issued.Certificate)
+				return nil
+			}
+			if err := os.WriteFile(out, []byte(issued.Certificate), 0644); err != nil {
+				return err
+			}
+			fmt.Println("certificate", issued.SerialNumber, "written to", out)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&req.DNSProvider, "provider", "", "DNS provider used for the challenge, default_provider when empty")
+	flags.StringVar(&req.Profile, "profile", "", "ACME profile, e.g. tlsserver or shortlived, certs.profile when empty")
+	flags.StringVar(&out, "out", "", "file the certificate and its issuer are written to, stdout when empty")
+	return cmd
+}
+
+func newImportCmd(a *app) *cobra.Command {
+	var req models.ImportDomainsReq
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Create domains from a CSV or JSON export, prints the result of every row",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if req.Format == "" {
+				req.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[0])), ".")
+			}
+
+			service, closeRepo, err := a.newService()
+			if err != nil {
+				return err
+			}
+			defer closeRepo()
+
+			req.Data = data
+			req.CreatedBy = a.userID
+			req.TenantID = a.tenantID
+			resp, err := service.ImportDomains(req)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ROW\tDOMAIN\tSTATUS\tDETAILS")
+			for _, row := range resp.Rows {
+				details := row.DomainID
+				if row.Error != "" {
+					details = strings.ReplaceAll(row.Error, "\n", " ")
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", row.Row, row.Domain, row.Status, details)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Printf("%d created, %d failed\n", resp.Created, resp.Failed)
+			if resp.Failed > 0 {
+				return errors.New("some rows failed to import")
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&req.Format, "format", "", "csv or json, taken from the file extension when empty")
+	flags.StringVar(&req.DNSProvider, "provider", "", "DNS provider of rows that don't name one, default_provider when empty")
+	flags.BoolVar(&req.DeferIssuance, "defer", false, "only store the domains, the next renewal cycle issues the certificates")
+	return cmd
+}
+
+func newRenewCmd(a *app) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "renew <domain> | --all",
+		Short: "Renew the certificate of a domain, or run the renewal cycle for all expiring ones",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if all && len(args) > 0 {
+				return errors.New("either a domain or --all, not both")
+			}
+			if !all && len(args) != 1 {
+				return errors.New("a domain or --all is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, closeRepo, err := a.newService()
+			if err != nil {
+				return err
+			}
+			defer closeRepo()
+
+			if all {
+				service.RunRenewalCycle()
+				return nil
+			}
+			if err := service.RenewDomain(a.tenantID, args[0]); err != nil {
+				return err
+			}
+			fmt.Println("certificate renewed:", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "renew every domain that is due, across all tenants")
+	return cmd
+}
+
+func newListCmd(a *app) *cobra.Command {
+	filters := models.GetDomainsReq{}
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List domains and their certificate state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, closeRepo, err := a.newService()
+			if err != nil {
+				return err
+			}
+			defer closeRepo()
+
+			filters.TenantID = a.tenantID
+			resp, err := service.GetDomains(filters)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(resp)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tDOMAIN\tKIND\tSTATUS\tPROVIDER\tVALID TO\tTAGS")
+			for _, d := range resp.Domains {
+				validTo := "-"
+				if !d.Details.CertValidTo.IsZero() {
+					validTo = d.Details.CertValidTo.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					d.ID, d.DomainName, d.Details.Kind, d.Details.Status, d.Details.DNSProvider, validTo, strings.Join(d.Tags, ","))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Printf("page %d of %d, %d domains\n", resp.Page, resp.TotalPages, resp.TotalElements)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&filters.Status, "status", "", "only domains with this status")
+	flags.StringVar(&filters.DomainName, "domain", "", "domain name filter, matches alternative domains too")
+	flags.BoolVar(&filters.Fuzzy, "fuzzy", false, "similarity search on --domain")
+	flags.StringSliceVar(&filters.Tags, "tags", nil, "domains must carry all of these tags")
+	flags.IntVar(&filters.Page, "page", 1, "page number")
+	flags.IntVar(&filters.PageSize, "page-size", 50, "domains per page")
+	flags.BoolVar(&asJSON, "json", false, "print the API response instead of a table")
+	return cmd
+}
+
+func newRevokeCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <domain>",
+		Short: "Revoke the active certificate of a domain at the CA",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, closeRepo, err := a.newService()
+			if err != nil {
+				return err
+			}
+			defer closeRepo()
+
+			err = service.RevokeCertificate(models.RevokeCertificateReq{
+				DomainName: args[0],
+				UserID:     a.userID,
+				TenantID:   a.tenantID,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Println("certificate revoked:", args[0])
+			return nil
+		},
+	}
+}
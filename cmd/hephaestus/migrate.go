@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	repositories "hephaestus/internal/repositories"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database migrations without starting the service",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply all pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return a.withMigrator(func(m *repositories.Migrator) error {
+					return m.Up()
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "down [steps]",
+			Short: "Roll back migrations, one by default",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				steps := 1
+				if len(args) > 0 {
+					var err error
+					steps, err = strconv.Atoi(args[0])
+					if err != nil {
+						return fmt.Errorf("invalid steps %q: %w", args[0], err)
+					}
+				}
+				return a.withMigrator(func(m *repositories.Migrator) error {
+					return m.Down(steps)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show the current migration version",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return a.withMigrator(func(m *repositories.Migrator) error {
+					status, err := m.Status()
+					if err != nil {
+						return err
+					}
+					if !status.Applied {
+						fmt.Println("no migrations applied")
+						return nil
+					}
+					fmt.Printf("version: %d, dirty: %t\n", status.Version, status.Dirty)
+					return nil
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "force <version>",
+			Short: "Set the migration version after a failed migration was fixed by hand",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid version %q: %w", args[0], err)
+				}
+				return a.withMigrator(func(m *repositories.Migrator) error {
+					return m.Force(version)
+				})
+			},
+		},
+	)
+	return cmd
+}
+
+func (a *app) withMigrator(fn func(m *repositories.Migrator) error) error {
+	migrator, err := repositories.NewMigrator(a.cfg, a.log)
+	if err != nil {
+		return fmt.Errorf("create migrator: %w", err)
+	}
+	defer migrator.Close()
+
+	return fn(migrator)
+}
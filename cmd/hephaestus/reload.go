@@ -0,0 +1,55 @@
+package main
+
+import (
+	acme "hephaestus/internal/acme"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	services "hephaestus/internal/services"
+	storage "hephaestus/internal/storage"
+	utils "hephaestus/internal/utils"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// watchReload reloads the configuration on SIGHUP, a config that fails to load or
+// yields no DNS clients is rejected and the running one is kept
+func watchReload(configPath string, cfg *utils.Config, service *services.Service, files storage.FileTable, log *utils.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		current := cfg
+		for range hup {
+			log.Info("SIGHUP received, reloading configuration...")
+
+			// provider keys come from the environment, .env values replace the ones loaded at start
+			_ = godotenv.Overload()
+			next, err := utils.LoadConfig(configPath)
+			if err != nil {
+				log.Error("Config reload failed, keeping the running config: ", err)
+				continue
+			}
+
+			providers, err := dnsproviders.CreateProviders(next, log)
+			if err != nil {
+				log.Error("Config reload failed, keeping the running config: ", err)
+				continue
+			}
+			issuer, err := acme.NewIssuer(next, files, log)
+			if err != nil {
+				log.Error("Config reload failed, keeping the running config: ", err)
+				continue
+			}
+
+			if !reflect.DeepEqual(current.Database, next.Database) || !reflect.DeepEqual(current.Server, next.Server) {
+				log.Warn("Database and server settings changed, they are applied on restart only")
+			}
+
+			service.Reload(next, issuer, providers)
+			current = next
+		}
+	}()
+}
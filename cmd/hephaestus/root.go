@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	acme "hephaestus/internal/acme"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	repositories "hephaestus/internal/repositories"
+	services "hephaestus/internal/services"
+	utils "hephaestus/internal/utils"
+	version "hephaestus/internal/version"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// app holds what every subcommand needs, filled in before the subcommand runs
+type app struct {
+	configPath string
+	tenantID   string
+	userID     string
+	sandbox    bool
+
+	cfg *utils.Config
+	log *utils.Logger
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+
+	root := &cobra.Command{
+		Use:          "hephaestus",
+		Short:        "Creates and renews TLS certificates with the ACME DNS-01 challenge",
+		Version:      version.Get().String(),
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.sandbox {
+				// read like the other env overrides, so the config validates without DNS credentials
+				os.Setenv("HEPHAESTUS_SANDBOX_ENABLED", "true")
+			}
+			return a.loadConfig()
+		},
+		// without a subcommand the service starts, as before the CLI existed
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.serve()
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.configPath, "config", utils.Getenv("CONFIG_PATH"), "path to the YAML config (env HEPHAESTUS_CONFIG_PATH or CONFIG_PATH)")
+	flags.StringVar(&a.tenantID, "tenant", "", "tenant the command works in")
+	flags.StringVar(&a.userID, "user", "cli", "user recorded as the author of changes and events")
+	flags.BoolVar(&a.sandbox, "sandbox", false, "issue from a local Pebble CA and serve DNS-01 records from memory (env HEPHAESTUS_SANDBOX_ENABLED)")
+
+	root.AddCommand(
+		newServeCmd(a),
+		newIssueCmd(a),
+		newIssueCSRCmd(a),
+		newImportCmd(a),
+		newRenewCmd(a),
+		newListCmd(a),
+		newRevokeCmd(a),
+		newMigrateCmd(a),
+		newConfigCmd(a),
+	)
+	return root
+}
+
+func (a *app) loadConfig() error {
+	if a.configPath == "" {
+		return errors.New("config path is not set, use --config or HEPHAESTUS_CONFIG_PATH")
+	}
+
+	cfg, err := utils.LoadConfig(a.configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	a.cfg = cfg
+	a.log = utils.NewLogger(cfg.Logger.LogLevel)
+	if len(cfg.APIS) > 0 {
+		a.log.Warn("Config: 'apis' is deprecated, move the entries to 'providers'")
+	}
+	if cfg.Sandbox.Enabled {
+		a.log.Warn("Sandbox mode: certificates come from ", cfg.Sandbox.CADirURL, ", DNS-01 records are served on ", cfg.Sandbox.DNSAddress)
+	}
+	return nil
+}
+
+// newService wires the service for one-off commands, the schema must already be migrated
+func (a *app) newService() (*services.Service, func(), error) {
+	repo, err := repositories.NewRepository(a.cfg, a.log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create repository: %w", err)
+	}
+
+	providers, err := dnsproviders.CreateProviders(a.cfg, a.log)
+	if err != nil {
+		repo.Close()
+		return nil, nil, fmt.Errorf("create dns providers: %w", err)
+	}
+	issuer, err := acme.NewIssuer(a.cfg, repo, a.log)
+	if err != nil {
+		repo.Close()
+		return nil, nil, fmt.Errorf("create acme issuer: %w", err)
+	}
+
+	service, err := services.NewService(a.cfg, issuer, providers, repo, a.log)
+	if err != nil {
+		repo.Close()
+		return nil, nil, fmt.Errorf("create service: %w", err)
+	}
+	if err := service.LoadDNSProviders(); err != nil {
+		a.log.Error("Runtime DNS providers not loaded: ", err)
+	}
+	return service, repo.Close, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	acme "hephaestus/internal/acme"
+	routes "hephaestus/internal/api/routes"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	repositories "hephaestus/internal/repositories"
+	services "hephaestus/internal/services"
+	version "hephaestus/internal/version"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Apply migrations, start the schedulers and the HTTP API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.serve()
+		},
+	}
+}
+
+func (a *app) serve() error {
+	log := a.log
+	log.Info("Hephaestus ", version.Get().String())
+
+	// repository creation
+	repo, err := repositories.NewRepository(a.cfg, log)
+	if err != nil {
+		return fmt.Errorf("create repository: %w", err)
+	}
+	log.Info("Repository created successful")
+
+	// start migrations, the service must not run against a schema in unknown state
+	if err := repo.RunMigrations(a.cfg); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+	log.Info("Migrations applied successfully")
+
+	// creating dns providers and the acme issuer
+	providers, err := dnsproviders.CreateProviders(a.cfg, log)
+	if err != nil {
+		return fmt.Errorf("create dns providers: %w", err)
+	}
+	// the postgres storage backend keeps the certificates in the migrated schema
+	issuer, err := acme.NewIssuer(a.cfg, repo, log)
+	if err != nil {
+		return fmt.Errorf("create acme issuer: %w", err)
+	}
+	log.Info("DNS providers and ACME issuer created successful")
+
+	// creating service
+	service, err := services.NewService(a.cfg, issuer, providers, repo, log)
+	if err != nil {
+		return fmt.Errorf("create service: %w", err)
+	}
+	log.Info("Service created successful")
+
+	// providers without credentials are skipped, not fatal
+	if err := service.RecordDisabledProviders(); err != nil {
+		log.Error("Disabled DNS providers not recorded: ", err)
+	}
+
+	// providers registered through the API
+	if err := service.LoadDNSProviders(); err != nil {
+		log.Error("Runtime DNS providers not loaded: ", err)
+	}
+
+	// orders a crash interrupted, after the providers their challenge records were created with.
+	// Reissuing can take minutes, the API doesn't wait for it
+	go func() {
+		if err := service.ResumePendingOrders(); err != nil {
+			log.Error("Pending orders not resumed: ", err)
+		}
+	}()
+
+	// starting scheduler
+	service.StartCertificateRenewalScheduler()
+	log.Info("Certificate renewal scheduler started")
+
+	service.StartPurgeScheduler()
+	service.StartMonitorScheduler()
+
+	watchReload(a.configPath, a.cfg, service, repo, log)
+
+	// creating routes
+	router, err := routes.CreateRoutes(service, a.cfg, log)
+	if err != nil {
+		return fmt.Errorf("create routes: %w", err)
+	}
+	log.Info("Routes created successful")
+
+	// starting http server
+	log.Info("Starting the server on port ", a.cfg.Server.Port)
+	return http.ListenAndServe(a.cfg.Server.Port, router)
+}
@@ -0,0 +1,203 @@
+package acme
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"os"
+	"sync"
+	"time"
+
+	legoacme "github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+const (
+	accountDoesNotExistErr = "urn:ietf:params:acme:error:accountDoesNotExist"
+	unauthorizedErr        = "urn:ietf:params:acme:error:unauthorized"
+)
+
+// accountStore keeps the registrations of the account key in acme_account.json next to acme_user.key,
+// one per CA directory, so an issuance doesn't register the account again
+type accountStore struct {
+	path string
+	log  *utils.Logger
+
+	mu       sync.Mutex
+	accounts map[string]*registration.Resource
+}
+
+func loadAccountStore(path string, log *utils.Logger) (*accountStore, error) {
+	store := &accountStore{path: path, log: log, accounts: map[string]*registration.Resource{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read account file: %w", err)
+	}
+	if err := json.Unmarshal(b, &store.accounts); err != nil {
+		return nil, fmt.Errorf("parse account file: %w", err)
+	}
+	return store, nil
+}
+
+// get returns the stored account of the CA directory, nil when there is none yet
+func (s *accountStore) get(dirURL string) *registration.Resource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accounts[dirURL]
+}
+
+// resolve looks up the account of the key on the CA directory, registering it when it has none,
+// and stores it. lg must be created without a registration, the lookup sets its key id
+func (s *accountStore) resolve(lg *lego.Client, dirURL string, register func() (*registration.Resource, error)) (*registration.Resource, error) {
+	// the key may already have an account, e.g. when the file was lost
+	s.log.Debug("Resolving ACME account by key on ", dirURL)
+	reg, err := lg.Registration.ResolveAccountByKey()
+	var problem *legoacme.ProblemDetails
+	if errors.As(err, &problem) && problem.Type == accountDoesNotExistErr {
+		s.log.Info("Registering ACME account on ", dirURL)
+		reg, err = register()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[dirURL] = reg
+	if err := s.save(); err != nil {
+		// the account is known again through its key next time
+		s.log.Warn("Failed to save ACME account: ", err)
+	}
+	return reg, nil
+}
+
+func (s *accountStore) save() error {
+	b, err := json.MarshalIndent(s.accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// forget drops the registration of the CA directory, the next order looks it up by key again
+func (s *accountStore) forget(dirURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.accounts, dirURL)
+	return s.save()
+}
+
+// reset forgets every registration, they belong to an account key that was replaced
+func (s *accountStore) reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = map[string]*registration.Resource{}
+	return s.save()
+}
+
+// accountKeyFile is the account key in certs.storage_dir
+const accountKeyFile = "acme_user.key"
+
+func (i *Issuer) key() crypto.PrivateKey {
+	i.keyMu.RLock()
+	defer i.keyMu.RUnlock()
+	return i.accountKey
+}
+
+// DeactivateAccount deactivates the account of the key on the current CA, which can't be undone.
+// The key is archived next to its file and a new one is generated, it registers a new account on
+// the next order. Registrations of the old key on other CAs are forgotten, they aren't deactivated.
+// Other replicas load the new key when the CA refuses an order of the old account, see refreshAccount
+func (i *Issuer) DeactivateAccount() (*models.DeactivatedAccount, error) {
+	i.log.Debug("DeactivateAccount(): called")
+	lg, err := i.newLegoClient(i.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	reg := i.accounts.get(i.directoryURL())
+	if reg == nil {
+		return nil, errors.New("no ACME account is registered on the CA")
+	}
+	if err := lg.Registration.DeleteRegistration(); err != nil {
+		return nil, fmt.Errorf("failed to deactivate acme account: %w", err)
+	}
+	i.log.Warn("ACME account ", reg.URI, " deactivated")
+
+	i.keyMu.Lock()
+	defer i.keyMu.Unlock()
+
+	// the key is archived by copy, a key without an archive is never removed
+	archived := fmt.Sprintf("%s.deactivated-%s", accountKeyFile, time.Now().UTC().Format("20060102T150405Z"))
+	keyPEM, err := i.keys.Read(accountKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read acme user key: %w", err)
+	}
+	if err := i.keys.Write(archived, keyPEM); err != nil {
+		return nil, fmt.Errorf("failed to archive acme user key: %w", err)
+	}
+	// written over the old key, other replicas never find the file missing and create a key of their own
+	priv, err := createAccountKey(i.keys, accountKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create acme user key: %w", err)
+	}
+	i.accountKey = priv
+	if err := i.accounts.reset(); err != nil {
+		i.log.Warn("Failed to reset ACME accounts: ", err)
+	}
+
+	return &models.DeactivatedAccount{
+		AccountURI:  reg.URI,
+		CADirURL:    i.directoryURL(),
+		ArchivedKey: i.keys.Location(archived),
+	}, nil
+}
+
+// refreshAccount handles an order the CA refused for its account and tells whether to place it again.
+// The account key is read from the storage backend again: another replica may have deactivated the
+// account and stored a new key. A registration the CA doesn't know is forgotten and looked up again
+func (i *Issuer) refreshAccount(err error) bool {
+	var problem *legoacme.ProblemDetails
+	if !errors.As(err, &problem) || (problem.Type != accountDoesNotExistErr && problem.Type != unauthorizedErr) {
+		return false
+	}
+	priv, loadErr := loadOrCreateAccountKey(i.keys, accountKeyFile, i.log)
+	if loadErr != nil {
+		i.log.Warn("Failed to reload ACME user key: ", loadErr)
+		return false
+	}
+
+	i.keyMu.Lock()
+	current, ok := i.accountKey.(interface{ Equal(crypto.PrivateKey) bool })
+	replaced := !ok || !current.Equal(priv)
+	if replaced {
+		i.accountKey = priv
+	}
+	i.keyMu.Unlock()
+
+	switch {
+	case replaced:
+		i.log.Warn("ACME user key was replaced, e.g. by a deactivation on another replica, ordering with the new key")
+		if err := i.accounts.reset(); err != nil {
+			i.log.Warn("Failed to reset ACME accounts: ", err)
+		}
+		return true
+	case problem.Type == accountDoesNotExistErr:
+		i.log.Warn("ACME account of the key isn't known to ", i.directoryURL(), ", resolving it again")
+		if err := i.accounts.forget(i.directoryURL()); err != nil {
+			i.log.Warn("Failed to reset ACME account: ", err)
+		}
+		return true
+	}
+	return false
+}
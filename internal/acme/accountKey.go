@@ -0,0 +1,57 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	storage "hephaestus/internal/storage"
+	utils "hephaestus/internal/utils"
+	"os"
+)
+
+// loadOrCreateAccountKey reads the ACME account key from the key backend, a new one is generated on first start
+func loadOrCreateAccountKey(keys storage.Backend, name string, log *utils.Logger) (crypto.PrivateKey, error) {
+	log.Debug("loadOrCreateAccountKey(): called, location=", keys.Location(name))
+	b, err := keys.Read(name)
+	if err == nil {
+		// load
+		log.Debug("Key file exists. Loading...")
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("invalid pem in key file")
+		}
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		return priv, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	// create
+	priv, err := createAccountKey(keys, name)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Key loaded successfully")
+	return priv, nil
+}
+
+// createAccountKey generates an account key and writes it over the one stored under name
+func createAccountKey(keys storage.Backend, name string) (crypto.PrivateKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	pemBlock := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}
+	if err := keys.Write(name, pem.EncodeToMemory(pemBlock)); err != nil {
+		return nil, fmt.Errorf("write key file: %w", err)
+	}
+	return priv, nil
+}
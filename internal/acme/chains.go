@@ -0,0 +1,94 @@
+package acme
+
+import (
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/lego"
+)
+
+// alternateLink matches the URLs of a Link header with rel="alternate", RFC 8555 section 7.4.2
+var alternateLink = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?alternate"?`)
+
+// alternateLinks collects the alternate chains the CA links from certificate downloads,
+// by the URL of the certificate
+type alternateLinks struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	urls map[string][]string
+}
+
+// client wraps client so the alternate chains of the certificates it downloads are collected
+func (l *alternateLinks) client(client *http.Client) *http.Client {
+	collecting := *client
+	l.base = client.Transport
+	if l.base == nil {
+		l.base = http.DefaultTransport
+	}
+	collecting.Transport = l
+	return &collecting
+}
+
+func (l *alternateLinks) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := l.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	var urls []string
+	for _, link := range resp.Header.Values("Link") {
+		for _, m := range alternateLink.FindAllStringSubmatch(link, -1) {
+			urls = append(urls, m[1])
+		}
+	}
+	if len(urls) > 0 {
+		l.mu.Lock()
+		if l.urls == nil {
+			l.urls = map[string][]string{}
+		}
+		l.urls[req.URL.String()] = urls
+		l.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// alternateChains downloads the alternate chains of the certificate, in the order the CA offered them.
+// A chain that can't be downloaded is left out, the certificate is usable with the default one
+func (i *Issuer) alternateChains(lg *lego.Client, links *alternateLinks, certRes *certificate.Resource) [][]byte {
+	links.mu.Lock()
+	urls := links.urls[certRes.CertURL]
+	links.mu.Unlock()
+
+	var chains [][]byte
+	for _, url := range urls {
+		alt, err := lg.Certificate.Get(url, false)
+		if err != nil {
+			i.log.Warn("Alternate chain ", url, " not downloaded: ", err)
+			continue
+		}
+		if len(alt.IssuerCertificate) == 0 {
+			continue
+		}
+		chains = append(chains, alt.IssuerCertificate)
+	}
+	if len(chains) > 0 {
+		i.log.Debug("Downloaded ", len(chains), " alternate chains of ", certRes.Domain)
+	}
+	return chains
+}
+
+// setAltChains adds the alternate chains to the certificate with the root of every chain, the default first
+func setAltChains(data *models.CertificateData, chains [][]byte) {
+	if len(chains) == 0 {
+		return
+	}
+	data.AltChains = chains
+	data.ChainRoots = []string{utils.ChainRoot(data.Chain)}
+	for _, chain := range chains {
+		data.ChainRoots = append(data.ChainRoots, utils.ChainRoot(chain))
+	}
+}
@@ -0,0 +1,444 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	models "hephaestus/internal/models"
+	storage "hephaestus/internal/storage"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	legoacme "github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// keyTypes maps the key type names of utils.CertKeyTypes, stored as certificates.key_type, to lego's
+var keyTypes = map[string]certcrypto.KeyType{
+	"ec256":   certcrypto.EC256,
+	"ec384":   certcrypto.EC384,
+	"rsa2048": certcrypto.RSA2048,
+	"rsa3072": certcrypto.RSA3072,
+	"rsa4096": certcrypto.RSA4096,
+	"rsa8192": certcrypto.RSA8192,
+}
+
+// IssuerInterface orders and revokes certificates, used by services.Service
+type IssuerInterface interface {
+	Obtain(provider *dnsproviders.Provider, domain string, san []string, keyType, profile string, reuseKey []byte) (*models.CertificateData, error)
+	ObtainForCSR(provider *dnsproviders.Provider, csr *x509.CertificateRequest, profile string) (*models.CertificateData, error)
+	Revoke(certPEM []byte) error
+	DeactivateAccount() (*models.DeactivatedAccount, error)
+	SetOrderJournal(journal OrderJournal)
+}
+
+var _ IssuerInterface = (*Issuer)(nil)
+
+// Issuer talks to the ACME CA with one account, the DNS provider is chosen per order
+type Issuer struct {
+	cfg        *utils.Config
+	log        *utils.Logger
+	keys       storage.Backend // account key and keys of the orders in progress
+	keyMu      sync.RWMutex    // guards accountKey, replaced when the account is deactivated
+	accountKey crypto.PrivateKey
+	accounts   *accountStore
+	httpClient *http.Client // outbound client of the ACME CA, from http_client
+	devCA      *devCA       // signs for selfsigned providers
+
+	journalMu sync.RWMutex
+	journal   OrderJournal // keeps the orders in progress, nil when they aren't tracked
+}
+
+func NewIssuer(cfg *utils.Config, files storage.FileTable, log *utils.Logger) (*Issuer, error) {
+	log.Debug("Ensuring storage directory exists: ", cfg.Certs.StorageDir)
+	// ensure storage dir exists
+	if err := os.MkdirAll(cfg.Certs.StorageDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to ensure storage dir: %w", err)
+	}
+
+	// private keys are kept in the storage backend, storage_dir by default
+	keys, err := storage.NewBackend(cfg, files, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage backend: %w", err)
+	}
+
+	// load or create ACME user key
+	log.Debug("Loading or creating ACME user key: ", keys.Location(accountKeyFile))
+	priv, err := loadOrCreateAccountKey(keys, accountKeyFile, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load/create acme user key: %w", err)
+	}
+	log.Debug("ACME user key successfully loaded")
+
+	accounts, err := loadAccountStore(filepath.Join(cfg.Certs.StorageDir, "acme_account.json"), log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load acme account: %w", err)
+	}
+
+	// a private CA serves its directory with a certificate of its own root, so does Pebble. Without its root
+	// only the ACME client of the sandbox skips verification, storage and publish targets keep verifying
+	httpConfig := cfg.HTTPClient
+	switch {
+	case cfg.Certs.CARootBundle != "":
+		if httpConfig.RootCAs, err = os.ReadFile(cfg.Certs.CARootBundle); err != nil {
+			return nil, fmt.Errorf("failed to read ca_root_bundle: %w", err)
+		}
+	case cfg.Sandbox.Enabled:
+		log.Warn("Sandbox mode: TLS certificate of the Pebble CA isn't verified, set certs.ca_root_bundle to its root to verify it")
+		httpConfig.InsecureSkipVerify = true
+	}
+	httpClient, err := utils.NewHTTPClient(httpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("http client: %w", err)
+	}
+
+	return &Issuer{
+		cfg:        cfg,
+		log:        log,
+		keys:       keys,
+		accountKey: priv,
+		accounts:   accounts,
+		httpClient: httpClient,
+		devCA:      &devCA{keys: keys, log: log},
+	}, nil
+}
+
+type LegoUser struct {
+	Email        string
+	Registration *registration.Resource
+	PrivateKey   crypto.PrivateKey
+}
+
+func (u *LegoUser) GetEmail() string                        { return u.Email }
+func (u *LegoUser) GetRegistration() *registration.Resource { return u.Registration }
+func (u *LegoUser) GetPrivateKey() crypto.PrivateKey        { return u.PrivateKey }
+
+// Obtain orders a certificate for the domain and its SANs, solving DNS-01 with the provider.
+// An empty keyType uses certs.key_type, an empty profile certs.profile. reuseKey is the PEM key
+// the certificate is ordered for, a new one is generated when it's nil
+func (i *Issuer) Obtain(provider *dnsproviders.Provider, domain string, san []string, keyType, profile string, reuseKey []byte) (*models.CertificateData, error) {
+	if keyType == "" {
+		keyType = i.cfg.Certs.CertKeyType()
+	}
+	if profile == "" {
+		profile = i.cfg.Certs.Profile
+	}
+	i.log.Debug("Obtain(): called",
+		" domain=", domain,
+		" SAN=", san,
+		" provider=", provider.Name,
+		" keyType=", keyType,
+		" profile=", profile,
+		" reuseKey=", reuseKey != nil,
+	)
+	privateKey, err := i.certificateKey(keyType, reuseKey)
+	if err != nil {
+		return nil, err
+	}
+	if validity, ok := provider.SelfSigned(); ok {
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s key can't sign", keyType)
+		}
+		return i.obtainSelfSigned(uniqueDomains(append([]string{domain}, san...)), privateKey, signer.Public(), keyType, validity)
+	}
+
+	// domains list (unique)
+	domains := uniqueDomains(append([]string{domain}, san...))
+	i.log.Debug("Final domain list for certificate: ", domains)
+
+	// a certificate issued right before the process stopped is downloaded instead of ordered again
+	if data := i.resumeOrder(provider, domains, keyType); data != nil {
+		return data, nil
+	}
+
+	// the order is tracked through its client, a crash leaves what's needed to resume it
+	recorder := i.startOrder(domain, domains, provider.Name, keyType, certcrypto.PEMEncode(privateKey))
+	defer recorder.finish()
+	links := &alternateLinks{}
+	client := func() (*lego.Client, error) {
+		lg, err := i.newLegoClient(links.client(recorder.httpClient(i.httpClient)))
+		if err != nil {
+			return nil, err
+		}
+		i.log.Debug("Setting DNS provider...")
+		if err := lg.Challenge.SetDNS01Provider(recorder.provider(provider.Challenge()), provider.ChallengeOptions()...); err != nil {
+			return nil, fmt.Errorf("failed to set dns provider: %w", err)
+		}
+		return lg, nil
+	}
+
+	req := certificate.ObtainRequest{
+		Domains:    domains,
+		Bundle:     true,
+		PrivateKey: privateKey,
+		Profile:    profile,
+	}
+
+	i.log.Debug("Requesting certificate from ACME...")
+	var certRes *certificate.Resource
+	lg, err := client()
+	if err == nil {
+		certRes, err = lg.Certificate.Obtain(req)
+	}
+	if i.refreshAccount(err) {
+		if lg, err = client(); err == nil {
+			certRes, err = lg.Certificate.Obtain(req)
+		}
+	}
+	if err != nil {
+		return nil, obtainError(err)
+	}
+	i.log.Info("Certificate obtained. Parsing validity...")
+	data, err := i.certificateData(certRes, keyType)
+	if err != nil {
+		return nil, err
+	}
+	setAltChains(data, i.alternateChains(lg, links, certRes))
+
+	i.log.Debug("Obtain(): completed successfully")
+	return data, nil
+}
+
+// ObtainForCSR orders a certificate for the names of a CSR whose key stays with the requester,
+// the returned data has no key. An empty profile uses certs.profile
+func (i *Issuer) ObtainForCSR(provider *dnsproviders.Provider, csr *x509.CertificateRequest, profile string) (*models.CertificateData, error) {
+	if profile == "" {
+		profile = i.cfg.Certs.Profile
+	}
+	i.log.Debug("ObtainForCSR(): called",
+		" subject=", csr.Subject.CommonName,
+		" SAN=", csr.DNSNames,
+		" provider=", provider.Name,
+		" profile=", profile,
+	)
+	if validity, ok := provider.SelfSigned(); ok {
+		names := csr.DNSNames
+		if csr.Subject.CommonName != "" {
+			names = uniqueDomains(append([]string{csr.Subject.CommonName}, csr.DNSNames...))
+		}
+		return i.obtainSelfSigned(names, nil, csr.PublicKey, "", validity)
+	}
+
+	req := certificate.ObtainForCSRRequest{
+		CSR:     csr,
+		Bundle:  true,
+		Profile: profile,
+	}
+	obtain := func() (*certificate.Resource, error) {
+		lg, err := i.newLegoClient(i.httpClient)
+		if err != nil {
+			return nil, err
+		}
+		if err := lg.Challenge.SetDNS01Provider(provider.Challenge(), provider.ChallengeOptions()...); err != nil {
+			return nil, fmt.Errorf("failed to set dns provider: %w", err)
+		}
+		return lg.Certificate.ObtainForCSR(req)
+	}
+
+	certRes, err := obtain()
+	if i.refreshAccount(err) {
+		certRes, err = obtain()
+	}
+	if err != nil {
+		return nil, obtainError(err)
+	}
+	i.log.Info("Certificate obtained for CSR")
+	return i.certificateData(certRes, "")
+}
+
+// certificateKey parses the key to reuse, a new key of keyType is generated when there is none. A key
+// that can't be reused fails the order, silently replacing it would break the TLSA records pinning it
+func (i *Issuer) certificateKey(keyType string, reuseKey []byte) (crypto.PrivateKey, error) {
+	kt, ok := keyTypes[keyType]
+	if !ok {
+		return nil, fmt.Errorf("unknown key type %q", keyType)
+	}
+	if reuseKey != nil {
+		key, err := certcrypto.ParsePEMPrivateKey(reuseKey)
+		if err != nil {
+			return nil, fmt.Errorf("stored certificate key can't be reused: %w", err)
+		}
+		if privateKeyType(key) != keyType {
+			return nil, fmt.Errorf("stored certificate key is %s instead of %s, turn reuse_key off for one renewal to replace it", privateKeyType(key), keyType)
+		}
+		i.log.Debug("Reusing the stored certificate key")
+		return key, nil
+	}
+	privateKey, err := certcrypto.GeneratePrivateKey(kt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s certificate key: %w", keyType, err)
+	}
+	return privateKey, nil
+}
+
+// privateKeyType names the key like utils.CertKeyTypes, empty for other keys
+func privateKeyType(key crypto.PrivateKey) string {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return fmt.Sprintf("rsa%d", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		return fmt.Sprintf("ec%d", k.Curve.Params().BitSize)
+	}
+	return ""
+}
+
+// certificateData parses the validity and identity of an issued certificate, a certificate that can't
+// be parsed fails the order since its serial, fingerprint and issuer are what it's tracked by
+func (i *Issuer) certificateData(certRes *certificate.Resource, keyType string) (*models.CertificateData, error) {
+	leaf, err := utils.ParseLeafCertificate(certRes.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("parse issued certificate: %w", err)
+	}
+	data := &models.CertificateData{
+		Cert:  certRes.Certificate,
+		Key:   certRes.PrivateKey,
+		Chain: certRes.IssuerCertificate,
+
+		KeyType:      keyType,
+		ValidFrom:    leaf.NotBefore,
+		ValidTo:      leaf.NotAfter,
+		SerialNumber: utils.CertificateSerial(leaf),
+		Fingerprint:  utils.CertificateFingerprint(leaf),
+		Issuer:       utils.CertificateIssuer(leaf),
+	}
+	i.log.Debug("Parsed certificate validity: ",
+		" from=", data.ValidFrom,
+		" to=", data.ValidTo,
+		" serial=", data.SerialNumber,
+		" fingerprint=", data.Fingerprint,
+	)
+	return data, nil
+}
+
+// Revoke revokes a PEM encoded certificate issued with the ACME account
+func (i *Issuer) Revoke(certPEM []byte) error {
+	i.log.Debug("Revoke(): called")
+	if i.devCA.signedBy(certPEM) {
+		i.log.Info("Certificate was signed by the development CA, there is no CA to revoke it at")
+		return nil
+	}
+	revoke := func() error {
+		lg, err := i.newLegoClient(i.httpClient)
+		if err != nil {
+			return err
+		}
+		return lg.Certificate.Revoke(certPEM)
+	}
+	err := revoke()
+	if i.refreshAccount(err) {
+		err = revoke()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke certificate: %w", err)
+	}
+	i.log.Info("Certificate revoked")
+	return nil
+}
+
+// newLegoClient sets up the ACME account, registered once per CA, the caller sets the challenge provider
+func (i *Issuer) newLegoClient(httpClient *http.Client) (*lego.Client, error) {
+	dirURL := i.directoryURL()
+
+	// prepare user, a stored account is used as is
+	i.log.Debug("Preparing LegoUser with email: ", i.cfg.Certs.Email)
+	user := &LegoUser{
+		Email:        i.cfg.Certs.Email,
+		PrivateKey:   i.key(),
+		Registration: i.accounts.get(dirURL),
+	}
+
+	config := lego.NewConfig(user)
+	config.CADirURL = dirURL
+	config.HTTPClient = httpClient
+	if i.cfg.Certs.ObtainTimeout > 0 {
+		config.Certificate.Timeout = i.cfg.Certs.ObtainTimeout
+	}
+
+	i.log.Debug("Creating lego client with CADir: ", config.CADirURL)
+	lg, err := lego.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lego client: %w", err)
+	}
+	if user.Registration != nil {
+		return lg, nil
+	}
+
+	// ACME account, registered on the first order with the CA
+	reg, err := i.accounts.resolve(lg, dirURL, func() (*registration.Resource, error) {
+		if i.cfg.Certs.EABKeyID != "" && !i.cfg.Sandbox.Enabled {
+			return lg.Registration.RegisterWithExternalAccountBinding(registration.RegisterEABOptions{
+				TermsOfServiceAgreed: true,
+				Kid:                  i.cfg.Certs.EABKeyID,
+				HmacEncoded:          i.cfg.Certs.EABHMACKey,
+			})
+		}
+		return lg.Registration.Register(registration.RegisterOptions{
+			TermsOfServiceAgreed: true,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register acme account: %w", err)
+	}
+	user.Registration = reg
+
+	return lg, nil
+}
+
+// directoryURL is the ACME directory orders go to, Pebble's in sandbox mode
+func (i *Issuer) directoryURL() string {
+	if i.cfg.Sandbox.Enabled {
+		return i.cfg.Sandbox.CADirURL
+	}
+	return i.cfg.Certs.DirectoryURL()
+}
+
+func uniqueDomains(domains []string) []string {
+	seen := map[string]struct{}{}
+	out := []string{}
+	for _, d := range domains {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if _, ok := seen[d]; ok {
+			continue
+		}
+		seen[d] = struct{}{}
+		out = append(out, d)
+	}
+	return out
+}
+
+const rateLimitedErr = "urn:ietf:params:acme:error:rateLimited"
+
+// obtainError tags the failures API clients can act on: a rate limit of the CA, an authorization the
+// CA found invalid and a TXT record that didn't show up in time
+func obtainError(err error) error {
+	err = fmt.Errorf("failed to obtain certificate: %w", err)
+
+	var problem *legoacme.ProblemDetails
+	if errors.As(err, &problem) && problem.Type == rateLimitedErr {
+		return models.WithCode(models.CodeACMERateLimited, err)
+	}
+	// lego's authorization checks fail with these messages, only they count against the failed validations limit
+	if msg := err.Error(); strings.Contains(msg, "invalid challenge:") || strings.Contains(msg, "invalid authorization") {
+		return models.WithCode(models.CodeACMEAuthorizationFailed, err)
+	}
+	// lego's propagation wait gives up with this message, there is no error type for it
+	if strings.Contains(err.Error(), "propagation: time limit exceeded") {
+		return models.WithCode(models.CodeDNSPropagationTimeout, err)
+	}
+	return err
+}
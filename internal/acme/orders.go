@@ -0,0 +1,281 @@
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// OrderJournal keeps the orders in progress, so a restart after a crash can download a certificate
+// that was already issued and remove the challenge records that were left behind
+type OrderJournal interface {
+	// StartOrder stores the order and sets its ID
+	StartOrder(order *models.PendingOrderDTO) error
+	UpdateOrder(order *models.PendingOrderDTO) error
+	// FinishOrder drops the order and its key
+	FinishOrder(order *models.PendingOrderDTO) error
+	// PendingOrder returns the order left for the names on the CA, nil when there is none
+	PendingOrder(caURL string, names []string) (*models.PendingOrderDTO, error)
+}
+
+// pendingDir holds the keys of the orders in progress in the storage backend
+const pendingDir = "pending"
+
+// maxOrderBody bounds the ACME responses read to follow an order
+const maxOrderBody = 1 << 20
+
+// SetOrderJournal sets where the orders in progress are kept, orders aren't tracked without one
+func (i *Issuer) SetOrderJournal(journal OrderJournal) {
+	i.journalMu.Lock()
+	defer i.journalMu.Unlock()
+	i.journal = journal
+}
+
+func (i *Issuer) orderJournal() OrderJournal {
+	i.journalMu.RLock()
+	defer i.journalMu.RUnlock()
+	return i.journal
+}
+
+// startOrder records the order of names and writes its key, nil when orders aren't tracked or the
+// order couldn't be recorded, the order then runs untracked
+func (i *Issuer) startOrder(domain string, names []string, provider, keyType string, key []byte) *orderRecorder {
+	journal := i.orderJournal()
+	if journal == nil {
+		return nil
+	}
+	keyPath := path.Join(pendingDir, fmt.Sprintf("%s-%d.key", strings.TrimPrefix(domain, "*."), time.Now().UnixNano()))
+	if err := i.keys.Write(keyPath, key); err != nil {
+		i.log.Warn("Order of ", domain, " not tracked: ", err)
+		return nil
+	}
+
+	order := &models.PendingOrderDTO{
+		DomainName:  domain,
+		Names:       sortedNames(names),
+		DNSProvider: provider,
+		CAURL:       i.directoryURL(),
+		KeyType:     keyType,
+		KeyPath:     keyPath,
+	}
+	if err := journal.StartOrder(order); err != nil {
+		i.log.Warn("Order of ", domain, " not tracked: ", err)
+		_ = i.keys.Delete(keyPath)
+		return nil
+	}
+	return &orderRecorder{journal: journal, log: i.log, order: order}
+}
+
+// resumeOrder downloads the certificate of an order for the names that was issued before the
+// process stopped, nil when there is none. Other orders left for the names are dropped after their
+// challenge records are removed, a new order reuses the authorizations the CA still has
+func (i *Issuer) resumeOrder(provider *dnsproviders.Provider, names []string, keyType string) *models.CertificateData {
+	journal := i.orderJournal()
+	if journal == nil {
+		return nil
+	}
+	order, err := journal.PendingOrder(i.directoryURL(), sortedNames(names))
+	if err != nil {
+		i.log.Warn("Pending orders not checked: ", err)
+		return nil
+	}
+	if order == nil {
+		return nil
+	}
+	defer func() {
+		if err := journal.FinishOrder(order); err != nil {
+			i.log.Warn("Pending order ", order.ID, " not removed: ", err)
+		}
+	}()
+	if order.DNSProvider == provider.Name {
+		for _, c := range order.Challenges {
+			if err := provider.Challenge().CleanUp(c.Domain, c.Token, c.KeyAuth); err != nil {
+				i.log.Warn("Challenge record of ", c.Domain, " not removed: ", err)
+			}
+		}
+	}
+	if order.CertificateURL == nil || order.KeyType != keyType {
+		i.log.Info("Pending order of ", order.DomainName, " has no certificate, ordering again")
+		return nil
+	}
+
+	key, err := i.keys.Read(order.KeyPath)
+	if err != nil {
+		i.log.Warn("Key of the pending order of ", order.DomainName, " can't be read, ordering again: ", err)
+		return nil
+	}
+	if _, err := certcrypto.ParsePEMPrivateKey(key); err != nil {
+		i.log.Warn("Key of the pending order of ", order.DomainName, " is invalid, ordering again: ", err)
+		return nil
+	}
+	links := &alternateLinks{}
+	lg, err := i.newLegoClient(links.client(i.httpClient))
+	if err != nil {
+		i.log.Warn("Certificate of the pending order of ", order.DomainName, " can't be downloaded, ordering again: ", err)
+		return nil
+	}
+	certRes, err := lg.Certificate.Get(*order.CertificateURL, true)
+	if err != nil {
+		i.log.Warn("Certificate of the pending order of ", order.DomainName, " can't be downloaded, ordering again: ", err)
+		return nil
+	}
+	certRes.PrivateKey = key
+
+	data, err := i.certificateData(certRes, keyType)
+	if err != nil {
+		i.log.Warn("Certificate of the pending order of ", order.DomainName, " is invalid, ordering again: ", err)
+		return nil
+	}
+	i.log.Info("Certificate of the interrupted order of ", order.DomainName, " downloaded")
+	setAltChains(data, i.alternateChains(lg, links, certRes))
+	return data
+}
+
+// orderRecorder saves the progress of one order, failures are logged, the order goes on
+type orderRecorder struct {
+	journal OrderJournal
+	log     *utils.Logger
+
+	mu    sync.Mutex
+	order *models.PendingOrderDTO
+}
+
+func (r *orderRecorder) update(change func(order *models.PendingOrderDTO) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !change(r.order) {
+		return
+	}
+	if err := r.journal.UpdateOrder(r.order); err != nil {
+		r.log.Warn("Progress of the order of ", r.order.DomainName, " not saved: ", err)
+	}
+}
+
+// finish drops the order once lego returned, it removed its challenge records itself
+func (r *orderRecorder) finish() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.journal.FinishOrder(r.order); err != nil {
+		r.log.Warn("Order of ", r.order.DomainName, " not removed from the pending orders: ", err)
+	}
+}
+
+// provider records the challenges of p before they are presented, a crash in between leaves a
+// record to clean up that may not exist, which is harmless
+func (r *orderRecorder) provider(p challenge.Provider) challenge.Provider {
+	if r == nil {
+		return p
+	}
+	return &recordingProvider{Provider: p, recorder: r}
+}
+
+// httpClient follows the order through the responses of the CA: the URL of the order and, once
+// issued, of its certificate
+func (r *orderRecorder) httpClient(client *http.Client) *http.Client {
+	if r == nil {
+		return client
+	}
+	recording := *client
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	recording.Transport = &recordingTransport{base: base, recorder: r}
+	return &recording
+}
+
+type recordingProvider struct {
+	challenge.Provider
+	recorder *orderRecorder
+}
+
+func (p *recordingProvider) Present(domain, token, keyAuth string) error {
+	p.recorder.update(func(order *models.PendingOrderDTO) bool {
+		order.Challenges = append(order.Challenges, models.PendingChallenge{Domain: domain, Token: token, KeyAuth: keyAuth})
+		return true
+	})
+	return p.Provider.Present(domain, token, keyAuth)
+}
+
+func (p *recordingProvider) CleanUp(domain, token, keyAuth string) error {
+	err := p.Provider.CleanUp(domain, token, keyAuth)
+	p.recorder.update(func(order *models.PendingOrderDTO) bool {
+		order.Challenges = slices.DeleteFunc(order.Challenges, func(c models.PendingChallenge) bool {
+			return c.Domain == domain && c.Token == token
+		})
+		return true
+	})
+	return err
+}
+
+// Timeout keeps the propagation timeout of the wrapped provider
+func (p *recordingProvider) Timeout() (timeout, interval time.Duration) {
+	if withTimeout, ok := p.Provider.(challenge.ProviderTimeout); ok {
+		return withTimeout.Timeout()
+	}
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
+
+type recordingTransport struct {
+	base     http.RoundTripper
+	recorder *orderRecorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || req.Method != http.MethodPost || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return resp, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOrderBody))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var order struct {
+		Authorizations []string `json:"authorizations"`
+		Finalize       string   `json:"finalize"`
+		Certificate    string   `json:"certificate"`
+	}
+	if json.Unmarshal(body, &order) != nil || order.Finalize == "" {
+		return resp, nil
+	}
+	location := resp.Header.Get("Location")
+	t.recorder.update(func(pending *models.PendingOrderDTO) bool {
+		changed := false
+		if resp.StatusCode == http.StatusCreated && location != "" && pending.OrderURL == nil {
+			pending.OrderURL = &location
+			changed = true
+		}
+		if order.Certificate != "" && (pending.CertificateURL == nil || *pending.CertificateURL != order.Certificate) {
+			pending.CertificateURL = &order.Certificate
+			changed = true
+		}
+		return changed
+	})
+	return resp, nil
+}
+
+func sortedNames(names []string) []string {
+	out := slices.Clone(names)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
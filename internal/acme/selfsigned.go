@@ -0,0 +1,192 @@
+package acme
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	storage "hephaestus/internal/storage"
+	utils "hephaestus/internal/utils"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+)
+
+const (
+	devCAKeyFile  = "selfsigned_ca.key"
+	devCACertFile = "selfsigned_ca.crt"
+	devCAValidity = 10 * 365 * 24 * time.Hour
+)
+
+// devCA signs the certificates of selfsigned providers, it is created in the storage backend on first
+// use so clients can trust selfsigned_ca.crt once. Its key is kept like the account key
+type devCA struct {
+	keys storage.Backend
+	log  *utils.Logger
+
+	mu   sync.Mutex
+	key  crypto.Signer
+	cert *x509.Certificate
+	pem  []byte
+}
+
+// load returns the CA of the storage backend, a new one is written when there is none
+func (ca *devCA) load() error {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if ca.cert != nil {
+		return nil
+	}
+
+	keyPEM, err := ca.keys.Read(devCAKeyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return ca.create()
+	}
+	if err != nil {
+		return fmt.Errorf("read development CA key: %w", err)
+	}
+	certPEM, err := ca.keys.Read(devCACertFile)
+	if err != nil {
+		return fmt.Errorf("read development CA certificate: %w", err)
+	}
+
+	key, err := certcrypto.ParsePEMPrivateKey(keyPEM)
+	if err != nil {
+		return fmt.Errorf("parse development CA key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return errors.New("development CA key can't sign")
+	}
+	cert, err := utils.ParseLeafCertificate(certPEM)
+	if err != nil {
+		return fmt.Errorf("parse development CA certificate: %w", err)
+	}
+	ca.key, ca.cert, ca.pem = signer, cert, certPEM
+	return nil
+}
+
+func (ca *devCA) create() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate development CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Hephaestus Development CA", Organization: []string{"Hephaestus"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(devCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return fmt.Errorf("create development CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ca.keys.Write(devCAKeyFile, certcrypto.PEMEncode(key)); err != nil {
+		return fmt.Errorf("write development CA key: %w", err)
+	}
+	if err := ca.keys.Write(devCACertFile, certPEM); err != nil {
+		return fmt.Errorf("write development CA certificate: %w", err)
+	}
+	ca.log.Info("Development CA created, trust ", ca.keys.Location(devCACertFile), " to accept selfsigned certificates")
+	ca.key, ca.cert, ca.pem = key, cert, certPEM
+	return nil
+}
+
+// sign issues a certificate for the names to the public key, valid for validity
+func (ca *devCA) sign(names []string, pub crypto.PublicKey, validity time.Duration) (*certificate.Resource, error) {
+	if err := ca.load(); err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	usage := x509.KeyUsageDigitalSignature
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		usage |= x509.KeyUsageKeyEncipherment
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     usage,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, pub, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+
+	// bundled like ACME certificates, leaf then issuer
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return &certificate.Resource{
+		Domain:            names[0],
+		Certificate:       append(leafPEM, ca.pem...),
+		IssuerCertificate: ca.pem,
+	}, nil
+}
+
+// signedBy reports whether the PEM certificate was issued by the development CA, false when there is none yet
+func (ca *devCA) signedBy(certPEM []byte) bool {
+	if _, err := ca.keys.Read(devCACertFile); err != nil || ca.load() != nil {
+		return false
+	}
+	leaf, err := utils.ParseLeafCertificate(certPEM)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(leaf.RawIssuer, ca.cert.RawSubject) && leaf.CheckSignatureFrom(ca.cert) == nil
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+// obtainSelfSigned signs a certificate for the names with the development CA, key is the new
+// certificate key, nil for a CSR whose key stays with the requester
+func (i *Issuer) obtainSelfSigned(names []string, key crypto.PrivateKey, pub crypto.PublicKey, keyType string, validity time.Duration) (*models.CertificateData, error) {
+	i.log.Debug("Signing certificate locally for ", names)
+	certRes, err := i.devCA.sign(names, pub, validity)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		certRes.PrivateKey = certcrypto.PEMEncode(key)
+	}
+	i.log.Info("Certificate signed by the development CA")
+	return i.certificateData(certRes, keyType)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	models "hephaestus/internal/models"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func (c *Controller) withAdmin(handler func(w http.ResponseWriter, r *http.Request, token string, user models.Identity)) http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		if !c.Service.IsAdmin(user.UserID) {
+			WriteProblem(w, http.StatusForbidden, "", "admin access required")
+			return
+		}
+		handler(w, r, token, user)
+	})
+}
+
+func (c *Controller) HandleGetQueryStats() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		writeJSON(w, c.Service.GetQueryStats())
+	})
+}
+
+func (c *Controller) HandleGetConfig() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		writeJSON(w, c.Service.GetConfig())
+	})
+}
+
+func (c *Controller) HandleGetFeatures() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		writeJSON(w, c.Service.GetFeatures())
+	})
+}
+
+// HandleGetRateLimitBudget reports the usage of the CA's rate limits, ?domain= narrows it to one registered domain
+func (c *Controller) HandleGetRateLimitBudget() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		budget, err := c.Service.GetRateLimitBudget(r.URL.Query().Get("domain"))
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, budget)
+	})
+}
+
+func (c *Controller) HandleGetMaintenance() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		writeJSON(w, c.Service.Maintenance())
+	})
+}
+
+func (c *Controller) HandleSetMaintenance() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.SetMaintenanceReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+
+		state, err := c.Service.SetMaintenance(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, state)
+	})
+}
+
+// HandleDeactivateAcmeAccount deactivates the ACME account at the CA, body {"domains": "reassign"|"block"}
+func (c *Controller) HandleDeactivateAcmeAccount() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.DeactivateAccountReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+
+		resp, err := c.Service.DeactivateAcmeAccount(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, resp)
+	})
+}
+
+// WithMaintenance rejects every mutation while the instance is read-only, reads and the maintenance
+// switch itself stay available
+func (c *Controller) WithMaintenance(next http.Handler, switchPath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+		if readOnly || r.URL.Path == switchPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		state := c.Service.Maintenance()
+		if !state.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		detail := "service is in read-only maintenance mode"
+		if state.Reason != "" {
+			detail += ": " + state.Reason
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(state.RetryAfter))
+		WriteProblem(w, http.StatusServiceUnavailable, models.CodeMaintenance, detail)
+	})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"errors"
+	models "hephaestus/internal/models"
 	services "hephaestus/internal/services"
 	utils "hephaestus/internal/utils"
 	"net/http"
@@ -37,21 +38,21 @@ func fetchAuthorizationHeader(r *http.Request) (string, error) {
 	return token, nil
 }
 
-func (c *Controller) withAuth(handler func(w http.ResponseWriter, r *http.Request, token string, id string)) http.HandlerFunc {
+func (c *Controller) withAuth(handler func(w http.ResponseWriter, r *http.Request, token string, user models.Identity)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token, err := fetchAuthorizationHeader(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, err, http.StatusUnauthorized)
 			return
 		}
 
-		id, err := c.Service.Validate(token)
+		user, err := c.Service.Validate(token)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeError(w, err, http.StatusUnauthorized)
 			return
 		}
 
-		handler(w, r, token, id)
+		handler(w, r, token, user)
 	}
 }
 
@@ -59,3 +60,83 @@ func writeJSON(w http.ResponseWriter, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
 }
+
+// codeStatus is the HTTP status of every code services tag their errors with
+var codeStatus = map[string]int{
+	models.CodeDomainExists:            http.StatusConflict,
+	models.CodeDomainConflict:          http.StatusConflict,
+	models.CodeIssuanceInProgress:      http.StatusConflict,
+	models.CodeNameserverMismatch:      http.StatusUnprocessableEntity,
+	models.CodeRateLimitBudget:         http.StatusTooManyRequests,
+	models.CodeDomainNotFound:          http.StatusNotFound,
+	models.CodeProviderNotFound:        http.StatusNotFound,
+	models.CodeACMERateLimited:         http.StatusTooManyRequests,
+	models.CodeACMEAuthorizationFailed: http.StatusUnprocessableEntity,
+	models.CodeDNSPropagationTimeout:   http.StatusGatewayTimeout,
+}
+
+// statusCode is the code of errors nobody tagged
+var statusCode = map[int]string{
+	http.StatusBadRequest:       models.CodeBadRequest,
+	http.StatusUnauthorized:     models.CodeUnauthorized,
+	http.StatusForbidden:        models.CodeForbidden,
+	http.StatusNotFound:         models.CodeNotFound,
+	http.StatusMethodNotAllowed: models.CodeMethodNotAllowed,
+}
+
+// writeError answers with an application/problem+json body. Validation errors list the invalid fields,
+// conflicts the clashing domains, errors tagged with a code get the status of that code and status otherwise
+func writeError(w http.ResponseWriter, err error, status int) {
+	var verr *models.ValidationError
+	var cerr *models.ConflictError
+	switch {
+	case errors.As(err, &verr):
+		p := newProblem(http.StatusBadRequest, models.CodeValidationFailed, "request has invalid fields")
+		p.Errors = verr.Errors
+		writeProblemJSON(w, p)
+	case errors.As(err, &cerr):
+		p := newProblem(http.StatusConflict, models.CodeDomainConflict, "domains are already covered by other domains")
+		p.Conflicts = cerr.Conflicts
+		writeProblemJSON(w, p)
+	default:
+		code := models.ErrorCode(err)
+		if s, ok := codeStatus[code]; ok {
+			status = s
+		}
+		WriteProblem(w, status, code, err.Error())
+	}
+}
+
+// WriteProblem answers with a problem+json body, an empty code is derived from status
+func WriteProblem(w http.ResponseWriter, status int, code, detail string) {
+	writeProblemJSON(w, newProblem(status, code, detail))
+}
+
+func newProblem(status int, code, detail string) models.Problem {
+	if code == "" {
+		code = statusCode[status]
+	}
+	if code == "" {
+		code = models.CodeInternal
+	}
+	return models.Problem{
+		Type:   "urn:hephaestus:error:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func writeProblemJSON(w http.ResponseWriter, p models.Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -4,23 +4,37 @@ import (
 	"encoding/json"
 	models "hephaestus/internal/models"
 	utils "hephaestus/internal/utils"
+	"io"
+	"mime"
 	"net/http"
 )
 
 func (c *Controller) HandleGetDomains() http.HandlerFunc {
-	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, userid string) {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
 		query := r.URL.Query()
 		filters := models.GetDomainsReq{
 			Status:     query.Get("status"),
 			DomainName: query.Get("domain_name"),
+			Fuzzy:      utils.GetDefaultBoolQueryValue(query, "fuzzy", false),
+			Tags:       utils.GetListQueryValue(query, "tags"),
 			PageSize:   utils.GetDefaultIntegerQueryValue(query, "page_size", 10),
 			Page:       utils.GetDefaultIntegerQueryValue(query, "page", 1),
 		}
-		filters.UserID = userid
+		filters.UserID = user.UserID
+		filters.TenantID = user.TenantID
+
+		if cursor := query.Get("cursor"); cursor != "" {
+			decoded, err := models.DecodeDomainsCursor(cursor)
+			if err != nil {
+				writeError(w, err, http.StatusBadRequest)
+				return
+			}
+			filters.Cursor = decoded
+		}
 
 		domains, err := c.Service.GetDomains(filters)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 		writeJSON(w, domains)
@@ -28,17 +42,18 @@ func (c *Controller) HandleGetDomains() http.HandlerFunc {
 }
 
 func (c *Controller) HandleCreateDomain() http.HandlerFunc {
-	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, userid string) {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
 		var req models.CreateDomainReq
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, err, http.StatusBadRequest)
 			return
 		}
-		req.CreatedBy = userid
+		req.CreatedBy = user.UserID
+		req.TenantID = user.TenantID
 
 		domainID, err := c.Service.CreateDomain(req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 
@@ -47,15 +62,100 @@ func (c *Controller) HandleCreateDomain() http.HandlerFunc {
 	})
 }
 
+func (c *Controller) HandleIssueFromCSR() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.IssueFromCSRReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+		req.TenantID = user.TenantID
+
+		issued, err := c.Service.IssueFromCSR(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSONStatus(w, http.StatusCreated, issued)
+	})
+}
+
+// maxImportSize limits the body of an import
+const maxImportSize = 10 << 20
+
+func (c *Controller) HandleImportDomains() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = importFormat(r.Header.Get("Content-Type"))
+		}
+		if format == "" {
+			WriteProblem(w, http.StatusBadRequest, "", "missing format, set format=csv|json or the Content-Type")
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		resp, err := c.Service.ImportDomains(models.ImportDomainsReq{
+			CreatedBy:   user.UserID,
+			TenantID:    user.TenantID,
+			Format:      format,
+			Data:        data,
+			DNSProvider: query.Get("dns_provider"),
+			// up to maxImportRows orders don't fit in a request, the renewal cycle issues the certificates
+			DeferIssuance: true,
+		})
+		if err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, resp)
+	})
+}
+
+// importFormat guesses the import format from the Content-Type
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return "csv"
+	case "application/json":
+		return "json"
+	}
+	return ""
+}
+
+func (c *Controller) HandleUpdateDomain() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.UpdateDomainReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UpdatedBy = user.UserID
+		req.TenantID = user.TenantID
+
+		if err := c.Service.UpdateDomain(req); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Domain updated successfully"})
+	})
+}
+
 func (c *Controller) HandleDeleteDomain() http.HandlerFunc {
-	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, userid string) {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
 		query := r.URL.Query()
 		domID := query.Get("domain_id")
 		domName := query.Get("domain_name")
-		if domID == "" && domName == "" {
-			http.Error(w, "missing domain_id or domain_name", http.StatusBadRequest)
-			return
-		}
 		var filters models.DeleteDomainReq
 		if domID != "" {
 			filters.DomainID = domID
@@ -63,11 +163,12 @@ func (c *Controller) HandleDeleteDomain() http.HandlerFunc {
 		if domName != "" {
 			filters.DomainName = domName
 		}
-		filters.UserID = userid
+		filters.UserID = user.UserID
+		filters.TenantID = user.TenantID
 
 		err := c.Service.DeleteDomain(filters)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err, http.StatusInternalServerError)
 			return
 		}
 
@@ -75,3 +176,138 @@ func (c *Controller) HandleDeleteDomain() http.HandlerFunc {
 		json.NewEncoder(w).Encode(map[string]string{"message": "Domain deleted successfully"})
 	})
 }
+
+func (c *Controller) HandleGetCertificates() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		query := r.URL.Query()
+		req := models.GetCertificatesReq{
+			DomainID:   query.Get("domain_id"),
+			DomainName: query.Get("domain_name"),
+			TenantID:   user.TenantID,
+		}
+
+		certs, err := c.Service.GetCertificates(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, certs)
+	})
+}
+
+func (c *Controller) HandleCheckCertificateDrift() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		req := models.CheckDriftReq{
+			DomainName: r.URL.Query().Get("domain_name"),
+			TenantID:   user.TenantID,
+		}
+
+		drift, err := c.Service.CheckCertificateDrift(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, drift)
+	})
+}
+
+func (c *Controller) HandleListArchivedCertificates() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		req := models.ArchivedCertificatesReq{
+			DomainName: r.URL.Query().Get("domain_name"),
+			TenantID:   user.TenantID,
+		}
+
+		versions, err := c.Service.ListArchivedCertificates(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, versions)
+	})
+}
+
+func (c *Controller) HandleRestoreCertificate() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.RestoreCertificateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+		req.TenantID = user.TenantID
+
+		if err := c.Service.RestoreCertificate(req); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Certificate restored successfully"})
+	})
+}
+
+func (c *Controller) HandleSwitchCertificateChain() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.SwitchChainReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+		req.TenantID = user.TenantID
+
+		if err := c.Service.SwitchCertificateChain(req); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Certificate chain switched successfully"})
+	})
+}
+
+// HandleExportCertificate answers with the file itself instead of JSON, errors stay JSON
+func (c *Controller) HandleExportCertificate() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.ExportCertificateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+		req.TenantID = user.TenantID
+
+		export, err := c.Service.ExportCertificate(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", export.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename}))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(export.Data)
+	})
+}
+
+func (c *Controller) HandleActivateCertificate() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.ActivateCertificateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+		req.TenantID = user.TenantID
+
+		if err := c.Service.ActivateCertificate(req); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Certificate activated successfully"})
+	})
+}
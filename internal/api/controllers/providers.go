@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"encoding/json"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"net/http"
+)
+
+func (c *Controller) HandleRegisterDNSProvider() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req utils.ProviderConfig
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err := c.Service.RegisterDNSProvider(req, user.UserID); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"message": "DNS provider registered successfully", "name": req.Name})
+	})
+}
+
+func (c *Controller) HandleDeleteDNSProvider() http.HandlerFunc {
+	return c.withAdmin(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			WriteProblem(w, http.StatusBadRequest, "", "missing name")
+			return
+		}
+
+		if err := c.Service.DeleteDNSProvider(name, user.UserID); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "DNS provider deleted successfully"})
+	})
+}
+
+func (c *Controller) HandleDelegateAcmeDNS() http.HandlerFunc {
+	return c.withAuth(func(w http.ResponseWriter, r *http.Request, token string, user models.Identity) {
+		var req models.DelegateAcmeDNSReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+		req.UserID = user.UserID
+		req.TenantID = user.TenantID
+
+		delegation, err := c.Service.DelegateAcmeDNS(req)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, delegation)
+	})
+}
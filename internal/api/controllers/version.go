@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	version "hephaestus/internal/version"
+	"net/http"
+)
+
+// HandleGetVersion is public, it identifies the running build for operators and bug reports
+func (c *Controller) HandleGetVersion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, version.Get())
+	}
+}
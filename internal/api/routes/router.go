@@ -14,17 +14,93 @@ func CreateRoutes(service services.ServiceInterface, cfg *utils.Config, log *uti
 		return nil, errors.New("service is nil")
 	}
 
-	domains := controllers.NewController(service, cfg, log)
+	controller := controllers.NewController(service, cfg, log)
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/hephaestus/api/v1/domains", methodRouter(map[string]http.HandlerFunc{
-		http.MethodGet:    domains.HandleGetDomains(),
-		http.MethodPost:   domains.HandleCreateDomain(),
-		http.MethodDelete: domains.HandleDeleteDomain(),
+		http.MethodGet:    controller.HandleGetDomains(),
+		http.MethodPost:   controller.HandleCreateDomain(),
+		http.MethodPatch:  controller.HandleUpdateDomain(),
+		http.MethodDelete: controller.HandleDeleteDomain(),
 	}))
 
-	return mux, nil
+	mux.Handle("/hephaestus/api/v1/domains/import", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleImportDomains(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/certificates/csr", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleIssueFromCSR(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleGetCertificates(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates/activate", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleActivateCertificate(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates/chain", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleSwitchCertificateChain(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates/export", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleExportCertificate(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates/archive", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleListArchivedCertificates(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates/archive/restore", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleRestoreCertificate(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/domains/certificates/drift", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleCheckCertificateDrift(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/providers", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost:   controller.HandleRegisterDNSProvider(),
+		http.MethodDelete: controller.HandleDeleteDNSProvider(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/providers/acmedns/delegate", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleDelegateAcmeDNS(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/version", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleGetVersion(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/admin/db/stats", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleGetQueryStats(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/admin/config", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleGetConfig(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/admin/features", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleGetFeatures(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/admin/rate-limits", methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet: controller.HandleGetRateLimitBudget(),
+	}))
+
+	mux.Handle("/hephaestus/api/v1/admin/acme-account/deactivate", methodRouter(map[string]http.HandlerFunc{
+		http.MethodPost: controller.HandleDeactivateAcmeAccount(),
+	}))
+
+	const maintenancePath = "/hephaestus/api/v1/admin/maintenance"
+	mux.Handle(maintenancePath, methodRouter(map[string]http.HandlerFunc{
+		http.MethodGet:  controller.HandleGetMaintenance(),
+		http.MethodPost: controller.HandleSetMaintenance(),
+	}))
+
+	return controller.WithMaintenance(mux, maintenancePath), nil
 }
 
 func methodRouter(routes map[string]http.HandlerFunc) http.Handler {
@@ -33,6 +109,6 @@ func methodRouter(routes map[string]http.HandlerFunc) http.Handler {
 			h(w, r)
 			return
 		}
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		controllers.WriteProblem(w, http.StatusMethodNotAllowed, "", "method not allowed")
 	})
 }
@@ -0,0 +1,173 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// an acme-dns server answers the challenges of every domain that delegates _acme-challenge to it
+// with a CNAME, the zone itself stays wherever it is hosted. Each domain has its own account on
+// the server, registered on first use and kept in the database through an AcmeDNSStore
+
+// AcmeDNSAccount is what /register of acme-dns returns, FullDomain is the CNAME target
+type AcmeDNSAccount struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Subdomain  string `json:"subdomain"`
+	FullDomain string `json:"fulldomain"`
+}
+
+// AcmeDNSStore keeps the acme-dns accounts of the domains per server
+type AcmeDNSStore interface {
+	// AcmeDNSAccount returns nil when the domain has no account on the server yet
+	AcmeDNSAccount(ctx context.Context, serverURL, domain string) (*AcmeDNSAccount, error)
+	// SaveAcmeDNSAccount returns the stored account, the one of another replica when it registered first
+	SaveAcmeDNSAccount(ctx context.Context, serverURL, domain string, account AcmeDNSAccount) (*AcmeDNSAccount, error)
+}
+
+// AcmeDNSCNAME is the record delegating the challenge of a domain to its acme-dns account
+type AcmeDNSCNAME struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+}
+
+// AcmeDNSCNAMEError fails the challenge until the CNAME is in place
+type AcmeDNSCNAMEError struct {
+	AcmeDNSCNAME
+}
+
+func (e *AcmeDNSCNAMEError) Error() string {
+	return fmt.Sprintf("create the CNAME record %s pointing to %s, then retry", e.Name, e.Target)
+}
+
+type acmeDNSProvider struct {
+	propagation
+	api       *restClient
+	serverURL string
+	allowFrom []string
+
+	mu    sync.RWMutex
+	store AcmeDNSStore
+}
+
+func newAcmeDNS(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.AcmeDNS == nil || provider.AcmeDNS.ServerURL == "" {
+		return nil, errors.New("acmedns server_url is missing")
+	}
+	return &acmeDNSProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api:         newRESTClient(httpClient, provider.AcmeDNS.ServerURL, nil),
+		serverURL:   strings.TrimSuffix(provider.AcmeDNS.ServerURL, "/"),
+		allowFrom:   provider.AcmeDNS.AllowFrom,
+	}, nil
+}
+
+func (p *acmeDNSProvider) setStore(store AcmeDNSStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.store = store
+}
+
+func (p *acmeDNSProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	// a domain and its wildcard share the challenge name and so the account
+	domain = strings.TrimPrefix(domain, "*.")
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+
+	account, registered, err := p.account(ctx, domain)
+	if err != nil {
+		return fmt.Errorf("acmedns: %w", err)
+	}
+	cname := &AcmeDNSCNAMEError{AcmeDNSCNAME{Name: dns01.UnFqdn(info.FQDN), Target: account.FullDomain}}
+	if registered {
+		return cname
+	}
+	// a missing delegation would only show up as a failed validation at the CA
+	target, err := net.DefaultResolver.LookupCNAME(ctx, info.FQDN)
+	if err != nil || !strings.EqualFold(dns01.UnFqdn(target), account.FullDomain) {
+		return cname
+	}
+
+	// acme-dns keeps the two latest values, enough for a domain and its wildcard
+	update := map[string]string{"subdomain": account.Subdomain, "txt": info.Value}
+	if err := p.accountClient(account).do(ctx, http.MethodPost, "/update", update, nil); err != nil {
+		return fmt.Errorf("acmedns: update record: %w", err)
+	}
+	return nil
+}
+
+// CleanUp has nothing to do, the next update replaces the values
+func (p *acmeDNSProvider) CleanUp(domain, token, keyAuth string) error {
+	return nil
+}
+
+// account returns the account of domain, registered is true when it was registered by this call.
+// Wildcards must already be stripped from domain
+func (p *acmeDNSProvider) account(ctx context.Context, domain string) (account *AcmeDNSAccount, registered bool, err error) {
+	p.mu.RLock()
+	store := p.store
+	p.mu.RUnlock()
+	if store == nil {
+		return nil, false, errors.New("no account store")
+	}
+
+	if account, err = store.AcmeDNSAccount(ctx, p.serverURL, domain); err != nil {
+		return nil, false, fmt.Errorf("get account: %w", err)
+	}
+	if account != nil {
+		return account, false, nil
+	}
+
+	var created AcmeDNSAccount
+	var body any
+	if len(p.allowFrom) > 0 {
+		body = map[string][]string{"allowfrom": p.allowFrom}
+	}
+	if err := p.api.do(ctx, http.MethodPost, "/register", body, &created); err != nil {
+		return nil, false, fmt.Errorf("register: %w", err)
+	}
+	if account, err = store.SaveAcmeDNSAccount(ctx, p.serverURL, domain, created); err != nil {
+		return nil, false, fmt.Errorf("save account: %w", err)
+	}
+	return account, true, nil
+}
+
+func (p *acmeDNSProvider) accountClient(account *AcmeDNSAccount) *restClient {
+	return newRESTClient(p.api.http, p.serverURL, func(req *http.Request, _ []byte) error {
+		req.Header.Set("X-Api-User", account.Username)
+		req.Header.Set("X-Api-Key", account.Password)
+		return nil
+	})
+}
+
+// AcmeDNSDelegate registers the domain on the acme-dns server of the provider when needed and
+// returns the CNAME record to create
+func (p *Provider) AcmeDNSDelegate(ctx context.Context, domain string) (AcmeDNSCNAME, error) {
+	acmeDNS, ok := p.challenge.(*acmeDNSProvider)
+	if !ok {
+		return AcmeDNSCNAME{}, fmt.Errorf("DNS provider '%s' is not an acmedns provider", p.Name)
+	}
+	domain = strings.TrimPrefix(domain, "*.")
+	account, _, err := acmeDNS.account(ctx, domain)
+	if err != nil {
+		return AcmeDNSCNAME{}, err
+	}
+	info := dns01.GetChallengeInfo(domain, "")
+	return AcmeDNSCNAME{Name: dns01.UnFqdn(info.FQDN), Target: account.FullDomain}, nil
+}
+
+// setAcmeDNSStore hands the account store to acme-dns providers, other types ignore it
+func (p *Provider) setAcmeDNSStore(store AcmeDNSStore) {
+	if acmeDNS, ok := p.challenge.(*acmeDNSProvider); ok {
+		acmeDNS.setStore(store)
+	}
+}
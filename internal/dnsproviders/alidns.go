@@ -0,0 +1,146 @@
+package dnsproviders
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+const (
+	aliDNSBaseURL = "https://alidns.aliyuncs.com"
+	aliDNSVersion = "2015-01-09"
+	// the minimum TTL of the free edition
+	aliDNSMinTTL = 600
+)
+
+type aliDNSProvider struct {
+	propagation
+	api       *restClient
+	accessKey string
+	secretKey string
+}
+
+func newAliDNS(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.AliDNS == nil {
+		return nil, errors.New("alidns block is missing")
+	}
+	if provider.AliDNS.AccessKey == "" || provider.AliDNS.SecretKey == "" {
+		return nil, errors.New("alidns access_key and secret_key are required")
+	}
+	baseURL := aliDNSBaseURL
+	if provider.AliDNS.RegionID != "" {
+		baseURL = "https://alidns." + provider.AliDNS.RegionID + ".aliyuncs.com"
+	}
+
+	p := &aliDNSProvider{
+		// AliDNS nameservers usually serve a change within a minute, some regions take longer
+		propagation: newPropagation(provider, cfg.Certs, 5*time.Minute, 10*time.Second),
+		accessKey:   provider.AliDNS.AccessKey,
+		secretKey:   provider.AliDNS.SecretKey,
+	}
+	p.api = newRESTClient(httpClient, baseURL, p.sign)
+	return p, nil
+}
+
+func (p *aliDNSProvider) Present(domain, token, keyAuth string) error {
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("alidns: %w", err)
+	}
+	params := url.Values{
+		"DomainName": {zone},
+		"RR":         {name},
+		"Type":       {"TXT"},
+		"Value":      {value},
+		"TTL":        {strconv.Itoa(aliDNSMinTTL)},
+	}
+	if err := p.call(context.Background(), "AddDomainRecord", params, nil); err != nil {
+		return fmt.Errorf("alidns: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *aliDNSProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("alidns: %w", err)
+	}
+
+	var list struct {
+		DomainRecords struct {
+			Record []struct {
+				RecordID string `json:"RecordId"`
+				Value    string `json:"Value"`
+			} `json:"Record"`
+		} `json:"DomainRecords"`
+	}
+	params := url.Values{"SubDomain": {name + "." + zone}, "Type": {"TXT"}, "PageSize": {"500"}}
+	if err := p.call(ctx, "DescribeSubDomainRecords", params, &list); err != nil {
+		return fmt.Errorf("alidns: list records: %w", err)
+	}
+	for _, r := range list.DomainRecords.Record {
+		if strings.Trim(r.Value, `"`) != value {
+			continue
+		}
+		if err := p.call(ctx, "DeleteDomainRecord", url.Values{"RecordId": {r.RecordID}}, nil); err != nil {
+			return fmt.Errorf("alidns: delete record: %w", err)
+		}
+	}
+	return nil
+}
+
+// call runs an RPC action, its parameters travel in the query and are signed by sign
+func (p *aliDNSProvider) call(ctx context.Context, action string, params url.Values, out any) error {
+	params.Set("Action", action)
+	return p.api.do(ctx, http.MethodGet, "/?"+params.Encode(), nil, out)
+}
+
+// sign adds the common parameters and the HMAC-SHA1 signature of Alibaba Cloud's RPC APIs (signature version 1.0)
+func (p *aliDNSProvider) sign(req *http.Request, _ []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	query := req.URL.Query()
+	query.Set("Format", "JSON")
+	query.Set("Version", aliDNSVersion)
+	query.Set("AccessKeyId", p.accessKey)
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("SignatureNonce", hex.EncodeToString(nonce))
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	query.Del("Signature")
+	query.Set("Signature", aliSignature(req.Method, query, p.secretKey))
+
+	req.URL.RawQuery = aliPercentEncode(query.Encode())
+	return nil
+}
+
+// aliSignature signs the method and the query parameters with the secret key
+func aliSignature(method string, query url.Values, secretKey string) string {
+	// url.Values.Encode sorts by key, only the escaping differs from what the API expects
+	canonical := aliPercentEncode(query.Encode())
+	stringToSign := method + "&" + url.QueryEscape("/") + "&" + url.QueryEscape(canonical)
+	mac := hmac.New(sha1.New, []byte(secretKey+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliPercentEncode turns form escaping into the RFC 3986 escaping of the signature
+func aliPercentEncode(s string) string {
+	return strings.NewReplacer("+", "%20", "*", "%2A", "%7E", "~").Replace(s)
+}
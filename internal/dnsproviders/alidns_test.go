@@ -0,0 +1,62 @@
+package dnsproviders
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestAliDNSPresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+
+	var actions []url.Values
+	_, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /": func(w http.ResponseWriter, r *http.Request, body []byte) {
+			query := r.URL.Query()
+			signature := query.Get("Signature")
+			query.Del("Signature")
+			if signature != aliSignature(r.Method, query, "testsecret") {
+				reply(http.StatusBadRequest, map[string]string{"Code": "SignatureDoesNotMatch"})(w, r, body)
+				return
+			}
+			actions = append(actions, query)
+			switch query.Get("Action") {
+			case "DescribeSubDomainRecords":
+				reply(http.StatusOK, map[string]any{"DomainRecords": map[string]any{"Record": []map[string]string{
+					{"RecordId": "1", "Value": value},
+					{"RecordId": "2", "Value": "another-order"},
+				}}})(w, r, body)
+			default:
+				reply(http.StatusOK, map[string]string{"RequestId": "r"})(w, r, body)
+			}
+		},
+	})
+	p, err := newAliDNS(utils.ProviderConfig{Name: "alidns", AliDNS: &utils.AliDNSConfig{AccessKey: "testid", SecretKey: "testsecret"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*aliDNSProvider).api, server, "")
+
+	if err := p.Present("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CleanUp("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 3 {
+		t.Fatalf("actions %v", actions)
+	}
+	add, list, del := actions[0], actions[1], actions[2]
+	if add.Get("Action") != "AddDomainRecord" || add.Get("DomainName") != "example.com" || add.Get("RR") != "_acme-challenge.www" || add.Get("Value") != value || add.Get("Type") != "TXT" {
+		t.Errorf("add %v", add)
+	}
+	if list.Get("Action") != "DescribeSubDomainRecords" || list.Get("SubDomain") != "_acme-challenge.www.example.com" {
+		t.Errorf("list %v", list)
+	}
+	if del.Get("Action") != "DeleteDomainRecord" || del.Get("RecordId") != "1" {
+		t.Errorf("delete %v, want only the challenge record", del)
+	}
+}
@@ -0,0 +1,26 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	cf "github.com/go-acme/lego/v4/providers/dns/cloudflare"
+)
+
+func newCloudflare(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Cloudflare == nil {
+		return nil, errors.New("cloudflare block is missing")
+	}
+	config := cf.NewDefaultConfig()
+	config.AuthToken = provider.Cloudflare.Token
+	config.ZoneToken = provider.Cloudflare.ZoneToken
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := cf.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
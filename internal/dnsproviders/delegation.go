@@ -0,0 +1,109 @@
+package dnsproviders
+
+import (
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// delegation sends the challenge records of names whose _acme-challenge is a CNAME into zone
+// to another provider, the account hosting that zone
+type delegation struct {
+	zone     string
+	provider string
+}
+
+func delegationsOf(provider utils.ProviderConfig) []delegation {
+	out := make([]delegation, 0, len(provider.Delegations))
+	for _, d := range provider.Delegations {
+		out = append(out, delegation{zone: strings.ToLower(dns01.UnFqdn(d.Zone)), provider: d.Provider})
+	}
+	return out
+}
+
+// delegatingProvider presents the record with the provider of the zone the _acme-challenge CNAME
+// points to, names without a CNAME or pointing to a zone without delegation stay with own
+type delegatingProvider struct {
+	owner *Provider
+}
+
+func (d *delegatingProvider) Present(domain, token, keyAuth string) error {
+	target, err := d.owner.challengeFor(domain)
+	if err != nil {
+		return err
+	}
+	return target.Present(domain, token, keyAuth)
+}
+
+func (d *delegatingProvider) CleanUp(domain, token, keyAuth string) error {
+	target, err := d.owner.challengeFor(domain)
+	if err != nil {
+		return err
+	}
+	return target.CleanUp(domain, token, keyAuth)
+}
+
+// Timeout is the longest of the provider and the ones it delegates to, lego asks once per order
+func (d *delegatingProvider) Timeout() (timeout, interval time.Duration) {
+	timeout, interval = timeoutOf(d.owner.challenge)
+	for _, del := range d.owner.delegations {
+		target, err := d.owner.lookup(del.provider)
+		if err != nil {
+			continue
+		}
+		t, i := timeoutOf(target.challenge)
+		timeout, interval = max(timeout, t), max(interval, i)
+	}
+	return timeout, interval
+}
+
+func timeoutOf(p challenge.Provider) (time.Duration, time.Duration) {
+	if withTimeout, ok := p.(challenge.ProviderTimeout); ok {
+		return withTimeout.Timeout()
+	}
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
+
+// delegatedTo returns the provider of the delegation matching the CNAME target of the challenge
+// record of domain, nil when the record isn't delegated
+func (p *Provider) delegatedTo(domain string) (*Provider, error) {
+	if len(p.delegations) == 0 {
+		return nil, nil
+	}
+	info := dns01.GetChallengeInfo(strings.TrimPrefix(domain, "*."), "")
+	if info.EffectiveFQDN == info.FQDN {
+		return nil, nil
+	}
+	effective := strings.ToLower(dns01.UnFqdn(info.EffectiveFQDN))
+	for _, d := range p.delegations {
+		if effective != d.zone && !strings.HasSuffix(effective, "."+d.zone) {
+			continue
+		}
+		if p.lookup == nil {
+			return nil, fmt.Errorf("DNS provider '%s' delegates %s to '%s' which isn't loaded", p.Name, d.zone, d.provider)
+		}
+		target, err := p.lookup(d.provider)
+		if err != nil {
+			return nil, fmt.Errorf("delegation of %s by '%s': %w", d.zone, p.Name, err)
+		}
+		return target, nil
+	}
+	return nil, nil
+}
+
+func (p *Provider) challengeFor(domain string) (challenge.Provider, error) {
+	target, err := p.delegatedTo(domain)
+	if err != nil || target == nil {
+		return p.challenge, err
+	}
+	return target.challenge, nil
+}
+
+// setLookup lets the provider find the ones it delegates to
+func (p *Provider) setLookup(lookup func(name string) (*Provider, error)) {
+	p.lookup = lookup
+}
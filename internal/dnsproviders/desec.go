@@ -0,0 +1,125 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+const (
+	desecBaseURL = "https://desec.io/api/v1"
+	// deSEC rejects TTLs below the minimum of the domain, 3600 unless raised by support
+	desecMinTTL = 3600
+	// the per-domain limits allow about one change a second
+	desecRateLimitRetries = 5
+)
+
+type desecProvider struct {
+	propagation
+	api *restClient
+}
+
+// desecRRSet holds all TXT values of a name, the apex and wildcard challenges of a domain share one
+type desecRRSet struct {
+	Subname string   `json:"subname"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl,omitempty"`
+	Records []string `json:"records"`
+}
+
+func newDeSEC(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.DeSEC == nil || provider.DeSEC.Token == "" {
+		return nil, errors.New("desec token is missing")
+	}
+	api := newRESTClient(httpClient, desecBaseURL, bearer("Token", provider.DeSEC.Token))
+	api.rateLimitRetries = desecRateLimitRetries
+	return &desecProvider{
+		// changes reach deSEC's anycast nameservers within a minute
+		propagation: newPropagation(provider, cfg.Certs, 3*time.Minute, 5*time.Second),
+		api:         api,
+	}, nil
+}
+
+func (p *desecProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("desec: %w", err)
+	}
+	record := strconv.Quote(value)
+
+	rrset, err := p.rrset(ctx, zone, name)
+	if err != nil {
+		return fmt.Errorf("desec: %w", err)
+	}
+	if rrset == nil {
+		rrset = &desecRRSet{Subname: name, Type: "TXT", TTL: desecMinTTL, Records: []string{record}}
+		if err := p.api.do(ctx, http.MethodPost, "/domains/"+url.PathEscape(zone)+"/rrsets/", rrset, nil); err != nil {
+			return fmt.Errorf("desec: create record: %w", err)
+		}
+		return nil
+	}
+
+	rrset.Records = append(rrset.Records, record)
+	if err := p.update(ctx, zone, rrset); err != nil {
+		return fmt.Errorf("desec: update record: %w", err)
+	}
+	return nil
+}
+
+func (p *desecProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("desec: %w", err)
+	}
+
+	rrset, err := p.rrset(ctx, zone, name)
+	if err != nil {
+		return fmt.Errorf("desec: %w", err)
+	}
+	if rrset == nil {
+		return nil
+	}
+	records := rrset.Records[:0]
+	for _, r := range rrset.Records {
+		if strings.Trim(r, `"`) != value {
+			records = append(records, r)
+		}
+	}
+	// an empty record list deletes the rrset
+	rrset.Records = records
+	if err := p.update(ctx, zone, rrset); err != nil {
+		return fmt.Errorf("desec: delete record: %w", err)
+	}
+	return nil
+}
+
+// rrset returns the TXT rrset of name, nil when there is none
+func (p *desecProvider) rrset(ctx context.Context, zone, name string) (*desecRRSet, error) {
+	var rrset desecRRSet
+	err := p.api.do(ctx, http.MethodGet, desecRRSetPath(zone, name), nil, &rrset)
+	if isStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get record: %w", err)
+	}
+	return &rrset, nil
+}
+
+func (p *desecProvider) update(ctx context.Context, zone string, rrset *desecRRSet) error {
+	return p.api.do(ctx, http.MethodPatch, desecRRSetPath(zone, rrset.Subname), map[string]any{"records": rrset.Records}, nil)
+}
+
+func desecRRSetPath(zone, name string) string {
+	return "/domains/" + url.PathEscape(zone) + "/rrsets/" + url.PathEscape(name) + "/TXT/"
+}
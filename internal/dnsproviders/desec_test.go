@@ -0,0 +1,79 @@
+package dnsproviders
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestDeSECSharesTheRRSetOfAName(t *testing.T) {
+	fakeZone(t, "example.com")
+	const path = "/api/v1/domains/example.com/rrsets/_acme-challenge/TXT/"
+
+	// the rrset of the fake API, nil when it doesn't exist
+	var stored *desecRRSet
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET " + path: func(w http.ResponseWriter, r *http.Request, body []byte) {
+			if stored == nil {
+				reply(http.StatusNotFound, map[string]string{"detail": "Not found."})(w, r, body)
+				return
+			}
+			reply(http.StatusOK, stored)(w, r, body)
+		},
+		"POST /api/v1/domains/example.com/rrsets/": func(w http.ResponseWriter, r *http.Request, body []byte) {
+			stored = &desecRRSet{}
+			if err := json.Unmarshal(body, stored); err != nil {
+				t.Fatal(err)
+			}
+			reply(http.StatusCreated, stored)(w, r, body)
+		},
+		"PATCH " + path: func(w http.ResponseWriter, r *http.Request, body []byte) {
+			var patch desecRRSet
+			if err := json.Unmarshal(body, &patch); err != nil {
+				t.Fatal(err)
+			}
+			if stored.Records = patch.Records; len(stored.Records) == 0 {
+				stored = nil
+				reply(http.StatusNoContent, nil)(w, r, body)
+				return
+			}
+			reply(http.StatusOK, stored)(w, r, body)
+		},
+	})
+	p, err := newDeSEC(utils.ProviderConfig{Name: "desec", DeSEC: &utils.DeSECConfig{Token: "desec-token"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*desecProvider).api, server, "/api/v1")
+
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Present("example.com", "token", "other.thumbprint"); err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.TTL != desecMinTTL || len(stored.Records) != 2 || stored.Records[0] != strconv.Quote(testChallengeValue()) {
+		t.Fatalf("stored %+v", stored)
+	}
+	if auth := api.last(http.MethodPost, "/api/v1/domains/example.com/rrsets/").header.Get("Authorization"); auth != "Token desec-token" {
+		t.Errorf("authorized with %q", auth)
+	}
+
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || slices.Contains(stored.Records, strconv.Quote(testChallengeValue())) || len(stored.Records) != 1 {
+		t.Fatalf("after the first cleanup %+v", stored)
+	}
+	// an empty record list deletes the rrset
+	if err := p.CleanUp("example.com", "token", "other.thumbprint"); err != nil {
+		t.Fatal(err)
+	}
+	if stored != nil {
+		t.Errorf("rrset %+v left after the last cleanup", stored)
+	}
+}
@@ -0,0 +1,25 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	dod "github.com/go-acme/lego/v4/providers/dns/digitalocean"
+)
+
+func newDigitalOcean(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.DigitalOcean == nil {
+		return nil, errors.New("digitalocean block is missing")
+	}
+	config := dod.NewDefaultConfig()
+	config.AuthToken = provider.DigitalOcean.Token
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := dod.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,119 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const dnsimpleBaseURL = "https://api.dnsimple.com/v2"
+
+type dnsimpleProvider struct {
+	propagation
+	api       *restClient
+	accountID string
+}
+
+type dnsimpleRecord struct {
+	ID      int64  `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+func newDNSimple(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.DNSimple == nil {
+		return nil, errors.New("dnsimple block is missing")
+	}
+	if provider.DNSimple.Token == "" {
+		return nil, errors.New("dnsimple token is missing")
+	}
+	baseURL := provider.DNSimple.BaseURL
+	if baseURL == "" {
+		baseURL = dnsimpleBaseURL
+	}
+	return &dnsimpleProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api:         newRESTClient(httpClient, baseURL, bearer("Bearer", provider.DNSimple.Token)),
+		accountID:   provider.DNSimple.AccountID,
+	}, nil
+}
+
+func (p *dnsimpleProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("dnsimple: %w", err)
+	}
+	account, err := p.account(ctx)
+	if err != nil {
+		return fmt.Errorf("dnsimple: %w", err)
+	}
+
+	record := dnsimpleRecord{Name: name, Type: "TXT", Content: value, TTL: dns01.DefaultTTL}
+	path := fmt.Sprintf("/%s/zones/%s/records", account, url.PathEscape(zone))
+	if err := p.api.do(ctx, http.MethodPost, path, record, nil); err != nil {
+		return fmt.Errorf("dnsimple: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *dnsimpleProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("dnsimple: %w", err)
+	}
+	account, err := p.account(ctx)
+	if err != nil {
+		return fmt.Errorf("dnsimple: %w", err)
+	}
+
+	var list struct {
+		Data []dnsimpleRecord `json:"data"`
+	}
+	path := fmt.Sprintf("/%s/zones/%s/records", account, url.PathEscape(zone))
+	query := url.Values{"name": {name}, "type": {"TXT"}}
+	if err := p.api.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &list); err != nil {
+		return fmt.Errorf("dnsimple: list records: %w", err)
+	}
+	for _, r := range list.Data {
+		if strings.Trim(r.Content, `"`) != value {
+			continue
+		}
+		if err := p.api.do(ctx, http.MethodDelete, path+"/"+strconv.FormatInt(r.ID, 10), nil, nil); err != nil {
+			return fmt.Errorf("dnsimple: delete record: %w", err)
+		}
+	}
+	return nil
+}
+
+// account returns the configured account id, or the one of the account token
+func (p *dnsimpleProvider) account(ctx context.Context) (string, error) {
+	if p.accountID != "" {
+		return p.accountID, nil
+	}
+	var whoami struct {
+		Data struct {
+			Account *struct {
+				ID int64 `json:"id"`
+			} `json:"account"`
+		} `json:"data"`
+	}
+	if err := p.api.do(ctx, http.MethodGet, "/whoami", nil, &whoami); err != nil {
+		return "", fmt.Errorf("whoami: %w", err)
+	}
+	if whoami.Data.Account == nil {
+		return "", errors.New("the token isn't an account token, set dnsimple.account_id")
+	}
+	return strconv.FormatInt(whoami.Data.Account.ID, 10), nil
+}
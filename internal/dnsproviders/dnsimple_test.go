@@ -0,0 +1,100 @@
+package dnsproviders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge"
+
+	utils "hephaestus/internal/utils"
+)
+
+func newTestDNSimple(t *testing.T, server *httptest.Server, accountID string) challenge.Provider {
+	t.Helper()
+	p, err := newDNSimple(utils.ProviderConfig{
+		Name:     "dnsimple",
+		DNSimple: &utils.DNSimpleConfig{Token: "dnsimple-token", AccountID: accountID, BaseURL: server.URL + "/v2"},
+	}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDNSimplePresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /v2/whoami": reply(http.StatusOK, map[string]any{"data": map[string]any{"account": map[string]any{"id": 1010}, "user": nil}}),
+		"POST /v2/1010/zones/example.com/records": reply(http.StatusCreated, map[string]any{"data": map[string]any{"id": 5}}),
+		"GET /v2/1010/zones/example.com/records": reply(http.StatusOK, map[string]any{"data": []map[string]any{
+			{"id": 5, "name": "_acme-challenge.www", "type": "TXT", "content": `"` + value + `"`},
+			{"id": 6, "name": "_acme-challenge.www", "type": "TXT", "content": "another-order"},
+		}}),
+		"DELETE /v2/1010/zones/example.com/records/5": reply(http.StatusNoContent, nil),
+	})
+	p := newTestDNSimple(t, server, "")
+
+	if err := p.Present("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	created := decodeJSON(t, api.last(http.MethodPost, "/v2/1010/zones/example.com/records").body)
+	if created["name"] != "_acme-challenge.www" || created["type"] != "TXT" || created["content"] != value || created["ttl"] != float64(120) {
+		t.Errorf("created %v", created)
+	}
+
+	if err := p.CleanUp("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	list := api.last(http.MethodGet, "/v2/1010/zones/example.com/records")
+	if !strings.Contains(list.path, "name=_acme-challenge.www") || !strings.Contains(list.path, "type=TXT") {
+		t.Errorf("records listed with %s", list.path)
+	}
+	// only the record of this challenge is removed
+	if slices.Contains(api.calls(), "DELETE /v2/1010/zones/example.com/records/6") {
+		t.Error("the record of another order was deleted")
+	}
+	for _, r := range api.requests {
+		if r.header.Get("Authorization") != "Bearer dnsimple-token" {
+			t.Errorf("%s %s authorized with %q", r.method, r.path, r.header.Get("Authorization"))
+		}
+	}
+}
+
+func TestDNSimpleConfiguredAccount(t *testing.T) {
+	fakeZone(t, "example.com")
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"POST /v2/2020/zones/example.com/records": reply(http.StatusCreated, nil),
+	})
+	if err := newTestDNSimple(t, server, "2020").Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if calls := api.calls(); len(calls) != 1 {
+		t.Errorf("calls %v, the configured account needs no whoami", calls)
+	}
+}
+
+func TestDNSimpleUserToken(t *testing.T) {
+	fakeZone(t, "example.com")
+	_, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /v2/whoami": reply(http.StatusOK, map[string]any{"data": map[string]any{"account": nil, "user": map[string]any{"id": 1}}}),
+	})
+	err := newTestDNSimple(t, server, "").Present("example.com", "token", testKeyAuth)
+	if err == nil || !strings.Contains(err.Error(), "account_id") {
+		t.Errorf("got %v, want a hint at dnsimple.account_id", err)
+	}
+}
+
+func TestDNSimpleAPIError(t *testing.T) {
+	fakeZone(t, "example.com")
+	_, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"POST /v2/1010/zones/example.com/records": reply(http.StatusUnauthorized, map[string]string{"message": "Authentication failed"}),
+	})
+	err := newTestDNSimple(t, server, "1010").Present("example.com", "token", testKeyAuth)
+	if !isStatus(err, http.StatusUnauthorized) || !strings.Contains(err.Error(), "Authentication failed") {
+		t.Errorf("got %v, want the API error", err)
+	}
+}
@@ -0,0 +1,28 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	gd "github.com/go-acme/lego/v4/providers/dns/gandiv5"
+)
+
+func newGandi(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Gandi == nil {
+		return nil, errors.New("gandi block is missing")
+	}
+	config := gd.NewDefaultConfig()
+	config.PersonalAccessToken = provider.Gandi.PersonalAccessToken
+	if config.PersonalAccessToken == "" {
+		config.APIKey = provider.Gandi.APIKey //nolint:staticcheck // older accounts still use API keys
+	}
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := gd.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
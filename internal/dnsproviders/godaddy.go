@@ -0,0 +1,26 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	gd "github.com/go-acme/lego/v4/providers/dns/godaddy"
+)
+
+func newGoDaddy(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.GoDaddy == nil {
+		return nil, errors.New("godaddy block is missing")
+	}
+	config := gd.NewDefaultConfig()
+	config.APIKey = provider.GoDaddy.APIKey
+	config.APISecret = provider.GoDaddy.APISecret
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := gd.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,26 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	hz "github.com/go-acme/lego/v4/providers/dns/hetzner"
+)
+
+func newHetzner(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Hetzner == nil {
+		return nil, errors.New("hetzner block is missing")
+	}
+	config := hz.NewDefaultConfig()
+	config.APIToken = provider.Hetzner.APIToken
+	config.APIKey = provider.Hetzner.APIKey //nolint:staticcheck // DNS console keys are still in use
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := hz.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,88 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// httpreq hands the records to an external service, it receives POST <endpoint>/present and
+// <endpoint>/cleanup with the same bodies as lego's httpreq provider, so existing hooks keep working
+
+type httpReqProvider struct {
+	propagation
+	api *restClient
+	raw bool
+}
+
+// httpReqRecord is the default body, the record to create or delete
+type httpReqRecord struct {
+	FQDN  string `json:"fqdn"`
+	Value string `json:"value"`
+}
+
+// httpReqRaw is the body in RAW mode, the endpoint computes the record itself
+type httpReqRaw struct {
+	Domain  string `json:"domain"`
+	Token   string `json:"token"`
+	KeyAuth string `json:"keyAuth"`
+}
+
+func newHTTPReq(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	c := provider.HTTPReq
+	if c == nil || c.Endpoint == "" {
+		return nil, errors.New("httpreq endpoint is missing")
+	}
+	if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+		return nil, fmt.Errorf("httpreq endpoint %q is not an http(s) URL", c.Endpoint)
+	}
+	switch strings.ToUpper(c.Mode) {
+	case "", "RAW":
+	default:
+		return nil, fmt.Errorf("unknown httpreq mode %q", c.Mode)
+	}
+
+	headers := c.Headers
+	username, password := c.Username, c.Password
+	return &httpReqProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api: newRESTClient(httpClient, c.Endpoint, func(req *http.Request, _ []byte) error {
+			for _, h := range headers {
+				req.Header.Set(h.Name, h.Value)
+			}
+			if username != "" {
+				req.SetBasicAuth(username, password)
+			}
+			return nil
+		}),
+		raw: strings.EqualFold(c.Mode, "RAW"),
+	}, nil
+}
+
+func (p *httpReqProvider) Present(domain, token, keyAuth string) error {
+	if err := p.api.do(context.Background(), http.MethodPost, "/present", p.body(domain, token, keyAuth), nil); err != nil {
+		return fmt.Errorf("httpreq: present: %w", err)
+	}
+	return nil
+}
+
+func (p *httpReqProvider) CleanUp(domain, token, keyAuth string) error {
+	if err := p.api.do(context.Background(), http.MethodPost, "/cleanup", p.body(domain, token, keyAuth), nil); err != nil {
+		return fmt.Errorf("httpreq: cleanup: %w", err)
+	}
+	return nil
+}
+
+func (p *httpReqProvider) body(domain, token, keyAuth string) any {
+	if p.raw {
+		return httpReqRaw{Domain: domain, Token: token, KeyAuth: keyAuth}
+	}
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return httpReqRecord{FQDN: info.EffectiveFQDN, Value: info.Value}
+}
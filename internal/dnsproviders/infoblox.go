@@ -0,0 +1,107 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const defaultInfobloxWAPIVersion = "2.11"
+
+type infobloxProvider struct {
+	propagation
+	api  *restClient
+	view string
+}
+
+type infobloxRecord struct {
+	Ref    string `json:"_ref,omitempty"`
+	Name   string `json:"name"`
+	Text   string `json:"text"`
+	TTL    int    `json:"ttl,omitempty"`
+	UseTTL bool   `json:"use_ttl,omitempty"`
+	View   string `json:"view,omitempty"`
+}
+
+func newInfoblox(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	c := provider.Infoblox
+	if c == nil {
+		return nil, errors.New("infoblox block is missing")
+	}
+	if c.Host == "" || c.Username == "" || c.Password == "" {
+		return nil, errors.New("infoblox host, username and password are required")
+	}
+
+	// grid masters often run with a self-signed certificate
+	if c.SSLVerify != nil && !*c.SSLVerify {
+		httpConfig := cfg.HTTPClient
+		httpConfig.InsecureSkipVerify = true
+		var err error
+		if httpClient, err = utils.NewHTTPClient(httpConfig); err != nil {
+			return nil, fmt.Errorf("http client: %w", err)
+		}
+	}
+
+	host := c.Host
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	version := strings.TrimPrefix(c.WAPIVersion, "v")
+	if version == "" {
+		version = defaultInfobloxWAPIVersion
+	}
+
+	username, password := c.Username, c.Password
+	return &infobloxProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api: newRESTClient(httpClient, strings.TrimSuffix(host, "/")+"/wapi/v"+version, func(req *http.Request, _ []byte) error {
+			req.SetBasicAuth(username, password)
+			return nil
+		}),
+		view: c.DNSView,
+	}, nil
+}
+
+func (p *infobloxProvider) Present(domain, token, keyAuth string) error {
+	// records are addressed by their full name, the zone doesn't need to resolve from here
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+	record := infobloxRecord{
+		Name:   dns01.UnFqdn(info.EffectiveFQDN),
+		Text:   info.Value,
+		TTL:    dns01.DefaultTTL,
+		UseTTL: true,
+		View:   p.view,
+	}
+	if err := p.api.do(context.Background(), http.MethodPost, "/record:txt", record, nil); err != nil {
+		return fmt.Errorf("infoblox: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *infobloxProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+
+	var records []infobloxRecord
+	query := url.Values{"name": {dns01.UnFqdn(info.EffectiveFQDN)}, "text": {info.Value}}
+	if p.view != "" {
+		query.Set("view", p.view)
+	}
+	if err := p.api.do(ctx, http.MethodGet, "/record:txt?"+query.Encode(), nil, &records); err != nil {
+		return fmt.Errorf("infoblox: list records: %w", err)
+	}
+	for _, r := range records {
+		// the reference already holds the object type, e.g. record:txt/ZG5z...:name/default
+		if err := p.api.do(ctx, http.MethodDelete, "/"+r.Ref, nil, nil); err != nil {
+			return fmt.Errorf("infoblox: delete record: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package dnsproviders
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestInfobloxPresentAndCleanUp(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+	value := testChallengeValue()
+	ref := "record:txt/ZG5zLmJpbmRfdHh0:_acme-challenge.www.example.com/internal"
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"POST /wapi/v2.12/record:txt": reply(http.StatusCreated, ref),
+		"GET /wapi/v2.12/record:txt":  reply(http.StatusOK, []infobloxRecord{{Ref: ref, Name: "_acme-challenge.www.example.com", Text: value}}),
+		"DELETE /wapi/v2.12/" + ref:   reply(http.StatusOK, ref),
+	})
+	p, err := newInfoblox(utils.ProviderConfig{Name: "infoblox", Infoblox: &utils.InfobloxConfig{
+		Host: server.URL + "/", WAPIVersion: "v2.12", Username: "admin", Password: "infoblox", DNSView: "internal",
+	}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// records are addressed by their full name, no zone lookup
+	if err := p.Present("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	post := api.last(http.MethodPost, "/wapi/v2.12/record:txt")
+	created := decodeJSON(t, post.body)
+	if created["name"] != "_acme-challenge.www.example.com" || created["text"] != value || created["view"] != "internal" || created["use_ttl"] != true {
+		t.Errorf("created %v", created)
+	}
+	if user, password, ok := (&http.Request{Header: post.header}).BasicAuth(); !ok || user != "admin" || password != "infoblox" {
+		t.Errorf("authorized as %q", user)
+	}
+
+	if err := p.CleanUp("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	list := api.last(http.MethodGet, "/wapi/v2.12/record:txt")
+	if list.path != "/wapi/v2.12/record:txt?name=_acme-challenge.www.example.com&text="+value+"&view=internal" {
+		t.Errorf("records listed with %s", list.path)
+	}
+	if !slices.Contains(api.calls(), "DELETE /wapi/v2.12/"+ref) {
+		t.Errorf("the record wasn't deleted by its reference: %v", api.calls())
+	}
+}
@@ -0,0 +1,112 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const (
+	ionosBaseURL = "https://api.hosting.ionos.com/dns/v1"
+	ionosMinTTL  = 300
+)
+
+type ionosProvider struct {
+	propagation
+	api *restClient
+}
+
+// ionosRecord names are fully qualified, without the trailing dot
+type ionosRecord struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+func newIONOS(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.IONOS == nil || provider.IONOS.APIKey == "" {
+		return nil, errors.New("ionos api_key is missing")
+	}
+	apiKey := provider.IONOS.APIKey
+	return &ionosProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api: newRESTClient(httpClient, ionosBaseURL, func(req *http.Request, _ []byte) error {
+			req.Header.Set("X-API-Key", apiKey)
+			return nil
+		}),
+	}, nil
+}
+
+func (p *ionosProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("ionos: %w", err)
+	}
+	zoneID, err := p.zoneID(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("ionos: %w", err)
+	}
+
+	records := []ionosRecord{{Name: name + "." + zone, Type: "TXT", Content: value, TTL: ionosMinTTL}}
+	if err := p.api.do(ctx, http.MethodPost, "/zones/"+url.PathEscape(zoneID)+"/records", records, nil); err != nil {
+		return fmt.Errorf("ionos: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *ionosProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("ionos: %w", err)
+	}
+	zoneID, err := p.zoneID(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("ionos: %w", err)
+	}
+
+	var details struct {
+		Records []ionosRecord `json:"records"`
+	}
+	base := "/zones/" + url.PathEscape(zoneID)
+	query := url.Values{"recordName": {name + "." + zone}, "recordType": {"TXT"}}
+	if err := p.api.do(ctx, http.MethodGet, base+"?"+query.Encode(), nil, &details); err != nil {
+		return fmt.Errorf("ionos: list records: %w", err)
+	}
+	for _, r := range details.Records {
+		if strings.Trim(r.Content, `"`) != value {
+			continue
+		}
+		if err := p.api.do(ctx, http.MethodDelete, base+"/records/"+url.PathEscape(r.ID), nil, nil); err != nil {
+			return fmt.Errorf("ionos: delete record: %w", err)
+		}
+	}
+	return nil
+}
+
+// zoneID looks up the IONOS zone of the zone name, zones are only addressed by id
+func (p *ionosProvider) zoneID(ctx context.Context, zone string) (string, error) {
+	var zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := p.api.do(ctx, http.MethodGet, "/zones", nil, &zones); err != nil {
+		return "", fmt.Errorf("list zones: %w", err)
+	}
+	for _, z := range zones {
+		if strings.EqualFold(z.Name, zone) {
+			return z.ID, nil
+		}
+	}
+	return "", fmt.Errorf("zone %s not found in the account", zone)
+}
@@ -0,0 +1,57 @@
+package dnsproviders
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestIONOSPresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /dns/v1/zones": reply(http.StatusOK, []map[string]string{
+			{"id": "z-org", "name": "example.org"},
+			{"id": "z-com", "name": "example.com"},
+		}),
+		"POST /dns/v1/zones/z-com/records": reply(http.StatusCreated, []ionosRecord{{ID: "r1"}}),
+		"GET /dns/v1/zones/z-com": reply(http.StatusOK, map[string]any{"records": []ionosRecord{
+			{ID: "r1", Name: "_acme-challenge.example.com", Type: "TXT", Content: `"` + value + `"`},
+			{ID: "r2", Name: "_acme-challenge.example.com", Type: "TXT", Content: `"another-order"`},
+		}}),
+		"DELETE /dns/v1/zones/z-com/records/r1": reply(http.StatusOK, nil),
+	})
+	p, err := newIONOS(utils.ProviderConfig{Name: "ionos", IONOS: &utils.IONOSConfig{APIKey: "prefix.secret"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*ionosProvider).api, server, "/dns/v1")
+
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	post := api.last(http.MethodPost, "/dns/v1/zones/z-com/records")
+	var created []ionosRecord
+	if err := json.Unmarshal(post.body, &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 || created[0] != (ionosRecord{Name: "_acme-challenge.example.com", Type: "TXT", Content: value, TTL: ionosMinTTL}) {
+		t.Errorf("created %s", post.body)
+	}
+	if post.header.Get("X-API-Key") != "prefix.secret" {
+		t.Error("the request isn't authorized with the api key")
+	}
+
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if list := api.last(http.MethodGet, "/dns/v1/zones/z-com"); list.path != "/dns/v1/zones/z-com?recordName=_acme-challenge.example.com&recordType=TXT" {
+		t.Errorf("records listed with %s", list.path)
+	}
+	if calls := api.calls(); !slices.Contains(calls, "DELETE /dns/v1/zones/z-com/records/r1") || slices.Contains(calls, "DELETE /dns/v1/zones/z-com/records/r2") {
+		t.Errorf("deleted %v, want only the challenge record", calls)
+	}
+}
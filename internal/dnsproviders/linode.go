@@ -0,0 +1,122 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+const (
+	linodeBaseURL  = "https://api.linode.com/v4"
+	linodeMinTTL   = 300
+	linodePageSize = 500
+)
+
+type linodeProvider struct {
+	propagation
+	api *restClient
+}
+
+type linodeRecord struct {
+	ID     int64  `json:"id,omitempty"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	TTLSec int    `json:"ttl_sec,omitempty"`
+}
+
+func newLinode(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Linode == nil || provider.Linode.Token == "" {
+		return nil, errors.New("linode token is missing")
+	}
+	return &linodeProvider{
+		// Linode's nameservers load zone changes every 15 minutes
+		propagation: newPropagation(provider, cfg.Certs, 17*time.Minute, 15*time.Second),
+		api:         newRESTClient(httpClient, linodeBaseURL, bearer("Bearer", provider.Linode.Token)),
+	}, nil
+}
+
+func (p *linodeProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("linode: %w", err)
+	}
+	domainID, err := p.domainID(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("linode: %w", err)
+	}
+
+	record := linodeRecord{Type: "TXT", Name: name, Target: value, TTLSec: linodeMinTTL}
+	if err := p.api.do(ctx, http.MethodPost, "/domains/"+domainID+"/records", record, nil); err != nil {
+		return fmt.Errorf("linode: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *linodeProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("linode: %w", err)
+	}
+	domainID, err := p.domainID(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("linode: %w", err)
+	}
+
+	for page := 1; ; page++ {
+		var list struct {
+			Data  []linodeRecord `json:"data"`
+			Pages int            `json:"pages"`
+		}
+		path := fmt.Sprintf("/domains/%s/records?page=%d&page_size=%d", domainID, page, linodePageSize)
+		if err := p.api.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+			return fmt.Errorf("linode: list records: %w", err)
+		}
+		for _, r := range list.Data {
+			if r.Type != "TXT" || r.Name != name || strings.Trim(r.Target, `"`) != value {
+				continue
+			}
+			path := "/domains/" + domainID + "/records/" + strconv.FormatInt(r.ID, 10)
+			if err := p.api.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+				return fmt.Errorf("linode: delete record: %w", err)
+			}
+		}
+		if page >= list.Pages {
+			return nil
+		}
+	}
+}
+
+// domainID looks up the Linode domain of the zone, domains are only addressed by id
+func (p *linodeProvider) domainID(ctx context.Context, zone string) (string, error) {
+	for page := 1; ; page++ {
+		var list struct {
+			Data []struct {
+				ID     int64  `json:"id"`
+				Domain string `json:"domain"`
+			} `json:"data"`
+			Pages int `json:"pages"`
+		}
+		path := fmt.Sprintf("/domains?page=%d&page_size=%d", page, linodePageSize)
+		if err := p.api.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+			return "", fmt.Errorf("list domains: %w", err)
+		}
+		for _, d := range list.Data {
+			if strings.EqualFold(d.Domain, zone) {
+				return strconv.FormatInt(d.ID, 10), nil
+			}
+		}
+		if page >= list.Pages {
+			return "", fmt.Errorf("domain %s not found in the account", zone)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package dnsproviders
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestLinodePresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		// the zone is on the second page of domains
+		"GET /v4/domains": func(w http.ResponseWriter, r *http.Request, body []byte) {
+			if r.URL.Query().Get("page") == "1" {
+				reply(http.StatusOK, map[string]any{"data": []map[string]any{{"id": 1, "domain": "example.org"}}, "pages": 2})(w, r, body)
+				return
+			}
+			reply(http.StatusOK, map[string]any{"data": []map[string]any{{"id": 42, "domain": "Example.com"}}, "pages": 2})(w, r, body)
+		},
+		"POST /v4/domains/42/records": reply(http.StatusOK, linodeRecord{ID: 7}),
+		"GET /v4/domains/42/records": reply(http.StatusOK, map[string]any{"pages": 1, "data": []linodeRecord{
+			{ID: 7, Type: "TXT", Name: "_acme-challenge", Target: value},
+			{ID: 8, Type: "TXT", Name: "_acme-challenge", Target: "another-order"},
+			{ID: 9, Type: "TXT", Name: "other", Target: value},
+		}}),
+		"DELETE /v4/domains/42/records/7": reply(http.StatusOK, map[string]any{}),
+	})
+	p, err := newLinode(utils.ProviderConfig{Name: "linode", Linode: &utils.LinodeConfig{Token: "linode-token"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*linodeProvider).api, server, "/v4")
+
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	post := api.last(http.MethodPost, "/v4/domains/42/records")
+	created := decodeJSON(t, post.body)
+	if created["type"] != "TXT" || created["name"] != "_acme-challenge" || created["target"] != value || created["ttl_sec"] != float64(linodeMinTTL) {
+		t.Errorf("created %v", created)
+	}
+	if post.header.Get("Authorization") != "Bearer linode-token" {
+		t.Errorf("authorized with %q", post.header.Get("Authorization"))
+	}
+
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range api.calls() {
+		if strings.HasPrefix(c, "DELETE ") && c != "DELETE /v4/domains/42/records/7" {
+			t.Errorf("deleted a record of another name or order: %s", c)
+		}
+	}
+	if !slices.Contains(api.calls(), "DELETE /v4/domains/42/records/7") {
+		t.Error("the challenge record wasn't deleted")
+	}
+}
+
+func TestLinodeUnknownZone(t *testing.T) {
+	fakeZone(t, "example.com")
+	_, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /v4/domains": reply(http.StatusOK, map[string]any{"data": []map[string]any{{"id": 1, "domain": "example.org"}}, "pages": 1}),
+	})
+	p, err := newLinode(utils.ProviderConfig{Name: "linode", Linode: &utils.LinodeConfig{Token: "linode-token"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*linodeProvider).api, server, "/v4")
+	if err := p.Present("example.com", "token", testKeyAuth); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("got %v, want the zone not found", err)
+	}
+}
@@ -0,0 +1,34 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	nc "github.com/go-acme/lego/v4/providers/dns/namecheap"
+)
+
+const namecheapSandboxURL = "https://api.sandbox.namecheap.com/xml.response"
+
+func newNamecheap(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Namecheap == nil {
+		return nil, errors.New("namecheap block is missing")
+	}
+	// lego's defaults (1h timeout, 15s polling) fit Namecheap's slow updates, certs.propagation_timeout
+	// is usually tuned for faster providers, so only the provider entry overrides them
+	config := nc.NewDefaultConfig()
+	config.APIUser = provider.Namecheap.APIUser
+	config.APIKey = provider.Namecheap.APIKey
+	config.ClientIP = provider.Namecheap.ClientIP
+	if provider.Namecheap.Sandbox {
+		config.BaseURL = namecheapSandboxURL
+	}
+	config.HTTPClient = httpClient
+	applyPropagation(provider, utils.CertsConfig{}, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := nc.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
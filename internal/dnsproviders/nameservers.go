@@ -0,0 +1,100 @@
+package dnsproviders
+
+import (
+	"context"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// knownNameservers are the nameservers the hosted DNS services delegate zones to, as path.Match
+// patterns. Self-hosted types have none, their entries can list them in nameservers
+var knownNameservers = map[string][]string{
+	utils.ProviderCloudflare:   {"*.ns.cloudflare.com"},
+	utils.ProviderHetzner:      {"*.ns.hetzner.com", "*.ns.hetzner.de", "*.your-server.de", "*.first-ns.de", "*.second-ns.de", "*.second-ns.com"},
+	utils.ProviderDigitalOcean: {"ns?.digitalocean.com"},
+	utils.ProviderRoute53:      {"ns-*.awsdns-*"},
+	utils.ProviderDNSimple:     {"ns?.dnsimple.com", "ns?.dnsimple-edge.net", "ns?.dnsimple-edge.org"},
+	utils.ProviderNamecheap:    {"dns?.registrar-servers.com"},
+	utils.ProviderGandi:        {"*.gandi.net"},
+	utils.ProviderOVH:          {"*.ovh.net", "*.ovh.ca", "*.anycast.me"},
+	utils.ProviderPorkbun:      {"*.porkbun.com"},
+	utils.ProviderLinode:       {"ns?.linode.com"},
+	utils.ProviderVultr:        {"ns?.vultr.com"},
+	utils.ProviderDeSEC:        {"ns?.desec.io", "ns?.desec.org"},
+	utils.ProviderGoDaddy:      {"*.domaincontrol.com"},
+	utils.ProviderScaleway:     {"ns?.dom.scw.cloud"},
+	utils.ProviderNS1:          {"dns?.p??.nsone.net"},
+	utils.ProviderOracleCloud:  {"ns?.p??.dns.oraclecloud.net", "ns?.p??.dynect.net"},
+	utils.ProviderAliDNS:       {"*.alidns.com", "*.hichina.com"},
+	utils.ProviderIONOS:        {"ns*.ui-dns.*"},
+	// Netlify DNS runs on NS1
+	utils.ProviderNetlify: {"dns?.p??.nsone.net"},
+}
+
+// NameserverMismatchError means the zone of a challenge is served by nameservers of another DNS
+// service, the record the provider writes would never be seen by the CA
+type NameserverMismatchError struct {
+	Domain      string
+	Zone        string
+	Nameservers []string
+	Provider    string
+}
+
+func (e *NameserverMismatchError) Error() string {
+	return fmt.Sprintf("zone %s of %s is served by %s, not by DNS provider '%s', pick the provider hosting the zone or fix its delegation",
+		e.Zone, e.Domain, strings.Join(e.Nameservers, ", "), e.Provider)
+}
+
+// CheckNameservers verifies that the zone the challenge record of domain goes to, after following
+// a CNAME on _acme-challenge, is delegated to the provider. Lookups that fail are no mismatch, the
+// order then fails or succeeds on its own; providers without known nameservers always pass
+func (p *Provider) CheckNameservers(ctx context.Context, domain string) error {
+	domain = strings.TrimPrefix(domain, "*.")
+	// a delegated record is written by the provider of the zone the CNAME points to
+	if target, err := p.delegatedTo(domain); err != nil {
+		return err
+	} else if target != nil {
+		p = target
+	}
+	if len(p.nameservers) == 0 {
+		return nil
+	}
+	info := dns01.GetChallengeInfo(domain, "")
+	zone, err := dns01.FindZoneByFqdn(info.EffectiveFQDN)
+	if err != nil {
+		return nil
+	}
+	records, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+
+	found := make([]string, 0, len(records))
+	for _, ns := range records {
+		host := strings.ToLower(dns01.UnFqdn(ns.Host))
+		for _, pattern := range p.nameservers {
+			if ok, _ := path.Match(pattern, host); ok {
+				return nil
+			}
+		}
+		found = append(found, host)
+	}
+	return &NameserverMismatchError{Domain: domain, Zone: dns01.UnFqdn(zone), Nameservers: found, Provider: p.Name}
+}
+
+// nameserversOf returns the patterns of the provider entry, the known ones of its type by default
+func nameserversOf(provider utils.ProviderConfig, providerType string) []string {
+	if len(provider.Nameservers) > 0 {
+		patterns := make([]string, len(provider.Nameservers))
+		for i, ns := range provider.Nameservers {
+			patterns[i] = strings.ToLower(dns01.UnFqdn(ns))
+		}
+		return patterns
+	}
+	return knownNameservers[providerType]
+}
@@ -0,0 +1,25 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	nl "github.com/go-acme/lego/v4/providers/dns/netlify"
+)
+
+func newNetlify(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Netlify == nil {
+		return nil, errors.New("netlify block is missing")
+	}
+	config := nl.NewDefaultConfig()
+	config.Token = provider.Netlify.Token
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := nl.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
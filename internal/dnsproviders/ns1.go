@@ -0,0 +1,134 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const (
+	ns1BaseURL = "https://api.nsone.net/v1"
+	// NS1 limits requests per key and answers bursts with 429
+	ns1RateLimitRetries = 5
+)
+
+type ns1Provider struct {
+	propagation
+	api *restClient
+}
+
+// ns1Record holds all TXT answers of a name, the apex and wildcard challenges of a domain share one
+type ns1Record struct {
+	Zone    string      `json:"zone"`
+	Domain  string      `json:"domain"`
+	Type    string      `json:"type"`
+	TTL     int         `json:"ttl,omitempty"`
+	Answers []ns1Answer `json:"answers"`
+}
+
+type ns1Answer struct {
+	Answer []string `json:"answer"`
+}
+
+func newNS1(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.NS1 == nil || provider.NS1.APIKey == "" {
+		return nil, errors.New("ns1 api_key is missing")
+	}
+	apiKey := provider.NS1.APIKey
+	api := newRESTClient(httpClient, ns1BaseURL, func(req *http.Request, _ []byte) error {
+		req.Header.Set("X-NSONE-Key", apiKey)
+		return nil
+	})
+	api.rateLimitRetries = ns1RateLimitRetries
+	return &ns1Provider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api:         api,
+	}, nil
+}
+
+func (p *ns1Provider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("ns1: %w", err)
+	}
+	fqdn := name + "." + zone
+
+	record, err := p.record(ctx, zone, fqdn)
+	if err != nil {
+		return fmt.Errorf("ns1: %w", err)
+	}
+	if record == nil {
+		record = &ns1Record{Zone: zone, Domain: fqdn, Type: "TXT", TTL: dns01.DefaultTTL}
+		record.Answers = []ns1Answer{{Answer: []string{value}}}
+		if err := p.api.do(ctx, http.MethodPut, ns1RecordPath(zone, fqdn), record, nil); err != nil {
+			return fmt.Errorf("ns1: create record: %w", err)
+		}
+		return nil
+	}
+
+	record.Answers = append(record.Answers, ns1Answer{Answer: []string{value}})
+	if err := p.api.do(ctx, http.MethodPost, ns1RecordPath(zone, fqdn), record, nil); err != nil {
+		return fmt.Errorf("ns1: update record: %w", err)
+	}
+	return nil
+}
+
+func (p *ns1Provider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("ns1: %w", err)
+	}
+	fqdn := name + "." + zone
+
+	record, err := p.record(ctx, zone, fqdn)
+	if err != nil {
+		return fmt.Errorf("ns1: %w", err)
+	}
+	if record == nil {
+		return nil
+	}
+	answers := record.Answers[:0]
+	for _, a := range record.Answers {
+		if len(a.Answer) != 1 || a.Answer[0] != value {
+			answers = append(answers, a)
+		}
+	}
+
+	// NS1 refuses records without answers, the last challenge removes the record
+	if len(answers) == 0 {
+		if err := p.api.do(ctx, http.MethodDelete, ns1RecordPath(zone, fqdn), nil, nil); err != nil {
+			return fmt.Errorf("ns1: delete record: %w", err)
+		}
+		return nil
+	}
+	record.Answers = answers
+	if err := p.api.do(ctx, http.MethodPost, ns1RecordPath(zone, fqdn), record, nil); err != nil {
+		return fmt.Errorf("ns1: update record: %w", err)
+	}
+	return nil
+}
+
+// record returns the TXT record of fqdn, nil when there is none
+func (p *ns1Provider) record(ctx context.Context, zone, fqdn string) (*ns1Record, error) {
+	var record ns1Record
+	err := p.api.do(ctx, http.MethodGet, ns1RecordPath(zone, fqdn), nil, &record)
+	if isStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get record: %w", err)
+	}
+	return &record, nil
+}
+
+func ns1RecordPath(zone, fqdn string) string {
+	return "/zones/" + url.PathEscape(zone) + "/" + url.PathEscape(fqdn) + "/TXT"
+}
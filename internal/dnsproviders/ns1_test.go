@@ -0,0 +1,81 @@
+package dnsproviders
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestNS1SharesTheRecordOfAName(t *testing.T) {
+	fakeZone(t, "example.com")
+	const path = "/v1/zones/example.com/_acme-challenge.example.com/TXT"
+
+	// the record of the fake API, nil when it doesn't exist
+	var stored *ns1Record
+	save := func(w http.ResponseWriter, r *http.Request, body []byte) {
+		stored = &ns1Record{}
+		if err := json.Unmarshal(body, stored); err != nil {
+			t.Fatal(err)
+		}
+		reply(http.StatusOK, stored)(w, r, body)
+	}
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET " + path: func(w http.ResponseWriter, r *http.Request, body []byte) {
+			if stored == nil {
+				reply(http.StatusNotFound, map[string]string{"message": "record not found"})(w, r, body)
+				return
+			}
+			reply(http.StatusOK, stored)(w, r, body)
+		},
+		"PUT " + path:  save,
+		"POST " + path: save,
+		"DELETE " + path: func(w http.ResponseWriter, r *http.Request, body []byte) {
+			stored = nil
+			reply(http.StatusOK, map[string]any{})(w, r, body)
+		},
+	})
+	p, err := newNS1(utils.ProviderConfig{Name: "ns1", NS1: &utils.NS1Config{APIKey: "ns1-key"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*ns1Provider).api, server, "/v1")
+
+	// the challenges of the apex and of the wildcard answer on the same name
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Present("example.com", "token", "other.thumbprint"); err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.Zone != "example.com" || stored.Domain != "_acme-challenge.example.com" || len(stored.Answers) != 2 {
+		t.Fatalf("stored %+v", stored)
+	}
+	if stored.Answers[0].Answer[0] != testChallengeValue() {
+		t.Errorf("answers %+v", stored.Answers)
+	}
+	if api.last(http.MethodPut, path).header.Get("X-NSONE-Key") != "ns1-key" {
+		t.Error("the request isn't authorized with the api key")
+	}
+
+	// cleaning up one challenge keeps the answer of the other
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || len(stored.Answers) != 1 || stored.Answers[0].Answer[0] == testChallengeValue() {
+		t.Fatalf("after the first cleanup %+v", stored)
+	}
+	// the last one removes the record, NS1 refuses records without answers
+	if err := p.CleanUp("example.com", "token", "other.thumbprint"); err != nil {
+		t.Fatal(err)
+	}
+	if stored != nil || !slices.Contains(api.calls(), "DELETE "+path) {
+		t.Errorf("record %+v left after the last cleanup", stored)
+	}
+	// nothing to clean up
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,201 @@
+package dnsproviders
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+type oracleCloudProvider struct {
+	propagation
+	api           *restClient
+	compartmentID string
+
+	keyID string
+	key   *rsa.PrivateKey
+}
+
+// oracleRecord is a record of a zone, OCI returns TXT rdata quoted
+type oracleRecord struct {
+	Domain     string `json:"domain"`
+	RType      string `json:"rtype"`
+	RData      string `json:"rdata,omitempty"`
+	TTL        int    `json:"ttl,omitempty"`
+	RecordHash string `json:"recordHash,omitempty"`
+	Operation  string `json:"operation,omitempty"`
+}
+
+func newOracleCloud(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	c := provider.OracleCloud
+	if c == nil {
+		return nil, errors.New("oraclecloud block is missing")
+	}
+	if c.TenancyOCID == "" || c.UserOCID == "" || c.Fingerprint == "" || c.Region == "" {
+		return nil, errors.New("oraclecloud tenancy_ocid, user_ocid, fingerprint and region are required")
+	}
+	key, err := oracleCloudKey(c)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &oracleCloudProvider{
+		propagation:   newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		compartmentID: c.CompartmentID,
+		keyID:         c.TenancyOCID + "/" + c.UserOCID + "/" + c.Fingerprint,
+		key:           key,
+	}
+	p.api = newRESTClient(httpClient, "https://dns."+c.Region+".oraclecloud.com/20180115", p.sign)
+	return p, nil
+}
+
+func (p *oracleCloudProvider) Present(domain, token, keyAuth string) error {
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("oraclecloud: %w", err)
+	}
+	fqdn := name + "." + zone
+
+	op := oracleRecord{Operation: "ADD", Domain: fqdn, RType: "TXT", RData: value, TTL: dns01.DefaultTTL}
+	if err := p.patch(context.Background(), zone, fqdn, []oracleRecord{op}); err != nil {
+		return fmt.Errorf("oraclecloud: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *oracleCloudProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("oraclecloud: %w", err)
+	}
+	fqdn := name + "." + zone
+
+	var list struct {
+		Items []oracleRecord `json:"items"`
+	}
+	if err := p.api.do(ctx, http.MethodGet, p.recordsPath(zone, fqdn, url.Values{"rtype": {"TXT"}}), nil, &list); err != nil {
+		return fmt.Errorf("oraclecloud: list records: %w", err)
+	}
+	var ops []oracleRecord
+	for _, r := range list.Items {
+		if strings.Trim(r.RData, `"`) == value {
+			ops = append(ops, oracleRecord{Operation: "REMOVE", Domain: fqdn, RType: "TXT", RecordHash: r.RecordHash})
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := p.patch(ctx, zone, fqdn, ops); err != nil {
+		return fmt.Errorf("oraclecloud: delete record: %w", err)
+	}
+	return nil
+}
+
+func (p *oracleCloudProvider) patch(ctx context.Context, zone, fqdn string, ops []oracleRecord) error {
+	return p.api.do(ctx, http.MethodPatch, p.recordsPath(zone, fqdn, url.Values{}), map[string]any{"items": ops}, nil)
+}
+
+// recordsPath addresses the zone by name, the compartment is needed when the name isn't unique in the tenancy
+func (p *oracleCloudProvider) recordsPath(zone, fqdn string, query url.Values) string {
+	if p.compartmentID != "" {
+		query.Set("compartmentId", p.compartmentID)
+	}
+	path := "/zones/" + url.PathEscape(zone) + "/records/" + url.PathEscape(fqdn)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// sign adds the OCI HTTP signature (draft-cavage with rsa-sha256), requests with a body also sign its hash
+func (p *oracleCloudProvider) sign(req *http.Request, body []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"date", "(request-target)", "host"}
+	if req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch {
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines[i] = h + ": " + strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			lines[i] = h + ": " + req.URL.Host
+		default:
+			lines[i] = h + ": " + req.Header.Get(h)
+		}
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		p.keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// oracleCloudKey parses the API signing key, PKCS#1 or PKCS#8, from private_key or private_key_file
+func oracleCloudKey(c *utils.OracleCloudConfig) (*rsa.PrivateKey, error) {
+	data := []byte(c.PrivateKey)
+	if len(data) == 0 {
+		if c.PrivateKeyFile == "" {
+			return nil, errors.New("oraclecloud private_key or private_key_file is required")
+		}
+		var err error
+		if data, err = os.ReadFile(c.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("read oraclecloud private key: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("oraclecloud private key is not PEM encoded")
+	}
+	der := block.Bytes
+	// keys encrypted by the OCI console and openssl genrsa -aes128 use the legacy PEM encryption
+	if x509.IsEncryptedPEMBlock(block) { //nolint:staticcheck
+		var err error
+		if der, err = x509.DecryptPEMBlock(block, []byte(c.PrivateKeyPassphrase)); err != nil { //nolint:staticcheck
+			return nil, fmt.Errorf("decrypt oraclecloud private key: %w", err)
+		}
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse oraclecloud private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("oraclecloud private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,89 @@
+package dnsproviders
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestOracleCloudPresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+	const path = "/20180115/zones/example.com/records/_acme-challenge.example.com"
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"PATCH " + path: reply(http.StatusOK, map[string]any{"items": []any{}}),
+		"GET " + path: reply(http.StatusOK, map[string]any{"items": []oracleRecord{
+			{Domain: "_acme-challenge.example.com", RType: "TXT", RData: `"` + value + `"`, RecordHash: "hash-1"},
+			{Domain: "_acme-challenge.example.com", RType: "TXT", RData: `"another-order"`, RecordHash: "hash-2"},
+		}}),
+	})
+	p, err := newOracleCloud(utils.ProviderConfig{Name: "oraclecloud", OracleCloud: &utils.OracleCloudConfig{
+		TenancyOCID:   "ocid1.tenancy.oc1..aaa",
+		UserOCID:      "ocid1.user.oc1..bbb",
+		Fingerprint:   "20:3b:97:13",
+		PrivateKey:    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustPKCS8(t, key)})),
+		Region:        "us-phoenix-1",
+		CompartmentID: "ocid1.compartment.oc1..ccc",
+	}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*oracleCloudProvider).api, server, "/20180115")
+
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	add := api.last(http.MethodPatch, path)
+	if add.path != path+"?compartmentId=ocid1.compartment.oc1..ccc" {
+		t.Errorf("patched %s", add.path)
+	}
+	var ops struct {
+		Items []oracleRecord `json:"items"`
+	}
+	if err := json.Unmarshal(add.body, &ops); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops.Items) != 1 || ops.Items[0] != (oracleRecord{Operation: "ADD", Domain: "_acme-challenge.example.com", RType: "TXT", RData: value, TTL: 120}) {
+		t.Errorf("operations %s", add.body)
+	}
+	verifyOracleSignature(t, signedRequest(add, server.URL), &key.PublicKey)
+
+	// only the record with the challenge value is removed, by its hash
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	remove := api.last(http.MethodPatch, path)
+	ops.Items = nil
+	if err := json.Unmarshal(remove.body, &ops); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops.Items) != 1 || ops.Items[0] != (oracleRecord{Operation: "REMOVE", Domain: "_acme-challenge.example.com", RType: "TXT", RecordHash: "hash-1"}) {
+		t.Errorf("operations %s", remove.body)
+	}
+}
+
+func mustPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// signedRequest rebuilds the request the fake API received for the signature check
+func signedRequest(r apiRequest, serverURL string) *http.Request {
+	req, _ := http.NewRequest(r.method, serverURL+r.path, nil)
+	req.Header = r.header
+	return req
+}
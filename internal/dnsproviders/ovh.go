@@ -0,0 +1,178 @@
+package dnsproviders
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// ovhEndpoints are the API endpoints by the names OVH's own clients use
+var ovhEndpoints = map[string]string{
+	"ovh-eu":        "https://eu.api.ovh.com/1.0",
+	"ovh-ca":        "https://ca.api.ovh.com/1.0",
+	"ovh-us":        "https://api.us.ovhcloud.com/1.0",
+	"kimsufi-eu":    "https://eu.api.kimsufi.com/1.0",
+	"kimsufi-ca":    "https://ca.api.kimsufi.com/1.0",
+	"soyoustart-eu": "https://eu.api.soyoustart.com/1.0",
+	"soyoustart-ca": "https://ca.api.soyoustart.com/1.0",
+}
+
+const defaultOVHEndpoint = "ovh-eu"
+
+type ovhProvider struct {
+	propagation
+	api *restClient
+
+	applicationKey    string
+	applicationSecret string
+	consumerKey       string
+
+	// requests are signed with the server time, the offset to it is read on the first request
+	clockMu    sync.Mutex
+	clockRead  bool
+	clockDelta time.Duration
+}
+
+type ovhRecord struct {
+	ID        int64  `json:"id,omitempty"`
+	FieldType string `json:"fieldType"`
+	SubDomain string `json:"subDomain"`
+	Target    string `json:"target"`
+	TTL       int    `json:"ttl,omitempty"`
+}
+
+func newOVH(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	c := provider.OVH
+	if c == nil {
+		return nil, errors.New("ovh block is missing")
+	}
+	if c.ApplicationKey == "" || c.ApplicationSecret == "" || c.ConsumerKey == "" {
+		return nil, errors.New("ovh application_key, application_secret and consumer_key are required")
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultOVHEndpoint
+	}
+	baseURL, ok := ovhEndpoints[endpoint]
+	if !ok {
+		if !strings.HasPrefix(endpoint, "https://") {
+			return nil, fmt.Errorf("unknown ovh endpoint %q", endpoint)
+		}
+		baseURL = endpoint
+	}
+
+	p := &ovhProvider{
+		propagation:       newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		applicationKey:    c.ApplicationKey,
+		applicationSecret: c.ApplicationSecret,
+		consumerKey:       c.ConsumerKey,
+	}
+	p.api = newRESTClient(httpClient, baseURL, p.sign)
+	return p, nil
+}
+
+func (p *ovhProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("ovh: %w", err)
+	}
+
+	record := ovhRecord{FieldType: "TXT", SubDomain: name, Target: value, TTL: dns01.DefaultTTL}
+	if err := p.api.do(ctx, http.MethodPost, ovhZonePath(zone, "/record"), record, nil); err != nil {
+		return fmt.Errorf("ovh: create record: %w", err)
+	}
+	return p.refresh(ctx, zone)
+}
+
+func (p *ovhProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("ovh: %w", err)
+	}
+
+	var ids []int64
+	query := url.Values{"fieldType": {"TXT"}, "subDomain": {name}}
+	if err := p.api.do(ctx, http.MethodGet, ovhZonePath(zone, "/record?"+query.Encode()), nil, &ids); err != nil {
+		return fmt.Errorf("ovh: list records: %w", err)
+	}
+	for _, id := range ids {
+		path := ovhZonePath(zone, "/record/"+strconv.FormatInt(id, 10))
+		var record ovhRecord
+		if err := p.api.do(ctx, http.MethodGet, path, nil, &record); err != nil {
+			return fmt.Errorf("ovh: get record: %w", err)
+		}
+		if strings.Trim(record.Target, `"`) != value {
+			continue
+		}
+		if err := p.api.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+			return fmt.Errorf("ovh: delete record: %w", err)
+		}
+	}
+	return p.refresh(ctx, zone)
+}
+
+// refresh applies the record changes to the zone, OVH doesn't serve them before
+func (p *ovhProvider) refresh(ctx context.Context, zone string) error {
+	if err := p.api.do(ctx, http.MethodPost, ovhZonePath(zone, "/refresh"), nil, nil); err != nil {
+		return fmt.Errorf("ovh: refresh zone: %w", err)
+	}
+	return nil
+}
+
+func ovhZonePath(zone, suffix string) string {
+	return "/domain/zone/" + url.PathEscape(zone) + suffix
+}
+
+// sign adds OVH's request signature: "$1$" + SHA1 of the secrets, method, URL, body and timestamp
+func (p *ovhProvider) sign(req *http.Request, body []byte) error {
+	delta, err := p.serverClockDelta(req.Context())
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Add(delta).Unix(), 10)
+	req.Header.Set("X-Ovh-Application", p.applicationKey)
+	req.Header.Set("X-Ovh-Consumer", p.consumerKey)
+	req.Header.Set("X-Ovh-Timestamp", timestamp)
+	req.Header.Set("X-Ovh-Signature", ovhSignature(p.applicationSecret, p.consumerKey, req.Method, req.URL.String(), body, timestamp))
+	return nil
+}
+
+func ovhSignature(applicationSecret, consumerKey, method, rawURL string, body []byte, timestamp string) string {
+	sum := sha1.Sum([]byte(strings.Join([]string{
+		applicationSecret, consumerKey, method, rawURL, string(body), timestamp,
+	}, "+")))
+	return "$1$" + hex.EncodeToString(sum[:])
+}
+
+// serverClockDelta reads /auth/time, the only call that isn't signed, a failed read is retried on the next request
+func (p *ovhProvider) serverClockDelta(ctx context.Context) (time.Duration, error) {
+	p.clockMu.Lock()
+	defer p.clockMu.Unlock()
+	if p.clockRead {
+		return p.clockDelta, nil
+	}
+
+	var serverTime int64
+	unsigned := newRESTClient(p.api.http, p.api.baseURL, nil)
+	if err := unsigned.do(ctx, http.MethodGet, "/auth/time", nil, &serverTime); err != nil {
+		return 0, fmt.Errorf("read server time: %w", err)
+	}
+	p.clockDelta, p.clockRead = time.Until(time.Unix(serverTime, 0)), true
+	return p.clockDelta, nil
+}
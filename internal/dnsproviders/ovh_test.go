@@ -0,0 +1,87 @@
+package dnsproviders
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestOVHPresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /1.0/auth/time":                            reply(http.StatusOK, time.Now().Unix()),
+		"POST /1.0/domain/zone/example.com/record":      reply(http.StatusOK, ovhRecord{ID: 11}),
+		"POST /1.0/domain/zone/example.com/refresh":     reply(http.StatusOK, nil),
+		"GET /1.0/domain/zone/example.com/record":       reply(http.StatusOK, []int64{11, 12}),
+		"GET /1.0/domain/zone/example.com/record/11":    reply(http.StatusOK, ovhRecord{ID: 11, FieldType: "TXT", SubDomain: "_acme-challenge.www", Target: `"` + value + `"`}),
+		"GET /1.0/domain/zone/example.com/record/12":    reply(http.StatusOK, ovhRecord{ID: 12, FieldType: "TXT", SubDomain: "_acme-challenge.www", Target: "another-order"}),
+		"DELETE /1.0/domain/zone/example.com/record/11": reply(http.StatusOK, nil),
+	})
+	p, err := newOVH(utils.ProviderConfig{Name: "ovh", OVH: &utils.OVHConfig{
+		ApplicationKey: "app", ApplicationSecret: "secret", ConsumerKey: "consumer",
+	}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*ovhProvider).api, server, "/1.0")
+
+	if err := p.Present("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	created := decodeJSON(t, api.last(http.MethodPost, "/1.0/domain/zone/example.com/record").body)
+	if created["fieldType"] != "TXT" || created["subDomain"] != "_acme-challenge.www" || created["target"] != value {
+		t.Errorf("created %v", created)
+	}
+
+	if err := p.CleanUp("www.example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if list := api.last(http.MethodGet, "/1.0/domain/zone/example.com/record"); list.path != "/1.0/domain/zone/example.com/record?fieldType=TXT&subDomain=_acme-challenge.www" {
+		t.Errorf("records listed with %s", list.path)
+	}
+	calls := api.calls()
+	if slices.Contains(calls, "DELETE /1.0/domain/zone/example.com/record/12") {
+		t.Error("the record of another order was deleted")
+	}
+	// both changes are applied to the zone, the server time is read once
+	refreshes, clockReads := 0, 0
+	for _, c := range calls {
+		switch c {
+		case "POST /1.0/domain/zone/example.com/refresh":
+			refreshes++
+		case "GET /1.0/auth/time":
+			clockReads++
+		}
+	}
+	if refreshes != 2 || clockReads != 1 {
+		t.Errorf("refreshed %d times, read the server time %d times", refreshes, clockReads)
+	}
+}
+
+func TestOVHEndpoint(t *testing.T) {
+	config := func(endpoint string) utils.ProviderConfig {
+		return utils.ProviderConfig{Name: "ovh", OVH: &utils.OVHConfig{
+			Endpoint: endpoint, ApplicationKey: "app", ApplicationSecret: "secret", ConsumerKey: "consumer",
+		}}
+	}
+	for endpoint, want := range map[string]string{
+		"":                           "https://eu.api.ovh.com/1.0",
+		"ovh-ca":                     "https://ca.api.ovh.com/1.0",
+		"https://api.example.test/1": "https://api.example.test/1",
+	} {
+		p, err := newOVH(config(endpoint), &utils.Config{}, http.DefaultClient)
+		if err != nil {
+			t.Fatalf("%q: %v", endpoint, err)
+		}
+		if got := p.(*ovhProvider).api.baseURL; got != want {
+			t.Errorf("%q: got %s, want %s", endpoint, got, want)
+		}
+	}
+	if _, err := newOVH(config("ovh-mars"), &utils.Config{}, http.DefaultClient); err == nil {
+		t.Error("accepted an unknown endpoint")
+	}
+}
@@ -0,0 +1,36 @@
+package dnsproviders
+
+import (
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+
+	"github.com/go-acme/lego/v4/challenge"
+	pd "github.com/go-acme/lego/v4/providers/dns/pdns"
+)
+
+func newPowerDNS(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.PowerDNS == nil {
+		return nil, errors.New("pdns block is missing")
+	}
+	host, err := url.Parse(provider.PowerDNS.APIURL)
+	if err != nil {
+		return nil, fmt.Errorf("pdns api_url: %w", err)
+	}
+	config := pd.NewDefaultConfig()
+	config.Host = host
+	config.APIKey = provider.PowerDNS.APIKey
+	if provider.PowerDNS.ServerName != "" {
+		config.ServerName = provider.PowerDNS.ServerName
+	}
+	config.APIVersion = provider.PowerDNS.APIVersion
+	config.HTTPClient = httpClient
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := pd.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
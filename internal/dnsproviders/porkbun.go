@@ -0,0 +1,114 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+const (
+	porkbunBaseURL = "https://api.porkbun.com/api/json/v3"
+	porkbunMinTTL  = 600 // lower values are raised by Porkbun anyway
+)
+
+type porkbunProvider struct {
+	propagation
+	api       *restClient
+	apiKey    string
+	secretKey string
+}
+
+// porkbunReq is the body of every call, the credentials travel in it instead of headers
+type porkbunReq struct {
+	APIKey    string `json:"apikey"`
+	SecretKey string `json:"secretapikey"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Content   string `json:"content,omitempty"`
+	TTL       string `json:"ttl,omitempty"`
+}
+
+type porkbunResp struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Records []struct {
+		ID      string `json:"id"`
+		Content string `json:"content"`
+	} `json:"records"`
+}
+
+func newPorkbun(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Porkbun == nil {
+		return nil, errors.New("porkbun block is missing")
+	}
+	if provider.Porkbun.APIKey == "" || provider.Porkbun.SecretKey == "" {
+		return nil, errors.New("porkbun api_key and secret_key are required")
+	}
+	return &porkbunProvider{
+		// Porkbun's nameservers pick up changes within a few minutes
+		propagation: newPropagation(provider, cfg.Certs, 10*time.Minute, 10*time.Second),
+		api:         newRESTClient(httpClient, porkbunBaseURL, nil),
+		apiKey:      provider.Porkbun.APIKey,
+		secretKey:   provider.Porkbun.SecretKey,
+	}, nil
+}
+
+func (p *porkbunProvider) Present(domain, token, keyAuth string) error {
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("porkbun: %w", err)
+	}
+	req := p.request()
+	req.Name, req.Type, req.Content, req.TTL = name, "TXT", value, strconv.Itoa(porkbunMinTTL)
+	if _, err := p.call(context.Background(), "/dns/create/"+url.PathEscape(zone), req); err != nil {
+		return fmt.Errorf("porkbun: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *porkbunProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("porkbun: %w", err)
+	}
+
+	path := "/dns/retrieveByNameType/" + url.PathEscape(zone) + "/TXT/" + url.PathEscape(name)
+	list, err := p.call(ctx, path, p.request())
+	if err != nil {
+		return fmt.Errorf("porkbun: list records: %w", err)
+	}
+	for _, r := range list.Records {
+		if strings.Trim(r.Content, `"`) != value {
+			continue
+		}
+		if _, err := p.call(ctx, "/dns/delete/"+url.PathEscape(zone)+"/"+url.PathEscape(r.ID), p.request()); err != nil {
+			return fmt.Errorf("porkbun: delete record: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *porkbunProvider) request() porkbunReq {
+	return porkbunReq{APIKey: p.apiKey, SecretKey: p.secretKey}
+}
+
+// call posts req to path, Porkbun reports some failures with a 200 and status ERROR
+func (p *porkbunProvider) call(ctx context.Context, path string, req porkbunReq) (*porkbunResp, error) {
+	var resp porkbunResp
+	if err := p.api.do(ctx, http.MethodPost, path, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "SUCCESS" {
+		return nil, fmt.Errorf("%s: %s", resp.Status, resp.Message)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,68 @@
+package dnsproviders
+
+import (
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+)
+
+// resolverTimeout bounds one TXT query to a configured resolver
+const resolverTimeout = 5 * time.Second
+
+// propagationOptions turns the resolvers of the provider entry, or certs.resolvers, into a pre-check:
+// once lego sees the record on the zone's authoritative nameservers it must also be visible on every
+// resolver. lego's own recursive nameserver setting is process-wide, so it isn't used per provider
+func propagationOptions(provider utils.ProviderConfig, certs utils.CertsConfig) []dns01.ChallengeOption {
+	resolvers := provider.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = certs.Resolvers
+	}
+	if len(resolvers) == 0 && !provider.SkipAuthoritativeCheck {
+		return nil
+	}
+	resolvers = dns01.ParseNameservers(resolvers)
+	skipAuthoritative := provider.SkipAuthoritativeCheck
+
+	return []dns01.ChallengeOption{
+		dns01.WrapPreCheck(func(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+			if !skipAuthoritative {
+				if ok, err := check(fqdn, value); err != nil || !ok {
+					return ok, err
+				}
+			}
+			return txtOnResolvers(fqdn, value, resolvers)
+		}),
+	}
+}
+
+// txtOnResolvers reports whether every resolver answers the TXT record of fqdn with value
+func txtOnResolvers(fqdn, value string, resolvers []string) (bool, error) {
+	client := &dns.Client{Timeout: resolverTimeout}
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(fqdn), dns.TypeTXT)
+	msg.RecursionDesired = true
+
+	for _, resolver := range resolvers {
+		in, _, err := client.Exchange(msg, resolver)
+		if err != nil {
+			return false, fmt.Errorf("query %s on %s: %w", fqdn, resolver, err)
+		}
+		if !hasTXT(in, value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func hasTXT(msg *dns.Msg, value string) bool {
+	for _, rr := range msg.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package dnsproviders
+
+import (
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Provider is a DNS account that solves DNS-01 challenges, Name is what domains reference in dns_provider
+type Provider struct {
+	Name      string
+	Type      string
+	challenge challenge.Provider
+	options   []dns01.ChallengeOption
+
+	nameservers []string // path.Match patterns of the nameservers hosting its zones
+
+	delegations []delegation
+	lookup      func(name string) (*Provider, error) // set by the registry the provider is in
+}
+
+// Challenge returns the lego provider set on the ACME client for the DNS-01 challenge
+func (p *Provider) Challenge() challenge.Provider {
+	if len(p.delegations) > 0 {
+		return &delegatingProvider{owner: p}
+	}
+	return p.challenge
+}
+
+// ChallengeOptions are passed along with Challenge, e.g. the resolver of the sandbox provider
+func (p *Provider) ChallengeOptions() []dns01.ChallengeOption {
+	return p.options
+}
+
+// builder creates the lego provider of one type from its typed config block
+type builder func(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error)
+
+// builders has one entry per provider type, a new provider adds its file and an entry here
+var builders = map[string]builder{
+	utils.ProviderCloudflare:   newCloudflare,
+	utils.ProviderHetzner:      newHetzner,
+	utils.ProviderDigitalOcean: newDigitalOcean,
+	utils.ProviderRoute53:      newRoute53,
+	utils.ProviderDNSimple:     newDNSimple,
+	utils.ProviderNamecheap:    newNamecheap,
+	utils.ProviderGandi:        newGandi,
+	utils.ProviderOVH:          newOVH,
+	utils.ProviderPorkbun:      newPorkbun,
+	utils.ProviderPowerDNS:     newPowerDNS,
+	utils.ProviderRFC2136:      newRFC2136,
+	utils.ProviderLinode:       newLinode,
+	utils.ProviderVultr:        newVultr,
+	utils.ProviderDeSEC:        newDeSEC,
+	utils.ProviderGoDaddy:      newGoDaddy,
+	utils.ProviderScaleway:     newScaleway,
+	utils.ProviderNS1:          newNS1,
+	utils.ProviderInfoblox:     newInfoblox,
+	utils.ProviderOracleCloud:  newOracleCloud,
+	utils.ProviderAliDNS:       newAliDNS,
+	utils.ProviderIONOS:        newIONOS,
+	utils.ProviderAcmeDNS:      newAcmeDNS,
+	utils.ProviderHTTPReq:      newHTTPReq,
+	utils.ProviderNetlify:      newNetlify,
+	utils.ProviderSelfSigned:   newSelfSigned,
+	utils.ProviderSandbox:      newSandbox,
+}
+
+// New builds the provider without touching the process environment, so several accounts
+// of the same type can be used side by side
+func New(provider utils.ProviderConfig, cfg *utils.Config, log *utils.Logger) (*Provider, error) {
+	log.Debug("dnsproviders.New(): called",
+		" name=", provider.Name,
+		" type=", provider.ProviderType(),
+	)
+
+	providerType := provider.ProviderType()
+	// in sandbox mode no provider talks to a real DNS account, the names stay so domains keep resolving them.
+	// selfsigned doesn't talk to anything in the first place
+	if cfg.Sandbox.Enabled && providerType != utils.ProviderSelfSigned {
+		providerType = utils.ProviderSandbox
+	}
+	build, ok := builders[providerType]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider type: %s", providerType)
+	}
+
+	httpClient, err := utils.NewHTTPClient(cfg.HTTPClient)
+	if err != nil {
+		return nil, fmt.Errorf("http client: %w", err)
+	}
+
+	p, err := build(provider, cfg, httpClient)
+	if err != nil {
+		log.Error("DNS provider init failed: ", provider.Name, " ", err)
+		return nil, fmt.Errorf("%s provider init: %w", provider.Name, err)
+	}
+
+	log.Debug("DNS provider initialized: ", provider.Name)
+	out := &Provider{
+		Name:      provider.Name,
+		Type:      providerType,
+		challenge: p,
+	}
+	switch sp := p.(type) {
+	case *sandboxProvider:
+		out.options = sp.challengeOptions()
+	case *selfSignedProvider:
+	default:
+		out.options = propagationOptions(provider, cfg.Certs)
+		out.nameservers = nameserversOf(provider, providerType)
+		out.delegations = delegationsOf(provider)
+	}
+	return out, nil
+}
+
+// CreateProviders builds the enabled providers of the config, a provider that fails to
+// initialize is logged and skipped
+func CreateProviders(cfg *utils.Config, log *utils.Logger) ([]*Provider, error) {
+	var providers []*Provider
+	for _, provider := range cfg.DisabledProviders() {
+		log.Warn("DNS provider '", provider.Name, "' disabled: ", provider.Disabled)
+	}
+	for _, provider := range cfg.EnabledProviders() {
+		p, err := New(provider, cfg, log)
+		if err != nil {
+			log.Error("failed to create DNS provider: ", err)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("0 DNS providers created")
+	}
+	return providers, nil
+}
+
+// applyPropagation overrides the provider's propagation defaults with certs.propagation_timeout
+// and certs.dns_poll_interval when they are set, the values of the provider entry win over both
+func applyPropagation(provider utils.ProviderConfig, certs utils.CertsConfig, timeout, interval *time.Duration) {
+	if certs.PropagationTimeout > 0 {
+		*timeout = certs.PropagationTimeout
+	}
+	if certs.DNSPollInterval > 0 {
+		*interval = certs.DNSPollInterval
+	}
+	if provider.PropagationTimeout > 0 {
+		*timeout = provider.PropagationTimeout
+	}
+	if provider.PollingInterval > 0 {
+		*interval = provider.PollingInterval
+	}
+}
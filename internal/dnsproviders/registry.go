@@ -0,0 +1,98 @@
+package dnsproviders
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry holds the providers of the config file and the ones registered at runtime,
+// both lists are swapped as a whole so running issuances keep the provider they started with
+type Registry struct {
+	mu      sync.RWMutex
+	static  []*Provider
+	runtime []*Provider
+
+	// acmeDNS is handed to every acme-dns provider added to the registry
+	acmeDNS AcmeDNSStore
+}
+
+func NewRegistry(static []*Provider) *Registry {
+	r := &Registry{static: static}
+	r.attach(static...)
+	return r
+}
+
+// Get returns the provider with the name, config providers win over runtime ones
+func (r *Registry) Get(name string) (*Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, list := range [][]*Provider{r.static, r.runtime} {
+		for _, p := range list {
+			if p.Name == name {
+				return p, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("DNS provider '%s' not found", name)
+}
+
+// SetStatic replaces the providers of the config file, e.g. on reload
+func (r *Registry) SetStatic(providers []*Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attach(providers...)
+	r.static = providers
+}
+
+// SetRuntime replaces the providers registered at runtime
+func (r *Registry) SetRuntime(providers []*Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attach(providers...)
+	r.runtime = providers
+}
+
+// PutRuntime adds a runtime provider or replaces the one with the same name
+func (r *Registry) PutRuntime(provider *Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attach(provider)
+	r.runtime = append(without(r.runtime, provider.Name), provider)
+}
+
+// RemoveRuntime drops a runtime provider
+func (r *Registry) RemoveRuntime(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runtime = without(r.runtime, name)
+}
+
+// SetAcmeDNSStore sets where acme-dns providers keep the accounts of the domains
+func (r *Registry) SetAcmeDNSStore(store AcmeDNSStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.acmeDNS = store
+	r.attach(r.static...)
+	r.attach(r.runtime...)
+}
+
+// attach hands the registry's lookup to delegating providers and the account store to acme-dns ones
+func (r *Registry) attach(providers ...*Provider) {
+	for _, p := range providers {
+		p.setLookup(r.Get)
+		if r.acmeDNS != nil {
+			p.setAcmeDNSStore(r.acmeDNS)
+		}
+	}
+}
+
+func without(list []*Provider, name string) []*Provider {
+	out := make([]*Provider, 0, len(list))
+	for _, p := range list {
+		if p.Name != name {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package dnsproviders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// providers whose lego package pulls in a vendor SDK talk to the API directly through restClient,
+// records are looked up by name and value on cleanup so no state is kept between Present and CleanUp
+
+const (
+	// maxErrorBody is how much of an error response ends up in the returned error
+	maxErrorBody = 512
+	// rateLimitWait is the first wait after a 429 without Retry-After, doubled on every retry
+	rateLimitWait = time.Second
+)
+
+// restClient sends JSON requests to a provider API, authorize adds the credentials to every request.
+// APIs with tight rate limits set rateLimitRetries, a 429 is then retried for every method since
+// the request wasn't processed
+type restClient struct {
+	http             *http.Client
+	baseURL          string
+	authorize        func(req *http.Request, body []byte) error
+	rateLimitRetries int
+}
+
+func newRESTClient(httpClient *http.Client, baseURL string, authorize func(req *http.Request, body []byte) error) *restClient {
+	return &restClient{http: httpClient, baseURL: strings.TrimSuffix(baseURL, "/"), authorize: authorize}
+}
+
+// bearer authorizes with "Authorization: <scheme> <token>"
+func bearer(scheme, token string) func(req *http.Request, body []byte) error {
+	return func(req *http.Request, _ []byte) error {
+		req.Header.Set("Authorization", scheme+" "+token)
+		return nil
+	}
+}
+
+// do sends in as the JSON body and decodes the response into out, both may be nil
+func (c *restClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	wait := rateLimitWait
+	for attempt := 0; ; attempt++ {
+		resp, data, err := c.send(ctx, method, path, body, in != nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.rateLimitRetries {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			wait *= 2
+			continue
+		}
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			if len(data) > maxErrorBody {
+				data = data[:maxErrorBody]
+			}
+			return &apiError{method: method, path: path, status: resp.Status, code: resp.StatusCode, body: strings.TrimSpace(string(data))}
+		}
+		if out == nil || len(data) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+		}
+		return nil
+	}
+}
+
+// apiError is an error response of the API, code lets providers treat e.g. a 404 as an empty result
+type apiError struct {
+	method, path string
+	status       string
+	code         int
+	body         string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.method, e.path, e.status, e.body)
+}
+
+func isStatus(err error, code int) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.code == code
+}
+
+func (c *restClient) send(ctx context.Context, method, path string, body []byte, hasBody bool) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.authorize != nil {
+		if err := c.authorize(req, body); err != nil {
+			return nil, nil, fmt.Errorf("authorize request: %w", err)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: read response: %w", method, path, err)
+	}
+	return resp, data, nil
+}
+
+// propagation is the Timeout of the providers built here, timeout and interval are the defaults of the provider
+type propagation struct {
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func newPropagation(provider utils.ProviderConfig, certs utils.CertsConfig, timeout, interval time.Duration) propagation {
+	p := propagation{timeout: timeout, interval: interval}
+	applyPropagation(provider, certs, &p.timeout, &p.interval)
+	return p
+}
+
+func (p propagation) Timeout() (timeout, interval time.Duration) {
+	return p.timeout, p.interval
+}
+
+// findZone looks up the zone a challenge record is created in, through the SOA records in DNS
+var findZone = dns01.FindZoneByFqdn
+
+// challengeRecord splits the challenge of domain into its zone and the record name relative to it
+func challengeRecord(domain, keyAuth string) (zone, name, value string, err error) {
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+	authZone, err := findZone(info.EffectiveFQDN)
+	if err != nil {
+		return "", "", "", fmt.Errorf("find zone of %s: %w", info.EffectiveFQDN, err)
+	}
+	name, err = dns01.ExtractSubDomain(info.EffectiveFQDN, authZone)
+	if err != nil {
+		return "", "", "", err
+	}
+	return dns01.UnFqdn(authZone), name, info.Value, nil
+}
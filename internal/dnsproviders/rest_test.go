@@ -0,0 +1,238 @@
+package dnsproviders
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// the providers talking to their API through restClient are tested against fakes of the documented
+// endpoints, the challenge records are found through fakeZone without DNS
+
+const testKeyAuth = "token.thumbprint"
+
+// testChallengeValue is the TXT value of testKeyAuth, computed like RFC 8555 section 8.4
+func testChallengeValue() string {
+	sum := sha256.Sum256([]byte(testKeyAuth))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// fakeZone answers the zone lookup of challenge records under zone, CNAMEs aren't followed
+func fakeZone(t *testing.T, zone string) {
+	t.Helper()
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+	previous := findZone
+	findZone = func(fqdn string) (string, error) {
+		if !strings.HasSuffix(fqdn, "."+dns01.ToFqdn(zone)) {
+			return "", fmt.Errorf("%s isn't in zone %s", fqdn, zone)
+		}
+		return dns01.ToFqdn(zone), nil
+	}
+	t.Cleanup(func() { findZone = previous })
+}
+
+// apiRequest is a request a fakeAPI received
+type apiRequest struct {
+	method string
+	path   string // with the query
+	header http.Header
+	body   []byte
+}
+
+// fakeAPI serves the routes, keyed by method and path like "POST /zones", and keeps every request.
+// Requests without a route fail the test
+type fakeAPI struct {
+	t      *testing.T
+	routes map[string]func(w http.ResponseWriter, r *http.Request, body []byte)
+
+	mu       sync.Mutex
+	requests []apiRequest
+}
+
+func newFakeAPI(t *testing.T, routes map[string]func(w http.ResponseWriter, r *http.Request, body []byte)) (*fakeAPI, *httptest.Server) {
+	t.Helper()
+	api := &fakeAPI{t: t, routes: routes}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	a.mu.Lock()
+	a.requests = append(a.requests, apiRequest{method: r.Method, path: r.URL.RequestURI(), header: r.Header.Clone(), body: body})
+	a.mu.Unlock()
+
+	route, ok := a.routes[r.Method+" "+r.URL.Path]
+	if !ok {
+		a.t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		return
+	}
+	route(w, r, body)
+}
+
+// calls lists the requests as "METHOD path"
+func (a *fakeAPI) calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var calls []string
+	for _, r := range a.requests {
+		calls = append(calls, r.method+" "+r.path)
+	}
+	return calls
+}
+
+// last returns the last request of the method and path without the query
+func (a *fakeAPI) last(method, path string) apiRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := len(a.requests) - 1; i >= 0; i-- {
+		r := a.requests[i]
+		if p, _, _ := strings.Cut(r.path, "?"); r.method == method && p == path {
+			return r
+		}
+	}
+	a.t.Fatalf("no request %s %s, got %v", method, path, a.requests)
+	return apiRequest{}
+}
+
+// reply answers with status and v as JSON
+func reply(status int, v any) func(w http.ResponseWriter, r *http.Request, body []byte) {
+	return func(w http.ResponseWriter, _ *http.Request, _ []byte) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if v != nil {
+			_ = json.NewEncoder(w).Encode(v)
+		}
+	}
+}
+
+// decodeJSON decodes a request body, failing the test on invalid JSON
+func decodeJSON(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatalf("request body %q: %v", body, err)
+	}
+	return v
+}
+
+// pointAt sends the requests of a provider built with its fixed API URL to the fake API
+func pointAt(api *restClient, server *httptest.Server, path string) {
+	api.baseURL = server.URL + path
+}
+
+func TestRESTClientSendsJSON(t *testing.T) {
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"POST /records": reply(http.StatusCreated, map[string]string{"id": "r1"}),
+		"GET /records":  reply(http.StatusOK, nil),
+	})
+	var signed []byte
+	client := newRESTClient(server.Client(), server.URL+"/", func(req *http.Request, body []byte) error {
+		signed = body
+		req.Header.Set("X-Auth", "secret")
+		return nil
+	})
+
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := client.do(context.Background(), http.MethodPost, "/records", map[string]string{"name": "_acme-challenge"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != "r1" {
+		t.Errorf("decoded %+v", out)
+	}
+	post := api.last(http.MethodPost, "/records")
+	if string(signed) != string(post.body) || string(post.body) != `{"name":"_acme-challenge"}` {
+		t.Errorf("authorized %q, sent %q", signed, post.body)
+	}
+	if post.header.Get("Content-Type") != "application/json" || post.header.Get("X-Auth") != "secret" {
+		t.Errorf("headers %v", post.header)
+	}
+
+	// no body, no content type, an empty response isn't decoded
+	if err := client.do(context.Background(), http.MethodGet, "/records", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if get := api.last(http.MethodGet, "/records"); get.header.Get("Content-Type") != "" || len(get.body) != 0 {
+		t.Errorf("GET sent %q with content type %q", get.body, get.header.Get("Content-Type"))
+	}
+}
+
+func TestRESTClientErrors(t *testing.T) {
+	_, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /missing": reply(http.StatusNotFound, map[string]string{"message": "no such zone"}),
+		"GET /broken": func(w http.ResponseWriter, _ *http.Request, _ []byte) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, strings.Repeat("x", 2*maxErrorBody))
+		},
+	})
+	client := newRESTClient(server.Client(), server.URL, nil)
+
+	err := client.do(context.Background(), http.MethodGet, "/missing", nil, nil)
+	if !isStatus(err, http.StatusNotFound) || !strings.Contains(err.Error(), "no such zone") {
+		t.Errorf("got %v, want a 404 with the message", err)
+	}
+	err = client.do(context.Background(), http.MethodGet, "/broken", nil, nil)
+	if !isStatus(err, http.StatusInternalServerError) {
+		t.Fatalf("got %v, want a 500", err)
+	}
+	if n := strings.Count(err.Error(), "x"); n != maxErrorBody {
+		t.Errorf("error carries %d bytes of the body, want %d", n, maxErrorBody)
+	}
+}
+
+func TestRESTClientRetriesRateLimits(t *testing.T) {
+	var calls int
+	_, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"DELETE /records/1": func(w http.ResponseWriter, _ *http.Request, _ []byte) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+	client := newRESTClient(server.Client(), server.URL, nil)
+
+	// without retries the 429 is the answer
+	if err := client.do(context.Background(), http.MethodDelete, "/records/1", nil, nil); !isStatus(err, http.StatusTooManyRequests) {
+		t.Fatalf("got %v, want a 429", err)
+	}
+	calls = 0
+	client.rateLimitRetries = 2
+	if err := client.do(context.Background(), http.MethodDelete, "/records/1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("sent %d requests, want 2", calls)
+	}
+}
+
+func TestChallengeRecord(t *testing.T) {
+	fakeZone(t, "example.com")
+	zone, name, value, err := challengeRecord("www.example.com", testKeyAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone != "example.com" || name != "_acme-challenge.www" || value != testChallengeValue() {
+		t.Errorf("got zone %q, name %q, value %q", zone, name, value)
+	}
+	if _, name, _, _ = challengeRecord("example.com", testKeyAuth); name != "_acme-challenge" {
+		t.Errorf("record of the apex is %q", name)
+	}
+}
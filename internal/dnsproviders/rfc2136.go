@@ -0,0 +1,32 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge"
+	rfc "github.com/go-acme/lego/v4/providers/dns/rfc2136"
+)
+
+// newRFC2136 sends TSIG signed dynamic updates straight to the primary nameserver, no HTTP involved
+func newRFC2136(provider utils.ProviderConfig, cfg *utils.Config, _ *http.Client) (challenge.Provider, error) {
+	c := provider.RFC2136
+	if c == nil {
+		return nil, errors.New("rfc2136 block is missing")
+	}
+	config := rfc.NewDefaultConfig()
+	config.Nameserver = c.Nameserver
+	config.TSIGFile = c.TSIGFile
+	config.TSIGKey = c.TSIGKey
+	config.TSIGSecret = c.TSIGSecret
+	if c.TSIGAlgorithm != "" {
+		config.TSIGAlgorithm = c.TSIGAlgorithm
+	}
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	p, err := rfc.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
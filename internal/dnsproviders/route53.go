@@ -0,0 +1,71 @@
+package dnsproviders
+
+import (
+	"context"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/go-acme/lego/v4/challenge"
+	r53 "github.com/go-acme/lego/v4/providers/dns/route53"
+)
+
+func newRoute53(provider utils.ProviderConfig, cfg *utils.Config, _ *http.Client) (challenge.Provider, error) {
+	// empty values keep the AWS_* environment defaults of lego
+	config := r53.NewDefaultConfig()
+	if r := provider.Route53; r != nil {
+		if r.AccessKey != "" {
+			config.AccessKeyID = r.AccessKey
+			config.SecretAccessKey = r.SecretKey
+		}
+		if r.Region != "" {
+			config.Region = r.Region
+		}
+		if r.HostedZoneID != "" {
+			config.HostedZoneID = r.HostedZoneID
+		}
+	}
+	applyPropagation(provider, cfg.Certs, &config.PropagationTimeout, &config.PollingInterval)
+	client, err := newRoute53Client(config, cfg.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	config.Client = client
+	p, err := r53.NewDNSProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newRoute53Client builds the Route53 API client with the outbound HTTP settings, the AWS SDK
+// retries on its own so the client is used without our retries
+func newRoute53Client(config *r53.Config, httpCfg utils.HTTPClientConfig) (*route53.Client, error) {
+	httpCfg.Retries = 0
+	httpClient, err := utils.NewHTTPClient(httpCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithHTTPClient(httpClient),
+		awsconfig.WithRetryMaxAttempts(config.MaxRetries),
+	}
+	if config.AccessKeyID != "" && config.SecretAccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(config.AccessKeyID, config.SecretAccessKey, config.SessionToken),
+		))
+	}
+	if config.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(config.Region))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	return route53.NewFromConfig(awsCfg), nil
+}
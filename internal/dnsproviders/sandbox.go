@@ -0,0 +1,134 @@
+package dnsproviders
+
+import (
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+)
+
+// sandboxZone holds the challenge records of sandbox mode and answers TXT queries for them,
+// one server per process is shared by every provider since they all listen on the same address
+type sandboxZone struct {
+	mu      sync.RWMutex
+	records map[string][]string // fqdn -> TXT values
+}
+
+var (
+	sandboxOnce   sync.Once
+	sandboxShared *sandboxZone
+	sandboxErr    error
+)
+
+// newSandbox stands in for every provider type in sandbox mode, no DNS account is touched
+func newSandbox(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	sandboxOnce.Do(func() {
+		sandboxShared, sandboxErr = startSandboxZone(cfg.Sandbox.DNSAddress)
+	})
+	if sandboxErr != nil {
+		return nil, sandboxErr
+	}
+	return &sandboxProvider{zone: sandboxShared, resolver: sandboxResolver(cfg.Sandbox.DNSAddress)}, nil
+}
+
+func startSandboxZone(address string) (*sandboxZone, error) {
+	zone := &sandboxZone{records: map[string][]string{}}
+
+	// listening up front reports a taken port right away instead of from the server goroutine
+	pc, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox dns: %w", err)
+	}
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		pc.Close()
+		return nil, fmt.Errorf("sandbox dns: %w", err)
+	}
+	go (&dns.Server{PacketConn: pc, Handler: zone}).ActivateAndServe()
+	go (&dns.Server{Listener: ln, Handler: zone}).ActivateAndServe()
+	return zone, nil
+}
+
+// ServeDNS answers TXT queries from memory, anything else gets an empty authoritative answer
+func (z *sandboxZone) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	z.mu.RLock()
+	for _, q := range r.Question {
+		if q.Qtype != dns.TypeTXT {
+			continue
+		}
+		for _, value := range z.records[strings.ToLower(q.Name)] {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+				Txt: []string{value},
+			})
+		}
+	}
+	z.mu.RUnlock()
+
+	_ = w.WriteMsg(m)
+}
+
+func (z *sandboxZone) add(fqdn, value string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	fqdn = strings.ToLower(fqdn)
+	z.records[fqdn] = append(z.records[fqdn], value)
+}
+
+func (z *sandboxZone) remove(fqdn, value string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	fqdn = strings.ToLower(fqdn)
+	z.records[fqdn] = slices.DeleteFunc(z.records[fqdn], func(v string) bool { return v == value })
+	if len(z.records[fqdn]) == 0 {
+		delete(z.records, fqdn)
+	}
+}
+
+type sandboxProvider struct {
+	zone     *sandboxZone
+	resolver string
+}
+
+func (p *sandboxProvider) Present(domain, token, keyAuth string) error {
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+	p.zone.add(info.EffectiveFQDN, info.Value)
+	return nil
+}
+
+func (p *sandboxProvider) CleanUp(domain, token, keyAuth string) error {
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+	p.zone.remove(info.EffectiveFQDN, info.Value)
+	return nil
+}
+
+// challengeOptions sends lego's lookups to the sandbox server, the records exist as soon as they are added
+func (p *sandboxProvider) challengeOptions() []dns01.ChallengeOption {
+	return []dns01.ChallengeOption{
+		dns01.AddRecursiveNameservers([]string{p.resolver}),
+		dns01.PropagationWait(0, true),
+	}
+}
+
+// sandboxResolver is the address lego queries, the loopback address when the server listens on all interfaces
+func sandboxResolver(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
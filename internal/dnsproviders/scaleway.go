@@ -0,0 +1,116 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const (
+	scalewayBaseURL = "https://api.scaleway.com/domain/v2beta1"
+	scalewayMinTTL  = 60
+)
+
+type scalewayProvider struct {
+	propagation
+	api       *restClient
+	projectID string
+}
+
+type scalewayRecord struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+// scalewayChange is one entry of the changes list of PATCH /dns-zones/{zone}/records
+type scalewayChange struct {
+	Add    *scalewayAdd    `json:"add,omitempty"`
+	Delete *scalewayDelete `json:"delete,omitempty"`
+}
+
+type scalewayAdd struct {
+	Records []scalewayRecord `json:"records"`
+}
+
+type scalewayDelete struct {
+	IDFields scalewayRecord `json:"id_fields"`
+}
+
+func newScaleway(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Scaleway == nil {
+		return nil, errors.New("scaleway block is missing")
+	}
+	if provider.Scaleway.SecretKey == "" || provider.Scaleway.ProjectID == "" {
+		return nil, errors.New("scaleway secret_key and project_id are required")
+	}
+	secretKey := provider.Scaleway.SecretKey
+	return &scalewayProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api: newRESTClient(httpClient, scalewayBaseURL, func(req *http.Request, _ []byte) error {
+			req.Header.Set("X-Auth-Token", secretKey)
+			return nil
+		}),
+		projectID: provider.Scaleway.ProjectID,
+	}, nil
+}
+
+func (p *scalewayProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("scaleway: %w", err)
+	}
+	if err := p.checkZone(ctx, zone); err != nil {
+		return fmt.Errorf("scaleway: %w", err)
+	}
+
+	record := scalewayRecord{Name: name, Type: "TXT", Data: strconv.Quote(value), TTL: scalewayMinTTL}
+	if err := p.patch(ctx, zone, scalewayChange{Add: &scalewayAdd{Records: []scalewayRecord{record}}}); err != nil {
+		return fmt.Errorf("scaleway: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *scalewayProvider) CleanUp(domain, token, keyAuth string) error {
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("scaleway: %w", err)
+	}
+
+	// records are deleted by their name, type and data, no lookup needed
+	record := scalewayRecord{Name: name, Type: "TXT", Data: strconv.Quote(value)}
+	if err := p.patch(context.Background(), zone, scalewayChange{Delete: &scalewayDelete{IDFields: record}}); err != nil {
+		return fmt.Errorf("scaleway: delete record: %w", err)
+	}
+	return nil
+}
+
+func (p *scalewayProvider) patch(ctx context.Context, zone string, change scalewayChange) error {
+	body := map[string]any{"changes": []scalewayChange{change}, "return_all_records": false}
+	return p.api.do(ctx, http.MethodPatch, "/dns-zones/"+url.PathEscape(zone)+"/records", body, nil)
+}
+
+// checkZone makes sure the zone belongs to the configured project, a key of several projects
+// could otherwise change a zone of another one
+func (p *scalewayProvider) checkZone(ctx context.Context, zone string) error {
+	var list struct {
+		TotalCount int `json:"total_count"`
+	}
+	query := url.Values{"project_id": {p.projectID}, "dns_zone": {zone}}
+	if err := p.api.do(ctx, http.MethodGet, "/dns-zones?"+query.Encode(), nil, &list); err != nil {
+		return fmt.Errorf("list zones: %w", err)
+	}
+	if list.TotalCount == 0 {
+		return fmt.Errorf("zone %s not found in project %s", zone, p.projectID)
+	}
+	return nil
+}
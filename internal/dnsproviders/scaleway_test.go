@@ -0,0 +1,73 @@
+package dnsproviders
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func newTestScaleway(t *testing.T, routes map[string]func(http.ResponseWriter, *http.Request, []byte)) (*fakeAPI, *scalewayProvider) {
+	t.Helper()
+	api, server := newFakeAPI(t, routes)
+	p, err := newScaleway(utils.ProviderConfig{Name: "scaleway", Scaleway: &utils.ScalewayConfig{SecretKey: "scw-secret", ProjectID: "project-1"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*scalewayProvider).api, server, "/domain/v2beta1")
+	return api, p.(*scalewayProvider)
+}
+
+func TestScalewayPresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	quoted := strconv.Quote(testChallengeValue())
+	api, p := newTestScaleway(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /domain/v2beta1/dns-zones":                       reply(http.StatusOK, map[string]any{"total_count": 1}),
+		"PATCH /domain/v2beta1/dns-zones/example.com/records": reply(http.StatusOK, map[string]any{"records": []any{}}),
+	})
+
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	if zones := api.last(http.MethodGet, "/domain/v2beta1/dns-zones"); !strings.Contains(zones.path, "project_id=project-1") || !strings.Contains(zones.path, "dns_zone=example.com") {
+		t.Errorf("zone looked up with %s", zones.path)
+	}
+	add := api.last(http.MethodPatch, "/domain/v2beta1/dns-zones/example.com/records")
+	var body struct {
+		Changes []scalewayChange `json:"changes"`
+	}
+	if err := json.Unmarshal(add.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Changes) != 1 || body.Changes[0].Add == nil || body.Changes[0].Add.Records[0] != (scalewayRecord{Name: "_acme-challenge", Type: "TXT", Data: quoted, TTL: scalewayMinTTL}) {
+		t.Errorf("changes %s", add.body)
+	}
+	if add.header.Get("X-Auth-Token") != "scw-secret" {
+		t.Error("the request isn't authorized with the secret key")
+	}
+
+	// records are deleted by their fields
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	del := api.last(http.MethodPatch, "/domain/v2beta1/dns-zones/example.com/records")
+	if err := json.Unmarshal(del.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Changes) != 1 || body.Changes[0].Delete == nil || body.Changes[0].Delete.IDFields != (scalewayRecord{Name: "_acme-challenge", Type: "TXT", Data: quoted}) {
+		t.Errorf("changes %s", del.body)
+	}
+}
+
+func TestScalewayZoneOfAnotherProject(t *testing.T) {
+	fakeZone(t, "example.com")
+	_, p := newTestScaleway(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /domain/v2beta1/dns-zones": reply(http.StatusOK, map[string]any{"total_count": 0}),
+	})
+	if err := p.Present("example.com", "token", testKeyAuth); err == nil || !strings.Contains(err.Error(), "project-1") {
+		t.Errorf("got %v, want the zone not found in the project", err)
+	}
+}
@@ -0,0 +1,46 @@
+package dnsproviders
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+// defaultSelfSignedValidity is the lifetime of locally signed certificates when selfsigned.validity is 0
+const defaultSelfSignedValidity = 90 * 24 * time.Hour
+
+var errSelfSignedChallenge = errors.New("selfsigned provider signs certificates locally, it doesn't solve challenges")
+
+// selfSignedProvider marks a provider whose certificates the issuer signs with its development CA,
+// it is never set on an ACME client
+type selfSignedProvider struct {
+	validity time.Duration
+}
+
+func newSelfSigned(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	validity := defaultSelfSignedValidity
+	if provider.SelfSigned != nil && provider.SelfSigned.Validity > 0 {
+		validity = provider.SelfSigned.Validity
+	}
+	return &selfSignedProvider{validity: validity}, nil
+}
+
+func (p *selfSignedProvider) Present(domain, token, keyAuth string) error {
+	return errSelfSignedChallenge
+}
+
+func (p *selfSignedProvider) CleanUp(domain, token, keyAuth string) error {
+	return nil
+}
+
+// SelfSigned reports whether the provider's certificates are signed locally, and for how long they are valid
+func (p *Provider) SelfSigned() (validity time.Duration, ok bool) {
+	sp, ok := p.challenge.(*selfSignedProvider)
+	if !ok {
+		return 0, false
+	}
+	return sp.validity, true
+}
@@ -0,0 +1,185 @@
+package dnsproviders
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestOVHSignature(t *testing.T) {
+	// computed with: printf '%s' 'secret+consumer+<method>+<url>+<body>+1700000000' | sha1sum
+	tests := []struct {
+		method, url, body, want string
+	}{
+		{"GET", "https://eu.api.ovh.com/1.0/domain/zone/example.com/record?fieldType=TXT", "", "$1$7ba4e17b2c2cddf67f80cf4208d54ed0435c65e7"},
+		{"POST", "https://eu.api.ovh.com/1.0/domain/zone/example.com/record", `{"fieldType":"TXT"}`, "$1$bb4d9e1ea7e6fb056da65bbedbb52f27429cda1d"},
+	}
+	for _, tt := range tests {
+		if got := ovhSignature("secret", "consumer", tt.method, tt.url, []byte(tt.body), "1700000000"); got != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestOVHSignsWithTheServerTime(t *testing.T) {
+	serverTime := time.Now().Add(-time.Hour).Unix()
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"GET /1.0/auth/time":                        reply(http.StatusOK, serverTime),
+		"POST /1.0/domain/zone/example.com/refresh": reply(http.StatusOK, nil),
+	})
+	p, err := newOVH(utils.ProviderConfig{Name: "ovh", OVH: &utils.OVHConfig{
+		Endpoint: "ovh-eu", ApplicationKey: "app", ApplicationSecret: "secret", ConsumerKey: "consumer",
+	}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*ovhProvider).api.baseURL = server.URL + "/1.0"
+
+	if err := p.(*ovhProvider).refresh(context.Background(), "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if clock := api.last(http.MethodGet, "/1.0/auth/time"); clock.header.Get("X-Ovh-Signature") != "" {
+		t.Error("the server time request was signed")
+	}
+	refresh := api.last(http.MethodPost, "/1.0/domain/zone/example.com/refresh")
+	timestamp := refresh.header.Get("X-Ovh-Timestamp")
+	// the timestamp follows the server clock, not the local one
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || ts < serverTime || ts > serverTime+60 {
+		t.Errorf("signed at %q, the server time is %d", timestamp, serverTime)
+	}
+	want := ovhSignature("secret", "consumer", http.MethodPost, server.URL+"/1.0/domain/zone/example.com/refresh", nil, timestamp)
+	if refresh.header.Get("X-Ovh-Signature") != want || refresh.header.Get("X-Ovh-Application") != "app" || refresh.header.Get("X-Ovh-Consumer") != "consumer" {
+		t.Errorf("headers %v, want signature %s", refresh.header, want)
+	}
+}
+
+func TestAliDNSSignature(t *testing.T) {
+	// the example of Alibaba Cloud's signature documentation for RPC APIs
+	query := url.Values{
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"Format":           {"XML"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"SignatureVersion": {"1.0"},
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Version":          {"2014-05-26"},
+	}
+	if got, want := aliSignature(http.MethodGet, query, "testsecret"), "OLeaidS1JvxuMvnyHOwuJ+uX5qY="; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestAliDNSSignsTheQuery(t *testing.T) {
+	p, err := newAliDNS(utils.ProviderConfig{Name: "alidns", AliDNS: &utils.AliDNSConfig{AccessKey: "testid", SecretKey: "testsecret"}}, &utils.Config{}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, aliDNSBaseURL+"/?Action=DescribeSubDomainRecords&SubDomain=_acme-challenge.example.com", nil)
+	if err := p.(*aliDNSProvider).sign(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(req.URL.RawQuery, "+") {
+		t.Errorf("query %s isn't RFC 3986 escaped", req.URL.RawQuery)
+	}
+	query := req.URL.Query()
+	signature := query.Get("Signature")
+	query.Del("Signature")
+	if signature == "" || signature != aliSignature(http.MethodGet, query, "testsecret") {
+		t.Errorf("signature %q doesn't match the sent query %s", signature, req.URL.RawQuery)
+	}
+	if query.Get("AccessKeyId") != "testid" || query.Get("Version") != aliDNSVersion || query.Get("SignatureNonce") == "" {
+		t.Errorf("common parameters %v", query)
+	}
+}
+
+var oracleAuthorization = regexp.MustCompile(`^Signature version="1",keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"$`)
+
+// verifyOracleSignature checks the signature of req with the public key, the signing string is built
+// from the headers as described in OCI's request signing documentation
+func verifyOracleSignature(t *testing.T, req *http.Request, key *rsa.PublicKey) (keyID string, headers []string) {
+	t.Helper()
+	m := oracleAuthorization.FindStringSubmatch(req.Header.Get("Authorization"))
+	if m == nil {
+		t.Fatalf("authorization %q", req.Header.Get("Authorization"))
+	}
+	headers = strings.Fields(m[2])
+	var lines []string
+	for _, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, "(request-target): "+strings.ToLower(req.Method)+" "+req.URL.RequestURI())
+		case "host":
+			lines = append(lines, "host: "+req.URL.Host)
+		default:
+			lines = append(lines, h+": "+req.Header.Get(h))
+		}
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	signature, err := base64.StdEncoding.DecodeString(m[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("signature of %s %s: %v", req.Method, req.URL, err)
+	}
+	return m[1], headers
+}
+
+func TestOracleCloudSignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newOracleCloud(utils.ProviderConfig{Name: "oraclecloud", OracleCloud: &utils.OracleCloudConfig{
+		TenancyOCID: "ocid1.tenancy.oc1..aaa",
+		UserOCID:    "ocid1.user.oc1..bbb",
+		Fingerprint: "20:3b:97:13",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		Region:      "us-phoenix-1",
+	}}, &utils.Config{}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := p.(*oracleCloudProvider)
+
+	get, _ := http.NewRequest(http.MethodGet, signer.api.baseURL+"/zones/example.com/records/_acme-challenge.example.com?rtype=TXT", nil)
+	if err := signer.sign(get, nil); err != nil {
+		t.Fatal(err)
+	}
+	keyID, headers := verifyOracleSignature(t, get, &key.PublicKey)
+	if keyID != "ocid1.tenancy.oc1..aaa/ocid1.user.oc1..bbb/20:3b:97:13" {
+		t.Errorf("key id %s", keyID)
+	}
+	if strings.Join(headers, " ") != "date (request-target) host" {
+		t.Errorf("GET signs %v", headers)
+	}
+
+	// a body is signed through its length, type and hash
+	body := []byte(`{"items":[]}`)
+	patch, _ := http.NewRequest(http.MethodPatch, signer.api.baseURL+"/zones/example.com/records/_acme-challenge.example.com", nil)
+	if err := signer.sign(patch, body); err != nil {
+		t.Fatal(err)
+	}
+	if _, headers = verifyOracleSignature(t, patch, &key.PublicKey); strings.Join(headers, " ") != "date (request-target) host content-length content-type x-content-sha256" {
+		t.Errorf("PATCH signs %v", headers)
+	}
+	sum := sha256.Sum256(body)
+	if patch.Header.Get("X-Content-Sha256") != base64.StdEncoding.EncodeToString(sum[:]) || patch.Header.Get("Content-Length") != "12" {
+		t.Errorf("body headers %v", patch.Header)
+	}
+}
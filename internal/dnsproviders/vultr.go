@@ -0,0 +1,99 @@
+package dnsproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const (
+	vultrBaseURL = "https://api.vultr.com/v2"
+	// Vultr answers bursts with 429, several domains issued at once easily hit it
+	vultrRateLimitRetries = 5
+)
+
+type vultrProvider struct {
+	propagation
+	api *restClient
+}
+
+type vultrRecord struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+func newVultr(provider utils.ProviderConfig, cfg *utils.Config, httpClient *http.Client) (challenge.Provider, error) {
+	if provider.Vultr == nil || provider.Vultr.APIKey == "" {
+		return nil, errors.New("vultr api_key is missing")
+	}
+	api := newRESTClient(httpClient, vultrBaseURL, bearer("Bearer", provider.Vultr.APIKey))
+	api.rateLimitRetries = vultrRateLimitRetries
+	return &vultrProvider{
+		propagation: newPropagation(provider, cfg.Certs, dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval),
+		api:         api,
+	}, nil
+}
+
+func (p *vultrProvider) Present(domain, token, keyAuth string) error {
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("vultr: %w", err)
+	}
+	// TXT data is stored quoted
+	record := vultrRecord{Type: "TXT", Name: name, Data: strconv.Quote(value), TTL: dns01.DefaultTTL}
+	if err := p.api.do(context.Background(), http.MethodPost, "/domains/"+url.PathEscape(zone)+"/records", record, nil); err != nil {
+		return fmt.Errorf("vultr: create record: %w", err)
+	}
+	return nil
+}
+
+func (p *vultrProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	zone, name, value, err := challengeRecord(domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("vultr: %w", err)
+	}
+
+	base := "/domains/" + url.PathEscape(zone) + "/records"
+	cursor := ""
+	for {
+		var list struct {
+			Records []vultrRecord `json:"records"`
+			Meta    struct {
+				Links struct {
+					Next string `json:"next"`
+				} `json:"links"`
+			} `json:"meta"`
+		}
+		query := url.Values{"per_page": {"500"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		if err := p.api.do(ctx, http.MethodGet, base+"?"+query.Encode(), nil, &list); err != nil {
+			return fmt.Errorf("vultr: list records: %w", err)
+		}
+		for _, r := range list.Records {
+			if r.Type != "TXT" || r.Name != name || strings.Trim(r.Data, `"`) != value {
+				continue
+			}
+			if err := p.api.do(ctx, http.MethodDelete, base+"/"+url.PathEscape(r.ID), nil, nil); err != nil {
+				return fmt.Errorf("vultr: delete record: %w", err)
+			}
+		}
+		if list.Meta.Links.Next == "" {
+			return nil
+		}
+		cursor = list.Meta.Links.Next
+	}
+}
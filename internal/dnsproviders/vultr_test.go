@@ -0,0 +1,57 @@
+package dnsproviders
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	utils "hephaestus/internal/utils"
+)
+
+func TestVultrPresentAndCleanUp(t *testing.T) {
+	fakeZone(t, "example.com")
+	value := testChallengeValue()
+	api, server := newFakeAPI(t, map[string]func(http.ResponseWriter, *http.Request, []byte){
+		"POST /v2/domains/example.com/records": reply(http.StatusCreated, map[string]any{"record": vultrRecord{ID: "a"}}),
+		// the challenge record is on the second page
+		"GET /v2/domains/example.com/records": func(w http.ResponseWriter, r *http.Request, body []byte) {
+			if r.URL.Query().Get("cursor") == "" {
+				reply(http.StatusOK, map[string]any{
+					"records": []vultrRecord{{ID: "b", Type: "TXT", Name: "_acme-challenge", Data: `"another-order"`}},
+					"meta":    map[string]any{"links": map[string]string{"next": "page2"}},
+				})(w, r, body)
+				return
+			}
+			reply(http.StatusOK, map[string]any{
+				"records": []vultrRecord{{ID: "a", Type: "TXT", Name: "_acme-challenge", Data: strconv.Quote(value)}},
+				"meta":    map[string]any{"links": map[string]string{"next": ""}},
+			})(w, r, body)
+		},
+		"DELETE /v2/domains/example.com/records/a": reply(http.StatusNoContent, nil),
+	})
+	p, err := newVultr(utils.ProviderConfig{Name: "vultr", Vultr: &utils.VultrConfig{APIKey: "vultr-key"}}, &utils.Config{}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointAt(p.(*vultrProvider).api, server, "/v2")
+
+	if err := p.Present("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	created := decodeJSON(t, api.last(http.MethodPost, "/v2/domains/example.com/records").body)
+	if created["type"] != "TXT" || created["name"] != "_acme-challenge" || created["data"] != strconv.Quote(value) {
+		t.Errorf("created %v, the data is stored quoted", created)
+	}
+
+	if err := p.CleanUp("example.com", "token", testKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	calls := api.calls()
+	if !slices.Contains(calls, "GET /v2/domains/example.com/records?cursor=page2&per_page=500") {
+		t.Errorf("the next page wasn't listed: %v", calls)
+	}
+	if !slices.Contains(calls, "DELETE /v2/domains/example.com/records/a") || slices.Contains(calls, "DELETE /v2/domains/example.com/records/b") {
+		t.Errorf("deleted %v, want only the challenge record", calls)
+	}
+}
@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultExecTimeout = 30 * time.Second
+	// outputLimit bounds the output of a command kept for errors, the rest is read and dropped
+	outputLimit = 1 << 16
+	// exitPollInterval is how often an exec whose stream ended is inspected until it reports its exit code
+	exitPollInterval = 50 * time.Millisecond
+)
+
+// ErrContainerNotFound is returned for containers the daemon doesn't know
+var ErrContainerNotFound = errors.New("container not found")
+
+// Client reloads nginx through the Docker Engine API on the unix socket, so no docker CLI is needed next to
+// hephaestus, only the mounted socket. The API version is negotiated with the daemon on the first call
+type Client struct {
+	socket      string
+	timeout     time.Duration
+	execTimeout time.Duration
+	api         *client.Client
+	log         *utils.Logger
+}
+
+func NewClient(cfg utils.DockerConfig, log *utils.Logger) (*Client, error) {
+	c := &Client{
+		socket:      cfg.SocketPath(),
+		timeout:     cfg.Timeout,
+		execTimeout: cfg.ExecTimeout,
+		log:         log,
+	}
+	if c.timeout == 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.execTimeout == 0 {
+		c.execTimeout = defaultExecTimeout
+	}
+	api, err := client.NewClientWithOpts(client.WithHost("unix://"+c.socket), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("docker socket %s: %w", c.socket, err)
+	}
+	c.api = api
+	return c, nil
+}
+
+// Close releases the idle connections to the socket, calls in flight finish on theirs
+func (c *Client) Close() {
+	_ = c.api.Close()
+}
+
+// Inspect returns the container with the name or id, ErrContainerNotFound when there is none
+func (c *Client) Inspect(ctx context.Context, name string) (container.InspectResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	info, err := c.api.ContainerInspect(ctx, name)
+	if cerrdefs.IsNotFound(err) {
+		return info, fmt.Errorf("inspect container %s: %w: %s", name, ErrContainerNotFound, err)
+	}
+	if err != nil {
+		return info, fmt.Errorf("inspect container %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// Exec runs the command in the running container and returns its output, a non-zero exit code is an
+// error carrying the output
+func (c *Client) Exec(ctx context.Context, name string, cmd []string) (string, error) {
+	info, err := c.Inspect(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if info.State == nil || !info.State.Running {
+		status := "unknown"
+		if info.State != nil {
+			status = string(info.State.Status)
+		}
+		return "", fmt.Errorf("container %s is not running, it is %s", name, status)
+	}
+
+	createCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	created, err := c.api.ContainerExecCreate(createCtx, info.ID, container.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create exec in %s: %w", name, err)
+	}
+
+	output, err := c.start(ctx, created.ID)
+	if err != nil {
+		return output, fmt.Errorf("run %s in %s: %w", strings.Join(cmd, " "), name, err)
+	}
+
+	exitCode, err := c.exitCode(ctx, created.ID)
+	if err != nil {
+		return output, fmt.Errorf("inspect exec in %s: %w", name, err)
+	}
+	if exitCode != 0 {
+		return output, fmt.Errorf("%s in %s exited with %d: %s", strings.Join(cmd, " "), name, exitCode, strings.TrimSpace(output))
+	}
+	c.log.Debug("Ran ", strings.Join(cmd, " "), " in container ", name)
+	return output, nil
+}
+
+// start runs the exec and reads its output until the command exits. The attached stream is a
+// hijacked connection the context doesn't reach once it is open, it is closed when exec_timeout passes
+func (c *Client) start(ctx context.Context, id string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.execTimeout)
+	defer cancel()
+	stream, err := c.api.ContainerExecAttach(ctx, id, container.ExecAttachOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	stop := context.AfterFunc(ctx, stream.Close)
+	defer stop()
+
+	// stdout and stderr are joined, the stream is read to its end which the daemon sends when the command exits
+	out := &limitedBuffer{limit: outputLimit}
+	if _, err := stdcopy.StdCopy(out, out, stream.Reader); err != nil {
+		if ctx.Err() != nil {
+			return out.String(), fmt.Errorf("read output: %w", ctx.Err())
+		}
+		return out.String(), fmt.Errorf("read output: %w", err)
+	}
+	return out.String(), nil
+}
+
+// exitCode inspects the exec until it stopped running, the daemon may close the stream before it
+// records the exit code
+func (c *Client) exitCode(ctx context.Context, id string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	for {
+		result, err := c.api.ContainerExecInspect(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if !result.Running {
+			return result.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("command still running: %w", ctx.Err())
+		case <-time.After(exitPollInterval):
+		}
+	}
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the rest. The buffer isn't embedded,
+// its ReadFrom would let a copy fill it past the limit
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string { return b.buf.String() }
@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	utils "hephaestus/internal/utils"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// versionPrefix is the API version the client negotiated, the fake daemon serves every version
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// fakeDaemon serves the Engine API calls of an nginx reload on a unix socket
+type fakeDaemon struct {
+	running bool
+	output  []byte
+	// exit is reported once the exec was inspected runningInspects times
+	exit            int
+	runningInspects int32
+	// hang keeps the exec stream open without output
+	hang bool
+
+	inspects atomic.Int32
+	// written is the output the client read off the exec stream
+	written atomic.Int64
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := versionPrefix.ReplaceAllString(r.URL.Path, "")
+	switch {
+	case path == "/_ping":
+		w.Header().Set("Api-Version", "1.41")
+		_, _ = w.Write([]byte("OK"))
+	case r.Method == http.MethodGet && path == "/containers/nginx/json":
+		status := "exited"
+		if d.running {
+			status = "running"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Id": "c1", "Name": "/nginx", "State": map[string]any{"Status": status, "Running": d.running}})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/containers/"):
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "No such container: " + path})
+	case r.Method == http.MethodPost && path == "/containers/c1/exec":
+		_ = json.NewEncoder(w).Encode(map[string]string{"Id": "e1"})
+	case r.Method == http.MethodPost && path == "/exec/e1/start":
+		d.stream(w, r)
+	case r.Method == http.MethodGet && path == "/exec/e1/json":
+		if d.inspects.Add(1) <= d.runningInspects {
+			_ = json.NewEncoder(w).Encode(map[string]any{"Running": true, "ExitCode": nil})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Running": false, "ExitCode": d.exit})
+	default:
+		http.Error(w, "unexpected call", http.StatusBadRequest)
+	}
+}
+
+// stream upgrades the connection like the daemon does for an attached exec and writes the output in
+// multiplexed frames
+func (d *fakeDaemon) stream(w http.ResponseWriter, r *http.Request) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	if err := buf.Flush(); err != nil {
+		return
+	}
+	if d.hang {
+		// the client closing the connection ends the read
+		_, _ = conn.Read(make([]byte, 1))
+		return
+	}
+	for chunk := range slicesOf(d.output, 4096) {
+		header := make([]byte, 8)
+		header[0] = 1
+		binary.BigEndian.PutUint32(header[4:], uint32(len(chunk)))
+		n, err := conn.Write(append(header, chunk...))
+		if err != nil {
+			return
+		}
+		d.written.Add(int64(n - len(header)))
+	}
+}
+
+func slicesOf(data []byte, size int) func(func([]byte) bool) {
+	return func(yield func([]byte) bool) {
+		for len(data) > 0 {
+			n := min(size, len(data))
+			if !yield(data[:n]) {
+				return
+			}
+			data = data[n:]
+		}
+	}
+}
+
+func newTestClient(t *testing.T, d *fakeDaemon) *Client {
+	return newTestClientWithConfig(t, d, utils.DockerConfig{})
+}
+
+func newTestClientWithConfig(t *testing.T, d *fakeDaemon, cfg utils.DockerConfig) *Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: d}
+	go server.Serve(listener)
+	t.Cleanup(func() { _ = server.Close() })
+
+	cfg.Socket = "unix://" + socket
+	c, err := NewClient(cfg, utils.NewLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestExecReturnsTheOutput(t *testing.T) {
+	c := newTestClient(t, &fakeDaemon{running: true, output: []byte("signal process started\n")})
+	output, err := c.Exec(context.Background(), "nginx", []string{"nginx", "-s", "reload"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "signal process started\n" {
+		t.Errorf("got output %q", output)
+	}
+}
+
+func TestExecReadsLongOutputToTheEnd(t *testing.T) {
+	d := &fakeDaemon{running: true, output: bytes.Repeat([]byte("x"), 4*outputLimit), exit: 1}
+	c := newTestClient(t, d)
+	output, err := c.Exec(context.Background(), "nginx", []string{"nginx", "-s", "reload"})
+	if err == nil || !strings.Contains(err.Error(), "exited with 1") {
+		t.Errorf("got %v, want the exit code of the command", err)
+	}
+	if len(output) != outputLimit {
+		t.Errorf("kept %d bytes of output, want %d", len(output), outputLimit)
+	}
+	if got := d.written.Load(); got != int64(len(d.output)) {
+		t.Errorf("the stream was left after %d of %d bytes", got, len(d.output))
+	}
+}
+
+func TestExecWaitsForTheExitCode(t *testing.T) {
+	d := &fakeDaemon{running: true, exit: 1, runningInspects: 2}
+	c := newTestClient(t, d)
+	if _, err := c.Exec(context.Background(), "nginx", []string{"nginx", "-s", "reload"}); err == nil || !strings.Contains(err.Error(), "exited with 1") {
+		t.Errorf("got %v, want the exit code reported after the command stopped", err)
+	}
+	if got := d.inspects.Load(); got != 3 {
+		t.Errorf("exec inspected %d times, want 3", got)
+	}
+}
+
+func TestExecInStoppedContainer(t *testing.T) {
+	c := newTestClient(t, &fakeDaemon{})
+	if _, err := c.Exec(context.Background(), "nginx", []string{"nginx", "-s", "reload"}); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("got %v, want a stopped container error", err)
+	}
+}
+
+func TestInspectUnknownContainer(t *testing.T) {
+	c := newTestClient(t, &fakeDaemon{})
+	if _, err := c.Inspect(context.Background(), "missing"); !errors.Is(err, ErrContainerNotFound) {
+		t.Errorf("got %v, want ErrContainerNotFound", err)
+	}
+}
+
+func TestExecTimesOutAHangingCommand(t *testing.T) {
+	c := newTestClientWithConfig(t, &fakeDaemon{running: true, hang: true}, utils.DockerConfig{ExecTimeout: 100 * time.Millisecond})
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Exec(context.Background(), "nginx", []string{"nginx", "-s", "reload"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want the exec timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the exec stream wasn't closed after exec_timeout")
+	}
+}
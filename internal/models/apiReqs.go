@@ -4,22 +4,180 @@ type GetDomainsReq struct {
 	Page       int `json:"page"`
 	PageSize   int `json:"page_size"`
 	UserID     string
-	Status     string `json:"status,omitempty"`
-	DomainName string `json:"domain_name,omitempty"`
+	TenantID   string
+	Status     string   `json:"status,omitempty"`
+	DomainName string   `json:"domain_name,omitempty"`
+	Fuzzy      bool     `json:"fuzzy,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Cursor     *DomainsCursor
 }
 
+// Domain kinds, monitored domains are never issued, only the served certificate is checked
+const (
+	DomainKindManaged   = "managed"
+	DomainKindMonitored = "monitored"
+)
+
 type CreateDomainReq struct {
 	CreatedBy          string
-	Domain             string   `json:"domain"`
-	AltDomains         []string `json:"alternative_domains"`
-	VerificationMethod string   `json:"verification_method"`
-	AutoRenew          bool     `json:"auto_renew"`
-	NginxContainerName string   `json:"nginx_container_name"`
-	DNSProvider        string   `json:"dns_provider"`
+	TenantID           string
+	Domain             string         `json:"domain"`
+	Kind               string         `json:"kind"`            // managed when empty
+	MonitorAddress     string         `json:"monitor_address"` // host:port of a monitored domain, <domain>:443 when empty
+	AltDomains         []string       `json:"alternative_domains"`
+	VerificationMethod string         `json:"verification_method"`
+	AutoRenew          bool           `json:"auto_renew"`
+	NginxContainerName string         `json:"nginx_container_name"`
+	DNSProvider        string         `json:"dns_provider"`
+	Tags               []string       `json:"tags"`     // e.g. env=prod, team=payments
+	Metadata           map[string]any `json:"metadata"` // e.g. ticket ids, owners, runbook links
+	Notes              string         `json:"notes"`
+	Revive             bool           `json:"revive"`         // re-create a previously deleted domain instead of failing
+	KeyType            string         `json:"key_type"`       // rsa2048, rsa3072, rsa4096, rsa8192, ec256 or ec384, certs.key_type when empty, kept for renewals
+	Profile            string         `json:"profile"`        // ACME profile, e.g. shortlived, certs.profile when empty, kept for renewals
+	ReuseKey           bool           `json:"reuse_key"`      // renewals keep the private key of the current certificate
+	OutputFormats      []string       `json:"output_formats"` // encodings of the files besides PEM, e.g. der, certs.output_formats when empty
+	DeferIssuance      bool           `json:"defer_issuance"` // store the domain as pending, the next renewal cycle issues the certificate
+}
+
+// ImportDomainsReq creates a domain for every row of a CSV or JSON export, defaults apply to rows
+// that leave the field empty
+type ImportDomainsReq struct {
+	CreatedBy     string
+	TenantID      string
+	Format        string // csv or json
+	Data          []byte
+	DNSProvider   string // default dns_provider of the rows
+	DeferIssuance bool   // store every row as pending, the API always does
+}
+
+// UpdateDomainReq changes only the fields that are set, Metadata replaces the stored object
+type UpdateDomainReq struct {
+	UpdatedBy          string
+	TenantID           string
+	DomainID           string         `json:"domain_id"`
+	DomainName         string         `json:"domain_name"`
+	AutoRenew          *bool          `json:"auto_renew"`
+	NginxContainerName *string        `json:"nginx_container_name"`
+	Tags               []string       `json:"tags"`
+	Metadata           map[string]any `json:"metadata"`
+	Notes              *string        `json:"notes"`    // empty string clears the notes
+	KeyType            *string        `json:"key_type"` // used from the next renewal on, empty string follows certs.key_type again
+	Profile            *string        `json:"profile"`  // used from the next renewal on, empty string follows certs.profile again
+	ReuseKey           *bool          `json:"reuse_key"`
+	OutputFormats      []string       `json:"output_formats"` // used from the next issuance on, an empty list follows certs.output_formats again
 }
 
 type DeleteDomainReq struct {
 	DomainID   string `json:"domain_id"`
 	DomainName string `json:"domain_name"`
 	UserID     string
+	TenantID   string
+}
+
+type GetCertificatesReq struct {
+	DomainID   string
+	DomainName string
+	TenantID   string
+}
+
+// ActivateCertificateReq makes a staged or retired certificate the active one of its key type,
+// e.g. to roll back to the previous certificate
+type ActivateCertificateReq struct {
+	DomainName    string `json:"domain_name"`
+	CertificateID string `json:"certificate_id"`
+	UserID        string
+	TenantID      string
+}
+
+// SwitchChainReq bundles a certificate with another of its stored chains, e.g. alt1
+type SwitchChainReq struct {
+	DomainName    string `json:"domain_name"`
+	CertificateID string `json:"certificate_id"`
+	Chain         string `json:"chain"`
+	UserID        string
+	TenantID      string
+}
+
+// Export formats of a certificate with its private key
+const (
+	ExportFormatPEM    = "pem"    // the key followed by the certificate and its chain
+	ExportFormatPKCS12 = "pkcs12" // a password protected .p12 file for Windows, IIS and Java
+)
+
+// ExportCertificateReq downloads a certificate with its private key, the primary one when CertificateID is empty
+type ExportCertificateReq struct {
+	DomainName    string `json:"domain_name"`
+	CertificateID string `json:"certificate_id"`
+	Format        string `json:"format"`   // pem or pkcs12
+	Password      string `json:"password"` // protects the pkcs12 file
+	Legacy        bool   `json:"legacy"`   // pkcs12 with 3DES and SHA-1 for Windows Server 2016 and older Java
+	UserID        string
+	TenantID      string
+}
+
+// ArchivedCertificatesReq lists the archived certificate versions of a domain
+type ArchivedCertificatesReq struct {
+	DomainName string
+	TenantID   string
+}
+
+// RestoreCertificateReq moves an archived version back out of the archive and activates its certificate
+type RestoreCertificateReq struct {
+	DomainName string `json:"domain_name"`
+	Version    string `json:"version"` // as listed by the archive, e.g. 20261015T144800Z
+	UserID     string
+	TenantID   string
+}
+
+// CheckDriftReq compares the active certificate of a domain with the deployed one
+type CheckDriftReq struct {
+	DomainName string
+	TenantID   string
+}
+
+// DelegateAcmeDNSReq registers a domain on the acme-dns server of an acmedns provider ahead of
+// its first issuance, an empty DNSProvider selects default_provider
+type DelegateAcmeDNSReq struct {
+	DomainName  string `json:"domain_name"`
+	DNSProvider string `json:"dns_provider"`
+	UserID      string
+	TenantID    string
+}
+
+// IssueFromCSRReq orders a certificate for a CSR, the key stays with the requester. An empty
+// DNSProvider selects default_provider
+type IssueFromCSRReq struct {
+	CSR         string `json:"csr"` // PEM encoded
+	DNSProvider string `json:"dns_provider"`
+	Profile     string `json:"profile"` // certs.profile when empty
+	UserID      string
+	TenantID    string
+}
+
+type RevokeCertificateReq struct {
+	DomainName string
+	UserID     string
+	TenantID   string
+}
+
+// SetMaintenanceReq switches read-only mode, RetryAfter is the Retry-After hint in seconds
+type SetMaintenanceReq struct {
+	Enabled    bool   `json:"enabled"`
+	Reason     string `json:"reason"`
+	RetryAfter int    `json:"retry_after"`
+	UserID     string
+}
+
+// DeactivateAccountReq deactivates the ACME account, Domains is what happens to the domains issued
+// with it: reassign (default) renews them with a new account, block turns their auto_renew off
+type DeactivateAccountReq struct {
+	Domains string `json:"domains"`
+	UserID  string
+}
+
+// Identity is the caller taken from a validated access token
+type Identity struct {
+	UserID   string
+	TenantID string // empty for single-tenant installations
 }
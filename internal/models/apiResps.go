@@ -11,14 +11,19 @@ type GetDomainsResp struct {
 	HasPrev       bool      `json:"has_prev"`
 	NextPage      int       `json:"next_page,omitempty"`
 	PrevPage      int       `json:"prev_page,omitempty"`
+	NextCursor    string    `json:"next_cursor,omitempty"`
 	Domains       []Domains `json:"domains"`
 }
 
 type Domains struct {
-	ID         string   `json:"id"`
-	DomainName string   `json:"domain_name"`
-	Details    Details  `json:"details"`
-	Sub        []string `json:"sub"`
+	ID          string         `json:"id"`
+	DomainName  string         `json:"domain_name"`
+	UnicodeName string         `json:"unicode_name,omitempty"` // set for internationalized names only
+	Details     Details        `json:"details"`
+	Sub         []string       `json:"sub"`
+	Tags        []string       `json:"tags"`
+	Metadata    map[string]any `json:"metadata"`
+	Notes       string         `json:"notes,omitempty"`
 }
 
 type Details struct {
@@ -32,5 +37,186 @@ type Details struct {
 	NginxContainerName  string    `json:"nginx_container_name"`
 	CertValidTo         time.Time `json:"certificate_valid_to"`
 	CertLastRenewal     time.Time `json:"certificate_last_renewal"`
-	CertRenewalAttempts int       `json:"certificate_renewal_attempts"`
+	CertRenewalAttempts int       `json:"certificate_renewal_attempts"` // failed attempts since the last certificate
+	NextRenewalAt       time.Time `json:"next_renewal_at"`              // the renewal cycle skips the domain until then after a failure
+	Kind                string    `json:"kind"`
+	MonitorAddress      string    `json:"monitor_address,omitempty"`
+	LastCheckedAt       time.Time `json:"last_checked_at"`
+	LastCheckError      string    `json:"last_check_error,omitempty"`
+	KeyType             string    `json:"key_type,omitempty"` // empty follows certs.key_type
+	Profile             string    `json:"profile,omitempty"`  // empty follows certs.profile
+	ReuseKey            bool      `json:"reuse_key"`
+	OutputFormats       []string  `json:"output_formats,omitempty"` // empty follows certs.output_formats
+	Wildcard            bool      `json:"wildcard"`                 // the domain or one of its alternative domains is a wildcard
+	OCSPStatus          string    `json:"ocsp_status,omitempty"`    // of the primary certificate, good, revoked or unknown
+	OCSPCheckedAt       time.Time `json:"ocsp_checked_at"`
+}
+
+type Certificate struct {
+	ID            string             `json:"id"`
+	SerialNumber  string             `json:"serial_number"`
+	Fingerprint   string             `json:"fingerprint_sha256"`
+	Active        bool               `json:"active"`  // in use for its key type
+	Primary       bool               `json:"primary"` // the certificate deployment targets with a single certificate use
+	State         string             `json:"state"`   // staged, active or retired
+	KeyType       string             `json:"key_type"`
+	Issuer        string             `json:"issuer"`
+	ValidFrom     time.Time          `json:"valid_from"`
+	ValidTo       time.Time          `json:"valid_to"`
+	LastRenewal   time.Time          `json:"last_renewal"`
+	CreatedAt     time.Time          `json:"created_at"`
+	CreatedBy     string             `json:"created_by"`
+	OCSPStatus    string             `json:"ocsp_status,omitempty"` // good, revoked or unknown, empty until checked
+	OCSPCheckedAt time.Time          `json:"ocsp_checked_at"`
+	OCSPRevokedAt time.Time          `json:"ocsp_revoked_at"`
+	Chains        []CertificateChain `json:"chains,omitempty"` // only when the CA offered alternate chains
+}
+
+// CertificateChain is one of the chains stored with a certificate, Active is the one bundled in cert.pem
+type CertificateChain struct {
+	Name   string `json:"name"` // default, alt1, alt2 and on
+	Root   string `json:"root"`
+	Active bool   `json:"active"`
+}
+
+// ArchivedCertificate is a replaced certificate version kept in the archive of its domain, the
+// certificate fields are empty when its cert.pem can't be parsed
+type ArchivedCertificate struct {
+	Version       string    `json:"version"` // archive timestamp, e.g. 20261015T144800Z
+	ArchivedAt    time.Time `json:"archived_at"`
+	CertificateID string    `json:"certificate_id,omitempty"` // empty when no certificate record points at the version
+	SerialNumber  string    `json:"serial_number"`
+	Fingerprint   string    `json:"fingerprint_sha256"`
+	ValidFrom     time.Time `json:"valid_from"`
+	ValidTo       time.Time `json:"valid_to"`
+	CertPath      string    `json:"cert_path"`
+}
+
+// CertificateExport is a certificate file to download
+type CertificateExport struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// CertificateDrift compares the active certificates of a domain with the ones on disk and the one served,
+// Drift is set when any of them is a different certificate than the active one of its key type
+type CertificateDrift struct {
+	Domain        string              `json:"domain"`
+	CertificateID string              `json:"certificate_id"` // the primary certificate, the live files follow it
+	SerialNumber  string              `json:"serial_number"`
+	Fingerprint   string              `json:"fingerprint_sha256"`
+	File          CertificateSource   `json:"file"`
+	KeyTypes      []CertificateSource `json:"key_types,omitempty"` // current-<key type> of the other active certificates
+	Served        *CertificateSource  `json:"served,omitempty"`    // not checked for wildcard domains
+	Drift         bool                `json:"drift"`
+	CheckedAt     time.Time           `json:"checked_at"`
+}
+
+// AcmeDNSDelegation is the CNAME a domain needs before an acmedns provider can solve its challenges
+type AcmeDNSDelegation struct {
+	DomainName  string `json:"domain_name"`
+	DNSProvider string `json:"dns_provider"`
+	CNAME       string `json:"cname"`
+	Target      string `json:"target"`
+}
+
+// CertificateSource is the certificate found at Location, a file path or host:port. Error is set when it couldn't be read
+// The certificate is compared with the active one of its key type, Expected is the serial number of that one
+type CertificateSource struct {
+	Location     string `json:"location"`
+	KeyType      string `json:"key_type,omitempty"`
+	SerialNumber string `json:"serial_number,omitempty"`
+	Fingerprint  string `json:"fingerprint_sha256,omitempty"`
+	Expected     string `json:"expected_serial_number,omitempty"`
+	Match        bool   `json:"match"`
+	Error        string `json:"error,omitempty"`
+}
+
+// MaintenanceState is the read-only mode of the instance, mutations are rejected while Enabled
+type MaintenanceState struct {
+	Enabled    bool       `json:"enabled"`
+	Reason     string     `json:"reason,omitempty"`
+	RetryAfter int        `json:"retry_after,omitempty"`
+	Since      *time.Time `json:"since,omitempty"`
+	By         string     `json:"by,omitempty"`
+}
+
+// DeactivateAccountResp is the deactivated ACME account and the domains that were reassigned or blocked
+type DeactivateAccountResp struct {
+	AccountURI  string   `json:"account_uri"`
+	CA          string   `json:"ca"`
+	ArchivedKey string   `json:"archived_key"`
+	Domains     string   `json:"domains"`
+	DomainNames []string `json:"domain_names"`
+}
+
+// RateLimitBudget is what is left of the CA's rate limits, as counted from the orders of this installation
+type RateLimitBudget struct {
+	CA        string                   `json:"ca"`
+	Enforced  bool                     `json:"enforced"`
+	NewOrders RateLimitUsage           `json:"new_orders"`
+	Domains   []RegisteredDomainBudget `json:"domains"`
+}
+
+// RateLimitUsage is one limit, ResetsAt is when the oldest counted order leaves the window
+type RateLimitUsage struct {
+	Used     int        `json:"used"`
+	Limit    int        `json:"limit"`
+	Window   string     `json:"window"`
+	ResetsAt *time.Time `json:"resets_at,omitempty"`
+}
+
+// RegisteredDomainBudget is the usage of a registered domain (public suffix + 1), NameSets are the
+// name sets certificates were issued for, counted against the duplicate certificate limit
+type RegisteredDomainBudget struct {
+	Domain            string         `json:"registered_domain"`
+	Certificates      RateLimitUsage `json:"certificates"`
+	FailedValidations RateLimitUsage `json:"failed_validations"`
+	NameSets          []NameSetUsage `json:"name_sets,omitempty"`
+}
+
+type NameSetUsage struct {
+	Names []string `json:"names"`
+	RateLimitUsage
+}
+
+type QueryStat struct {
+	Name          string        `json:"name"`
+	Count         int64         `json:"count"`
+	Errors        int64         `json:"errors"`
+	SlowCount     int64         `json:"slow_count"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	AvgDuration   time.Duration `json:"avg_duration_ns"`
+	MaxDuration   time.Duration `json:"max_duration_ns"`
+}
+
+// ImportDomainsResp reports the outcome of every imported row, Row is the line of a CSV (header is 1)
+// or the position in a JSON array, counted from 1
+type ImportDomainsResp struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+type ImportRowResult struct {
+	Row      int    `json:"row"`
+	Domain   string `json:"domain"`
+	Status   string `json:"status"` // created or failed
+	DomainID string `json:"domain_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Code     string `json:"code,omitempty"` // error code of a failed row, e.g. domain_exists
+}
+
+// IssuedCertificate is a certificate issued for a CSR, Certificate is the PEM leaf bundled with the
+// issuer like cert.pem, Chain the issuer alone like chain.pem
+type IssuedCertificate struct {
+	Domains      []string  `json:"domains"`
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint_sha256"`
+	Issuer       string    `json:"issuer"`
+	ValidFrom    time.Time `json:"valid_from"`
+	ValidTo      time.Time `json:"valid_to"`
+	Certificate  string    `json:"certificate"`
+	Chain        string    `json:"chain"`
 }
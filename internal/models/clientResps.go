@@ -8,6 +8,16 @@ type CertificateData struct {
 	Chain     []byte
 	ValidFrom time.Time
 	ValidTo   time.Time
+
+	SerialNumber string // hex encoded, empty when the certificate couldn't be parsed
+	KeyType      string // e.g. rsa2048 or ec256
+	Fingerprint  string // SHA-256 of the leaf certificate, empty like SerialNumber
+	Issuer       string // organization of the issuing CA
+
+	AltChains  [][]byte // alternate chains the CA offered, in its order
+	ChainRoots []string // root the default chain and each alternate lead to, empty without alternates
+
+	OutputFormats []string // encodings written besides PEM, e.g. der, set by the service before the files are stored
 }
 
 type CertificatePaths struct {
@@ -15,3 +25,10 @@ type CertificatePaths struct {
 	Key   string
 	Chain string
 }
+
+// DeactivatedAccount is the ACME account deactivated at the CA and where its key was archived
+type DeactivatedAccount struct {
+	AccountURI  string
+	CADirURL    string
+	ArchivedKey string
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DomainsCursor points at the last domain of a page, domains are ordered by created_at DESC, id DESC
+type DomainsCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func EncodeDomainsCursor(c DomainsCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeDomainsCursor(token string) (*DomainsCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, errors.New("invalid cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &DomainsCursor{CreatedAt: t, ID: id}, nil
+}
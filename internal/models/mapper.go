@@ -1,6 +1,20 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultChain names the chain the CA returned with a certificate, alternates are alt1, alt2 and on
+const DefaultChain = "default"
+
+// ChainName names the chain of index n among the default chain and the alternates after it
+func ChainName(n int) string {
+	if n == 0 {
+		return DefaultChain
+	}
+	return fmt.Sprintf("alt%d", n)
+}
 
 func safeString(s *string) string {
 	if s == nil {
@@ -41,6 +55,9 @@ func ConvertDomainsDTOToDomains(req DomainsDTO) Domains {
 		ID:         req.ID,
 		DomainName: req.DomainName,
 		Sub:        req.Sub,
+		Tags:       req.Tags,
+		Metadata:   req.Metadata,
+		Notes:      safeString(req.Notes),
 		Details: Details{
 			DNSProvider:         req.Details.DNSProvider,
 			Status:              req.Details.Status,
@@ -53,6 +70,52 @@ func ConvertDomainsDTOToDomains(req DomainsDTO) Domains {
 			CertValidTo:         safeTime(req.Details.CertValidTo),
 			CertLastRenewal:     safeTime(req.Details.CertLastRenewal),
 			CertRenewalAttempts: safeInt(req.Details.CertRenewalAttempts),
+			NextRenewalAt:       safeTime(req.Details.NextRenewalAt),
+			Kind:                req.Details.Kind,
+			MonitorAddress:      safeString(req.Details.MonitorAddress),
+			LastCheckedAt:       safeTime(req.Details.LastCheckedAt),
+			LastCheckError:      safeString(req.Details.LastCheckError),
+			KeyType:             safeString(req.Details.KeyType),
+			Profile:             safeString(req.Details.Profile),
+			ReuseKey:            req.Details.ReuseKey,
+			OutputFormats:       req.Details.OutputFormats,
+			Wildcard:            req.Details.Wildcard,
+			OCSPStatus:          safeString(req.Details.OCSPStatus),
+			OCSPCheckedAt:       safeTime(req.Details.OCSPCheckedAt),
 		},
 	}
 }
+
+func ConvertCertsDTOToCertificate(req CertsDTO) Certificate {
+	return Certificate{
+		ID:            req.ID,
+		SerialNumber:  safeString(req.SerialNumber),
+		Fingerprint:   safeString(req.Fingerprint),
+		Active:        req.Active,
+		Primary:       req.Primary,
+		State:         req.State,
+		KeyType:       req.KeyType,
+		Issuer:        safeString(req.Issuer),
+		ValidFrom:     safeTime(req.ValidFrom),
+		ValidTo:       safeTime(req.ValidTo),
+		LastRenewal:   safeTime(req.LastRenewal),
+		CreatedAt:     req.CreatedAt,
+		CreatedBy:     req.CreatedBy,
+		OCSPStatus:    safeString(req.OCSPStatus),
+		OCSPCheckedAt: safeTime(req.OCSPCheckedAt),
+		OCSPRevokedAt: safeTime(req.OCSPRevokedAt),
+		Chains:        certificateChains(req.Chains, req.ActiveChain),
+	}
+}
+
+func certificateChains(roots []string, active string) []CertificateChain {
+	if len(roots) == 0 {
+		return nil
+	}
+	chains := make([]CertificateChain, 0, len(roots))
+	for n, root := range roots {
+		name := ChainName(n)
+		chains = append(chains, CertificateChain{Name: name, Root: root, Active: name == active})
+	}
+	return chains
+}
@@ -0,0 +1,69 @@
+package models
+
+import "errors"
+
+// error codes of the API, they are part of the contract: clients branch on them, so they are never renamed
+const (
+	CodeBadRequest              = "bad_request"
+	CodeValidationFailed        = "validation_failed"
+	CodeUnauthorized            = "unauthorized"
+	CodeForbidden               = "forbidden"
+	CodeNotFound                = "not_found"
+	CodeMethodNotAllowed        = "method_not_allowed"
+	CodeDomainExists            = "domain_exists"
+	CodeDomainConflict          = "domain_conflict"
+	CodeDomainNotFound          = "domain_not_found"
+	CodeProviderNotFound        = "provider_not_found"
+	CodeACMERateLimited         = "acme_rate_limited"
+	CodeACMEAuthorizationFailed = "acme_authorization_failed"
+	CodeDNSPropagationTimeout   = "dns_propagation_timeout"
+	CodeMaintenance             = "maintenance"
+	CodeIssuanceInProgress      = "issuance_in_progress"
+	CodeNameserverMismatch      = "nameserver_mismatch"
+	CodeRateLimitBudget         = "rate_limit_budget_exhausted"
+	CodeInternal                = "internal_error"
+)
+
+// CodedError tags an error with the code the API answers it with, the message stays the wrapped one
+type CodedError struct {
+	Code string
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// WithCode tags err with code, nil stays nil
+func WithCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &CodedError{Code: code, Err: err}
+}
+
+// ErrorCode returns the code of the outermost tagged error in the chain, empty when there is none
+func ErrorCode(err error) string {
+	var cerr *CodedError
+	if errors.As(err, &cerr) {
+		return cerr.Code
+	}
+	return ""
+}
+
+// Problem is an RFC 7807 error response, Code is the stable machine-readable part.
+// Errors and Conflicts are set for validation failures and clashing domains
+type Problem struct {
+	Type      string           `json:"type"`
+	Title     string           `json:"title"`
+	Status    int              `json:"status"`
+	Detail    string           `json:"detail,omitempty"`
+	Instance  string           `json:"instance,omitempty"`
+	Code      string           `json:"code"`
+	Errors    []FieldError     `json:"errors,omitempty"`
+	Conflicts []DomainConflict `json:"conflicts,omitempty"`
+}
@@ -3,11 +3,22 @@ package models
 import "time"
 
 type DomainsFilters struct {
-	Limit      *int
-	Offset     *int
+	Limit        *int
+	Offset       *int
+	Cursor       *DomainsCursor // keyset pagination, used instead of Offset when set
+	DomainName   string
+	Fuzzy        bool   // trigram similarity match on DomainName instead of substring
+	PunycodeName string // DomainName with its Unicode labels in punycode, matched as well when set
+	Status       string
+	UserID       string
+	Tags         []string // domains must carry all of them
+	Kind         string   // managed or monitored, both when empty
+}
+
+// CertificatesFilters selects the domain by id or name
+type CertificatesFilters struct {
+	DomainID   string
 	DomainName string
-	Status     string
-	UserID     string
 }
 
 type Entity struct {
@@ -16,4 +27,21 @@ type Entity struct {
 	IntegerParameters map[string]int
 	TimeParameters    map[string]time.Time
 	BoolParameters    map[string]bool
+	ArrayParameters   map[string][]string
+	JSONParameters    map[string]map[string]any // stored as JSONB
+	NullParameters    []string                  // columns explicitly set to NULL
+}
+
+type UpsertOptions struct {
+	ConflictColumns []string
+	OnlyDeleted     bool // update the conflicting row only when it is soft-deleted
 }
+
+// ActivationScope is what an activated certificate replaces next to the active certificate of its key type
+type ActivationScope int
+
+const (
+	ReplaceKeyType ActivationScope = iota // the primary pointer follows only within the key type
+	ReplacePrimary                        // the primary certificate too, e.g. a renewal after the domain's key type changed
+	ReplaceAll                            // the active certificates of every key type
+)
@@ -16,6 +16,12 @@ type GetDomainsDTO struct {
 
 type CertsDTO struct {
 	ID              string
+	SerialNumber    *string
+	Fingerprint     *string
+	Active          bool // in use for its key type
+	Primary         bool // the domain's active_certificate_id
+	State           string
+	KeyType         string
 	Issuer          *string
 	CertPath        string
 	KeyPath         string
@@ -26,6 +32,11 @@ type CertsDTO struct {
 	RenewalAttempts int
 	CreatedAt       time.Time
 	CreatedBy       string
+	OCSPStatus      *string // good, revoked or unknown, NULL until checked
+	OCSPCheckedAt   *time.Time
+	OCSPRevokedAt   *time.Time
+	Chains          []string // root of the default chain and each alternate, empty without alternates
+	ActiveChain     string   // default, alt1, alt2 and on
 }
 
 type DomainsDTO struct {
@@ -33,6 +44,10 @@ type DomainsDTO struct {
 	DomainName string
 	Details    DetailsDTO
 	Sub        []string
+	Tags       []string
+	TenantID   string
+	Metadata   map[string]any
+	Notes      *string
 }
 
 type DetailsDTO struct {
@@ -46,5 +61,97 @@ type DetailsDTO struct {
 	NginxContainerName  string
 	CertValidTo         *time.Time
 	CertLastRenewal     *time.Time
-	CertRenewalAttempts *int
+	CertRenewalAttempts *int // failed attempts since the last certificate of the domain
+	NextRenewalAt       *time.Time
+	Kind                string
+	MonitorAddress      *string
+	LastCheckedAt       *time.Time
+	LastCheckError      *string
+	KeyType             *string // NULL follows certs.key_type
+	Profile             *string // NULL follows certs.profile
+	ReuseKey            bool
+	OutputFormats       []string // NULL follows certs.output_formats
+	CertValidFrom       *time.Time
+	Wildcard            bool
+	OCSPStatus          *string // of the primary certificate
+	OCSPCheckedAt       *time.Time
+}
+
+type PurgeCandidateDTO struct {
+	ID         string
+	DomainName string
+	DeletedAt  time.Time
+	TenantID   string
+}
+
+// DNSProviderDTO is a provider registered at runtime, Credentials is the encrypted provider config
+type DNSProviderDTO struct {
+	ID          string
+	Name        string
+	Type        string
+	Credentials []byte
+	CreatedAt   time.Time
+	CreatedBy   string
+}
+
+// AcmeDNSAccountDTO is the account of a domain on an acme-dns server, Credentials is the encrypted account
+type AcmeDNSAccountDTO struct {
+	ServerURL   string
+	DomainName  string
+	FullDomain  string
+	Credentials []byte
+	CreatedAt   time.Time
+}
+
+// IssuanceAttemptDTO is an ACME order counted against the rate limits of the CA at CAURL
+type IssuanceAttemptDTO struct {
+	ID                string
+	CAURL             string
+	Names             []string
+	RegisteredDomains []string
+	Succeeded         bool
+	Renewal           bool
+	Pending           bool // reserved, the CA hasn't answered yet
+	FailedValidation  bool // the CA found an authorization invalid
+	CreatedAt         time.Time
+}
+
+// PendingOrderDTO is an ACME order that was started and hasn't finished, the ones left after a
+// crash are resumed or cleaned up on the next start
+type PendingOrderDTO struct {
+	ID             string
+	DomainName     string
+	Names          []string // sorted
+	DNSProvider    string
+	CAURL          string
+	KeyType        string
+	KeyPath        string // the key the order is for, a name in the storage backend
+	OrderURL       *string
+	CertificateURL *string // set once the CA issued the certificate
+	Challenges     []PendingChallenge
+	CreatedAt      time.Time
+}
+
+// PendingChallenge is a DNS-01 record a provider presented and didn't clean up yet
+type PendingChallenge struct {
+	Domain  string `json:"domain"`
+	Token   string `json:"token"`
+	KeyAuth string `json:"key_auth"`
+}
+
+// CoveringDomainDTO is an active domain whose certificate already covers Name, through its own
+// name, an alternative domain or a wildcard
+type CoveringDomainDTO struct {
+	Name      string
+	CoveredBy string
+}
+
+// ServedCertificate is the leaf certificate a monitored domain presented on its last check
+type ServedCertificate struct {
+	SerialNumber string
+	Fingerprint  string
+	KeyType      string
+	Issuer       string
+	ValidFrom    time.Time
+	ValidTo      time.Time
 }
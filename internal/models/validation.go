@@ -0,0 +1,58 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with one field of a request, Field is the JSON name of the value
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request, so they can be fixed in one go
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid request:")
+	for _, fe := range e.Errors {
+		fmt.Fprintf(&b, "\n  - %s: %s", fe.Field, fe.Message)
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Add(field, format string, args ...any) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns nil when no field was reported
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// DomainConflict is a requested name already covered by another domain managed by Hephaestus
+type DomainConflict struct {
+	Domain    string `json:"domain"`
+	CoveredBy string `json:"covered_by"`
+}
+
+// ConflictError lists every requested name that clashes with an existing domain
+type ConflictError struct {
+	Conflicts []DomainConflict `json:"conflicts"`
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	b.WriteString("domains already covered:")
+	for _, c := range e.Conflicts {
+		fmt.Fprintf(&b, "\n  - %s: covered by %s", c.Domain, c.CoveredBy)
+	}
+	return b.String()
+}
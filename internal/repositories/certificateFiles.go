@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+)
+
+// certificate files are shared by all tenants like the ACME account, the queries are not tenant scoped.
+// Reads go to the primary, a replica may miss a key written by the order in progress
+
+// GetCertificateFile returns pgx.ErrNoRows when there is no file of the name
+func (r *Repository) GetCertificateFile(ctx context.Context, name string) ([]byte, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	err := r.DB.QueryRow(ctx, `SELECT content FROM certificate_files WHERE name = $1`, name).Scan(&content)
+	return content, err
+}
+
+func (r *Repository) SaveCertificateFile(ctx context.Context, name string, content []byte) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO certificate_files (name, content)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+	`, name, content)
+	return err
+}
+
+// DeleteCertificateFiles removes the file of the name and every file below it
+func (r *Repository) DeleteCertificateFiles(ctx context.Context, name string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Deleting certificate files: ", name)
+	_, err := r.DB.Exec(ctx, `
+		DELETE FROM certificate_files
+		WHERE name = $1 OR starts_with(name, $1 || '/')
+	`, name)
+	return err
+}
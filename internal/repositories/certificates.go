@@ -4,49 +4,123 @@ import (
 	"context"
 	"fmt"
 	models "hephaestus/internal/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
-func (r *Repository) GetCertificatesByDomain(ctx context.Context, domainID string) (models.CertsDTO, error) {
-	r.log.Debug("id in repo layer: ", domainID)
+// GetCertificatesByDomain returns every certificate issued for the domain, newest first,
+// the one in use is marked as active
+func (r *Repository) GetCertificatesByDomain(ctx context.Context, filters models.CertificatesFilters) ([]models.CertsDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Filters in repo layer: ", filters)
 	query := `
         SELECT 
-            id, issuer, cert_path, key_path, chain_path, valid_from,
-			valid_to, last_renewal, renewal_attempts, created_at, created_by
-        FROM certificates 
-        WHERE deleted_at IS NULL
+            c.id, c.serial_number, c.fingerprint, c.state = 'active', c.id IS NOT DISTINCT FROM d.active_certificate_id, c.state, c.key_type, c.issuer, COALESCE(c.cert_path, ''), COALESCE(c.key_path, ''), c.chain_path,
+			c.valid_from, c.valid_to, c.last_renewal, c.renewal_attempts, c.created_at, c.created_by,
+			c.ocsp_status, c.ocsp_checked_at, c.ocsp_revoked_at, c.chains, c.active_chain
+        FROM certificates c
+        JOIN domains d ON d.id = c.domain_id
+        WHERE c.deleted_at IS NULL AND d.deleted_at IS NULL
     `
 
 	args := []interface{}{}
 	argID := 1
 
-	query += fmt.Sprintf(" AND domain_id = $%d", argID)
-	args = append(args, "%"+domainID+"%")
-	argID++
+	if filters.DomainID != "" {
+		query += fmt.Sprintf(" AND d.id = $%d", argID)
+		args = append(args, filters.DomainID)
+		argID++
+	}
+	if filters.DomainName != "" {
+		query += fmt.Sprintf(" AND d.domain_name = $%d", argID)
+		args = append(args, filters.DomainName)
+		argID++
+	}
+
+	query, args, err := appendTenantScope(ctx, query, args, "d")
+	if err != nil {
+		return nil, err
+	}
+	query += " ORDER BY c.created_at DESC, c.id DESC"
 
 	r.log.Debug("Query execution: ", query)
 	rows, err := r.DB.Query(ctx, query, args...)
 	if err != nil {
-		return models.CertsDTO{}, err
+		return nil, err
 	}
 	r.log.Debug("Query executed.")
 	defer rows.Close()
 
-	var certs models.CertsDTO
-	r.log.Debug("Starting to scan rows")
-
-	err = rows.Scan(
-		&certs.ID, &certs.Issuer, &certs.CertPath, &certs.KeyPath, &certs.ChainPath, &certs.ValidFrom,
-		&certs.ValidTo, &certs.LastRenewal, &certs.RenewalAttempts, &certs.CreatedAt, &certs.CreatedBy,
-	)
-	if err != nil {
-		return certs, err
-
+	var certs []models.CertsDTO
+	for rows.Next() {
+		var cert models.CertsDTO
+		err = rows.Scan(
+			&cert.ID, &cert.SerialNumber, &cert.Fingerprint, &cert.Active, &cert.Primary, &cert.State, &cert.KeyType, &cert.Issuer, &cert.CertPath, &cert.KeyPath, &cert.ChainPath,
+			&cert.ValidFrom, &cert.ValidTo, &cert.LastRenewal, &cert.RenewalAttempts, &cert.CreatedAt, &cert.CreatedBy,
+			&cert.OCSPStatus, &cert.OCSPCheckedAt, &cert.OCSPRevokedAt, &cert.Chains, &cert.ActiveChain,
+		)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
 	}
-	r.log.Debug("Rows scanned.")
 
 	if err = rows.Err(); err != nil {
-		return certs, err
+		return nil, err
 	}
 
 	return certs, nil
 }
+
+// ActivateCertificateTx makes the certificate the active one of its key type and retires the previous one,
+// the domain's primary pointer follows when it has none yet or pointed at the same key type.
+// ReplacePrimary also retires the primary certificate of another key type and always moves the pointer,
+// ReplaceAll retires the active certificates of every key type
+func (r *Repository) ActivateCertificateTx(ctx context.Context, tx pgx.Tx, domainID, certID string, scope models.ActivationScope) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	exclusive, replacePrimary := scope == models.ReplaceAll, scope >= models.ReplacePrimary
+	// the pointer still names the previous primary, it is moved below
+	retire, args, err := appendTenantScope(ctx, `
+		UPDATE certificates c SET state = 'retired'
+		WHERE c.domain_id = $1 AND c.state = 'active' AND c.id <> $2
+		AND ($3 OR c.key_type = (SELECT key_type FROM certificates WHERE id = $2)
+			OR ($4 AND c.id = (SELECT active_certificate_id FROM domains WHERE id = $1)))`, []interface{}{domainID, certID, exclusive, replacePrimary}, "c")
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, retire, args...); err != nil {
+		return fmt.Errorf("retire certificates: %w", err)
+	}
+
+	activate, args, err := appendTenantScope(ctx, `
+		UPDATE certificates c SET state = 'active'
+		WHERE c.id = $1 AND c.domain_id = $2 AND c.deleted_at IS NULL`, []interface{}{certID, domainID}, "c")
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, activate, args...)
+	if err != nil {
+		return fmt.Errorf("activate certificate: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	primary, args, err := appendTenantScope(ctx, `
+		UPDATE domains d SET active_certificate_id = $2
+		WHERE d.id = $1 AND ($3 OR d.active_certificate_id IS NULL OR d.active_certificate_id = $2 OR EXISTS (
+			SELECT 1 FROM certificates p, certificates n
+			WHERE p.id = d.active_certificate_id AND n.id = $2 AND p.key_type = n.key_type))`,
+		[]interface{}{domainID, certID, replacePrimary}, "d")
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, primary, args...); err != nil {
+		return fmt.Errorf("update primary certificate: %w", err)
+	}
+	return nil
+}
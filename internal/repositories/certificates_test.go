@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	models "hephaestus/internal/models"
+)
+
+func TestActivationScopes(t *testing.T) {
+	tests := []struct {
+		scope                  models.ActivationScope
+		retireAll, takePrimary bool
+	}{
+		{scope: models.ReplaceKeyType},
+		// a renewal after the domain's key type changed retires the old primary and moves the pointer
+		{scope: models.ReplacePrimary, takePrimary: true},
+		{scope: models.ReplaceAll, retireAll: true, takePrimary: true},
+	}
+	for _, tt := range tests {
+		r, rec := newTestRepository()
+		if err := r.ActivateCertificateTx(WithSystemScope(context.Background()), rec, "domain", "cert", tt.scope); err != nil {
+			t.Fatal(err)
+		}
+		if len(rec.statements) != 3 {
+			t.Fatalf("scope %d ran %d statements", tt.scope, len(rec.statements))
+		}
+		retire, pointer := rec.statements[0], rec.statements[2]
+		if !strings.Contains(retire.sql, "active_certificate_id") {
+			t.Errorf("the retirement doesn't consider the primary certificate: %s", retire.sql)
+		}
+		if want := []any{"domain", "cert", tt.retireAll, tt.takePrimary}; !slices.Equal(retire.args, want) {
+			t.Errorf("scope %d retires with %v, want %v", tt.scope, retire.args, want)
+		}
+		if want := []any{"domain", "cert", tt.takePrimary}; !slices.Equal(pointer.args, want) {
+			t.Errorf("scope %d moves the pointer with %v, want %v", tt.scope, pointer.args, want)
+		}
+	}
+}
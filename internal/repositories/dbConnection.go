@@ -6,25 +6,54 @@ import (
 	utils "hephaestus/internal/utils"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	defaultMaxConns          = 10
+	defaultHealthCheckPeriod = 30 * time.Second
+	defaultConnectTimeout    = 5 * time.Second
+	defaultQueryTimeout      = 30 * time.Second
+)
+
 func (r *Repository) CreateConnection(cfg *utils.Config) (*pgxpool.Pool, error) {
 	r.log.Debug("Create connection................")
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		cfg.Database.User, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.Database)
 
-	r.log.Debug("Connect dsn: ", dsn)
+	r.log.Debug("Connect host: ", cfg.Database.Host, ":", cfg.Database.Port, "/", cfg.Database.Database)
+	return r.createPool(cfg, dsn)
+}
+
+func (r *Repository) CreateReadConnection(cfg *utils.Config) (*pgxpool.Pool, error) {
+	r.log.Debug("Create read replica connection................")
+	return r.createPool(cfg, cfg.Database.ReadDSN)
+}
+
+func (r *Repository) createPool(cfg *utils.Config, dsn string) (*pgxpool.Pool, error) {
 	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
-	poolConfig.MaxConns = 10 // max connection count
-	poolConfig.HealthCheckPeriod = 30 * time.Second
-	r.log.Debug("Max connections: ", poolConfig.MaxConns)
-	r.log.Debug("Health check: ", poolConfig.HealthCheckPeriod, " seconds")
+	if err := applyPoolConfig(poolConfig, cfg.Database.Pool); err != nil {
+		return nil, err
+	}
+	if r.tracer != nil {
+		poolConfig.ConnConfig.Tracer = r.tracer
+	}
+	if cfg.Database.StatementTimeout > 0 {
+		// the server cancels statements itself, also when the client deadline is lost with the connection
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = fmt.Sprint(cfg.Database.StatementTimeout.Milliseconds())
+	}
+	r.log.Debug("Max connections: ", poolConfig.MaxConns, ", min connections: ", poolConfig.MinConns)
+	r.log.Debug("Max connection lifetime: ", poolConfig.MaxConnLifetime, ", max idle time: ", poolConfig.MaxConnIdleTime)
+	r.log.Debug("Health check: ", poolConfig.HealthCheckPeriod)
+	r.log.Debug("Statement timeout: ", cfg.Database.StatementTimeout, ", query timeout: ", r.queryTimeout)
+	r.log.Debug("Query exec mode: ", poolConfig.ConnConfig.DefaultQueryExecMode,
+		", statement cache: ", poolConfig.ConnConfig.StatementCacheCapacity)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), poolConfig.ConnConfig.ConnectTimeout)
 	defer cancel()
 
 	dbPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
@@ -53,6 +82,66 @@ func (r *Repository) CreateConnection(cfg *utils.Config) (*pgxpool.Pool, error)
 	return dbPool, nil
 }
 
+func applyPoolConfig(poolConfig *pgxpool.Config, cfg utils.PoolConfig) error {
+	poolConfig.MaxConns = defaultMaxConns
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		if cfg.MinConns > poolConfig.MaxConns {
+			return fmt.Errorf("pool min_conns (%d) exceeds max_conns (%d)", cfg.MinConns, poolConfig.MaxConns)
+		}
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+
+	poolConfig.HealthCheckPeriod = defaultHealthCheckPeriod
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	poolConfig.ConnConfig.ConnectTimeout = defaultConnectTimeout
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+
+	if cfg.StatementCacheCapacity > 0 {
+		poolConfig.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	}
+
+	if cfg.QueryExecMode != "" {
+		mode, err := parseQueryExecMode(cfg.QueryExecMode)
+		if err != nil {
+			return err
+		}
+		poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+
+	return nil
+}
+
+func parseQueryExecMode(mode string) (pgx.QueryExecMode, error) {
+	switch mode {
+	case "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe_exec":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	default:
+		return 0, fmt.Errorf("unknown query exec mode: %s", mode)
+	}
+}
+
 func (r *Repository) checkConnection(dbPool *pgxpool.Pool) {
 	tx, err := dbPool.Begin(context.Background())
 	if err != nil {
@@ -77,3 +166,10 @@ func (r *Repository) checkConnection(dbPool *pgxpool.Pool) {
 		r.log.Debug("Current search_path: ", searchPath)
 	}
 }
+
+func (r *Repository) Close() {
+	if r.ReadDB != nil {
+		r.ReadDB.Close()
+	}
+	r.DB.Close()
+}
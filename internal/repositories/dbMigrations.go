@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	utils "hephaestus/internal/utils"
 	"path/filepath"
@@ -10,39 +11,122 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
-func (r *Repository) RunMigrations(cfg *utils.Config) error {
+type Migrator struct {
+	m   *migrate.Migrate
+	log *utils.Logger
+}
+
+type MigrationStatus struct {
+	Version uint
+	Dirty   bool
+	Applied bool // false when no migration was applied yet
+}
+
+func NewMigrator(cfg *utils.Config, log *utils.Logger) (*Migrator, error) {
 	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		cfg.Database.User, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
 
 	migrationsPath := "file://" + filepath.ToSlash(filepath.Join(cfg.Database.MigrationPath, "migrations"))
 
-	r.log.Debug("Migrations path:", migrationsPath)
+	log.Debug("Migrations path:", migrationsPath)
 
 	m, err := migrate.New(migrationsPath, dbURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	version, dirty, err := m.Version()
-	if err != nil && err != migrate.ErrNilVersion {
-		return err
+	return &Migrator{m: m, log: log}, nil
+}
+
+func (mg *Migrator) Close() {
+	srcErr, dbErr := mg.m.Close()
+	if srcErr != nil {
+		mg.log.Warn("Error closing migrations source: ", srcErr)
 	}
+	if dbErr != nil {
+		mg.log.Warn("Error closing migrations database: ", dbErr)
+	}
+}
 
-	r.log.Debug("Current migration version:", version)
+func (mg *Migrator) Status() (MigrationStatus, error) {
+	version, dirty, err := mg.m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return MigrationStatus{}, nil
+		}
+		return MigrationStatus{}, err
+	}
+	return MigrationStatus{Version: version, Dirty: dirty, Applied: true}, nil
+}
 
-	if dirty {
-		r.log.Warn("Database is in a dirty migration state! Manual intervention may be required.")
-		return fmt.Errorf("database is in a dirty state, please check the migration history")
+func (mg *Migrator) Up() error {
+	status, err := mg.checkClean()
+	if err != nil {
+		return err
 	}
+	mg.log.Debug("Current migration version:", status.Version)
 
-	if err := m.Up(); err != nil {
-		if err == migrate.ErrNoChange {
-			r.log.Debug("No new migrations to apply")
+	if err := mg.m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			mg.log.Debug("No new migrations to apply")
 			return nil
 		}
 		return fmt.Errorf("migration error: %w", err)
 	}
 
-	r.log.Info("Migrations applied successfully")
+	mg.log.Info("Migrations applied successfully")
+	return nil
+}
+
+// Down rolls back the given number of migrations
+func (mg *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	if _, err := mg.checkClean(); err != nil {
+		return err
+	}
+
+	if err := mg.m.Steps(-steps); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			mg.log.Debug("No migrations to roll back")
+			return nil
+		}
+		return fmt.Errorf("migration rollback error: %w", err)
+	}
+
+	mg.log.Info("Rolled back migrations: ", steps)
 	return nil
 }
+
+// Force sets the migration version without running migrations and clears the dirty flag,
+// to be used after a failed migration was fixed by hand
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("force migration version: %w", err)
+	}
+	mg.log.Info("Migration version forced to ", version)
+	return nil
+}
+
+func (mg *Migrator) checkClean() (MigrationStatus, error) {
+	status, err := mg.Status()
+	if err != nil {
+		return status, err
+	}
+	if status.Dirty {
+		mg.log.Warn("Database is in a dirty migration state! Manual intervention may be required.")
+		return status, fmt.Errorf("database is in a dirty state at version %d, fix the schema and run 'migrate force <version>'", status.Version)
+	}
+	return status, nil
+}
+
+func (r *Repository) RunMigrations(cfg *utils.Config) error {
+	migrator, err := NewMigrator(cfg, r.log)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
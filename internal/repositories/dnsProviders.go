@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	models "hephaestus/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// dns providers are shared by all tenants and managed by admins, so the queries are not tenant scoped
+
+func (r *Repository) GetDNSProviders(ctx context.Context) ([]models.DNSProviderDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Fetching runtime DNS providers")
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, name, type, credentials, created_at, created_by
+		FROM dns_providers
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var providers []models.DNSProviderDTO
+	for rows.Next() {
+		var p models.DNSProviderDTO
+		if err := rows.Scan(&p.ID, &p.Name, &p.Type, &p.Credentials, &p.CreatedAt, &p.CreatedBy); err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	return providers, rows.Err()
+}
+
+// UpsertDNSProvider registers a provider or replaces the credentials of the one with the same name
+func (r *Repository) UpsertDNSProvider(ctx context.Context, provider models.DNSProviderDTO) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Saving runtime DNS provider: ", provider.Name)
+
+	var id string
+	err := r.DB.QueryRow(ctx, `
+		INSERT INTO dns_providers (name, type, credentials, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+		SET type = EXCLUDED.type, credentials = EXCLUDED.credentials, updated_by = EXCLUDED.created_by
+		RETURNING id
+	`, provider.Name, provider.Type, provider.Credentials, provider.CreatedBy).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	r.log.Debug("Runtime DNS provider saved: ", id)
+	return id, nil
+}
+
+func (r *Repository) DeleteDNSProvider(ctx context.Context, name string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Deleting runtime DNS provider: ", name)
+
+	tag, err := r.DB.Exec(ctx, `DELETE FROM dns_providers WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	r.log.Debug("Runtime DNS provider deleted.")
+	return nil
+}
+
+// GetAcmeDNSAccount returns pgx.ErrNoRows when the domain has no account on the server
+func (r *Repository) GetAcmeDNSAccount(ctx context.Context, serverURL, domainName string) (models.AcmeDNSAccountDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Fetching acme-dns account: ", domainName)
+
+	account := models.AcmeDNSAccountDTO{ServerURL: serverURL, DomainName: domainName}
+	err := r.DB.QueryRow(ctx, `
+		SELECT full_domain, credentials, created_at
+		FROM acmedns_accounts
+		WHERE server_url = $1 AND domain_name = $2
+	`, serverURL, domainName).Scan(&account.FullDomain, &account.Credentials, &account.CreatedAt)
+	return account, err
+}
+
+// InsertAcmeDNSAccount keeps the account already stored for the domain, inserted is false then
+func (r *Repository) InsertAcmeDNSAccount(ctx context.Context, account models.AcmeDNSAccountDTO) (inserted bool, err error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Saving acme-dns account: ", account.DomainName)
+
+	tag, err := r.DB.Exec(ctx, `
+		INSERT INTO acmedns_accounts (server_url, domain_name, full_domain, credentials)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (server_url, domain_name) DO NOTHING
+	`, account.ServerURL, account.DomainName, account.FullDomain, account.Credentials)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
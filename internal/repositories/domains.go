@@ -4,9 +4,16 @@ import (
 	"context"
 	"fmt"
 	models "hephaestus/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// IsDomainExists checks the name across all tenants on purpose, domain names are unique installation-wide
 func (r *Repository) IsDomainExists(ctx context.Context, domain string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	const query = `SELECT EXISTS(SELECT 1 FROM domains WHERE domain_name = $1);`
 
 	var exists bool
@@ -18,36 +25,133 @@ func (r *Repository) IsDomainExists(ctx context.Context, domain string) (bool, e
 	return exists, nil
 }
 
-func (r *Repository) GetDomainsCount(ctx context.Context, filters models.DomainsFilters) (int, error) {
-	r.log.Debug("Filters in repo layer: ", filters)
+// IsDomainDeleted reports whether the domain exists and is soft-deleted
+func (r *Repository) IsDomainDeleted(ctx context.Context, domain string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	query := `
-		SELECT COUNT(*)
-		FROM domains
-		WHERE deleted_at IS NULL
-	`
-	args := []interface{}{}
-	argID := 1
+	query, args, err := appendTenantScope(ctx,
+		`SELECT 1 FROM domains WHERE domain_name = $1 AND deleted_at IS NOT NULL`, []interface{}{domain}, "domains")
+	if err != nil {
+		return false, err
+	}
 
-	if filters.DomainName != "" {
-		query += fmt.Sprintf(" AND domain_name ILIKE $%d", argID)
-		args = append(args, "%"+filters.DomainName+"%")
-		argID++
+	var deleted bool
+	err = r.DB.QueryRow(ctx, "SELECT EXISTS("+query+")", args...).Scan(&deleted)
+	if err != nil {
+		return false, err
 	}
-	if filters.Status != "" {
-		query += fmt.Sprintf(" AND status ILIKE $%d", argID)
-		args = append(args, "%"+filters.Status+"%")
-		argID++
+
+	return deleted, nil
+}
+
+// GetCoveringDomains finds the active domains already covering any of names, installation-wide like
+// IsDomainExists. A name is covered by an equal domain or alternative domain, or by the wildcard of its parent.
+// CoveredBy stays empty for domains of other tenants than the one of ctx, their names aren't disclosed
+func (r *Repository) GetCoveringDomains(ctx context.Context, names []string) ([]models.CoveringDomainDTO, error) {
+	tenantID, scoped, err := tenantScope(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if filters.UserID != "" {
-		query += fmt.Sprintf(" AND created_by = $%d", argID)
-		args = append(args, filters.UserID)
-		argID++
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	const query = `
+		WITH covered AS (
+			SELECT d.domain_name AS name, d.domain_name AS covered_by, d.tenant_id
+			FROM domains d
+			WHERE d.deleted_at IS NULL
+			UNION ALL
+			SELECT a.domain_name, d.domain_name, d.tenant_id
+			FROM alternative_domains a
+			JOIN domains d ON d.id = a.domain_id
+			WHERE a.deleted_at IS NULL AND d.deleted_at IS NULL
+		)
+		SELECT n.name, c.covered_by, c.tenant_id
+		FROM unnest($1::text[]) AS n(name)
+		JOIN covered c ON c.name = n.name
+			OR c.name = '*.' || substring(n.name FROM position('.' IN n.name) + 1)
+		ORDER BY n.name, c.covered_by;`
+
+	rows, err := r.DB.Query(ctx, query, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.CoveringDomainDTO
+	for rows.Next() {
+		var c models.CoveringDomainDTO
+		var owner string
+		if err := rows.Scan(&c.Name, &c.CoveredBy, &owner); err != nil {
+			return nil, err
+		}
+		if scoped && owner != tenantID {
+			c.CoveredBy = ""
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SoftDeleteDomainTx marks the domain and every row referencing it through domain_id as deleted
+// in one statement, so children can't be left behind when a delete fails halfway
+func (r *Repository) SoftDeleteDomainTx(ctx context.Context, tx pgx.Tx, domainID, deletedBy string, deletedAt time.Time) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Soft deleting domain: ", domainID)
+
+	domainsUpdate, args, err := appendTenantScope(ctx, `
+		UPDATE domains
+		SET status = 'deleted', deleted_at = $2, deleted_by = $3, updated_by = $3
+		WHERE id = $1 AND deleted_at IS NULL`, []interface{}{domainID, deletedAt, deletedBy}, "domains")
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf(`
+		WITH d AS (%s
+			RETURNING id, domain_name
+		), ad AS (
+			UPDATE alternative_domains
+			SET deleted_at = $2, deleted_by = $3, updated_by = $3
+			WHERE domain_id IN (SELECT id FROM d) AND deleted_at IS NULL
+		), c AS (
+			UPDATE certificates
+			SET deleted_at = $2, deleted_by = $3, updated_by = $3
+			WHERE domain_id IN (SELECT id FROM d) AND deleted_at IS NULL
+		)
+		SELECT domain_name FROM d`, domainsUpdate)
+
+	r.log.Debug("Query execution: ", query)
+	var domainName string
+	if err := tx.QueryRow(ctx, query, args...).Scan(&domainName); err != nil {
+		return "", err
+	}
+	r.log.Debug("Query executed.")
+
+	return domainName, nil
+}
+
+func (r *Repository) GetDomainsCount(ctx context.Context, filters models.DomainsFilters) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Filters in repo layer: ", filters)
+
+	query, args, err := appendDomainsFilters(ctx, `
+		SELECT COUNT(*)
+		FROM domains d
+		WHERE d.deleted_at IS NULL
+	`, nil, filters)
+	if err != nil {
+		return 0, err
 	}
 
 	var count int
 	r.log.Debug("Query execution: ", query)
-	err := r.DB.QueryRow(ctx, query, args...).Scan(&count)
+	err = r.reader().QueryRow(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -58,60 +162,65 @@ func (r *Repository) GetDomainsCount(ctx context.Context, filters models.Domains
 }
 
 func (r *Repository) GetDomainsList(ctx context.Context, filters models.DomainsFilters) ([]models.DomainsDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	r.log.Debug("Filters in repo layer: ", filters)
 
-	subQuery := `
+	subQuery, args, err := appendDomainsFilters(ctx, `
 		SELECT d.id
 		FROM domains d
 		WHERE d.deleted_at IS NULL
-	`
-	args := []interface{}{}
-	argID := 1
-
-	if filters.DomainName != "" {
-		subQuery += fmt.Sprintf(" AND d.domain_name ILIKE $%d", argID)
-		args = append(args, "%"+filters.DomainName+"%")
-		argID++
-	}
-	if filters.Status != "" {
-		subQuery += fmt.Sprintf(" AND d.status ILIKE $%d", argID)
-		args = append(args, "%"+filters.Status+"%")
-		argID++
-	}
-	if filters.UserID != "" {
-		subQuery += fmt.Sprintf(" AND d.created_by = $%d", argID)
-		args = append(args, filters.UserID)
-		argID++
+	`, nil, filters)
+	if err != nil {
+		return nil, err
 	}
+	argID := len(args) + 1
 
-	if filters.Limit != nil && filters.Offset != nil {
-		subQuery += fmt.Sprintf(" ORDER BY d.created_at DESC LIMIT $%d OFFSET $%d", argID, argID+1)
-		args = append(args, filters.Limit, filters.Offset)
+	// keyset pagination, relies on idx_domains_created_at_id
+	if filters.Cursor != nil {
+		subQuery += fmt.Sprintf(" AND (d.created_at, d.id) < ($%d, $%d)", argID, argID+1)
+		args = append(args, filters.Cursor.CreatedAt, filters.Cursor.ID)
 		argID += 2
 	}
 
+	if filters.Limit != nil {
+		subQuery += fmt.Sprintf(" ORDER BY d.created_at DESC, d.id DESC LIMIT $%d", argID)
+		args = append(args, filters.Limit)
+		argID++
+		if filters.Offset != nil && filters.Cursor == nil {
+			subQuery += fmt.Sprintf(" OFFSET $%d", argID)
+			args = append(args, filters.Offset)
+			argID++
+		}
+	}
+
 	query := fmt.Sprintf(`
 		SELECT 
 			d.id, d.domain_name, d.dns_provider, d.status, d.auto_renew,
 			d.nginx_container_name, d.verification_method, d.created_at, d.created_by, d.updated_at, 
-			c.valid_to, c.last_renewal, c.renewal_attempts,
+			c.valid_to, c.last_renewal, d.renewal_attempts, d.tags, d.tenant_id, d.metadata, d.notes,
+			d.kind, d.monitor_address, d.last_checked_at, d.last_check_error, d.key_type, d.wildcard,
+			c.ocsp_status, c.ocsp_checked_at, d.profile, c.valid_from, d.reuse_key, d.output_formats, d.next_renewal_at,
 			COALESCE(
 				array_agg(ad.domain_name) FILTER (WHERE ad.domain_name IS NOT NULL),
 				'{}'
 			) AS subdomains
 		FROM (%s) AS domains_list
 		JOIN domains d ON d.id = domains_list.id
-		LEFT JOIN certificates c ON c.domain_id = d.id AND c.deleted_at IS NULL
+		LEFT JOIN certificates c ON c.id = d.active_certificate_id
 		LEFT JOIN alternative_domains ad ON ad.domain_id = d.id AND ad.deleted_at IS NULL
 		GROUP BY 
 			d.id, d.domain_name, d.dns_provider, d.status, d.auto_renew,
 			d.nginx_container_name, d.verification_method, d.created_at, d.created_by, d.updated_at,
-			c.valid_to, c.last_renewal, c.renewal_attempts
-		ORDER BY d.id;
+			c.valid_to, c.last_renewal, d.renewal_attempts, d.tags, d.tenant_id, d.metadata, d.notes,
+			d.kind, d.monitor_address, d.last_checked_at, d.last_check_error, d.key_type, d.wildcard,
+			c.ocsp_status, c.ocsp_checked_at, d.profile, c.valid_from, d.reuse_key, d.output_formats, d.next_renewal_at
+		ORDER BY d.created_at DESC, d.id DESC;
 		`, subQuery)
 
 	r.log.Debug("Query execution: ", query)
-	rows, err := r.DB.Query(ctx, query, args...)
+	rows, err := r.reader().Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +237,10 @@ func (r *Repository) GetDomainsList(ctx context.Context, filters models.DomainsF
 			&domain.Details.AutoRenew, &domain.Details.NginxContainerName, &domain.Details.VerificationMethod,
 			&domain.Details.CreatedAt, &domain.Details.CreatedBy, &domain.Details.DomainLastUpdate,
 			&domain.Details.CertValidTo, &domain.Details.CertLastRenewal, &domain.Details.CertRenewalAttempts,
-			&domain.Sub,
+			&domain.Tags, &domain.TenantID, &domain.Metadata, &domain.Notes,
+			&domain.Details.Kind, &domain.Details.MonitorAddress, &domain.Details.LastCheckedAt, &domain.Details.LastCheckError,
+			&domain.Details.KeyType, &domain.Details.Wildcard,
+			&domain.Details.OCSPStatus, &domain.Details.OCSPCheckedAt, &domain.Details.Profile, &domain.Details.CertValidFrom, &domain.Details.ReuseKey, &domain.Details.OutputFormats, &domain.Details.NextRenewalAt, &domain.Sub,
 		)
 		if err != nil {
 			return nil, err
@@ -139,3 +251,63 @@ func (r *Repository) GetDomainsList(ctx context.Context, filters models.DomainsF
 
 	return domains, nil
 }
+
+// appendDomainsFilters adds the tenant scope and the filter conditions shared by domains queries,
+// the table must be aliased as d
+func appendDomainsFilters(ctx context.Context, query string, args []interface{}, filters models.DomainsFilters) (string, []interface{}, error) {
+	query, args, err := appendTenantScope(ctx, query, args, "d")
+	if err != nil {
+		return "", nil, err
+	}
+	argID := len(args) + 1
+
+	// domain names are matched against the main and the alternative domains, in their punycode
+	// and Unicode form, all backed by trigram indexes
+	if filters.DomainName != "" {
+		op, term, punycode := "ILIKE", "%"+filters.DomainName+"%", "%"+filters.PunycodeName+"%"
+		if filters.Fuzzy {
+			op, term, punycode = "%", filters.DomainName, filters.PunycodeName
+		}
+		args = append(args, term)
+		termArg := argID
+		argID++
+		punycodeArg := 0
+		if filters.PunycodeName != "" {
+			args = append(args, punycode)
+			punycodeArg = argID
+			argID++
+		}
+		match := func(alias string) string {
+			cond := fmt.Sprintf("%[1]s.domain_name %[2]s $%[3]d OR %[1]s.unicode_name %[2]s $%[3]d", alias, op, termArg)
+			if punycodeArg > 0 {
+				cond += fmt.Sprintf(" OR %s.domain_name %s $%d", alias, op, punycodeArg)
+			}
+			return cond
+		}
+		query += fmt.Sprintf(` AND (%s OR EXISTS (
+			SELECT 1 FROM alternative_domains ad
+			WHERE ad.domain_id = d.id AND ad.deleted_at IS NULL AND (%s)))`, match("d"), match("ad"))
+	}
+	if filters.Status != "" {
+		query += fmt.Sprintf(" AND d.status ILIKE $%d", argID)
+		args = append(args, "%"+filters.Status+"%")
+		argID++
+	}
+	if filters.UserID != "" {
+		query += fmt.Sprintf(" AND d.created_by = $%d", argID)
+		args = append(args, filters.UserID)
+		argID++
+	}
+	if len(filters.Tags) > 0 {
+		query += fmt.Sprintf(" AND d.tags @> $%d", argID)
+		args = append(args, filters.Tags)
+		argID++
+	}
+	if filters.Kind != "" {
+		query += fmt.Sprintf(" AND d.kind = $%d", argID)
+		args = append(args, filters.Kind)
+		argID++
+	}
+
+	return query, args, nil
+}
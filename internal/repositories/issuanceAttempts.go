@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	models "hephaestus/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// issuance attempts count against the ACME account, which is shared by all tenants, so the queries
+// are not tenant scoped. Reads go to the primary, a budget check must see the order recorded just before
+
+const issuanceAttemptsQuery = `
+	SELECT id, names, registered_domains, succeeded, renewal, pending, failed_validation, created_at
+	FROM issuance_attempts
+	WHERE ca_url = $1 AND created_at >= $2
+	ORDER BY created_at
+`
+
+func (r *Repository) GetIssuanceAttempts(ctx context.Context, caURL string, since time.Time) ([]models.IssuanceAttemptDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Fetching issuance attempts since: ", since)
+
+	rows, err := r.DB.Query(ctx, issuanceAttemptsQuery, caURL, since)
+	if err != nil {
+		return nil, err
+	}
+	return scanIssuanceAttempts(rows, caURL)
+}
+
+// GetIssuanceAttemptsTx reads the attempts like GetIssuanceAttempts and holds the lock of the CA until tx
+// ends, a budget check and the reservation of its order can't interleave with another replica's
+func (r *Repository) GetIssuanceAttemptsTx(ctx context.Context, tx pgx.Tx, caURL string, since time.Time) ([]models.IssuanceAttemptDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Locking issuance attempts of: ", caURL)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('issuance_attempts:' || $1))`, caURL); err != nil {
+		return nil, fmt.Errorf("lock issuance attempts: %w", err)
+	}
+	rows, err := tx.Query(ctx, issuanceAttemptsQuery, caURL, since)
+	if err != nil {
+		return nil, err
+	}
+	return scanIssuanceAttempts(rows, caURL)
+}
+
+func scanIssuanceAttempts(rows pgx.Rows, caURL string) ([]models.IssuanceAttemptDTO, error) {
+	defer rows.Close()
+
+	var attempts []models.IssuanceAttemptDTO
+	for rows.Next() {
+		a := models.IssuanceAttemptDTO{CAURL: caURL}
+		if err := rows.Scan(&a.ID, &a.Names, &a.RegisteredDomains, &a.Succeeded, &a.Renewal, &a.Pending, &a.FailedValidation, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// InsertIssuanceAttemptTx records an order, pending ones are reservations finished by
+// FinishIssuanceAttempt. Orders older than keepSince are removed, no limit looks back that far
+func (r *Repository) InsertIssuanceAttemptTx(ctx context.Context, tx pgx.Tx, attempt models.IssuanceAttemptDTO, keepSince time.Time) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Saving issuance attempt: ", attempt.Names)
+
+	var id string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO issuance_attempts (ca_url, names, registered_domains, succeeded, renewal, pending, failed_validation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, attempt.CAURL, attempt.Names, attempt.RegisteredDomains, attempt.Succeeded, attempt.Renewal, attempt.Pending, attempt.FailedValidation).Scan(&id); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM issuance_attempts WHERE created_at < $1`, keepSince); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// FinishIssuanceAttempt stores the outcome of a reserved order
+func (r *Repository) FinishIssuanceAttempt(ctx context.Context, id string, succeeded, failedValidation bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Finishing issuance attempt: ", id)
+
+	_, err := r.DB.Exec(ctx, `
+		UPDATE issuance_attempts SET pending = FALSE, succeeded = $2, failed_validation = $3
+		WHERE id = $1
+	`, id, succeeded, failedValidation)
+	return err
+}
+
+// DeleteIssuanceAttempt drops a reserved order that never reached the CA's validation
+func (r *Repository) DeleteIssuanceAttempt(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Deleting issuance attempt: ", id)
+
+	_, err := r.DB.Exec(ctx, `DELETE FROM issuance_attempts WHERE id = $1`, id)
+	return err
+}
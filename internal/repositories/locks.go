@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	models "hephaestus/internal/models"
+	"time"
+)
+
+const (
+	// leaseTTL is how long a lock outlives a replica that died holding it
+	leaseTTL = 2 * time.Minute
+	// leaseRenewal is how often the holder extends the lease while fn runs
+	leaseRenewal = leaseTTL / 4
+	// unlockTimeout bounds the release, fn may have outlived the caller's context
+	unlockTimeout = 5 * time.Second
+)
+
+// TryLock runs fn while holding the lease of name, acquired is false when another replica holds it
+// and fn didn't run. The lease is a row of locks taken and extended in short statements, so no
+// connection is kept from the pool while fn runs; a replica that dies leaves it to expire
+func (r *Repository) TryLock(ctx context.Context, name string, fn func() error) (acquired bool, err error) {
+	holder, err := leaseHolder()
+	if err != nil {
+		return false, err
+	}
+
+	lockCtx, cancel := r.withTimeout(ctx)
+	tag, err := r.DB.Exec(lockCtx, `
+		INSERT INTO locks (name, holder, expires_at)
+		VALUES ($1, $2, now() + make_interval(secs => $3))
+		ON CONFLICT (name) DO UPDATE
+		SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE locks.expires_at < now()
+	`, name, holder, leaseTTL.Seconds())
+	cancel()
+	if err != nil {
+		return false, fmt.Errorf("take lease %s: %w", name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		r.log.Debug("Lease held elsewhere: ", name)
+		return false, nil
+	}
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		r.renewLease(name, holder, done)
+	}()
+	defer func() {
+		close(done)
+		<-renewed
+		unlockCtx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		if _, err := r.DB.Exec(unlockCtx, `DELETE FROM locks WHERE name = $1 AND holder = $2`, name, holder); err != nil {
+			r.log.Error("Lease ", name, " not released, it expires in ", leaseTTL, ": ", err)
+		}
+	}()
+
+	return true, fn()
+}
+
+// renewLease extends the lease until done is closed
+func (r *Repository) renewLease(name, holder string, done <-chan struct{}) {
+	ticker := time.NewTicker(leaseRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		tag, err := r.DB.Exec(ctx, `UPDATE locks SET expires_at = now() + make_interval(secs => $3) WHERE name = $1 AND holder = $2`,
+			name, holder, leaseTTL.Seconds())
+		cancel()
+		switch {
+		case err != nil:
+			r.log.Error("Lease ", name, " not renewed: ", err)
+		case tag.RowsAffected() == 0:
+			r.log.Error("Lease ", name, " expired while held, another replica may take it over")
+		}
+	}
+}
+
+// leaseHolder returns a random id of one holding of a lease
+func leaseHolder() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("lease holder id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// maintenance is installation-wide, not tenant scoped
+
+func (r *Repository) GetMaintenance(ctx context.Context) (models.MaintenanceState, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var state models.MaintenanceState
+	var reason, setBy *string
+	err := r.DB.QueryRow(ctx, `
+		SELECT enabled, reason, retry_after, since, set_by
+		FROM maintenance
+		WHERE id
+	`).Scan(&state.Enabled, &reason, &state.RetryAfter, &state.Since, &setBy)
+	if err != nil {
+		return models.MaintenanceState{}, err
+	}
+	if reason != nil {
+		state.Reason = *reason
+	}
+	if setBy != nil {
+		state.By = *setBy
+	}
+	return state, nil
+}
+
+func (r *Repository) SetMaintenance(ctx context.Context, state models.MaintenanceState) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Saving maintenance state: ", state.Enabled)
+
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO maintenance (id, enabled, reason, retry_after, since, set_by)
+		VALUES (TRUE, $1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, reason = EXCLUDED.reason, retry_after = EXCLUDED.retry_after,
+		    since = EXCLUDED.since, set_by = EXCLUDED.set_by
+	`, state.Enabled, state.Reason, state.RetryAfter, state.Since, state.By)
+	return err
+}
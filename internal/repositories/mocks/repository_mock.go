@@ -0,0 +1,727 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository.go
+//
+// Generated by this command:
+//
+//	mockgen -source=repository.go -destination=mocks/repository_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	models "hephaestus/internal/models"
+	reflect "reflect"
+	time "time"
+
+	pgx "github.com/jackc/pgx/v5"
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRepositoryInterface is a mock of RepositoryInterface interface.
+type MockRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockRepositoryInterfaceMockRecorder is the mock recorder for MockRepositoryInterface.
+type MockRepositoryInterfaceMockRecorder struct {
+	mock *MockRepositoryInterface
+}
+
+// NewMockRepositoryInterface creates a new mock instance.
+func NewMockRepositoryInterface(ctrl *gomock.Controller) *MockRepositoryInterface {
+	mock := &MockRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// ActivateCertificateTx mocks base method.
+func (m *MockRepositoryInterface) ActivateCertificateTx(ctx context.Context, tx pgx.Tx, domainID, certID string, scope models.ActivationScope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActivateCertificateTx", ctx, tx, domainID, certID, scope)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ActivateCertificateTx indicates an expected call of ActivateCertificateTx.
+func (mr *MockRepositoryInterfaceMockRecorder) ActivateCertificateTx(ctx, tx, domainID, certID, scope any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActivateCertificateTx", reflect.TypeOf((*MockRepositoryInterface)(nil).ActivateCertificateTx), ctx, tx, domainID, certID, scope)
+}
+
+// BeginTx mocks base method.
+func (m *MockRepositoryInterface) BeginTx(ctx context.Context) (pgx.Tx, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTx", ctx)
+	ret0, _ := ret[0].(pgx.Tx)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginTx indicates an expected call of BeginTx.
+func (mr *MockRepositoryInterfaceMockRecorder) BeginTx(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTx", reflect.TypeOf((*MockRepositoryInterface)(nil).BeginTx), ctx)
+}
+
+// DeleteCertificateFiles mocks base method.
+func (m *MockRepositoryInterface) DeleteCertificateFiles(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCertificateFiles", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCertificateFiles indicates an expected call of DeleteCertificateFiles.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteCertificateFiles(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCertificateFiles", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteCertificateFiles), ctx, name)
+}
+
+// DeleteDNSProvider mocks base method.
+func (m *MockRepositoryInterface) DeleteDNSProvider(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDNSProvider", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDNSProvider indicates an expected call of DeleteDNSProvider.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteDNSProvider(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDNSProvider", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteDNSProvider), ctx, name)
+}
+
+// DeleteIssuanceAttempt mocks base method.
+func (m *MockRepositoryInterface) DeleteIssuanceAttempt(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIssuanceAttempt", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIssuanceAttempt indicates an expected call of DeleteIssuanceAttempt.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteIssuanceAttempt(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIssuanceAttempt", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteIssuanceAttempt), ctx, id)
+}
+
+// DeletePendingOrder mocks base method.
+func (m *MockRepositoryInterface) DeletePendingOrder(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePendingOrder", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePendingOrder indicates an expected call of DeletePendingOrder.
+func (mr *MockRepositoryInterfaceMockRecorder) DeletePendingOrder(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePendingOrder", reflect.TypeOf((*MockRepositoryInterface)(nil).DeletePendingOrder), ctx, id)
+}
+
+// FinishIssuanceAttempt mocks base method.
+func (m *MockRepositoryInterface) FinishIssuanceAttempt(ctx context.Context, id string, succeeded, failedValidation bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishIssuanceAttempt", ctx, id, succeeded, failedValidation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishIssuanceAttempt indicates an expected call of FinishIssuanceAttempt.
+func (mr *MockRepositoryInterfaceMockRecorder) FinishIssuanceAttempt(ctx, id, succeeded, failedValidation any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishIssuanceAttempt", reflect.TypeOf((*MockRepositoryInterface)(nil).FinishIssuanceAttempt), ctx, id, succeeded, failedValidation)
+}
+
+// GetAcmeDNSAccount mocks base method.
+func (m *MockRepositoryInterface) GetAcmeDNSAccount(ctx context.Context, serverURL, domainName string) (models.AcmeDNSAccountDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAcmeDNSAccount", ctx, serverURL, domainName)
+	ret0, _ := ret[0].(models.AcmeDNSAccountDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAcmeDNSAccount indicates an expected call of GetAcmeDNSAccount.
+func (mr *MockRepositoryInterfaceMockRecorder) GetAcmeDNSAccount(ctx, serverURL, domainName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAcmeDNSAccount", reflect.TypeOf((*MockRepositoryInterface)(nil).GetAcmeDNSAccount), ctx, serverURL, domainName)
+}
+
+// GetCertificateFile mocks base method.
+func (m *MockRepositoryInterface) GetCertificateFile(ctx context.Context, name string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCertificateFile", ctx, name)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCertificateFile indicates an expected call of GetCertificateFile.
+func (mr *MockRepositoryInterfaceMockRecorder) GetCertificateFile(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificateFile", reflect.TypeOf((*MockRepositoryInterface)(nil).GetCertificateFile), ctx, name)
+}
+
+// GetCertificatesByDomain mocks base method.
+func (m *MockRepositoryInterface) GetCertificatesByDomain(ctx context.Context, filters models.CertificatesFilters) ([]models.CertsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCertificatesByDomain", ctx, filters)
+	ret0, _ := ret[0].([]models.CertsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCertificatesByDomain indicates an expected call of GetCertificatesByDomain.
+func (mr *MockRepositoryInterfaceMockRecorder) GetCertificatesByDomain(ctx, filters any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificatesByDomain", reflect.TypeOf((*MockRepositoryInterface)(nil).GetCertificatesByDomain), ctx, filters)
+}
+
+// GetCoveringDomains mocks base method.
+func (m *MockRepositoryInterface) GetCoveringDomains(ctx context.Context, names []string) ([]models.CoveringDomainDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoveringDomains", ctx, names)
+	ret0, _ := ret[0].([]models.CoveringDomainDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCoveringDomains indicates an expected call of GetCoveringDomains.
+func (mr *MockRepositoryInterfaceMockRecorder) GetCoveringDomains(ctx, names any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoveringDomains", reflect.TypeOf((*MockRepositoryInterface)(nil).GetCoveringDomains), ctx, names)
+}
+
+// GetDNSProviders mocks base method.
+func (m *MockRepositoryInterface) GetDNSProviders(ctx context.Context) ([]models.DNSProviderDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDNSProviders", ctx)
+	ret0, _ := ret[0].([]models.DNSProviderDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDNSProviders indicates an expected call of GetDNSProviders.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDNSProviders(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDNSProviders", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDNSProviders), ctx)
+}
+
+// GetDomainsCount mocks base method.
+func (m *MockRepositoryInterface) GetDomainsCount(ctx context.Context, filters models.DomainsFilters) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDomainsCount", ctx, filters)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDomainsCount indicates an expected call of GetDomainsCount.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDomainsCount(ctx, filters any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDomainsCount", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDomainsCount), ctx, filters)
+}
+
+// GetDomainsList mocks base method.
+func (m *MockRepositoryInterface) GetDomainsList(ctx context.Context, filters models.DomainsFilters) ([]models.DomainsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDomainsList", ctx, filters)
+	ret0, _ := ret[0].([]models.DomainsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDomainsList indicates an expected call of GetDomainsList.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDomainsList(ctx, filters any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDomainsList", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDomainsList), ctx, filters)
+}
+
+// GetIDByNameTx mocks base method.
+func (m *MockRepositoryInterface) GetIDByNameTx(ctx context.Context, tx pgx.Tx, entity models.Entity) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIDByNameTx", ctx, tx, entity)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIDByNameTx indicates an expected call of GetIDByNameTx.
+func (mr *MockRepositoryInterfaceMockRecorder) GetIDByNameTx(ctx, tx, entity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIDByNameTx", reflect.TypeOf((*MockRepositoryInterface)(nil).GetIDByNameTx), ctx, tx, entity)
+}
+
+// GetIssuanceAttempts mocks base method.
+func (m *MockRepositoryInterface) GetIssuanceAttempts(ctx context.Context, caURL string, since time.Time) ([]models.IssuanceAttemptDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIssuanceAttempts", ctx, caURL, since)
+	ret0, _ := ret[0].([]models.IssuanceAttemptDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIssuanceAttempts indicates an expected call of GetIssuanceAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) GetIssuanceAttempts(ctx, caURL, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIssuanceAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).GetIssuanceAttempts), ctx, caURL, since)
+}
+
+// GetIssuanceAttemptsTx mocks base method.
+func (m *MockRepositoryInterface) GetIssuanceAttemptsTx(ctx context.Context, tx pgx.Tx, caURL string, since time.Time) ([]models.IssuanceAttemptDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIssuanceAttemptsTx", ctx, tx, caURL, since)
+	ret0, _ := ret[0].([]models.IssuanceAttemptDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIssuanceAttemptsTx indicates an expected call of GetIssuanceAttemptsTx.
+func (mr *MockRepositoryInterfaceMockRecorder) GetIssuanceAttemptsTx(ctx, tx, caURL, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIssuanceAttemptsTx", reflect.TypeOf((*MockRepositoryInterface)(nil).GetIssuanceAttemptsTx), ctx, tx, caURL, since)
+}
+
+// GetListOfSubDomains mocks base method.
+func (m *MockRepositoryInterface) GetListOfSubDomains(ctx context.Context, domainID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListOfSubDomains", ctx, domainID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListOfSubDomains indicates an expected call of GetListOfSubDomains.
+func (mr *MockRepositoryInterfaceMockRecorder) GetListOfSubDomains(ctx, domainID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListOfSubDomains", reflect.TypeOf((*MockRepositoryInterface)(nil).GetListOfSubDomains), ctx, domainID)
+}
+
+// GetMaintenance mocks base method.
+func (m *MockRepositoryInterface) GetMaintenance(ctx context.Context) (models.MaintenanceState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMaintenance", ctx)
+	ret0, _ := ret[0].(models.MaintenanceState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMaintenance indicates an expected call of GetMaintenance.
+func (mr *MockRepositoryInterfaceMockRecorder) GetMaintenance(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaintenance", reflect.TypeOf((*MockRepositoryInterface)(nil).GetMaintenance), ctx)
+}
+
+// GetPendingOrders mocks base method.
+func (m *MockRepositoryInterface) GetPendingOrders(ctx context.Context) ([]models.PendingOrderDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPendingOrders", ctx)
+	ret0, _ := ret[0].([]models.PendingOrderDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPendingOrders indicates an expected call of GetPendingOrders.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPendingOrders(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPendingOrders", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPendingOrders), ctx)
+}
+
+// GetPurgeableDomains mocks base method.
+func (m *MockRepositoryInterface) GetPurgeableDomains(ctx context.Context, deletedBefore time.Time) ([]models.PurgeCandidateDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPurgeableDomains", ctx, deletedBefore)
+	ret0, _ := ret[0].([]models.PurgeCandidateDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPurgeableDomains indicates an expected call of GetPurgeableDomains.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPurgeableDomains(ctx, deletedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPurgeableDomains", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPurgeableDomains), ctx, deletedBefore)
+}
+
+// InsertAcmeDNSAccount mocks base method.
+func (m *MockRepositoryInterface) InsertAcmeDNSAccount(ctx context.Context, account models.AcmeDNSAccountDTO) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAcmeDNSAccount", ctx, account)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertAcmeDNSAccount indicates an expected call of InsertAcmeDNSAccount.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertAcmeDNSAccount(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAcmeDNSAccount", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertAcmeDNSAccount), ctx, account)
+}
+
+// InsertIssuanceAttemptTx mocks base method.
+func (m *MockRepositoryInterface) InsertIssuanceAttemptTx(ctx context.Context, tx pgx.Tx, attempt models.IssuanceAttemptDTO, keepSince time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertIssuanceAttemptTx", ctx, tx, attempt, keepSince)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertIssuanceAttemptTx indicates an expected call of InsertIssuanceAttemptTx.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertIssuanceAttemptTx(ctx, tx, attempt, keepSince any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertIssuanceAttemptTx", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertIssuanceAttemptTx), ctx, tx, attempt, keepSince)
+}
+
+// InsertManyTx mocks base method.
+func (m *MockRepositoryInterface) InsertManyTx(ctx context.Context, tx pgx.Tx, entities []models.Entity) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertManyTx", ctx, tx, entities)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertManyTx indicates an expected call of InsertManyTx.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertManyTx(ctx, tx, entities any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertManyTx", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertManyTx), ctx, tx, entities)
+}
+
+// InsertPendingOrder mocks base method.
+func (m *MockRepositoryInterface) InsertPendingOrder(ctx context.Context, order models.PendingOrderDTO) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPendingOrder", ctx, order)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertPendingOrder indicates an expected call of InsertPendingOrder.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertPendingOrder(ctx, order any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPendingOrder", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertPendingOrder), ctx, order)
+}
+
+// InsertTx mocks base method.
+func (m *MockRepositoryInterface) InsertTx(ctx context.Context, tx pgx.Tx, entity models.Entity) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertTx", ctx, tx, entity)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertTx indicates an expected call of InsertTx.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertTx(ctx, tx, entity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertTx", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertTx), ctx, tx, entity)
+}
+
+// IsDomainDeleted mocks base method.
+func (m *MockRepositoryInterface) IsDomainDeleted(ctx context.Context, domain string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsDomainDeleted", ctx, domain)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsDomainDeleted indicates an expected call of IsDomainDeleted.
+func (mr *MockRepositoryInterfaceMockRecorder) IsDomainDeleted(ctx, domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDomainDeleted", reflect.TypeOf((*MockRepositoryInterface)(nil).IsDomainDeleted), ctx, domain)
+}
+
+// IsDomainExists mocks base method.
+func (m *MockRepositoryInterface) IsDomainExists(ctx context.Context, domain string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsDomainExists", ctx, domain)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsDomainExists indicates an expected call of IsDomainExists.
+func (mr *MockRepositoryInterfaceMockRecorder) IsDomainExists(ctx, domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDomainExists", reflect.TypeOf((*MockRepositoryInterface)(nil).IsDomainExists), ctx, domain)
+}
+
+// PurgeDomainTx mocks base method.
+func (m *MockRepositoryInterface) PurgeDomainTx(ctx context.Context, tx pgx.Tx, domainID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDomainTx", ctx, tx, domainID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeDomainTx indicates an expected call of PurgeDomainTx.
+func (mr *MockRepositoryInterfaceMockRecorder) PurgeDomainTx(ctx, tx, domainID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDomainTx", reflect.TypeOf((*MockRepositoryInterface)(nil).PurgeDomainTx), ctx, tx, domainID)
+}
+
+// QueryStats mocks base method.
+func (m *MockRepositoryInterface) QueryStats() []models.QueryStat {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryStats")
+	ret0, _ := ret[0].([]models.QueryStat)
+	return ret0
+}
+
+// QueryStats indicates an expected call of QueryStats.
+func (mr *MockRepositoryInterfaceMockRecorder) QueryStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryStats", reflect.TypeOf((*MockRepositoryInterface)(nil).QueryStats))
+}
+
+// SaveCertificateFile mocks base method.
+func (m *MockRepositoryInterface) SaveCertificateFile(ctx context.Context, name string, content []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCertificateFile", ctx, name, content)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveCertificateFile indicates an expected call of SaveCertificateFile.
+func (mr *MockRepositoryInterfaceMockRecorder) SaveCertificateFile(ctx, name, content any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCertificateFile", reflect.TypeOf((*MockRepositoryInterface)(nil).SaveCertificateFile), ctx, name, content)
+}
+
+// SetMaintenance mocks base method.
+func (m *MockRepositoryInterface) SetMaintenance(ctx context.Context, state models.MaintenanceState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMaintenance", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMaintenance indicates an expected call of SetMaintenance.
+func (mr *MockRepositoryInterfaceMockRecorder) SetMaintenance(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaintenance", reflect.TypeOf((*MockRepositoryInterface)(nil).SetMaintenance), ctx, state)
+}
+
+// SoftDeleteDomainTx mocks base method.
+func (m *MockRepositoryInterface) SoftDeleteDomainTx(ctx context.Context, tx pgx.Tx, domainID, deletedBy string, deletedAt time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteDomainTx", ctx, tx, domainID, deletedBy, deletedAt)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SoftDeleteDomainTx indicates an expected call of SoftDeleteDomainTx.
+func (mr *MockRepositoryInterfaceMockRecorder) SoftDeleteDomainTx(ctx, tx, domainID, deletedBy, deletedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteDomainTx", reflect.TypeOf((*MockRepositoryInterface)(nil).SoftDeleteDomainTx), ctx, tx, domainID, deletedBy, deletedAt)
+}
+
+// TryLock mocks base method.
+func (m *MockRepositoryInterface) TryLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryLock", ctx, name, fn)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryLock indicates an expected call of TryLock.
+func (mr *MockRepositoryInterfaceMockRecorder) TryLock(ctx, name, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryLock", reflect.TypeOf((*MockRepositoryInterface)(nil).TryLock), ctx, name, fn)
+}
+
+// UpdatePendingOrder mocks base method.
+func (m *MockRepositoryInterface) UpdatePendingOrder(ctx context.Context, order models.PendingOrderDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePendingOrder", ctx, order)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePendingOrder indicates an expected call of UpdatePendingOrder.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePendingOrder(ctx, order any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePendingOrder", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePendingOrder), ctx, order)
+}
+
+// UpdateTx mocks base method.
+func (m *MockRepositoryInterface) UpdateTx(ctx context.Context, tx pgx.Tx, entity models.Entity, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTx", ctx, tx, entity, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTx indicates an expected call of UpdateTx.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateTx(ctx, tx, entity, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTx", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateTx), ctx, tx, entity, id)
+}
+
+// UpsertDNSProvider mocks base method.
+func (m *MockRepositoryInterface) UpsertDNSProvider(ctx context.Context, provider models.DNSProviderDTO) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDNSProvider", ctx, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertDNSProvider indicates an expected call of UpsertDNSProvider.
+func (mr *MockRepositoryInterfaceMockRecorder) UpsertDNSProvider(ctx, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDNSProvider", reflect.TypeOf((*MockRepositoryInterface)(nil).UpsertDNSProvider), ctx, provider)
+}
+
+// UpsertTx mocks base method.
+func (m *MockRepositoryInterface) UpsertTx(ctx context.Context, tx pgx.Tx, entity models.Entity, opts models.UpsertOptions) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTx", ctx, tx, entity, opts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTx indicates an expected call of UpsertTx.
+func (mr *MockRepositoryInterfaceMockRecorder) UpsertTx(ctx, tx, entity, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTx", reflect.TypeOf((*MockRepositoryInterface)(nil).UpsertTx), ctx, tx, entity, opts)
+}
+
+// WithTx mocks base method.
+func (m *MockRepositoryInterface) WithTx(ctx context.Context, fn func(pgx.Tx) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryInterfaceMockRecorder) WithTx(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepositoryInterface)(nil).WithTx), ctx, fn)
+}
+
+// MockPool is a mock of Pool interface.
+type MockPool struct {
+	ctrl     *gomock.Controller
+	recorder *MockPoolMockRecorder
+	isgomock struct{}
+}
+
+// MockPoolMockRecorder is the mock recorder for MockPool.
+type MockPoolMockRecorder struct {
+	mock *MockPool
+}
+
+// NewMockPool creates a new mock instance.
+func NewMockPool(ctrl *gomock.Controller) *MockPool {
+	mock := &MockPool{ctrl: ctrl}
+	mock.recorder = &MockPoolMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPool) EXPECT() *MockPoolMockRecorder {
+	return m.recorder
+}
+
+// Begin mocks base method.
+func (m *MockPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", ctx)
+	ret0, _ := ret[0].(pgx.Tx)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockPoolMockRecorder) Begin(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockPool)(nil).Begin), ctx)
+}
+
+// Close mocks base method.
+func (m *MockPool) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockPoolMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockPool)(nil).Close))
+}
+
+// Exec mocks base method.
+func (m *MockPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, sql}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Exec", varargs...)
+	ret0, _ := ret[0].(pgconn.CommandTag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exec indicates an expected call of Exec.
+func (mr *MockPoolMockRecorder) Exec(ctx, sql any, args ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, sql}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockPool)(nil).Exec), varargs...)
+}
+
+// Query mocks base method.
+func (m *MockPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, sql}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Query", varargs...)
+	ret0, _ := ret[0].(pgx.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockPoolMockRecorder) Query(ctx, sql any, args ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, sql}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockPool)(nil).Query), varargs...)
+}
+
+// QueryRow mocks base method.
+func (m *MockPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, sql}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRow", varargs...)
+	ret0, _ := ret[0].(pgx.Row)
+	return ret0
+}
+
+// QueryRow indicates an expected call of QueryRow.
+func (mr *MockPoolMockRecorder) QueryRow(ctx, sql any, args ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, sql}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRow", reflect.TypeOf((*MockPool)(nil).QueryRow), varargs...)
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	models "hephaestus/internal/models"
+)
+
+// pending orders belong to the ACME account, which is shared by all tenants, so the queries are
+// not tenant scoped. They are written during every order, reads go to the primary
+
+func (r *Repository) GetPendingOrders(ctx context.Context) ([]models.PendingOrderDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Fetching pending orders")
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, domain_name, names, dns_provider, ca_url, key_type, key_path,
+			order_url, certificate_url, challenges, created_at
+		FROM pending_orders
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.PendingOrderDTO
+	for rows.Next() {
+		var o models.PendingOrderDTO
+		if err := rows.Scan(&o.ID, &o.DomainName, &o.Names, &o.DNSProvider, &o.CAURL, &o.KeyType, &o.KeyPath,
+			&o.OrderURL, &o.CertificateURL, &o.Challenges, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (r *Repository) InsertPendingOrder(ctx context.Context, order models.PendingOrderDTO) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Saving pending order: ", order.Names)
+
+	if order.Challenges == nil {
+		order.Challenges = []models.PendingChallenge{}
+	}
+	var id string
+	err := r.DB.QueryRow(ctx, `
+		INSERT INTO pending_orders (domain_name, names, dns_provider, ca_url, key_type, key_path, challenges)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, order.DomainName, order.Names, order.DNSProvider, order.CAURL, order.KeyType, order.KeyPath, order.Challenges).Scan(&id)
+	return id, err
+}
+
+// UpdatePendingOrder saves the progress of the order, its URLs and the challenges presented
+func (r *Repository) UpdatePendingOrder(ctx context.Context, order models.PendingOrderDTO) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if order.Challenges == nil {
+		order.Challenges = []models.PendingChallenge{}
+	}
+	_, err := r.DB.Exec(ctx, `
+		UPDATE pending_orders
+		SET order_url = $2, certificate_url = $3, challenges = $4, updated_at = NOW()
+		WHERE id = $1
+	`, order.ID, order.OrderURL, order.CertificateURL, order.Challenges)
+	return err
+}
+
+func (r *Repository) DeletePendingOrder(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.DB.Exec(ctx, `DELETE FROM pending_orders WHERE id = $1`, id)
+	return err
+}
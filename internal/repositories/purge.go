@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	models "hephaestus/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repository) GetPurgeableDomains(ctx context.Context, deletedBefore time.Time) ([]models.PurgeCandidateDTO, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Fetching domains deleted before: ", deletedBefore)
+
+	query, args, err := appendTenantScope(ctx, `
+		SELECT id, domain_name, deleted_at, tenant_id
+		FROM domains
+		WHERE deleted_at IS NOT NULL
+		AND deleted_at < $1
+	`, []interface{}{deletedBefore}, "domains")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.Query(ctx, query+" ORDER BY deleted_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []models.PurgeCandidateDTO
+	for rows.Next() {
+		var d models.PurgeCandidateDTO
+		if err := rows.Scan(&d.ID, &d.DomainName, &d.DeletedAt, &d.TenantID); err != nil {
+			return nil, err
+		}
+		domains = append(domains, d)
+	}
+
+	return domains, rows.Err()
+}
+
+// PurgeDomainTx removes a soft-deleted domain, alternative domains and certificates go with it
+// through ON DELETE CASCADE, events keep the audit trail with domain_id set to NULL
+func (r *Repository) PurgeDomainTx(ctx context.Context, tx pgx.Tx, domainID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Purging domain: ", domainID)
+
+	query, args, err := appendTenantScope(ctx,
+		`DELETE FROM domains WHERE id = $1 AND deleted_at IS NOT NULL`, []interface{}{domainID}, "domains")
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	r.log.Debug("Domain purged.")
+	return nil
+}
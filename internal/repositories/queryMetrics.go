@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const repositoryPackage = "hephaestus/internal/repositories."
+
+// queryTracer collects per-statement durations and logs slow queries, statements are named
+// after the repository method that issued them
+type queryTracer struct {
+	log           *utils.Logger
+	slowThreshold time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*models.QueryStat
+}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	name  string
+	start time.Time
+}
+
+func newQueryTracer(log *utils.Logger, slowThreshold time.Duration) *queryTracer {
+	return &queryTracer{
+		log:           log,
+		slowThreshold: slowThreshold,
+		stats:         map[string]*models.QueryStat{},
+	}
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, queryTrace{
+		name:  statementName(data.SQL),
+		start: time.Now(),
+	})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+	duration := time.Since(trace.start)
+	rows := data.CommandTag.RowsAffected()
+	slow := t.slowThreshold > 0 && duration >= t.slowThreshold
+
+	t.mu.Lock()
+	stat, ok := t.stats[trace.name]
+	if !ok {
+		stat = &models.QueryStat{Name: trace.name}
+		t.stats[trace.name] = stat
+	}
+	stat.Count++
+	stat.TotalDuration += duration
+	if duration > stat.MaxDuration {
+		stat.MaxDuration = duration
+	}
+	if data.Err != nil {
+		stat.Errors++
+	}
+	if slow {
+		stat.SlowCount++
+	}
+	t.mu.Unlock()
+
+	if slow {
+		t.log.Warn("Slow query: ", trace.name, " duration=", duration, " rows=", rows, " err=", data.Err)
+	}
+}
+
+func (t *queryTracer) snapshot() []models.QueryStat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]models.QueryStat, 0, len(t.stats))
+	for _, s := range t.stats {
+		stat := *s
+		if stat.Count > 0 {
+			stat.AvgDuration = stat.TotalDuration / time.Duration(stat.Count)
+		}
+		out = append(out, stat)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TotalDuration > out[j].TotalDuration })
+	return out
+}
+
+// statementName finds the repository method on the call stack, falls back to the leading SQL keyword
+func statementName(sql string) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if name, ok := strings.CutPrefix(frame.Function, repositoryPackage); ok &&
+			!strings.HasPrefix(name, "(*queryTracer)") {
+			// "(*Repository).GetDomainsList.func1" -> "GetDomainsList"
+			parts := strings.Split(name, ".")
+			if len(parts) > 1 && strings.HasPrefix(parts[0], "(") {
+				return parts[1]
+			}
+			return parts[0]
+		}
+		if !more {
+			break
+		}
+	}
+
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func (r *Repository) QueryStats() []models.QueryStat {
+	if r.tracer == nil {
+		return nil
+	}
+	return r.tracer.snapshot()
+}
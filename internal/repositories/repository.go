@@ -7,32 +7,145 @@ import (
 	"fmt"
 	models "hephaestus/internal/models"
 	utils "hephaestus/internal/utils"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+//go:generate go run go.uber.org/mock/mockgen@v0.6.0 -source=repository.go -destination=mocks/repository_mock.go -package=mocks
+//go:generate go run go.uber.org/mock/mockgen@v0.6.0 -destination=mocks/tx_mock.go -package=mocks github.com/jackc/pgx/v5 Tx
+
+// RepositoryInterface is the storage used by services.Service
+type RepositoryInterface interface {
+	BeginTx(ctx context.Context) (pgx.Tx, error)
+	WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error
+	InsertTx(ctx context.Context, tx pgx.Tx, entity models.Entity) (string, error)
+	InsertManyTx(ctx context.Context, tx pgx.Tx, entities []models.Entity) ([]string, error)
+	UpsertTx(ctx context.Context, tx pgx.Tx, entity models.Entity, opts models.UpsertOptions) (string, error)
+	UpdateTx(ctx context.Context, tx pgx.Tx, entity models.Entity, id string) error
+	GetIDByNameTx(ctx context.Context, tx pgx.Tx, entity models.Entity) (string, error)
+
+	IsDomainExists(ctx context.Context, domain string) (bool, error)
+	IsDomainDeleted(ctx context.Context, domain string) (bool, error)
+	GetCoveringDomains(ctx context.Context, names []string) ([]models.CoveringDomainDTO, error)
+	SoftDeleteDomainTx(ctx context.Context, tx pgx.Tx, domainID, deletedBy string, deletedAt time.Time) (string, error)
+	GetDomainsCount(ctx context.Context, filters models.DomainsFilters) (int, error)
+	GetDomainsList(ctx context.Context, filters models.DomainsFilters) ([]models.DomainsDTO, error)
+	GetListOfSubDomains(ctx context.Context, domainID string) ([]string, error)
+	GetCertificatesByDomain(ctx context.Context, filters models.CertificatesFilters) ([]models.CertsDTO, error)
+	ActivateCertificateTx(ctx context.Context, tx pgx.Tx, domainID, certID string, scope models.ActivationScope) error
+
+	GetPurgeableDomains(ctx context.Context, deletedBefore time.Time) ([]models.PurgeCandidateDTO, error)
+	PurgeDomainTx(ctx context.Context, tx pgx.Tx, domainID string) error
+
+	GetDNSProviders(ctx context.Context) ([]models.DNSProviderDTO, error)
+	UpsertDNSProvider(ctx context.Context, provider models.DNSProviderDTO) (string, error)
+	DeleteDNSProvider(ctx context.Context, name string) error
+	GetAcmeDNSAccount(ctx context.Context, serverURL, domainName string) (models.AcmeDNSAccountDTO, error)
+	InsertAcmeDNSAccount(ctx context.Context, account models.AcmeDNSAccountDTO) (inserted bool, err error)
+	GetIssuanceAttempts(ctx context.Context, caURL string, since time.Time) ([]models.IssuanceAttemptDTO, error)
+	GetIssuanceAttemptsTx(ctx context.Context, tx pgx.Tx, caURL string, since time.Time) ([]models.IssuanceAttemptDTO, error)
+	InsertIssuanceAttemptTx(ctx context.Context, tx pgx.Tx, attempt models.IssuanceAttemptDTO, keepSince time.Time) (string, error)
+	FinishIssuanceAttempt(ctx context.Context, id string, succeeded, failedValidation bool) error
+	DeleteIssuanceAttempt(ctx context.Context, id string) error
+	GetPendingOrders(ctx context.Context) ([]models.PendingOrderDTO, error)
+	InsertPendingOrder(ctx context.Context, order models.PendingOrderDTO) (string, error)
+	UpdatePendingOrder(ctx context.Context, order models.PendingOrderDTO) error
+	DeletePendingOrder(ctx context.Context, id string) error
+	GetCertificateFile(ctx context.Context, name string) ([]byte, error)
+	SaveCertificateFile(ctx context.Context, name string, content []byte) error
+	DeleteCertificateFiles(ctx context.Context, name string) error
+
+	TryLock(ctx context.Context, name string, fn func() error) (acquired bool, err error)
+	GetMaintenance(ctx context.Context) (models.MaintenanceState, error)
+	SetMaintenance(ctx context.Context, state models.MaintenanceState) error
+
+	QueryStats() []models.QueryStat
+}
+
+var _ RepositoryInterface = (*Repository)(nil)
+
+// Pool is the part of *pgxpool.Pool the queries run on
+type Pool interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Close()
+}
+
 type Repository struct {
-	DB  *pgxpool.Pool
-	log *utils.Logger
+	DB     Pool
+	ReadDB Pool // read replica, nil when not configured
+	log    *utils.Logger
+	tracer *queryTracer
+
+	queryTimeout time.Duration
 }
 
 func NewRepository(cfg *utils.Config, log *utils.Logger) (*Repository, error) {
-	tempRepo := &Repository{log: log}
+	queryTimeout := defaultQueryTimeout
+	if cfg.Database.QueryTimeout > 0 {
+		queryTimeout = cfg.Database.QueryTimeout
+	}
+
+	tempRepo := &Repository{
+		log:          log,
+		tracer:       newQueryTracer(log, cfg.Database.SlowQueryThreshold),
+		queryTimeout: queryTimeout,
+	}
 	conn, err := tempRepo.CreateConnection(cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &Repository{
-		DB:  conn,
-		log: log,
-	}, nil
+
+	repo := &Repository{
+		DB:     conn,
+		log:    log,
+		tracer: tempRepo.tracer,
+
+		queryTimeout: queryTimeout,
+	}
+	if cfg.Database.ReadDSN != "" {
+		readConn, err := tempRepo.CreateReadConnection(cfg)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("read replica connection: %w", err)
+		}
+		repo.ReadDB = readConn
+	}
+	return repo, nil
+}
+
+// reader returns the pool for queries that tolerate replication lag, falls back to the primary
+func (r *Repository) reader() Pool {
+	if r.ReadDB != nil {
+		return r.ReadDB
+	}
+	return r.DB
+}
+
+// withTimeout bounds a single repository call, so a hung database can't block handlers and schedulers,
+// an earlier deadline of the caller is kept
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
 }
 
 func (r *Repository) InsertTx(ctx context.Context, tx pgx.Tx, entity models.Entity) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	r.log.Debug("Inserting started...")
+	entity, err := scopeEntity(ctx, entity)
+	if err != nil {
+		return "", err
+	}
 	columns, values, placeholders := buildInsertQuery(entity)
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id", entity.EntityName, columns, placeholders)
@@ -54,8 +167,155 @@ func (r *Repository) InsertTx(ctx context.Context, tx pgx.Tx, entity models.Enti
 	return id, nil
 }
 
+// InsertManyTx inserts entities of one table with multi-row INSERT statements, every entity must have
+// the same set of columns. Ids are returned in the order of the entities.
+func (r *Repository) InsertManyTx(ctx context.Context, tx pgx.Tx, entities []models.Entity) ([]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	r.log.Debug("Batch inserting started, rows: ", len(entities))
+
+	scoped := make([]models.Entity, 0, len(entities))
+	for _, e := range entities {
+		e, err := scopeEntity(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		scoped = append(scoped, e)
+	}
+	entities = scoped
+
+	table := entities[0].EntityName
+	columns := entityColumns(entities[0])
+	if len(columns) == 0 {
+		return nil, errors.New("no fields to insert")
+	}
+
+	// postgres accepts at most 65535 bind parameters per statement
+	batchSize := maxQueryParams / len(columns)
+
+	ids := make([]string, 0, len(entities))
+	for start := 0; start < len(entities); start += batchSize {
+		end := min(start+batchSize, len(entities))
+
+		query, values, err := buildInsertManyQuery(table, columns, entities[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		r.log.Debug("Query execution: ", query)
+		var rows pgx.Rows
+		if tx != nil {
+			rows, err = tx.Query(ctx, query, values...)
+		} else {
+			rows, err = r.DB.Query(ctx, query, values...)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	r.log.Debug("Query executed.")
+
+	return ids, nil
+}
+
+// UpsertTx inserts the entity or updates the row conflicting on opts.ConflictColumns with the entity values.
+// When opts.OnlyDeleted is set and the conflicting row is not soft-deleted, pgx.ErrNoRows is returned.
+func (r *Repository) UpsertTx(ctx context.Context, tx pgx.Tx, entity models.Entity, opts models.UpsertOptions) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	r.log.Debug("Upserting started...")
+	if len(opts.ConflictColumns) == 0 {
+		return "", errors.New("no conflict columns for upsert")
+	}
+
+	_, tenantScoped, err := tenantScope(ctx)
+	if err != nil {
+		return "", err
+	}
+	entity, err = scopeEntity(ctx, entity)
+	if err != nil {
+		return "", err
+	}
+
+	columns, values, placeholders := buildInsertQuery(entity)
+
+	conflict := make(map[string]struct{}, len(opts.ConflictColumns))
+	for _, c := range opts.ConflictColumns {
+		conflict[c] = struct{}{}
+	}
+	var setParts []string
+	for _, col := range strings.Split(columns, ", ") {
+		if _, ok := conflict[col]; ok {
+			continue
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+	}
+	for _, col := range entity.NullParameters {
+		setParts = append(setParts, fmt.Sprintf("%s = NULL", col))
+	}
+	if len(setParts) == 0 {
+		return "", errors.New("no fields to update on conflict")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		entity.EntityName, columns, placeholders, strings.Join(opts.ConflictColumns, ", "), strings.Join(setParts, ", "))
+	var conditions []string
+	if opts.OnlyDeleted {
+		conditions = append(conditions, fmt.Sprintf("%s.deleted_at IS NOT NULL", entity.EntityName))
+	}
+	// a conflicting row of another tenant is never taken over
+	if tenantScoped {
+		conditions = append(conditions, fmt.Sprintf("%s.tenant_id = EXCLUDED.tenant_id", entity.EntityName))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " RETURNING id"
+
+	r.log.Debug("Query execution: ", query)
+	r.log.Debug("Values: ", values)
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, values...)
+	} else {
+		row = r.DB.QueryRow(ctx, query, values...)
+	}
+	r.log.Debug("Query executed.")
+
+	var id string
+	if err := row.Scan(&id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 func (r *Repository) UpdateTx(ctx context.Context, tx pgx.Tx, entity models.Entity, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	r.log.Debug("Updating started...")
+	entity, err := unscopeUpdate(ctx, entity)
+	if err != nil {
+		return err
+	}
 	setClause, values := buildUpdateQuery(entity)
 	if setClause == "" {
 		return errors.New("no fields to update")
@@ -63,10 +323,14 @@ func (r *Repository) UpdateTx(ctx context.Context, tx pgx.Tx, entity models.Enti
 
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", entity.EntityName, setClause, len(values)+1)
 	values = append(values, id)
+	query, values, err = appendTenantScope(ctx, query, values, entity.EntityName)
+	if err != nil {
+		return err
+	}
 
 	r.log.Debug("Query execution: ", query)
 	r.log.Debug("Values: ", values)
-	_, err := tx.Exec(ctx, query, values...)
+	_, err = tx.Exec(ctx, query, values...)
 	if err != nil {
 		return err
 	}
@@ -74,7 +338,11 @@ func (r *Repository) UpdateTx(ctx context.Context, tx pgx.Tx, entity models.Enti
 	return nil
 }
 
+// GetIDByNameTx returns the id of the row with the value, soft-deleted rows aren't found
 func (r *Repository) GetIDByNameTx(ctx context.Context, tx pgx.Tx, entity models.Entity) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	r.log.Debug("EntityName: '", entity.EntityName, "'")
 	var column, value string
 	for k, v := range entity.StringParameters {
@@ -82,15 +350,19 @@ func (r *Repository) GetIDByNameTx(ctx context.Context, tx pgx.Tx, entity models
 		value = v
 		break
 	}
-	query := fmt.Sprintf("SELECT id FROM %s WHERE %s = $1", entity.EntityName, column)
+	query, args, err := appendTenantScope(ctx,
+		fmt.Sprintf("SELECT id FROM %s WHERE %s = $1 AND deleted_at IS NULL", entity.EntityName, column), []interface{}{value}, entity.EntityName)
+	if err != nil {
+		return "", err
+	}
 	var id string
 	r.log.Debug("Query execution: ", query)
-	err := tx.QueryRow(ctx, query, value).Scan(&id)
+	err = tx.QueryRow(ctx, query, args...).Scan(&id)
 	if err != nil {
 		r.log.Debug("Error returned from query: ", err)
 		if errors.Is(err, pgx.ErrNoRows) {
 			r.log.Debug("No rows found, returning empty string + err 'no rows selected'")
-			return "", errors.New("no rows selected")
+			return "", fmt.Errorf("no rows selected: %w", err)
 		}
 		return "", err
 	}
@@ -122,10 +394,115 @@ func buildInsertQuery(entity models.Entity) (columns string, values []interface{
 		vals = append(vals, val)
 		i++
 	}
+	for key, val := range entity.BoolParameters {
+		cols = append(cols, key)
+		ph = append(ph, fmt.Sprintf("$%d", i))
+		vals = append(vals, val)
+		i++
+	}
+	for key, val := range entity.ArrayParameters {
+		if val == nil {
+			val = []string{}
+		}
+		cols = append(cols, key)
+		ph = append(ph, fmt.Sprintf("$%d", i))
+		vals = append(vals, val)
+		i++
+	}
+	for key, val := range entity.JSONParameters {
+		if val == nil {
+			val = map[string]any{}
+		}
+		cols = append(cols, key)
+		ph = append(ph, fmt.Sprintf("$%d", i))
+		vals = append(vals, val)
+		i++
+	}
 
 	return strings.Join(cols, ", "), vals, strings.Join(ph, ", ")
 }
 
+const maxQueryParams = 65535
+
+func entityColumns(entity models.Entity) []string {
+	var cols []string
+	for key := range entity.StringParameters {
+		cols = append(cols, key)
+	}
+	for key := range entity.IntegerParameters {
+		cols = append(cols, key)
+	}
+	for key := range entity.TimeParameters {
+		cols = append(cols, key)
+	}
+	for key := range entity.BoolParameters {
+		cols = append(cols, key)
+	}
+	for key := range entity.ArrayParameters {
+		cols = append(cols, key)
+	}
+	for key := range entity.JSONParameters {
+		cols = append(cols, key)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func entityValue(entity models.Entity, column string) (interface{}, bool) {
+	if v, ok := entity.StringParameters[column]; ok {
+		return v, true
+	}
+	if v, ok := entity.IntegerParameters[column]; ok {
+		return v, true
+	}
+	if v, ok := entity.TimeParameters[column]; ok {
+		return v, true
+	}
+	if v, ok := entity.BoolParameters[column]; ok {
+		return v, true
+	}
+	if v, ok := entity.ArrayParameters[column]; ok {
+		if v == nil {
+			v = []string{}
+		}
+		return v, true
+	}
+	if v, ok := entity.JSONParameters[column]; ok {
+		if v == nil {
+			v = map[string]any{}
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+func buildInsertManyQuery(table string, columns []string, entities []models.Entity) (string, []interface{}, error) {
+	values := make([]interface{}, 0, len(columns)*len(entities))
+	rows := make([]string, 0, len(entities))
+
+	i := 1
+	for _, e := range entities {
+		if e.EntityName != table {
+			return "", nil, fmt.Errorf("batch insert mixes tables %s and %s", table, e.EntityName)
+		}
+		ph := make([]string, 0, len(columns))
+		for _, col := range columns {
+			v, ok := entityValue(e, col)
+			if !ok {
+				return "", nil, fmt.Errorf("batch insert into %s: column %s missing in a row", table, col)
+			}
+			ph = append(ph, fmt.Sprintf("$%d", i))
+			values = append(values, v)
+			i++
+		}
+		rows = append(rows, "("+strings.Join(ph, ", ")+")")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s RETURNING id",
+		table, strings.Join(columns, ", "), strings.Join(rows, ", "))
+	return query, values, nil
+}
+
 func buildUpdateQuery(entity models.Entity) (setClause string, values []interface{}) {
 	var setParts []string
 	i := 1
@@ -151,6 +528,32 @@ func buildUpdateQuery(entity models.Entity) (setClause string, values []interfac
 		values = append(values, val)
 		i++
 	}
+	for key, val := range entity.BoolParameters {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", key, i))
+		values = append(values, val)
+		i++
+	}
+	for key, val := range entity.ArrayParameters {
+		// nil leaves the column untouched, an empty slice clears it
+		if val == nil {
+			continue
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", key, i))
+		values = append(values, val)
+		i++
+	}
+	for key, val := range entity.JSONParameters {
+		// same as arrays: nil leaves the column untouched
+		if val == nil {
+			continue
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", key, i))
+		values = append(values, val)
+		i++
+	}
+	for _, key := range entity.NullParameters {
+		setParts = append(setParts, fmt.Sprintf("%s = NULL", key))
+	}
 	if len(setParts) == 0 {
 		return "", nil
 	}
@@ -5,15 +5,21 @@ import (
 )
 
 func (r *Repository) GetListOfSubDomains(ctx context.Context, domainID string) ([]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	r.log.Debug("Filters in repo layer: ", domainID)
 
-	query := `
+	query, args, err := appendTenantScope(ctx, `
 		SELECT id
 		FROM alternative_domains
 		WHERE deleted_at IS NULL
 		AND domain_id = $1
-	`
-	rows, err := r.DB.Query(ctx, query, domainID)
+	`, []interface{}{domainID}, "alternative_domains")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+)
+
+// ErrNoTenantScope is returned when a repository call carries neither a tenant nor the system scope,
+// so a service that forgets to scope its context fails instead of reading other tenants' rows
+var ErrNoTenantScope = errors.New("repository call without tenant scope")
+
+type tenantKey struct{}
+
+type systemScopeKey struct{}
+
+// WithTenant scopes every repository call made with the returned context to the tenant,
+// an empty tenant is the default tenant of single-tenant installations
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// WithSystemScope lets background jobs (renewal, purge) work across all tenants
+func WithSystemScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemScopeKey{}, true)
+}
+
+// tenantScope returns the tenant of the context, scoped is false for the system scope
+func tenantScope(ctx context.Context) (tenantID string, scoped bool, err error) {
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenantID, true, nil
+	}
+	if system, _ := ctx.Value(systemScopeKey{}).(bool); system {
+		return "", false, nil
+	}
+	return "", false, ErrNoTenantScope
+}
+
+// appendTenantScope adds the tenant condition for the given table alias (or table name)
+func appendTenantScope(ctx context.Context, query string, args []interface{}, alias string) (string, []interface{}, error) {
+	tenantID, scoped, err := tenantScope(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if !scoped {
+		return query, args, nil
+	}
+
+	query += fmt.Sprintf(" AND %s.tenant_id = $%d", alias, len(args)+1)
+	return query, append(args, tenantID), nil
+}
+
+// scopeEntity forces the tenant of the context on inserted rows, the system scope keeps the entity's own tenant_id
+func scopeEntity(ctx context.Context, entity models.Entity) (models.Entity, error) {
+	tenantID, scoped, err := tenantScope(ctx)
+	if err != nil {
+		return entity, err
+	}
+	if !scoped {
+		return entity, nil
+	}
+
+	params := make(map[string]string, len(entity.StringParameters)+1)
+	for k, v := range entity.StringParameters {
+		params[k] = v
+	}
+	params["tenant_id"] = tenantID
+	entity.StringParameters = params
+	return entity, nil
+}
+
+// unscopeUpdate drops tenant_id from updated columns, a tenant can't hand its rows to another one.
+// The system scope keeps it
+func unscopeUpdate(ctx context.Context, entity models.Entity) (models.Entity, error) {
+	_, scoped, err := tenantScope(ctx)
+	if err != nil || !scoped {
+		return entity, err
+	}
+	if _, ok := entity.StringParameters["tenant_id"]; !ok {
+		return entity, nil
+	}
+
+	params := make(map[string]string, len(entity.StringParameters))
+	for k, v := range entity.StringParameters {
+		if k != "tenant_id" {
+			params[k] = v
+		}
+	}
+	entity.StringParameters = params
+	return entity, nil
+}
@@ -0,0 +1,275 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// the conformance suite: every repository method is either tenant scoped, so each of its statements on a
+// tenant table is limited to the tenant of the context, or listed as installation-wide with a reason
+
+const testTenant = "tenant-a"
+
+// tenantTables carry tenant_id, statements on them must be scoped
+var tenantTables = regexp.MustCompile(`\b(domains|alternative_domains|certificates|events)\b`)
+
+type statement struct {
+	sql  string
+	args []any
+}
+
+// recorder is a Pool and pgx.Tx keeping the statements instead of running them, queries return rows
+type recorder struct {
+	pgx.Tx
+	statements []statement
+	rows       [][]any
+}
+
+func (r *recorder) record(sql string, args []any) {
+	r.statements = append(r.statements, statement{sql: sql, args: args})
+}
+
+func (r *recorder) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	r.record(sql, args)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (r *recorder) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	r.record(sql, args)
+	return &fakeRows{rows: r.rows}, nil
+}
+
+func (r *recorder) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	r.record(sql, args)
+	return fakeRow{}
+}
+
+func (r *recorder) Begin(context.Context) (pgx.Tx, error) { return r, nil }
+func (r *recorder) Commit(context.Context) error          { return nil }
+func (r *recorder) Rollback(context.Context) error        { return nil }
+func (r *recorder) Close()                                {}
+
+type fakeRow struct{}
+
+func (fakeRow) Scan(...any) error { return pgx.ErrNoRows }
+
+// fakeRows returns rows, the values are assigned to the scan targets in order
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	cur  []any
+}
+
+func (r *fakeRows) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	r.cur, r.rows = r.rows[0], r.rows[1:]
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if len(dest) != len(r.cur) {
+		return fmt.Errorf("scan of %d values into %d targets", len(r.cur), len(dest))
+	}
+	for i, v := range r.cur {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+func (r *fakeRows) Close()     {}
+func (r *fakeRows) Err() error { return nil }
+
+func newTestRepository() (*Repository, *recorder) {
+	rec := &recorder{}
+	return &Repository{DB: rec, log: utils.NewLogger("error")}, rec
+}
+
+var domainEntity = models.Entity{
+	EntityName:       "domains",
+	StringParameters: map[string]string{"domain_name": "example.com", "tenant_id": "tenant-b"},
+}
+
+// scopedCalls run every tenant scoped method
+var scopedCalls = map[string]func(ctx context.Context, r *Repository, tx pgx.Tx) error{
+	"InsertTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		_, err := r.InsertTx(ctx, tx, domainEntity)
+		return err
+	},
+	"InsertManyTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		_, err := r.InsertManyTx(ctx, tx, []models.Entity{domainEntity, domainEntity})
+		return err
+	},
+	"UpsertTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		_, err := r.UpsertTx(ctx, tx, domainEntity, models.UpsertOptions{ConflictColumns: []string{"domain_name"}})
+		return err
+	},
+	"UpdateTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		return r.UpdateTx(ctx, tx, domainEntity, "id")
+	},
+	"GetIDByNameTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		_, err := r.GetIDByNameTx(ctx, tx, models.Entity{EntityName: "domains", StringParameters: map[string]string{"domain_name": "example.com"}})
+		return err
+	},
+	"IsDomainDeleted": func(ctx context.Context, r *Repository, _ pgx.Tx) error {
+		_, err := r.IsDomainDeleted(ctx, "example.com")
+		return err
+	},
+	"SoftDeleteDomainTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		_, err := r.SoftDeleteDomainTx(ctx, tx, "id", "user", time.Now())
+		return err
+	},
+	"GetDomainsCount": func(ctx context.Context, r *Repository, _ pgx.Tx) error {
+		_, err := r.GetDomainsCount(ctx, models.DomainsFilters{DomainName: "example", Tags: []string{"env=prod"}})
+		return err
+	},
+	"GetDomainsList": func(ctx context.Context, r *Repository, _ pgx.Tx) error {
+		limit := 10
+		_, err := r.GetDomainsList(ctx, models.DomainsFilters{DomainName: "example", Limit: &limit})
+		return err
+	},
+	"GetListOfSubDomains": func(ctx context.Context, r *Repository, _ pgx.Tx) error {
+		_, err := r.GetListOfSubDomains(ctx, "id")
+		return err
+	},
+	"GetCertificatesByDomain": func(ctx context.Context, r *Repository, _ pgx.Tx) error {
+		_, err := r.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainName: "example.com"})
+		return err
+	},
+	"ActivateCertificateTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		return r.ActivateCertificateTx(ctx, tx, "domain", "cert", models.ReplaceKeyType)
+	},
+	"GetPurgeableDomains": func(ctx context.Context, r *Repository, _ pgx.Tx) error {
+		_, err := r.GetPurgeableDomains(ctx, time.Now())
+		return err
+	},
+	"PurgeDomainTx": func(ctx context.Context, r *Repository, tx pgx.Tx) error {
+		return r.PurgeDomainTx(ctx, tx, "id")
+	},
+}
+
+// installationWide are the methods not scoped by tenant, with the reason
+var installationWide = map[string]string{
+	"BeginTx":                 "transactions carry no rows",
+	"WithTx":                  "transactions carry no rows",
+	"IsDomainExists":          "domain names are unique installation-wide, services answer with errDomainTaken",
+	"GetCoveringDomains":      "domain names are unique installation-wide, other tenants' names are redacted",
+	"GetDNSProviders":         "DNS providers are configured for the installation",
+	"UpsertDNSProvider":       "DNS providers are configured for the installation",
+	"DeleteDNSProvider":       "DNS providers are configured for the installation",
+	"GetAcmeDNSAccount":       "acme-dns accounts belong to the installation's ACME account",
+	"InsertAcmeDNSAccount":    "acme-dns accounts belong to the installation's ACME account",
+	"GetIssuanceAttempts":     "rate limits are counted per ACME account",
+	"GetIssuanceAttemptsTx":   "rate limits are counted per ACME account",
+	"InsertIssuanceAttemptTx": "rate limits are counted per ACME account",
+	"FinishIssuanceAttempt":   "rate limits are counted per ACME account",
+	"DeleteIssuanceAttempt":   "rate limits are counted per ACME account",
+	"GetPendingOrders":        "the order journal is resumed by the system",
+	"InsertPendingOrder":      "the order journal is resumed by the system",
+	"UpdatePendingOrder":      "the order journal is resumed by the system",
+	"DeletePendingOrder":      "the order journal is resumed by the system",
+	"GetCertificateFile":      "storage files are addressed by path, the domain rows are scoped",
+	"SaveCertificateFile":     "storage files are addressed by path, the domain rows are scoped",
+	"DeleteCertificateFiles":  "storage files are addressed by path, the domain rows are scoped",
+	"TryLock":                 "leases coordinate replicas",
+	"GetMaintenance":          "maintenance mode is installation-wide",
+	"SetMaintenance":          "maintenance mode is installation-wide",
+	"QueryStats":              "metrics of the process",
+}
+
+func TestEveryMethodIsClassified(t *testing.T) {
+	methods := reflect.TypeOf((*RepositoryInterface)(nil)).Elem()
+	for i := range methods.NumMethod() {
+		name := methods.Method(i).Name
+		_, scoped := scopedCalls[name]
+		_, wide := installationWide[name]
+		if scoped == wide {
+			t.Errorf("%s must be in exactly one of scopedCalls and installationWide", name)
+		}
+	}
+}
+
+func TestScopedMethodsLimitEveryStatementToTheTenant(t *testing.T) {
+	for name, call := range scopedCalls {
+		t.Run(name, func(t *testing.T) {
+			r, rec := newTestRepository()
+			_ = call(WithTenant(context.Background(), testTenant), r, rec)
+			if len(rec.statements) == 0 {
+				t.Fatal("no statement recorded")
+			}
+			for _, st := range rec.statements {
+				if !tenantTables.MatchString(st.sql) {
+					continue
+				}
+				if !strings.Contains(st.sql, "tenant_id") {
+					t.Errorf("statement isn't scoped: %s", st.sql)
+				}
+				if !slices.Contains(st.args, any(testTenant)) {
+					t.Errorf("tenant isn't an argument %v of: %s", st.args, st.sql)
+				}
+				if slices.Contains(st.args, any("tenant-b")) {
+					t.Errorf("the entity's own tenant_id was kept: %s", st.sql)
+				}
+			}
+		})
+	}
+}
+
+func TestScopedMethodsRequireAScope(t *testing.T) {
+	for name, call := range scopedCalls {
+		t.Run(name, func(t *testing.T) {
+			r, rec := newTestRepository()
+			if err := call(context.Background(), r, rec); !errors.Is(err, ErrNoTenantScope) {
+				t.Errorf("got %v, want ErrNoTenantScope", err)
+			}
+			if len(rec.statements) > 0 {
+				t.Errorf("statements ran without scope: %v", rec.statements)
+			}
+		})
+	}
+}
+
+func TestSystemScopeIsNotLimited(t *testing.T) {
+	r, rec := newTestRepository()
+	if _, err := r.GetDomainsCount(WithSystemScope(context.Background()), models.DomainsFilters{}); err == nil {
+		t.Fatal("the recorder has no count to scan")
+	}
+	if strings.Contains(rec.statements[0].sql, "tenant_id") {
+		t.Errorf("system scope was limited: %s", rec.statements[0].sql)
+	}
+}
+
+func TestCoveringDomainsOfOtherTenantsAreRedacted(t *testing.T) {
+	r, rec := newTestRepository()
+	rec.rows = [][]any{
+		{"www.example.com", "example.com", testTenant},
+		{"api.example.org", "*.example.org", "tenant-b"},
+	}
+	if _, err := r.GetCoveringDomains(context.Background(), nil); !errors.Is(err, ErrNoTenantScope) {
+		t.Errorf("got %v, want ErrNoTenantScope", err)
+	}
+	covering, err := r.GetCoveringDomains(WithTenant(context.Background(), testTenant), []string{"www.example.com", "api.example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.CoveringDomainDTO{
+		{Name: "www.example.com", CoveredBy: "example.com"},
+		{Name: "api.example.org", CoveredBy: ""},
+	}
+	if !reflect.DeepEqual(covering, want) {
+		t.Errorf("got %v, want %v", covering, want)
+	}
+}
@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -9,3 +11,35 @@ import (
 func (r *Repository) BeginTx(ctx context.Context) (pgx.Tx, error) {
 	return r.DB.Begin(ctx)
 }
+
+// WithTx runs fn inside a transaction, commits when fn returns nil and rolls back on error or panic
+func (r *Repository) WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	beginCtx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	tx, err := r.DB.Begin(beginCtx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		r.log.Warn("Rollback started: ", err)
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			r.log.Error("Rollback error: ", rbErr)
+		}
+		return err
+	}
+
+	commitCtx, cancelCommit := r.withTimeout(ctx)
+	defer cancelCommit()
+	if err := tx.Commit(commitCtx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// what happens to the domains issued with a deactivated ACME account
+const (
+	accountDomainsReassign = "reassign"
+	accountDomainsBlock    = "block"
+)
+
+// DeactivateAcmeAccount deactivates the ACME account at the CA and archives its key, the domains
+// issued with it renew with a new account or get auto_renew turned off. Every domain gets an event
+func (s *Service) DeactivateAcmeAccount(req models.DeactivateAccountReq) (models.DeactivateAccountResp, error) {
+	s.log.Debug("DeactivateAcmeAccount(): called by ", req.UserID)
+	if req.Domains == "" {
+		req.Domains = accountDomainsReassign
+	}
+	if !slices.Contains([]string{accountDomainsReassign, accountDomainsBlock}, req.Domains) {
+		var verr models.ValidationError
+		verr.Add("domains", "must be %s or %s, got %q", accountDomainsReassign, accountDomainsBlock, req.Domains)
+		return models.DeactivateAccountResp{}, verr.Err()
+	}
+
+	ctx := repositories.WithSystemScope(s.ctx)
+	domains, err := s.accountDomains(ctx)
+	if err != nil {
+		return models.DeactivateAccountResp{}, err
+	}
+
+	account, err := s.acmeIssuer().DeactivateAccount()
+	if err != nil {
+		return models.DeactivateAccountResp{}, err
+	}
+	resp := models.DeactivateAccountResp{
+		AccountURI:  account.AccountURI,
+		CA:          account.CADirURL,
+		ArchivedKey: account.ArchivedKey,
+		Domains:     req.Domains,
+		DomainNames: make([]string, 0, len(domains)),
+	}
+
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		for _, d := range domains {
+			domainCtx := repositories.WithTenant(s.ctx, d.TenantID)
+			details := fmt.Sprintf("ACME account %s deactivated, the next renewal registers a new account", account.AccountURI)
+			if req.Domains == accountDomainsBlock {
+				entity := NewEntity("domains", map[string]any{
+					"auto_renew": false,
+					"updated_by": req.UserID,
+				})
+				if err := s.repository.UpdateTx(domainCtx, tx, entity, d.ID); err != nil {
+					return fmt.Errorf("block %s: %w", d.DomainName, err)
+				}
+				details = fmt.Sprintf("ACME account %s deactivated, auto_renew turned off", account.AccountURI)
+			}
+			if err := s.writeEvent(domainCtx, tx, d.ID, "account_deactivated", details, req.UserID); err != nil {
+				return err
+			}
+			resp.DomainNames = append(resp.DomainNames, d.DomainName)
+		}
+		return s.writeEvent(ctx, tx, "", "account_deactivated",
+			fmt.Sprintf("ACME account %s on %s deactivated, key archived as %s, %d domains %s",
+				account.AccountURI, account.CADirURL, account.ArchivedKey, len(domains), req.Domains), req.UserID)
+	})
+	if err != nil {
+		// the account is gone at the CA either way, the domains keep renewing with a new one
+		return resp, fmt.Errorf("account %s deactivated, updating its domains failed: %w", account.AccountURI, err)
+	}
+
+	s.log.Warn("ACME account ", account.AccountURI, " deactivated by ", req.UserID, ", ", len(domains), " domains ", req.Domains)
+	return resp, nil
+}
+
+// accountDomains returns the domains with a certificate of the ACME account, managed domains that
+// aren't deleted, without the ones of selfsigned providers
+func (s *Service) accountDomains(ctx context.Context) ([]models.DomainsDTO, error) {
+	domains, err := s.repository.GetDomainsList(ctx, models.DomainsFilters{Kind: models.DomainKindManaged})
+	if err != nil {
+		return nil, fmt.Errorf("get domains: %w", err)
+	}
+	out := make([]models.DomainsDTO, 0, len(domains))
+	for _, d := range domains {
+		if d.Details.Status == "deleted" || d.Details.CertValidTo == nil {
+			continue
+		}
+		if provider, err := s.selectProvider(d.Details.DNSProvider); err == nil {
+			if _, ok := provider.SelfSigned(); ok {
+				continue
+			}
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
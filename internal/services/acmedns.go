@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DelegateAcmeDNS registers the domain on the acme-dns server of the provider, unless it already
+// has an account there, and returns the CNAME to create before the first issuance
+func (s *Service) DelegateAcmeDNS(req models.DelegateAcmeDNSReq) (models.AcmeDNSDelegation, error) {
+	s.log.Debug("DelegateAcmeDNS(): called for domain ", req.DomainName)
+	if err := validateDelegateAcmeDNS(&req); err != nil {
+		return models.AcmeDNSDelegation{}, err
+	}
+
+	provider, err := s.selectProvider(req.DNSProvider)
+	if err != nil {
+		return models.AcmeDNSDelegation{}, err
+	}
+	if provider.Type != utils.ProviderAcmeDNS {
+		var verr models.ValidationError
+		verr.Add("dns_provider", "%q is a %s provider, not acmedns", provider.Name, provider.Type)
+		return models.AcmeDNSDelegation{}, verr.Err()
+	}
+
+	cname, err := provider.AcmeDNSDelegate(s.ctx, req.DomainName)
+	if err != nil {
+		return models.AcmeDNSDelegation{}, fmt.Errorf("acme-dns delegation: %w", err)
+	}
+
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+	s.safeWriteEvent(ctx, req.UserID, "", "acmedns_delegated",
+		fmt.Sprintf("acme-dns delegation of %s through '%s': CNAME %s -> %s", req.DomainName, provider.Name, cname.Name, cname.Target))
+
+	return models.AcmeDNSDelegation{
+		DomainName:  req.DomainName,
+		DNSProvider: provider.Name,
+		CNAME:       cname.Name,
+		Target:      cname.Target,
+	}, nil
+}
+
+// acmeDNSAccounts keeps the acme-dns accounts in the repository, encrypted like runtime providers
+type acmeDNSAccounts struct {
+	s *Service
+}
+
+func (a acmeDNSAccounts) AcmeDNSAccount(ctx context.Context, serverURL, domain string) (*dnsproviders.AcmeDNSAccount, error) {
+	ctx = repositories.WithSystemScope(ctx)
+	stored, err := a.s.repository.GetAcmeDNSAccount(ctx, serverURL, domain)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := utils.DecryptSecret(a.s.config().Encryption.Key, stored.Credentials, nil)
+	if err != nil {
+		return nil, err
+	}
+	var account dnsproviders.AcmeDNSAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("decode account: %w", err)
+	}
+	return &account, nil
+}
+
+func (a acmeDNSAccounts) SaveAcmeDNSAccount(ctx context.Context, serverURL, domain string, account dnsproviders.AcmeDNSAccount) (*dnsproviders.AcmeDNSAccount, error) {
+	key := a.s.config().Encryption.Key
+	if key == "" {
+		return nil, utils.ErrNoEncryptionKey
+	}
+	data, err := json.Marshal(account)
+	if err != nil {
+		return nil, fmt.Errorf("encode account: %w", err)
+	}
+	credentials, err := utils.EncryptSecret(key, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := a.s.repository.InsertAcmeDNSAccount(repositories.WithSystemScope(ctx), models.AcmeDNSAccountDTO{
+		ServerURL:   serverURL,
+		DomainName:  domain,
+		FullDomain:  account.FullDomain,
+		Credentials: credentials,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !inserted {
+		// another replica registered the domain at the same time, its account is the one delegated to
+		return a.AcmeDNSAccount(ctx, serverURL, domain)
+	}
+
+	a.s.log.Info("acme-dns account registered for ", domain, " on ", serverURL)
+	return &account, nil
+}
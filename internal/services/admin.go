@@ -0,0 +1,25 @@
+package services
+
+import (
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+)
+
+func (s *Service) GetQueryStats() []models.QueryStat {
+	return s.repository.QueryStats()
+}
+
+// GetConfig returns the running configuration with secrets masked
+func (s *Service) GetConfig() map[string]any {
+	return utils.RedactedConfig(s.config())
+}
+
+// FeatureEnabled reports whether a feature flag is on in the current configuration
+func (s *Service) FeatureEnabled(name string) bool {
+	return s.config().FeatureEnabled(name)
+}
+
+// GetFeatures returns the state of every known feature flag
+func (s *Service) GetFeatures() map[string]bool {
+	return s.config().FeatureFlags()
+}
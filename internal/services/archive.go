@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// archiveVersion retires the version a renewal replaced. With certs.archive it is moved into the archive
+// of the domain and the paths of its certificate record follow it, so it can be restored later
+func (s *Service) archiveVersion(ctx context.Context, domain models.DomainsDTO, certPath string) error {
+	paths, err := s.certStore().Archive(domain.DomainName, certPath)
+	if err != nil || paths == nil {
+		return err
+	}
+	return s.moveCertificateFiles(ctx, domain.ID, certPath, paths)
+}
+
+// moveCertificateFiles points the certificate records of the domain with the files at certPath to paths
+func (s *Service) moveCertificateFiles(ctx context.Context, domainID, certPath string, paths *models.CertificatePaths) error {
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domainID})
+	if err != nil {
+		return fmt.Errorf("get certificates: %w", err)
+	}
+	updates := map[string]models.Entity{}
+	for _, c := range certs {
+		if c.CertPath != certPath {
+			continue
+		}
+		updates[c.ID] = NewEntity("certificates", map[string]any{
+			"cert_path":  paths.Cert,
+			"key_path":   paths.Key,
+			"chain_path": paths.Chain,
+		})
+	}
+	return s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		return s.updateMany(ctx, tx, updates)
+	})
+}
+
+// ListArchivedCertificates lists the archived versions of a domain, newest first, with the certificate
+// each one holds
+func (s *Service) ListArchivedCertificates(req models.ArchivedCertificatesReq) ([]models.ArchivedCertificate, error) {
+	s.log.Debug("Listing archived certificates of domain: ", req.DomainName)
+	if err := validateArchivedCertificates(&req); err != nil {
+		return nil, err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	domain, err := s.getDomainByName(ctx, req.DomainName)
+	if err != nil {
+		return nil, err
+	}
+	store := s.certStore()
+	versions, err := store.Archived(domain.DomainName)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []models.ArchivedCertificate{}
+	}
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return nil, fmt.Errorf("get certificates: %w", err)
+	}
+
+	for i := range versions {
+		v := &versions[i]
+		for _, c := range certs {
+			if c.CertPath == v.CertPath {
+				v.CertificateID = c.ID
+				break
+			}
+		}
+		data, err := store.Read(v.CertPath)
+		if err != nil {
+			s.log.Warn("Archived version ", v.Version, " of ", domain.DomainName, " unreadable: ", err)
+			continue
+		}
+		cert, err := utils.ParseLeafCertificate(data)
+		if err != nil {
+			s.log.Warn("Archived version ", v.Version, " of ", domain.DomainName, " unreadable: ", err)
+			continue
+		}
+		v.SerialNumber = utils.CertificateSerial(cert)
+		v.Fingerprint = utils.CertificateFingerprint(cert)
+		v.ValidFrom = cert.NotBefore
+		v.ValidTo = cert.NotAfter
+	}
+	return versions, nil
+}
+
+// RestoreCertificate moves an archived version out of the archive and activates its certificate like
+// ActivateCertificate, the version it replaces stays next to it
+func (s *Service) RestoreCertificate(req models.RestoreCertificateReq) error {
+	s.log.Info("Restoring archived version ", req.Version, " of domain: ", req.DomainName)
+	if err := validateRestoreCertificate(&req); err != nil {
+		return err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	domain, err := s.getDomainByName(ctx, req.DomainName)
+	if err != nil {
+		return err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return fmt.Errorf("domain '%s' is monitored, it has no stored versions", req.DomainName)
+	}
+	// a renewal archiving a version meanwhile would move the files away under the restore
+	return s.withIssuanceLock(ctx, domain.DomainName, func() error {
+		return s.restoreCertificate(ctx, domain, req)
+	})
+}
+
+// restoreCertificate restores the archived version, the caller holds the issuance lock of the domain
+func (s *Service) restoreCertificate(ctx context.Context, domain models.DomainsDTO, req models.RestoreCertificateReq) error {
+	store := s.certStore()
+	versions, err := store.Archived(domain.DomainName)
+	if err != nil {
+		return err
+	}
+	var archivedPath string
+	for _, v := range versions {
+		if v.Version == req.Version {
+			archivedPath = v.CertPath
+		}
+	}
+	if archivedPath == "" {
+		return models.WithCode(models.CodeNotFound, fmt.Errorf("domain '%s' has no archived version '%s'", req.DomainName, req.Version))
+	}
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return fmt.Errorf("get certificates: %w", err)
+	}
+	var certID string
+	for _, c := range certs {
+		if c.CertPath == archivedPath {
+			certID = c.ID
+		}
+	}
+	if certID == "" {
+		return models.WithCode(models.CodeNotFound, fmt.Errorf("no certificate of '%s' is stored in archived version '%s'", req.DomainName, req.Version))
+	}
+
+	paths, err := store.Restore(domain.DomainName, req.Version)
+	if err != nil {
+		return err
+	}
+	if err := s.moveCertificateFiles(ctx, domain.ID, archivedPath, paths); err != nil {
+		return fmt.Errorf("record restored files: %w", err)
+	}
+	_ = s.safeWriteEvent(ctx, req.UserID, domain.ID, "restored",
+		fmt.Sprintf("Archived version %s of '%s' restored", req.Version, domain.DomainName))
+
+	// a failed activation leaves the version restored, it can be activated like any other
+	return s.activateCertificate(ctx, domain, certID, req.UserID)
+}
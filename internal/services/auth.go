@@ -3,13 +3,18 @@ package services
 import (
 	"errors"
 	"fmt"
+	models "hephaestus/internal/models"
+	"slices"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
-func (s *Service) Validate(tokenStr string) (string, error) {
+const defaultTenantClaim = "tenant_id"
+
+func (s *Service) Validate(tokenStr string) (models.Identity, error) {
 	s.log.Debug("Validating token.........")
-	jwtSecret := []byte(s.cfg.Auth.AccessSecKey)
+	authCfg := s.config().Auth
+	jwtSecret := []byte(authCfg.AccessSecKey)
 
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -20,16 +25,31 @@ func (s *Service) Validate(tokenStr string) (string, error) {
 
 	if err != nil {
 		s.log.Error("Token parse error: ", err)
-		return "", err
+		return models.Identity{}, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		id, ok := claims["sub"].(string)
 		if !ok {
-			return "", errors.New("ID not found or not a string in token claims")
+			return models.Identity{}, errors.New("ID not found or not a string in token claims")
+		}
+
+		tenantClaim := authCfg.TenantClaim
+		if tenantClaim == "" {
+			tenantClaim = defaultTenantClaim
+		}
+		tenantID, _ := claims[tenantClaim].(string)
+		if tenantID == "" && authCfg.RequireTenant {
+			return models.Identity{}, fmt.Errorf("tenant claim %q not found in token claims", tenantClaim)
 		}
-		s.log.Debug("Token valid. ID:", id)
-		return id, nil
+
+		s.log.Debug("Token valid. ID:", id, " tenant:", tenantID)
+		return models.Identity{UserID: id, TenantID: tenantID}, nil
 	}
-	return "", errors.New("invalid token")
+	return models.Identity{}, errors.New("invalid token")
+}
+
+// IsAdmin reports whether the user may call the admin endpoints
+func (s *Service) IsAdmin(userID string) bool {
+	return slices.Contains(s.config().Auth.AdminIDs, userID)
 }
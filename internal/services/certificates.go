@@ -1,40 +1,105 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	models "hephaestus/internal/models"
-	"os/exec"
+	repositories "hephaestus/internal/repositories"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// GetCertificates returns the certificate history of a domain, newest first
+func (s *Service) GetCertificates(req models.GetCertificatesReq) ([]models.Certificate, error) {
+	s.log.Debug("Fetching certificates of domain: ", req.DomainID, req.DomainName)
+	if err := validateGetCertificates(&req); err != nil {
+		return nil, err
+	}
+	req.DomainName = lookupName(req.DomainName)
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{
+		DomainID:   req.DomainID,
+		DomainName: req.DomainName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get certificates: %w", err)
+	}
+
+	res := make([]models.Certificate, 0, len(certs))
+	for _, c := range certs {
+		res = append(res, models.ConvertCertsDTOToCertificate(c))
+	}
+	return res, nil
+}
+
 func (s *Service) StartCertificateRenewalScheduler() {
-	ticker := time.NewTicker(s.cfg.Certs.RenewalDuration * time.Hour)
+	cfg := s.config()
+	if cfg.Scheduler.RenewAt != "" {
+		s.startDailyRenewal(cfg.Scheduler)
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Certs.RenewalDuration)
+	s.mu.Lock()
+	s.renewalTicker = ticker
+	s.mu.Unlock()
 
 	go func() {
 		for range ticker.C {
-			s.log.Info("Running certificate renewal cycle...")
-			s.RenewExpiringCertificates()
+			s.runCycle("renewal", s.RenewExpiringCertificates)
 		}
 	}()
 }
 
 func (s *Service) RenewExpiringCertificates() {
-	domains, err := s.repository.GetDomainsList(s.ctx, models.DomainsFilters{})
+	// the renewal cycle works across all tenants
+	ctx := repositories.WithSystemScope(s.ctx)
+
+	// renewal can be restricted to tagged domains, e.g. env=prod
+	domains, err := s.repository.GetDomainsList(ctx, models.DomainsFilters{
+		Tags: normalizeTags(s.config().Certs.RenewalTags),
+		Kind: models.DomainKindManaged,
+	})
 	if err != nil {
 		s.log.Error("failed fetch domains:", err)
 		return
 	}
 
 	now := time.Now()
+	renewBefore := s.config().Certs.RenewBefore()
 
 	for _, d := range domains {
+		if d.Details.Status == "deleted" {
+			continue
+		}
+		// a failed attempt is retried once its backoff is over
+		if d.Details.NextRenewalAt != nil && now.Before(*d.Details.NextRenewalAt) {
+			continue
+		}
 
-		if d.Details.Status == "deleted" || !d.Details.AutoRenew {
+		// domains created with defer_issuance get their first certificate here, failed attempts are retried
+		// only with auto_renew
+		if d.Details.CertValidTo == nil {
+			if d.Details.AutoRenew || d.Details.Status == "pending" {
+				s.log.Info("Domain ", d.DomainName, " has no certificate yet. Issuance triggered.")
+				if err := s.RenewDomainCertificate(d); err != nil {
+					s.log.Error("Failed to issue certificate for", d.DomainName, ":", err)
+				}
+			}
 			continue
 		}
 
-		// renew threshold: 30 days before expiration
-		renewDate := d.Details.CertValidTo.Add(-30 * 24 * time.Hour)
+		if !d.Details.AutoRenew {
+			continue
+		}
+
+		// renew threshold: certs.renew_before_days before expiration
+		renewDate := d.Details.CertValidTo.Add(-renewalWindow(d.Details, renewBefore))
 
 		if now.Before(renewDate) {
 			continue
@@ -49,112 +114,404 @@ func (s *Service) RenewExpiringCertificates() {
 	}
 }
 
+// renewalBackoff is how long the renewal cycle waits after the given number of failed attempts: an hour,
+// doubled with every further failure up to a day
+func renewalBackoff(attempts int) time.Duration {
+	return min(time.Hour<<min(attempts-1, 5), 24*time.Hour)
+}
+
+// renewalWindow is how long before expiry the certificate of the domain is renewed, at most a third
+// of its lifetime, so short-lived certificates (e.g. 6 days of the shortlived profile) aren't
+// renewed on every cycle
+func renewalWindow(details models.DetailsDTO, renewBefore time.Duration) time.Duration {
+	if details.CertValidFrom == nil || details.CertValidTo == nil {
+		return renewBefore
+	}
+	return min(renewBefore, details.CertValidTo.Sub(*details.CertValidFrom)/3)
+}
+
+// outputFormats returns the encodings the files of a domain are written in, certs.output_formats unless
+// the domain has its own
+func (s *Service) outputFormats(formats []string) []string {
+	if len(formats) == 0 {
+		return s.config().Certs.OutputFormats
+	}
+	return formats
+}
+
 func (s *Service) RenewDomainCertificate(domain models.DomainsDTO) error {
+	ctx := repositories.WithTenant(s.ctx, domain.TenantID)
+	return s.withIssuanceLock(ctx, domain.DomainName, func() error {
+		return s.renewDomainCertificate(ctx, domain)
+	})
+}
+
+func (s *Service) renewDomainCertificate(ctx context.Context, domain models.DomainsDTO) error {
 	s.log.Info("Renewing certificate for domain: ", domain.DomainName)
 
-	tx, err := s.repository.BeginTx(s.ctx)
+	s.log.Debug("Selecting DNS provider...")
+	provider, err := s.selectProvider(domain.Details.DNSProvider)
 	if err != nil {
-		return fmt.Errorf("failed to begin tx: %w", err)
+		return fmt.Errorf("select DNS provider: %w", err)
 	}
 
-	defer func() {
-		if err != nil {
-			s.log.Warn("Rollback renewal tx")
-			_ = tx.Rollback(s.ctx)
-		}
-	}()
-
-	s.log.Debug("Selecting client...")
-	client, err := s.SelectClientByName(domain.Details.DNSProvider)
-
 	var san []string
 	if len(domain.Sub) > 0 {
 		san = domain.Sub
 	}
 
-	// request
-	certData, err := client.CreateCertificate(domain.DomainName, san)
+	// a zone moved to another DNS service fails here instead of after the propagation timeout
+	names := append([]string{domain.DomainName}, san...)
+	if err := s.checkNameservers(ctx, provider, names...); err != nil {
+		s.markRenewalFailed(ctx, domain, fmt.Sprintf("Nameserver check failed: %v", err))
+		return fmt.Errorf("check nameservers: %w", err)
+	}
+
+	// only the first certificate of the domain has no key to reuse, a key that can't be read fails the renewal
+	var reuseKey []byte
+	if domain.Details.ReuseKey {
+		reuseKey, err = s.certStore().ReadLiveKey(domain.DomainName)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && domain.Details.CertValidTo == nil:
+			reuseKey = nil
+		case err != nil:
+			s.markRenewalFailed(ctx, domain, fmt.Sprintf("Key to reuse can't be read: %v", err))
+			return fmt.Errorf("read key to reuse: %w", err)
+		}
+	}
+
+	// the ACME order runs outside the transaction, it can take minutes
+	var certData *models.CertificateData
+	err = s.withRateLimitBudget(ctx, provider, names, domain.Details.CertValidTo != nil, func() error {
+		certData, err = s.acmeIssuer().Obtain(provider, domain.DomainName, san, safeDeref(domain.Details.KeyType), safeDeref(domain.Details.Profile), reuseKey)
+		return err
+	})
+	// a used up budget defers the renewal to a later cycle, the domain isn't failed
+	if models.ErrorCode(err) == models.CodeRateLimitBudget {
+		s.log.Warn("Renewal of ", domain.DomainName, " deferred: ", err)
+		_ = s.safeWriteEvent(ctx, "system-renewal", domain.ID, "renewal_deferred", fmt.Sprintf("Renewal deferred: %v", err))
+		return err
+	}
 	if err != nil {
 		s.log.Error("renewal certificate failed:", err)
-		updateDomainsData := make(map[string]models.Entity)
-		updateDomainsData[domain.ID] = NewEntity("domains", map[string]any{
-			"status":     "update_failed",
-			"updated_by": "system-renewal",
-		})
-		err := s.updateMany(s.ctx, tx, updateDomainsData)
-		err = s.writeEvent(s.ctx, tx, domain.ID, "failed", fmt.Sprintf("Certificate issuance failed: %v", err), "system-renewal")
+		s.markRenewalFailed(ctx, domain, fmt.Sprintf("Certificate issuance failed: %v", err))
 		return fmt.Errorf("failed to create new certificate: %w", err)
 	}
 
-	// saving files
-	certPaths, err := client.SaveCertificateFiles(domain.DomainName, certData)
+	// the new version is written next to the current one, the previous version stays until it's served
+	store := s.certStore()
+	certData.OutputFormats = s.outputFormats(domain.Details.OutputFormats)
+	certPaths, err := store.Stage(domain.DomainName, certData)
 	if err != nil {
-		updateDomainsData := make(map[string]models.Entity)
-		updateDomainsData[domain.ID] = NewEntity("domains", map[string]any{
-			"status":     "update_failed",
-			"updated_by": "system-renewal",
-		})
-		_ = s.updateMany(s.ctx, tx, updateDomainsData)
+		s.markRenewalFailed(ctx, domain, fmt.Sprintf("Saving certificate files failed: %v", err))
 		return fmt.Errorf("failed to save cert files: %w", err)
 	}
+	chain := s.keepChain(ctx, domain, certData, certPaths.Cert)
+	previous, err := s.deployCertificate(domain, certPaths.Cert, certData.SerialNumber, true)
+	if err != nil {
+		if retireErr := store.Retire(domain.DomainName, certPaths.Cert); retireErr != nil {
+			s.log.Warn("Failed to remove staged certificate files of ", domain.DomainName, ": ", retireErr)
+		}
+		s.markRenewalFailed(ctx, domain, fmt.Sprintf("Deploying certificate %s failed, previous version restored: %v", certData.SerialNumber, err))
+		return fmt.Errorf("failed to deploy certificate: %w", err)
+	}
 
-	// updatind db certs
-	updateCertData := map[string]models.Entity{
-		domain.ID: NewEntity("certificates", map[string]any{
-			"cert_path":  certPaths.Cert,
-			"key_path":   certPaths.Key,
-			"chain_path": certPaths.Chain,
-			"updated_by": "system-renewal",
-			"valid_from": certData.ValidFrom,
-			"valid_to":   certData.ValidTo,
-			"updated_at": time.Now(),
-		}),
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		// every issuance is kept as its own row, previous ones stay as history. The renewal is of the domain's
+		// key type, after the key type changed it replaces the primary certificate of the old one
+		_, err := s.storeCertificateTx(ctx, tx, domain.ID, NewEntity("certificates", map[string]any{
+			"domain_id":     domain.ID,
+			"issuer":        certData.Issuer,
+			"cert_path":     certPaths.Cert,
+			"key_path":      certPaths.Key,
+			"chain_path":    certPaths.Chain,
+			"serial_number": certData.SerialNumber,
+			"fingerprint":   certData.Fingerprint,
+			"key_type":      certData.KeyType,
+			"chains":        chainRoots(certData),
+			"active_chain":  chain,
+			"created_by":    "system-renewal",
+			"valid_from":    certData.ValidFrom,
+			"valid_to":      certData.ValidTo,
+			"last_renewal":  time.Now(),
+		}), models.ReplacePrimary)
+		if err != nil {
+			return err
+		}
+
+		updateDomainsData := map[string]models.Entity{
+			domain.ID: NewEntity("domains", map[string]any{
+				"status":           "active",
+				"renewal_attempts": 0,
+				"next_renewal_at":  nil,
+				"updated_by":       "system-renewal",
+			}),
+		}
+		if err := s.updateMany(ctx, tx, updateDomainsData); err != nil {
+			return fmt.Errorf("failed to update domain status: %w", err)
+		}
+
+		// event
+		if err := s.writeEvent(ctx, tx, domain.ID, "renewed", fmt.Sprintf("Certificate for '%s' renewed", domain.DomainName), "system-renewal"); err != nil {
+			return fmt.Errorf("failed to insert event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// the new version is already served, keeping it beats going back to the old one
+		s.log.Error("Certificate of ", domain.DomainName, " deployed but not recorded: ", err)
+		return err
+	}
+
+	if previous != "" {
+		if err := s.archiveVersion(ctx, domain, previous); err != nil {
+			s.log.Warn("Failed to retire previous certificate files of ", domain.DomainName, ": ", err)
+		}
 	}
-	err = s.updateMany(s.ctx, tx, updateCertData)
+
+	s.publishCertificate(ctx, domain.ID, domain.DomainName, domain.Tags, "system-renewal")
+
+	s.log.Info("Domain %s successfully renewed!", domain.DomainName)
+	return nil
+}
+
+// deployCertificate makes the staged version current, reloads nginx and, with certs.verify_deployment,
+// checks the new serial is served. On failure the previous version is current again. replacePrimary
+// serves it over the primary version of another key type, see CertStoreInterface.Promote
+func (s *Service) deployCertificate(domain models.DomainsDTO, certPath, serial string, replacePrimary bool) (previous string, err error) {
+	store := s.certStore()
+	previous, err = store.Promote(domain.DomainName, certPath, replacePrimary)
 	if err != nil {
-		return fmt.Errorf("failed to update certificate: %w", err)
+		return "", err
 	}
 
-	updateDomainsData := make(map[string]models.Entity)
-	updateDomainsData[domain.ID] = NewEntity("domains", map[string]any{
-		"status":     "active",
-		"updated_by": "system-renewal",
-	})
-	err = s.updateMany(s.ctx, tx, updateDomainsData)
+	err = s.reloadNginxInContainer(domain)
+	if err == nil {
+		err = s.verifyDeployment(domain, serial)
+	}
+	if err == nil {
+		return previous, nil
+	}
+
+	s.log.Warn("Deployment of ", domain.DomainName, " failed, restoring previous certificate: ", err)
+	if previous != "" {
+		if _, rollbackErr := store.Promote(domain.DomainName, previous, replacePrimary); rollbackErr != nil {
+			return "", fmt.Errorf("%w (restoring previous certificate failed: %v)", err, rollbackErr)
+		}
+		if reloadErr := s.reloadNginxInContainer(domain); reloadErr != nil {
+			s.log.Error("nginx reload after restoring ", domain.DomainName, " failed: ", reloadErr)
+		}
+	}
+	return "", err
+}
+
+// verifyDeployment checks the domain serves the certificate with serial, wildcard domains have no host
+// to connect to and an unknown serial can't be compared
+func (s *Service) verifyDeployment(domain models.DomainsDTO, serial string) error {
+	cfg := s.config()
+	if !cfg.Certs.VerifyDeployment || serial == "" || strings.HasPrefix(domain.DomainName, "*.") {
+		return nil
+	}
+
+	// managed domains are served by the nginx next to Hephaestus, often on a private address
+	address := monitorAddress(domain.DomainName, "")
+	served, err := fetchServedCertificate(address, domain.DomainName, monitorTimeout(cfg.Monitor), false)
 	if err != nil {
-		return fmt.Errorf("failed to update domain status: %w", err)
+		return fmt.Errorf("verify deployment: %w", err)
+	}
+	if !strings.EqualFold(served.SerialNumber, serial) {
+		return fmt.Errorf("verify deployment: %s serves certificate %s, expected %s", address, served.SerialNumber, serial)
 	}
+	s.log.Debug("Deployment of ", domain.DomainName, " verified, serving ", serial)
+	return nil
+}
 
-	// event
-	err = s.writeEvent(s.ctx, tx, domain.ID, "renewed", fmt.Sprintf("Certificate for '%s' renewed", domain.DomainName), "system-renewal")
+// storeCertificateTx inserts an issued certificate as staged and activates it within scope,
+// the certificates it replaces are retired and kept as history
+func (s *Service) storeCertificateTx(ctx context.Context, tx pgx.Tx, domainID string, cert models.Entity, scope models.ActivationScope) (string, error) {
+	cert.StringParameters["state"] = "staged"
+	certID, err := s.repository.InsertTx(ctx, tx, cert)
 	if err != nil {
-		return fmt.Errorf("failed to insert event: %w", err)
+		return "", fmt.Errorf("insert certificate: %w", err)
+	}
+	if err := s.repository.ActivateCertificateTx(ctx, tx, domainID, certID, scope); err != nil {
+		return "", fmt.Errorf("activate certificate: %w", err)
 	}
+	return certID, nil
+}
+
+// ActivateCertificate switches the active certificate of a key type, e.g. back to the previous one
+func (s *Service) ActivateCertificate(req models.ActivateCertificateReq) error {
+	s.log.Info("Activating certificate ", req.CertificateID, " of domain: ", req.DomainName)
+	if err := validateActivateCertificate(&req); err != nil {
+		return err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
 
-	err = tx.Commit(s.ctx)
+	domain, err := s.getDomainByName(ctx, req.DomainName)
 	if err != nil {
-		return fmt.Errorf("failed commit: %w", err)
+		return err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return fmt.Errorf("domain '%s' is monitored, its active certificate is the served one", req.DomainName)
 	}
+	// the files are switched like by a renewal, which mustn't run meanwhile
+	return s.withIssuanceLock(ctx, domain.DomainName, func() error {
+		return s.activateCertificate(ctx, domain, req.CertificateID, req.UserID)
+	})
+}
 
-	s.log.Info("Domain %s successfully renewed!", domain.DomainName)
+// activateCertificate serves the certificate of the domain and records it as active, the caller holds the
+// issuance lock of the domain
+func (s *Service) activateCertificate(ctx context.Context, domain models.DomainsDTO, certID, userID string) error {
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return fmt.Errorf("get certificates: %w", err)
+	}
+	var cert *models.CertsDTO
+	for i := range certs {
+		if certs[i].ID == certID {
+			cert = &certs[i]
+			break
+		}
+	}
+	if cert == nil {
+		return models.WithCode(models.CodeNotFound, fmt.Errorf("certificate '%s' doesn't belong to domain '%s'", certID, domain.DomainName))
+	}
 
-	if err := s.reloadNginxInContainer(domain); err != nil {
-		return fmt.Errorf("certificate renewed but nginx reload failed: %w", err)
+	// the files are switched first, a certificate whose version was already retired can't be activated.
+	// The replaced version is kept, so a failed commit switches back to it
+	previous, err := s.deployCertificate(domain, cert.CertPath, safeDeref(cert.SerialNumber), false)
+	if err != nil {
+		return fmt.Errorf("deploy certificate: %w", err)
 	}
 
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.repository.ActivateCertificateTx(ctx, tx, domain.ID, certID, models.ReplaceKeyType); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return models.WithCode(models.CodeNotFound, fmt.Errorf("certificate '%s' doesn't belong to domain '%s'", certID, domain.DomainName))
+			}
+			return err
+		}
+		return s.writeEvent(ctx, tx, domain.ID, "activated",
+			fmt.Sprintf("Certificate %s of '%s' activated", certID, domain.DomainName), userID)
+	})
+	if err != nil {
+		if previous != "" {
+			// the files follow the database, the previous version is served again
+			if _, rollbackErr := s.deployCertificate(domain, previous, "", false); rollbackErr != nil {
+				s.log.Error("Failed to restore certificate files of ", domain.DomainName, ": ", rollbackErr)
+			}
+		}
+		return err
+	}
+	// the publish targets serve the live files too, RestoreCertificate activates through here as well
+	s.publishCertificate(ctx, domain.ID, domain.DomainName, domain.Tags, userID)
 	return nil
 }
 
+// markRenewalFailed stores the failed renewal in its own transaction, so it isn't lost with the rollback, and
+// backs the domain off the renewal cycle
+func (s *Service) markRenewalFailed(ctx context.Context, domain models.DomainsDTO, details string) {
+	attempts := 1
+	if domain.Details.CertRenewalAttempts != nil {
+		attempts += *domain.Details.CertRenewalAttempts
+	}
+	err := s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		err := s.updateMany(ctx, tx, map[string]models.Entity{
+			domain.ID: NewEntity("domains", map[string]any{
+				"status":           "update_failed",
+				"renewal_attempts": attempts,
+				"next_renewal_at":  time.Now().Add(renewalBackoff(attempts)),
+				"updated_by":       "system-renewal",
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update domain status: %w", err)
+		}
+		return s.writeEvent(ctx, tx, domain.ID, "failed", details, "system-renewal")
+	})
+	if err != nil {
+		s.log.Error("failed to record renewal failure:", err)
+	}
+}
+
+// RenewDomain renews the certificate of the domain right away, regardless of its expiry
+func (s *Service) RenewDomain(tenantID, domainName string) error {
+	domain, err := s.getDomainByName(repositories.WithTenant(s.ctx, tenantID), domainName)
+	if err != nil {
+		return err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return fmt.Errorf("domain '%s' is monitored, its certificate is issued outside of Hephaestus", domainName)
+	}
+	return s.RenewDomainCertificate(domain)
+}
+
+// RevokeCertificate revokes the active certificate of the domain at the CA, the files are kept
+func (s *Service) RevokeCertificate(req models.RevokeCertificateReq) error {
+	s.log.Info("Revoking certificate for domain: ", req.DomainName)
+	if err := validateRevokeCertificate(&req); err != nil {
+		return err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	domain, err := s.getDomainByName(ctx, req.DomainName)
+	if err != nil {
+		return err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return fmt.Errorf("domain '%s' is monitored, its certificate is issued outside of Hephaestus", req.DomainName)
+	}
+
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return fmt.Errorf("get certificates: %w", err)
+	}
+	var active *models.CertsDTO
+	for i := range certs {
+		if certs[i].Primary {
+			active = &certs[i]
+			break
+		}
+	}
+	if active == nil {
+		return fmt.Errorf("domain '%s' has no active certificate", req.DomainName)
+	}
+
+	certPEM, err := s.certStore().Read(active.CertPath)
+	if err != nil {
+		return err
+	}
+
+	if err := s.acmeIssuer().Revoke(certPEM); err != nil {
+		return err
+	}
+
+	return s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.updateStatus(ctx, tx, domain.ID, "domains", "revoked", req.UserID); err != nil {
+			return err
+		}
+		return s.writeEvent(ctx, tx, domain.ID, "revoked",
+			fmt.Sprintf("Certificate %s of '%s' revoked", safeSerial(active.SerialNumber), domain.DomainName), req.UserID)
+	})
+}
+
+func safeSerial(serial *string) string {
+	if serial == nil || *serial == "" {
+		return "(unknown serial)"
+	}
+	return *serial
+}
+
 func (s *Service) reloadNginxInContainer(domain models.DomainsDTO) error {
 	if domain.Details.NginxContainerName == "" {
 		return nil
 	}
 
-	cmd := exec.Command("docker", "exec", domain.Details.NginxContainerName, "nginx", "-s", "reload")
-	out, err := cmd.CombinedOutput()
-
+	_, err := s.dockerClient().Exec(s.ctx, domain.Details.NginxContainerName, []string{"nginx", "-s", "reload"})
 	if err != nil {
-		s.log.Error("Docker nginx reload error:", string(out))
+		s.log.Error("Docker nginx reload error: ", err)
 		return fmt.Errorf("failed to reload nginx inside container: %w", err)
 	}
 
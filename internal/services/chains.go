@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SwitchCertificateChain makes another stored chain of a certificate the one bundled in its files,
+// e.g. the alternate chain to an older root, without ordering a new certificate
+func (s *Service) SwitchCertificateChain(req models.SwitchChainReq) error {
+	s.log.Info("Switching chain of certificate ", req.CertificateID, " of domain ", req.DomainName, " to ", req.Chain)
+	if err := validateSwitchChain(&req); err != nil {
+		return err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	domain, err := s.getDomainByName(ctx, req.DomainName)
+	if err != nil {
+		return err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return fmt.Errorf("domain '%s' is monitored, it has no certificate files", req.DomainName)
+	}
+
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return fmt.Errorf("get certificates: %w", err)
+	}
+	idx := slices.IndexFunc(certs, func(c models.CertsDTO) bool { return c.ID == req.CertificateID })
+	if idx < 0 {
+		return models.WithCode(models.CodeNotFound, fmt.Errorf("certificate '%s' doesn't belong to domain '%s'", req.CertificateID, req.DomainName))
+	}
+	cert := certs[idx]
+	if !slices.Contains(chainNames(cert.Chains), req.Chain) {
+		var verr models.ValidationError
+		verr.Add("chain", "certificate %s has no chain %q, stored are %v", cert.ID, req.Chain, chainNames(cert.Chains))
+		return verr.Err()
+	}
+	if cert.ActiveChain == req.Chain {
+		return nil
+	}
+
+	store := s.certStore()
+	if err := store.SwitchChain(domain.DomainName, cert.CertPath, req.Chain); err != nil {
+		return fmt.Errorf("switch chain: %w", err)
+	}
+	// the files of the primary certificate are the current ones, nginx picks up the new chain on reload
+	if cert.Primary {
+		err = s.reloadNginxInContainer(domain)
+	}
+	if err == nil {
+		err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+			err := s.repository.UpdateTx(ctx, tx, NewEntity("certificates", map[string]any{
+				"active_chain": req.Chain,
+			}), cert.ID)
+			if err != nil {
+				return fmt.Errorf("update active chain: %w", err)
+			}
+			return s.writeEvent(ctx, tx, domain.ID, "chain_switched",
+				fmt.Sprintf("Certificate %s of '%s' switched from chain %s to %s", cert.ID, domain.DomainName, cert.ActiveChain, req.Chain), req.UserID)
+		})
+	}
+	if err != nil {
+		// the files follow the database, the previous chain is bundled again
+		if rollbackErr := store.SwitchChain(domain.DomainName, cert.CertPath, cert.ActiveChain); rollbackErr != nil {
+			s.log.Error("Failed to restore chain ", cert.ActiveChain, " of ", domain.DomainName, ": ", rollbackErr)
+		} else if cert.Primary {
+			if reloadErr := s.reloadNginxInContainer(domain); reloadErr != nil {
+				s.log.Error("nginx reload after restoring the chain of ", domain.DomainName, " failed: ", reloadErr)
+			}
+		}
+		return err
+	}
+	if cert.Primary {
+		s.publishCertificate(ctx, domain.ID, domain.DomainName, domain.Tags, req.UserID)
+	}
+	return nil
+}
+
+// keepChain bundles the renewed certificate with the chain to the root the active certificate of its
+// key type was switched to, when the CA still offers it. Returns the chain bundled
+func (s *Service) keepChain(ctx context.Context, domain models.DomainsDTO, certData *models.CertificateData, certPath string) string {
+	if len(certData.ChainRoots) == 0 {
+		return models.DefaultChain
+	}
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		s.log.Warn("Chain of ", domain.DomainName, " not kept, the default chain is used: ", err)
+		return models.DefaultChain
+	}
+	for _, c := range certs {
+		if !c.Active || c.KeyType != certData.KeyType || c.ActiveChain == models.DefaultChain {
+			continue
+		}
+		n := slices.Index(chainNames(c.Chains), c.ActiveChain)
+		if n < 0 {
+			break
+		}
+		root := c.Chains[n]
+		idx := slices.Index(certData.ChainRoots, root)
+		if idx <= 0 {
+			s.log.Warn("Chain to ", root, " of ", domain.DomainName, " isn't offered anymore, the default chain is used")
+			break
+		}
+		if err := s.certStore().SwitchChain(domain.DomainName, certPath, models.ChainName(idx)); err != nil {
+			s.log.Warn("Chain to ", root, " of ", domain.DomainName, " not kept, the default chain is used: ", err)
+			break
+		}
+		return models.ChainName(idx)
+	}
+	return models.DefaultChain
+}
+
+// chainNames names the stored chains of a certificate, default first
+func chainNames(roots []string) []string {
+	names := make([]string, 0, len(roots))
+	for n := range roots {
+		names = append(names, models.ChainName(n))
+	}
+	return names
+}
+
+// chainRoots are stored in certificates.chains, which is never NULL
+func chainRoots(certData *models.CertificateData) []string {
+	if certData.ChainRoots == nil {
+		return []string{}
+	}
+	return certData.ChainRoots
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+)
+
+// replicas coordinate through leases in Postgres only, no state is kept between requests
+
+// runCycle runs a scheduler cycle unless it is paused or another replica is running it
+func (s *Service) runCycle(job string, fn func()) {
+	if s.pausedForMaintenance(job) {
+		return
+	}
+	acquired, err := s.repository.TryLock(repositories.WithSystemScope(s.ctx), "cycle:"+job, func() error {
+		s.log.Info("Running ", job, " cycle...")
+		fn()
+		return nil
+	})
+	if err != nil {
+		s.log.Error("failed to run ", job, " cycle: ", err)
+		return
+	}
+	if !acquired {
+		s.log.Info("The ", job, " cycle is running on another replica, skipped")
+	}
+}
+
+// withIssuanceLock keeps two replicas from ordering a certificate for the same domain at once
+func (s *Service) withIssuanceLock(ctx context.Context, domain string, fn func() error) error {
+	acquired, err := s.repository.TryLock(ctx, "issue:"+domain, fn)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return models.WithCode(models.CodeIssuanceInProgress,
+			fmt.Errorf("a certificate for '%s' is already being issued", domain))
+	}
+	return nil
+}
+
+// RunRenewalCycle runs the renewal cycle the way the scheduler does, used by `renew --all`
+func (s *Service) RunRenewalCycle() {
+	s.runCycle("renewal", s.RenewExpiringCertificates)
+}
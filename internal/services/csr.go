@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"slices"
+	"strings"
+)
+
+// IssueFromCSR completes the challenges for the names of a CSR and returns the certificate chain,
+// the private key never reaches Hephaestus. Nothing is added to the inventory, renewals are up to
+// the requester with a new CSR
+func (s *Service) IssueFromCSR(req models.IssueFromCSRReq) (models.IssuedCertificate, error) {
+	s.log.Debug("IssueFromCSR(): called")
+	csr, names, err := validateIssueFromCSR(&req)
+	if err != nil {
+		return models.IssuedCertificate{}, err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	if err := s.checkOwnedNames(ctx, names); err != nil {
+		return models.IssuedCertificate{}, err
+	}
+
+	provider, err := s.selectProvider(req.DNSProvider)
+	if err != nil {
+		return models.IssuedCertificate{}, fmt.Errorf("select DNS provider: %w", err)
+	}
+	if err := s.checkNameservers(ctx, provider, names...); err != nil {
+		return models.IssuedCertificate{}, err
+	}
+
+	var certData *models.CertificateData
+	err = s.withIssuanceLock(ctx, names[0], func() error {
+		return s.withRateLimitBudget(ctx, provider, names, false, func() error {
+			certData, err = s.acmeIssuer().ObtainForCSR(provider, csr, req.Profile)
+			return err
+		})
+	})
+	if err != nil {
+		s.log.Error("CSR certificate creation failed:", err)
+		_ = s.safeWriteEvent(ctx, req.UserID, "", "failed",
+			fmt.Sprintf("Certificate creation from CSR for %s failed: %v", strings.Join(names, ", "), err))
+		return models.IssuedCertificate{}, fmt.Errorf("certificate creation failed: %w", err)
+	}
+
+	s.safeWriteEvent(ctx, req.UserID, "", "csr_issued",
+		fmt.Sprintf("Certificate %s issued from CSR for %s through '%s'", certData.SerialNumber, strings.Join(names, ", "), provider.Name))
+
+	return models.IssuedCertificate{
+		Domains:      names,
+		SerialNumber: certData.SerialNumber,
+		Fingerprint:  certData.Fingerprint,
+		Issuer:       certData.Issuer,
+		ValidFrom:    certData.ValidFrom,
+		ValidTo:      certData.ValidTo,
+		Certificate:  string(certData.Cert),
+		Chain:        string(certData.Chain),
+	}, nil
+}
+
+// checkOwnedNames accepts names only when each is covered by a domain of the tenant and by no domain
+// of another one, a CSR can't be used to obtain certificates for names the tenant doesn't hold
+func (s *Service) checkOwnedNames(ctx context.Context, names []string) error {
+	covering, err := s.repository.GetCoveringDomains(ctx, names)
+	if err != nil {
+		return fmt.Errorf("check CSR names: %w", err)
+	}
+
+	owned := make(map[string]bool, len(names))
+	conflict := &models.ConflictError{}
+	for _, c := range covering {
+		if c.CoveredBy == "" {
+			conflict.Conflicts = append(conflict.Conflicts, models.DomainConflict{Domain: c.Name})
+			continue
+		}
+		owned[c.Name] = true
+	}
+	if len(conflict.Conflicts) > 0 {
+		return conflict
+	}
+	for _, name := range names {
+		if !owned[name] {
+			return models.WithCode(models.CodeDomainNotFound,
+				fmt.Errorf("name '%s' isn't covered by any of your domains, add it as a domain or alternative domain first", name))
+		}
+	}
+	return nil
+}
+
+// parseCSR decodes a PEM encoded CSR and checks its signature
+func parseCSR(data string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST" {
+		return nil, errors.New("must be a PEM encoded CERTIFICATE REQUEST")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSR: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid CSR signature: %w", err)
+	}
+	return csr, nil
+}
+
+// csrNames lists the names of the CSR normalized like domains, the common name first
+func csrNames(csr *x509.CertificateRequest) ([]string, error) {
+	if len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return nil, errors.New("only DNS names can be validated over DNS-01")
+	}
+	raw := csr.DNSNames
+	if csr.Subject.CommonName != "" {
+		raw = append([]string{csr.Subject.CommonName}, raw...)
+	}
+	var names []string
+	for _, name := range raw {
+		normalized, err := utils.NormalizeDomainName(name)
+		if err != nil {
+			return nil, fmt.Errorf("name %q: %w", name, err)
+		}
+		// lego orders for exactly the CSR's names, punycode has to be in the CSR already
+		if normalized != strings.ToLower(name) {
+			return nil, fmt.Errorf("name %q must be lowercase ASCII (punycode), e.g. %q", name, normalized)
+		}
+		if !slices.Contains(names, normalized) {
+			names = append(names, normalized)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("has no DNS names")
+	}
+	return names, nil
+}
@@ -1,24 +1,50 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 func (s *Service) GetDomains(filters models.GetDomainsReq) (models.GetDomainsResp, error) {
 	s.log.Debug("Fetching list of domains started............")
+	if err := validateGetDomains(&filters); err != nil {
+		return models.GetDomainsResp{}, err
+	}
+	ctx := repositories.WithTenant(s.ctx, filters.TenantID)
 	offset := (filters.Page - 1) * filters.PageSize
 	repoFilters := models.DomainsFilters{
 		DomainName: filters.DomainName,
+		Fuzzy:      filters.Fuzzy,
 		Status:     filters.Status,
 		UserID:     filters.UserID,
+		Tags:       filters.Tags,
 		Limit:      &filters.PageSize,
 		Offset:     &offset,
 	}
+	// a Unicode search also finds names stored before their Unicode form was
+	if punycode, ok := utils.PunycodeTerm(filters.DomainName); ok {
+		repoFilters.PunycodeName = punycode
+	}
+
+	// keyset pagination: fetch one extra row to know whether there is a next page
+	limit := filters.PageSize + 1
+	if filters.Cursor != nil {
+		repoFilters.Cursor = filters.Cursor
+		repoFilters.Limit = &limit
+		repoFilters.Offset = nil
+	}
 
 	s.log.Debug("Fetching stocks count from repo...")
-	totalElements, err := s.repository.GetDomainsCount(s.ctx, repoFilters)
+	totalElements, err := s.repository.GetDomainsCount(ctx, repoFilters)
 	if err != nil {
 		s.log.Error("Error while getting total stock count: ", err)
 		return models.GetDomainsResp{}, err
@@ -38,16 +64,42 @@ func (s *Service) GetDomains(filters models.GetDomainsReq) (models.GetDomainsRes
 	}
 
 	s.log.Debug("Fetching list of domains from repo...")
-	domains, err := s.repository.GetDomainsList(s.ctx, repoFilters)
+	domains, err := s.repository.GetDomainsList(ctx, repoFilters)
 	if err != nil {
 		s.log.Error("Error while getting list of domains: ", err)
 		return models.GetDomainsResp{}, err
 	}
 	s.log.Debug("List of domains: ", domains)
 
+	nextCursor := ""
+	if filters.Cursor != nil {
+		hasPrev = true
+		hasNext = len(domains) > filters.PageSize
+		nextPage, prevPage = 0, 0
+		if hasNext {
+			domains = domains[:filters.PageSize]
+			last := domains[len(domains)-1]
+			nextCursor = models.EncodeDomainsCursor(models.DomainsCursor{
+				CreatedAt: last.Details.CreatedAt,
+				ID:        last.ID,
+			})
+		}
+	} else if hasNext && len(domains) > 0 {
+		// lets offset clients switch to keyset pagination from any page
+		last := domains[len(domains)-1]
+		nextCursor = models.EncodeDomainsCursor(models.DomainsCursor{
+			CreatedAt: last.Details.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
 	var d []models.Domains
 	for _, domain := range domains {
-		d = append(d, models.ConvertDomainsDTOToDomains(domain))
+		converted := models.ConvertDomainsDTOToDomains(domain)
+		if unicode := utils.UnicodeDomainName(domain.DomainName); unicode != domain.DomainName {
+			converted.UnicodeName = unicode
+		}
+		d = append(d, converted)
 	}
 
 	return models.GetDomainsResp{
@@ -59,244 +111,493 @@ func (s *Service) GetDomains(filters models.GetDomainsReq) (models.GetDomainsRes
 		HasPrev:       hasPrev,
 		NextPage:      nextPage,
 		PrevPage:      prevPage,
+		NextCursor:    nextCursor,
 		Domains:       d,
 	}, nil
 }
 
 func (s *Service) CreateDomain(req models.CreateDomainReq) (domainID string, err error) {
 	s.log.Debug("CreateDomain: start")
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	if err := validateCreateDomain(&req); err != nil {
+		return "", err
+	}
+	if err := s.validateCAKeyType(req.KeyType); err != nil {
+		return "", err
+	}
+	if err := s.validateMonitorAddress(req); err != nil {
+		return "", err
+	}
+	if err := s.checkCoveredNames(ctx, req.AltDomains); err != nil {
+		return "", err
+	}
+
+	// the existence check runs under the lock too, two replicas creating the same domain would both pass it
+	err = s.withIssuanceLock(ctx, req.Domain, func() error {
+		domainID, err = s.createDomain(ctx, req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return domainID, nil
+}
 
-	exists, err := s.repository.IsDomainExists(s.ctx, req.Domain)
+func (s *Service) createDomain(ctx context.Context, req models.CreateDomainReq) (domainID string, err error) {
+	exists, err := s.repository.IsDomainExists(ctx, req.Domain)
 	if err != nil {
 		return "", fmt.Errorf("check domain exists: %w", err)
 	}
+	revive := false
 	if exists {
-		return "", fmt.Errorf("domain already exists")
+		deleted, err := s.repository.IsDomainDeleted(ctx, req.Domain)
+		if err != nil {
+			return "", fmt.Errorf("check domain deleted: %w", err)
+		}
+		if !deleted {
+			return "", errDomainTaken
+		}
+		if !req.Revive {
+			return "", models.WithCode(models.CodeDomainExists, errors.New("domain already exists as deleted, set revive to re-create it"))
+		}
+		revive = true
+	}
+
+	if req.Kind == models.DomainKindMonitored {
+		return s.createMonitoredDomain(ctx, req, revive)
 	}
 
-	client, err := s.SelectClientByName(req.DNSProvider)
+	provider, err := s.selectProvider(req.DNSProvider)
 	if err != nil {
-		return "", fmt.Errorf("select DNS client: %w", err)
+		return "", fmt.Errorf("select DNS provider: %w", err)
+	}
+	// the domain records the provider actually used, also when default_provider was applied
+	req.DNSProvider = provider.Name
+
+	if req.DeferIssuance {
+		return s.createPendingDomain(ctx, req, revive)
 	}
 
-	certData, err := client.CreateCertificate(req.Domain, req.AltDomains)
+	names := append([]string{req.Domain}, req.AltDomains...)
+	if err := s.checkNameservers(ctx, provider, names...); err != nil {
+		_ = s.safeWriteEvent(ctx, req.CreatedBy, "", "failed",
+			fmt.Sprintf("Certificate creation failed: %v", err))
+		return "", err
+	}
+
+	var certData *models.CertificateData
+	err = s.withRateLimitBudget(ctx, provider, names, false, func() error {
+		certData, err = s.acmeIssuer().Obtain(provider, req.Domain, req.AltDomains, req.KeyType, req.Profile, nil)
+		return err
+	})
 	if err != nil {
 		s.log.Error("certificate creation failed:", err)
-		_ = s.safeWriteEvent(req.CreatedBy, "", "failed",
+		_ = s.safeWriteEvent(ctx, req.CreatedBy, "", "failed",
 			fmt.Sprintf("Certificate creation failed: %v", err))
 		return "", fmt.Errorf("certificate creation failed: %w", err)
 	}
 
-	certPaths, err := client.SaveCertificateFiles(req.Domain, certData)
+	certData.OutputFormats = s.outputFormats(req.OutputFormats)
+	certPaths, err := s.certStore().Save(req.Domain, certData)
 	if err != nil {
 		s.log.Error("saving certificate files failed:", err)
-		_ = s.safeWriteEvent(req.CreatedBy, "", "failed",
+		_ = s.safeWriteEvent(ctx, req.CreatedBy, "", "failed",
 			fmt.Sprintf("Saving certificate files failed: %v", err))
 		return "", fmt.Errorf("save certificate files: %w", err)
 	}
 
-	tx, err := s.repository.BeginTx(s.ctx)
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		return s.insertDomainTx(ctx, tx, req, revive, certData, certPaths, &domainID)
+	})
 	if err != nil {
-		return "", fmt.Errorf("begin tx: %w", err)
+		s.log.Warn("CreateDomain: rolled back due to error:", err)
+		// the domain row was rolled back, so the event can't reference it
+		_ = s.safeWriteEvent(ctx, req.CreatedBy, "", "failed",
+			fmt.Sprintf("Domain '%s' creation failed: %v", req.Domain, err))
+		return "", err
 	}
 
-	defer func() {
-		if err != nil {
-			s.log.Warn("CreateDomain: rollback due to error:", err)
-			_ = tx.Rollback(s.ctx)
-
-			_ = s.safeWriteEvent(
-				req.CreatedBy,
-				domainID,
-				"failed",
-				fmt.Sprintf("Domain creation failed: %v", err),
-			)
+	_ = s.safeWriteEvent(
+		ctx,
+		req.CreatedBy,
+		domainID,
+		"created",
+		"Domain and certificate created successfully",
+	)
+	s.publishCertificate(ctx, domainID, req.Domain, normalizeTags(req.Tags), req.CreatedBy)
+
+	s.log.Debug("CreateDomain: success")
+	return domainID, nil
+}
+
+// createPendingDomain stores the domain without a certificate, the renewal cycle issues it
+func (s *Service) createPendingDomain(ctx context.Context, req models.CreateDomainReq, revive bool) (domainID string, err error) {
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.insertDomainTx(ctx, tx, req, revive, nil, nil, &domainID); err != nil {
+			return err
 		}
-	}()
+		return s.writeEvent(ctx, tx, domainID, "created", "Domain created, certificate issuance deferred", req.CreatedBy)
+	})
+	if err != nil {
+		s.log.Warn("CreateDomain: rolled back due to error:", err)
+		return "", err
+	}
+	return domainID, nil
+}
+
+// checkCoveredNames rejects alternative domains another active domain already has a certificate for
+func (s *Service) checkCoveredNames(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	covering, err := s.repository.GetCoveringDomains(ctx, names)
+	if err != nil {
+		return fmt.Errorf("check alternative domains: %w", err)
+	}
+	if len(covering) == 0 {
+		return nil
+	}
+
+	conflict := &models.ConflictError{}
+	for _, c := range covering {
+		conflict.Conflicts = append(conflict.Conflicts, models.DomainConflict{Domain: c.Name, CoveredBy: c.CoveredBy})
+	}
+	return conflict
+}
 
+// altDomainConflict is an alternative domain another domain holds, checkCoveredNames reports the ones
+// known before the transaction, this the ones taken meanwhile
+func altDomainConflict(name string) error {
+	if name == "" {
+		return models.WithCode(models.CodeDomainConflict, errors.New("an alternative domain belongs to another domain"))
+	}
+	return models.WithCode(models.CodeDomainConflict, fmt.Errorf("alternative domain '%s' belongs to another domain", name))
+}
+
+// isUniqueViolation tells whether err is a unique constraint violation of postgres
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// insertDomainTx stores a domain with its alternative domains and issued certificate
+func (s *Service) insertDomainTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	req models.CreateDomainReq,
+	revive bool,
+	certData *models.CertificateData,
+	certPaths *models.CertificatePaths,
+	domainID *string,
+) (err error) {
 	domainEntity := NewEntity("domains", map[string]any{
 		"domain_name":          req.Domain,
+		"unicode_name":         utils.UnicodeDomainName(req.Domain),
 		"dns_provider":         req.DNSProvider,
 		"status":               "pending",
 		"verification_method":  req.VerificationMethod,
 		"nginx_container_name": req.NginxContainerName,
 		"created_by":           req.CreatedBy,
 		"auto_renew":           req.AutoRenew,
+		"tags":                 normalizeTags(req.Tags),
+		"metadata":             req.Metadata,
+		"kind":                 models.DomainKindManaged,
+		"wildcard":             hasWildcard(&req),
+		"reuse_key":            req.ReuseKey,
 	})
+	if req.Notes != "" {
+		domainEntity.StringParameters["notes"] = req.Notes
+	}
+	if req.KeyType != "" {
+		domainEntity.StringParameters["key_type"] = req.KeyType
+	}
+	if req.Profile != "" {
+		domainEntity.StringParameters["profile"] = req.Profile
+	}
+	if len(req.OutputFormats) > 0 {
+		domainEntity.ArrayParameters["output_formats"] = req.OutputFormats
+	}
 
-	domainID, err = s.repository.InsertTx(s.ctx, tx, domainEntity)
+	if revive {
+		// the soft-deleted row keeps the unique domain_name, so it is brought back instead
+		domainEntity.NullParameters = []string{"deleted_at", "deleted_by"}
+		// a revived domain doesn't keep the key type, profile and output formats it was deleted with
+		if req.KeyType == "" {
+			domainEntity.NullParameters = append(domainEntity.NullParameters, "key_type")
+		}
+		if req.Profile == "" {
+			domainEntity.NullParameters = append(domainEntity.NullParameters, "profile")
+		}
+		if len(req.OutputFormats) == 0 {
+			domainEntity.NullParameters = append(domainEntity.NullParameters, "output_formats")
+		}
+		*domainID, err = s.repository.UpsertTx(ctx, tx, domainEntity, models.UpsertOptions{
+			ConflictColumns: []string{"domain_name"},
+			OnlyDeleted:     true,
+		})
+	} else {
+		*domainID, err = s.repository.InsertTx(ctx, tx, domainEntity)
+	}
+	// the row was taken meanwhile, by a create of the same name or the revive of another tenant's domain
+	if errors.Is(err, pgx.ErrNoRows) || isUniqueViolation(err) {
+		return errDomainTaken
+	}
 	if err != nil {
-		return "", fmt.Errorf("insert domain: %w", err)
+		return fmt.Errorf("insert domain: %w", err)
 	}
 
+	subEntities := make([]models.Entity, 0, len(req.AltDomains))
 	for _, sub := range req.AltDomains {
-		subEntity := NewEntity("alternative_domains", map[string]any{
-			"domain_id":   domainID,
-			"domain_name": sub,
-			"created_by":  req.CreatedBy,
-		})
+		subEntities = append(subEntities, NewEntity("alternative_domains", map[string]any{
+			"domain_id":    *domainID,
+			"domain_name":  sub,
+			"unicode_name": utils.UnicodeDomainName(sub),
+			"created_by":   req.CreatedBy,
+		}))
+	}
 
-		_, err = s.repository.InsertTx(s.ctx, tx, subEntity)
-		if err != nil {
-			return "", fmt.Errorf("insert alt domain '%s': %w", sub, err)
+	if revive {
+		// alternative domains left over from deleted domains are taken over, active ones still conflict
+		for _, subEntity := range subEntities {
+			subEntity.NullParameters = []string{"deleted_at", "deleted_by"}
+			_, err = s.repository.UpsertTx(ctx, tx, subEntity, models.UpsertOptions{
+				ConflictColumns: []string{"domain_name"},
+				OnlyDeleted:     true,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return altDomainConflict(subEntity.StringParameters["domain_name"])
+			}
+			if err != nil {
+				return fmt.Errorf("upsert alt domain '%s': %w", subEntity.StringParameters["domain_name"], err)
+			}
 		}
+	} else if _, err = s.insertMany(ctx, tx, subEntities); err != nil {
+		if isUniqueViolation(err) {
+			return altDomainConflict("")
+		}
+		return fmt.Errorf("insert alt domains: %w", err)
+	}
+
+	// deferred domains stay pending without a certificate
+	if certData == nil {
+		return nil
 	}
 
 	certEntity := NewEntity("certificates", map[string]any{
-		"domain_id":  domainID,
-		"issuer":     "Let's Encrypt",
-		"cert_path":  certPaths.Cert,
-		"key_path":   certPaths.Key,
-		"chain_path": certPaths.Chain,
-		"created_by": req.CreatedBy,
-		"valid_from": certData.ValidFrom,
-		"valid_to":   certData.ValidTo,
+		"domain_id":     *domainID,
+		"issuer":        certData.Issuer,
+		"cert_path":     certPaths.Cert,
+		"key_path":      certPaths.Key,
+		"chain_path":    certPaths.Chain,
+		"serial_number": certData.SerialNumber,
+		"fingerprint":   certData.Fingerprint,
+		"key_type":      certData.KeyType,
+		"chains":        chainRoots(certData),
+		"created_by":    req.CreatedBy,
+		"valid_from":    certData.ValidFrom,
+		"valid_to":      certData.ValidTo,
 	})
 
-	_, err = s.repository.InsertTx(s.ctx, tx, certEntity)
-	if err != nil {
-		return "", fmt.Errorf("insert certificate: %w", err)
+	if _, err := s.storeCertificateTx(ctx, tx, *domainID, certEntity, models.ReplaceKeyType); err != nil {
+		return err
 	}
 
-	err = s.updateMany(s.ctx, tx, map[string]models.Entity{
-		domainID: NewEntity("domains", map[string]any{
+	err = s.updateMany(ctx, tx, map[string]models.Entity{
+		*domainID: NewEntity("domains", map[string]any{
 			"status":     "active",
 			"updated_by": req.CreatedBy,
 		}),
 	})
 	if err != nil {
-		return "", fmt.Errorf("update domain status: %w", err)
-	}
-
-	// Commit
-	if err = tx.Commit(s.ctx); err != nil {
-		return "", fmt.Errorf("commit tx: %w", err)
+		return fmt.Errorf("update domain status: %w", err)
 	}
 
-	_ = s.safeWriteEvent(
-		req.CreatedBy,
-		domainID,
-		"created",
-		"Domain and certificate created successfully",
-	)
-
-	s.log.Debug("CreateDomain: success")
-	return domainID, nil
+	return nil
 }
 
-func (s *Service) DeleteDomain(filters models.DeleteDomainReq) (err error) {
-	s.log.Debug("Deleting domain...")
-
-	// start transaction
-	tx, err := s.repository.BeginTx(s.ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+func (s *Service) UpdateDomain(req models.UpdateDomainReq) error {
+	s.log.Debug("UpdateDomain: start")
+	if err := validateUpdateDomain(&req); err != nil {
+		return err
 	}
-
-	// rollback on error
-	defer func() {
-		if err != nil {
-			s.log.Warn("Rollback started")
-			if rollbackErr := tx.Rollback(s.ctx); rollbackErr != nil {
-				s.log.Error("Rollback error:", rollbackErr)
-			}
+	req.DomainName = lookupName(req.DomainName)
+	if req.KeyType != nil {
+		if err := s.validateCAKeyType(*req.KeyType); err != nil {
+			return err
 		}
-	}()
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
 
-	// determine domain ID
-	domainID := filters.DomainID
-	if filters.DomainName != "" {
-		domainID, err = s.repository.GetIDByNameTx(s.ctx, tx, models.Entity{
-			EntityName:       "domains",
-			StringParameters: map[string]string{"domain_name": filters.DomainName},
-		})
-		if err != nil {
-			return fmt.Errorf("error while getting domain id: %w", err)
+	entity := NewEntity("domains", map[string]any{
+		"updated_by": req.UpdatedBy,
+	})
+	if req.AutoRenew != nil {
+		entity.BoolParameters["auto_renew"] = *req.AutoRenew
+	}
+	if req.NginxContainerName != nil {
+		entity.StringParameters["nginx_container_name"] = *req.NginxContainerName
+	}
+	if req.Tags != nil {
+		entity.ArrayParameters["tags"] = normalizeTags(req.Tags)
+	}
+	if req.Metadata != nil {
+		entity.JSONParameters["metadata"] = req.Metadata
+	}
+	if req.Notes != nil {
+		if *req.Notes == "" {
+			entity.NullParameters = append(entity.NullParameters, "notes")
+		} else {
+			entity.StringParameters["notes"] = *req.Notes
 		}
-		if domainID == "" {
-			return fmt.Errorf("domain doesn't exist")
+	}
+	if req.KeyType != nil {
+		if *req.KeyType == "" {
+			entity.NullParameters = append(entity.NullParameters, "key_type")
+		} else {
+			entity.StringParameters["key_type"] = *req.KeyType
 		}
 	}
-
-	// fetch subdomains
-	subDomains, err := s.repository.GetListOfSubDomains(s.ctx, domainID)
-	if err != nil {
-		return fmt.Errorf("error getting subdomains: %w", err)
+	if req.Profile != nil {
+		if *req.Profile == "" {
+			entity.NullParameters = append(entity.NullParameters, "profile")
+		} else {
+			entity.StringParameters["profile"] = *req.Profile
+		}
+	}
+	if req.ReuseKey != nil {
+		entity.BoolParameters["reuse_key"] = *req.ReuseKey
+	}
+	if req.OutputFormats != nil {
+		if len(req.OutputFormats) == 0 {
+			entity.NullParameters = append(entity.NullParameters, "output_formats")
+		} else {
+			entity.ArrayParameters["output_formats"] = req.OutputFormats
+		}
 	}
 
-	now := time.Now()
+	return s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		// the lookup also checks that the domain belongs to the tenant and isn't deleted
+		lookup := map[string]string{"id": req.DomainID}
+		if req.DomainName != "" {
+			lookup = map[string]string{"domain_name": req.DomainName}
+		}
+		domainID, err := s.repository.GetIDByNameTx(ctx, tx, models.Entity{
+			EntityName:       "domains",
+			StringParameters: lookup,
+		})
+		if err != nil || domainID == "" {
+			return errDomainNotFound
+		}
 
-	// prepare update data for domains
-	updateDomains := make(map[string]models.Entity, 1+len(subDomains))
+		if err := s.repository.UpdateTx(ctx, tx, entity, domainID); err != nil {
+			return fmt.Errorf("update domain: %w", err)
+		}
 
-	// main domain
-	updateDomains[domainID] = NewEntity("domains", map[string]any{
-		"status":     "deleted",
-		"deleted_by": filters.UserID,
-		"updated_by": filters.UserID,
-		"deleted_at": now,
+		if err := s.writeEvent(ctx, tx, domainID, "updated", "Domain details updated", req.UpdatedBy); err != nil {
+			return fmt.Errorf("error inserting event: %w", err)
+		}
+		return nil
 	})
+}
 
-	// subdomains
-	for _, id := range subDomains {
-		updateDomains[id] = NewEntity("alternative_domains", map[string]any{
-			"status":     "deleted",
-			"deleted_by": filters.UserID,
-			"updated_by": filters.UserID,
-			"deleted_at": now,
-		})
-	}
-
-	if err = s.updateMany(s.ctx, tx, updateDomains); err != nil {
-		return fmt.Errorf("error updating domain statuses: %w", err)
-	}
-
-	// fetch certificates
-	certs, err := s.repository.GetCertificatesByDomain(s.ctx, domainID)
-	if err != nil {
-		return fmt.Errorf("error fetching certificates: %w", err)
+func (s *Service) DeleteDomain(filters models.DeleteDomainReq) (err error) {
+	s.log.Debug("Deleting domain...")
+	if err := validateDeleteDomain(&filters); err != nil {
+		return err
 	}
+	filters.DomainName = lookupName(filters.DomainName)
+	ctx := repositories.WithTenant(s.ctx, filters.TenantID)
+
+	var domainName string
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		// determine domain ID
+		domainID := filters.DomainID
+		if filters.DomainName != "" {
+			domainID, err = s.repository.GetIDByNameTx(ctx, tx, models.Entity{
+				EntityName:       "domains",
+				StringParameters: map[string]string{"domain_name": filters.DomainName},
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errDomainNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("error while getting domain id: %w", err)
+			}
+			if domainID == "" {
+				return errDomainNotFound
+			}
+		}
 
-	// mark certificates as deleted
-	if certs.ID != "" {
-		err = s.updateMany(s.ctx, tx, map[string]models.Entity{
-			certs.ID: NewEntity("certificates", map[string]any{
-				"deleted_by": filters.UserID,
-				"updated_by": filters.UserID,
-				"deleted_at": now,
-			}),
-		})
+		// domain, alternative domains and certificates are marked together
+		domainName, err = s.repository.SoftDeleteDomainTx(ctx, tx, domainID, filters.UserID, time.Now())
 		if err != nil {
-			return fmt.Errorf("error updating certificate: %w", err)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errDomainNotFound
+			}
+			return fmt.Errorf("error deleting domain: %w", err)
 		}
-	}
-
-	// write event
-	if err = s.writeEvent(
-		s.ctx, tx, domainID, "deleted",
-		fmt.Sprintf("Domain '%s' and its certificates deleted", filters.DomainName),
-		filters.UserID,
-	); err != nil {
-		return fmt.Errorf("error inserting event: %w", err)
-	}
 
-	// COMMIT TRANSACTION
-	if err = tx.Commit(s.ctx); err != nil {
-		return fmt.Errorf("commit error: %w", err)
+		// write event
+		if err = s.writeEvent(
+			ctx, tx, domainID, "deleted",
+			fmt.Sprintf("Domain '%s' and its certificates deleted", domainName),
+			filters.UserID,
+		); err != nil {
+			return fmt.Errorf("error inserting event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// delete files safely AFTER commit
 	go func(domain string) {
-		client, cErr := s.SelectClientByName("no")
-		if cErr != nil {
-			s.log.Warn("Error selecting client:", cErr)
-			return
-		}
-		if dErr := client.DeleteCertificateFiles(domain); dErr != nil {
+		if dErr := s.certStore().Delete(domain); dErr != nil {
 			s.log.Warn("Error deleting certificate files:", dErr)
 		}
-	}(filters.DomainName)
+	}(domainName)
 
 	s.log.Debug("Domain deleted successfully")
 	return nil
 }
+
+var errDomainNotFound = models.WithCode(models.CodeDomainNotFound, errors.New("domain doesn't exist"))
+
+// errDomainTaken is the answer for names held by the tenant or another one alike, domain names are
+// unique installation-wide and the answer mustn't tell other tenants' domains apart
+var errDomainTaken = models.WithCode(models.CodeDomainExists, errors.New("domain name is not available"))
+
+// getDomainByName returns the domain with exactly this name, alternative domains don't match
+func (s *Service) getDomainByName(ctx context.Context, name string) (models.DomainsDTO, error) {
+	name = lookupName(name)
+	domains, err := s.repository.GetDomainsList(ctx, models.DomainsFilters{DomainName: name})
+	if err != nil {
+		return models.DomainsDTO{}, fmt.Errorf("get domain: %w", err)
+	}
+	for _, d := range domains {
+		if strings.EqualFold(d.DomainName, name) {
+			return d, nil
+		}
+	}
+	return models.DomainsDTO{}, errDomainNotFound
+}
+
+// normalizeTags trims tags and drops empty and duplicated ones, keeping the original order
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"strings"
+	"time"
+)
+
+// CheckCertificateDrift compares the active certificates of a domain with the files deployment targets
+// read and the certificate the domain serves
+func (s *Service) CheckCertificateDrift(req models.CheckDriftReq) (models.CertificateDrift, error) {
+	s.log.Debug("Checking certificate drift of domain: ", req.DomainName)
+	if err := validateCheckDrift(&req); err != nil {
+		return models.CertificateDrift{}, err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	domain, err := s.getDomainByName(ctx, req.DomainName)
+	if err != nil {
+		return models.CertificateDrift{}, err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return models.CertificateDrift{}, fmt.Errorf("domain '%s' is monitored, its active certificate is the served one", req.DomainName)
+	}
+	return s.checkDrift(ctx, domain)
+}
+
+// DetectCertificateDrift checks every managed domain with a certificate, across all tenants,
+// a drift_detected event is written for every domain that drifted
+func (s *Service) DetectCertificateDrift() {
+	ctx := repositories.WithSystemScope(s.ctx)
+
+	domains, err := s.repository.GetDomainsList(ctx, models.DomainsFilters{Kind: models.DomainKindManaged})
+	if err != nil {
+		s.log.Error("failed fetch domains:", err)
+		return
+	}
+
+	for _, d := range domains {
+		if d.Details.CertValidTo == nil || d.Details.Status == "deleted" {
+			continue
+		}
+		domainCtx := repositories.WithTenant(s.ctx, d.TenantID)
+		drift, err := s.checkDrift(domainCtx, d)
+		if err != nil {
+			s.log.Error("Failed to check certificate drift of ", d.DomainName, ": ", err)
+			continue
+		}
+		if !drift.Drift {
+			continue
+		}
+
+		s.log.Warn("Certificate of ", d.DomainName, " drifted from ", drift.SerialNumber)
+		_ = s.safeWriteEvent(domainCtx, "system-drift", d.ID, "drift_detected", driftDetails(drift))
+	}
+}
+
+func (s *Service) checkDrift(ctx context.Context, domain models.DomainsDTO) (models.CertificateDrift, error) {
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return models.CertificateDrift{}, fmt.Errorf("get certificates: %w", err)
+	}
+	// every key type has its own active certificate, a dual RSA and ECDSA domain serves either of them
+	active := map[string]*models.CertsDTO{}
+	var primary *models.CertsDTO
+	for i := range certs {
+		if !certs[i].Active {
+			continue
+		}
+		active[certs[i].KeyType] = &certs[i]
+		if certs[i].Primary {
+			primary = &certs[i]
+		}
+	}
+	if primary == nil {
+		return models.CertificateDrift{}, models.WithCode(models.CodeNotFound,
+			fmt.Errorf("domain '%s' has no active certificate", domain.DomainName))
+	}
+
+	drift := models.CertificateDrift{
+		Domain:        domain.DomainName,
+		CertificateID: primary.ID,
+		SerialNumber:  safeDeref(primary.SerialNumber),
+		Fingerprint:   safeDeref(primary.Fingerprint),
+		CheckedAt:     time.Now(),
+	}
+
+	// the live files follow the primary certificate, the other key types are read through their current link
+	path, data, err := s.certStore().ReadLive(domain.DomainName)
+	drift.File = fileSource(path, data, err, active)
+	for _, c := range certs {
+		if !c.Active || c.Primary {
+			continue
+		}
+		path, data, err := s.certStore().ReadCurrent(domain.DomainName, c.KeyType)
+		drift.KeyTypes = append(drift.KeyTypes, fileSource(path, data, err, active))
+	}
+
+	// a wildcard has no host of its own to connect to
+	if !strings.HasPrefix(domain.DomainName, "*.") {
+		address := monitorAddress(domain.DomainName, "")
+		served := models.CertificateSource{Location: address}
+		cert, err := fetchServedCertificate(address, domain.DomainName, monitorTimeout(s.config().Monitor), false)
+		if err != nil {
+			served.Error = err.Error()
+		} else {
+			served.KeyType = cert.KeyType
+			served.SerialNumber = cert.SerialNumber
+			served.Fingerprint = cert.Fingerprint
+			compareSource(&served, active)
+		}
+		drift.Served = &served
+	}
+
+	// unreadable sources are reported but aren't a drift, the check is repeated on the next cycle
+	for _, source := range driftSources(drift) {
+		if source.Error == "" && !source.Match {
+			drift.Drift = true
+		}
+	}
+	return drift, nil
+}
+
+// fileSource parses a stored certificate and compares it with the active certificate of its key type
+func fileSource(path string, data []byte, err error, active map[string]*models.CertsDTO) models.CertificateSource {
+	source := models.CertificateSource{Location: path}
+	if err == nil {
+		var leaf *x509.Certificate
+		if leaf, err = utils.ParseLeafCertificate(data); err == nil {
+			source.KeyType = utils.CertificateKeyType(leaf)
+			source.SerialNumber = utils.CertificateSerial(leaf)
+			source.Fingerprint = utils.CertificateFingerprint(leaf)
+			compareSource(&source, active)
+		}
+	}
+	if err != nil {
+		source.Error = err.Error()
+	}
+	return source
+}
+
+// compareSource sets Match when source is the active certificate of its key type, a key type without
+// an active certificate is a drift
+func compareSource(source *models.CertificateSource, active map[string]*models.CertsDTO) {
+	cert, ok := active[source.KeyType]
+	if !ok {
+		return
+	}
+	source.Expected = safeDeref(cert.SerialNumber)
+	source.Match = sameCertificate(cert, source.SerialNumber, source.Fingerprint)
+}
+
+// sameCertificate compares fingerprints, certificates stored before fingerprints were recorded by serial
+func sameCertificate(cert *models.CertsDTO, serial, fingerprint string) bool {
+	if f := safeDeref(cert.Fingerprint); f != "" {
+		return strings.EqualFold(f, fingerprint)
+	}
+	stored := safeDeref(cert.SerialNumber)
+	return stored != "" && strings.EqualFold(stored, serial)
+}
+
+// driftSources lists every source a drift check read
+func driftSources(drift models.CertificateDrift) []*models.CertificateSource {
+	sources := []*models.CertificateSource{&drift.File}
+	for i := range drift.KeyTypes {
+		sources = append(sources, &drift.KeyTypes[i])
+	}
+	if drift.Served != nil {
+		sources = append(sources, drift.Served)
+	}
+	return sources
+}
+
+func driftDetails(drift models.CertificateDrift) string {
+	var found []string
+	for _, source := range driftSources(drift) {
+		if source.Error != "" || source.Match {
+			continue
+		}
+		if source.Expected == "" {
+			found = append(found, fmt.Sprintf("%s has %s, a %s certificate without an active one", source.Location, source.SerialNumber, source.KeyType))
+			continue
+		}
+		found = append(found, fmt.Sprintf("%s has %s instead of %s", source.Location, source.SerialNumber, source.Expected))
+	}
+	return fmt.Sprintf("Active certificates of '%s' drifted: %s", drift.Domain, strings.Join(found, " and "))
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"slices"
+	"strings"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// ExportCertificate bundles a stored certificate with its private key and chain, as PEM or as a password
+// protected PKCS#12 file. Every export writes an exported event, the private key left Hephaestus
+func (s *Service) ExportCertificate(req models.ExportCertificateReq) (models.CertificateExport, error) {
+	s.log.Info("Exporting certificate of domain ", req.DomainName, " as ", req.Format)
+	if err := validateExportCertificate(&req); err != nil {
+		return models.CertificateExport{}, err
+	}
+	ctx := repositories.WithTenant(s.ctx, req.TenantID)
+
+	domain, err := s.getDomainByName(ctx, req.DomainName)
+	if err != nil {
+		return models.CertificateExport{}, err
+	}
+	if domain.Details.Kind == models.DomainKindMonitored {
+		return models.CertificateExport{}, fmt.Errorf("domain '%s' is monitored, its private key isn't stored", req.DomainName)
+	}
+
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return models.CertificateExport{}, fmt.Errorf("get certificates: %w", err)
+	}
+	idx := slices.IndexFunc(certs, func(c models.CertsDTO) bool {
+		if req.CertificateID == "" {
+			return c.Primary
+		}
+		return c.ID == req.CertificateID
+	})
+	if idx < 0 {
+		if req.CertificateID == "" {
+			return models.CertificateExport{}, models.WithCode(models.CodeNotFound, fmt.Errorf("domain '%s' has no active certificate", req.DomainName))
+		}
+		return models.CertificateExport{}, models.WithCode(models.CodeNotFound, fmt.Errorf("certificate '%s' doesn't belong to domain '%s'", req.CertificateID, req.DomainName))
+	}
+	cert := certs[idx]
+	if cert.KeyPath == "" {
+		return models.CertificateExport{}, fmt.Errorf("certificate '%s' has no stored private key", cert.ID)
+	}
+
+	store := s.certStore()
+	certPEM, err := store.Read(cert.CertPath)
+	if err != nil {
+		return models.CertificateExport{}, err
+	}
+	keyPEM, err := store.Read(cert.KeyPath)
+	if err != nil {
+		return models.CertificateExport{}, fmt.Errorf("read private key: %w", err)
+	}
+
+	// wildcard names can't be file names on Windows
+	name := strings.ReplaceAll(domain.DomainName, "*", "_")
+	export := models.CertificateExport{
+		Filename:    name + ".pem",
+		ContentType: "application/x-pem-file",
+		Data:        append(keyPEM, certPEM...),
+	}
+	if req.Format == models.ExportFormatPKCS12 {
+		chain, err := utils.ParseCertificateChain(certPEM)
+		if err != nil {
+			return models.CertificateExport{}, fmt.Errorf("parse certificate: %w", err)
+		}
+		key, err := certcrypto.ParsePEMPrivateKey(keyPEM)
+		if err != nil {
+			return models.CertificateExport{}, fmt.Errorf("parse private key: %w", err)
+		}
+		data, err := utils.EncodePKCS12(key, chain[0], chain[1:], domain.DomainName, req.Password, req.Legacy)
+		if err != nil {
+			return models.CertificateExport{}, fmt.Errorf("encode pkcs12: %w", err)
+		}
+		export = models.CertificateExport{Filename: name + ".p12", ContentType: "application/x-pkcs12", Data: data}
+	}
+
+	_ = s.safeWriteEvent(ctx, req.UserID, domain.ID, "exported",
+		fmt.Sprintf("Certificate %s of '%s' exported with its private key as %s", cert.ID, domain.DomainName, req.Format))
+	return export, nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxImportRows keeps a single import within a reasonable request time
+const maxImportRows = 1000
+
+// importRow is a parsed row with its position in the source, Err is set when the row can't be parsed
+type importRow struct {
+	Row int
+	Req models.CreateDomainReq
+	Err error
+}
+
+// ImportDomains creates a domain for every row, a failed row doesn't stop the import
+func (s *Service) ImportDomains(req models.ImportDomainsReq) (models.ImportDomainsResp, error) {
+	s.log.Debug("ImportDomains: start, format ", req.Format)
+
+	rows, err := parseImport(req.Format, req.Data)
+	if err != nil {
+		return models.ImportDomainsResp{}, err
+	}
+	if len(rows) > maxImportRows {
+		return models.ImportDomainsResp{}, fmt.Errorf("import has %d rows, at most %d are allowed", len(rows), maxImportRows)
+	}
+
+	resp := models.ImportDomainsResp{Rows: make([]models.ImportRowResult, 0, len(rows))}
+	for _, row := range rows {
+		result := models.ImportRowResult{Row: row.Row, Domain: row.Req.Domain}
+
+		err := row.Err
+		if err == nil {
+			create := row.Req
+			create.CreatedBy = req.CreatedBy
+			create.TenantID = req.TenantID
+			if create.DNSProvider == "" {
+				create.DNSProvider = req.DNSProvider
+			}
+			create.DeferIssuance = create.DeferIssuance || req.DeferIssuance
+			result.DomainID, err = s.CreateDomain(create)
+		}
+
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			result.Code = models.ErrorCode(err)
+			resp.Failed++
+		} else {
+			result.Status = "created"
+			resp.Created++
+		}
+		resp.Rows = append(resp.Rows, result)
+	}
+
+	s.log.Info("Domains imported: ", resp.Created, " created, ", resp.Failed, " failed")
+	return resp, nil
+}
+
+func parseImport(format string, data []byte) ([]importRow, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return parseImportCSV(data)
+	case "json":
+		return parseImportJSON(data)
+	default:
+		return nil, fmt.Errorf("unknown import format %q, expected csv or json", format)
+	}
+}
+
+// parseImportJSON reads an array of objects shaped like the POST /domains body
+func parseImportJSON(data []byte) ([]importRow, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse json: expected an array of domains: %w", err)
+	}
+
+	rows := make([]importRow, 0, len(raw))
+	for i, item := range raw {
+		// rows are counted from 1, fields a row leaves out keep the defaults of a CSV row
+		row := importRow{Row: i + 1, Req: newImportReq()}
+		if err := json.Unmarshal(item, &row.Req); err != nil {
+			row.Err = fmt.Errorf("parse row: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importColumns maps the accepted CSV headers to the field they fill, other columns (e.g. certbot's
+// "certificate name" or "expiry date") are ignored. Certbot's "domains" lists the main domain first
+var importColumns = map[string]string{
+	"domain":               "domain",
+	"domain_name":          "domain",
+	"domains":              "domains",
+	"alternative_domains":  "alternative_domains",
+	"san":                  "alternative_domains",
+	"dns_provider":         "dns_provider",
+	"provider":             "dns_provider",
+	"nginx_container_name": "nginx_container_name",
+	"verification_method":  "verification_method",
+	"auto_renew":           "auto_renew",
+	"tags":                 "tags",
+	"notes":                "notes",
+	"kind":                 "kind",
+	"monitor_address":      "monitor_address",
+	"key_type":             "key_type",
+	"profile":              "profile",
+	"output_formats":       "output_formats",
+	"reuse_key":            "reuse_key",
+	"defer_issuance":       "defer_issuance",
+}
+
+// parseImportCSV reads a CSV with a header row, lists inside a cell are separated by spaces, commas or semicolons
+func parseImportCSV(data []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		columns[i] = importColumns[name]
+	}
+	if !slices.Contains(columns, "domain") && !slices.Contains(columns, "domains") {
+		return nil, errors.New("parse csv header: a domain or domains column is required")
+	}
+
+	var rows []importRow
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		row := importRow{Row: line}
+		if err != nil {
+			row.Err = fmt.Errorf("parse row: %w", err)
+			rows = append(rows, row)
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		row.Req, row.Err = importRecord(columns, record)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// newImportReq returns the defaults of an imported domain, imported domains are renewed unless a row
+// sets auto_renew to false
+func newImportReq() models.CreateDomainReq {
+	return models.CreateDomainReq{AutoRenew: true}
+}
+
+func importRecord(columns, record []string) (models.CreateDomainReq, error) {
+	req := newImportReq()
+	for i, value := range record {
+		if i >= len(columns) {
+			return req, fmt.Errorf("row has %d values, the header has %d columns", len(record), len(columns))
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		var err error
+		switch columns[i] {
+		case "domain":
+			req.Domain = value
+		case "domains":
+			names := splitList(value)
+			if req.Domain == "" && len(names) > 0 {
+				req.Domain, names = names[0], names[1:]
+			}
+			req.AltDomains = append(req.AltDomains, names...)
+		case "alternative_domains":
+			req.AltDomains = append(req.AltDomains, splitList(value)...)
+		case "dns_provider":
+			req.DNSProvider = value
+		case "nginx_container_name":
+			req.NginxContainerName = value
+		case "verification_method":
+			req.VerificationMethod = value
+		case "auto_renew":
+			req.AutoRenew, err = strconv.ParseBool(value)
+		case "tags":
+			req.Tags = splitList(value)
+		case "notes":
+			req.Notes = value
+		case "kind":
+			req.Kind = value
+		case "monitor_address":
+			req.MonitorAddress = value
+		case "key_type":
+			req.KeyType = value
+		case "profile":
+			req.Profile = value
+		case "output_formats":
+			req.OutputFormats = splitList(value)
+		case "reuse_key":
+			req.ReuseKey, err = strconv.ParseBool(value)
+		case "defer_issuance":
+			req.DeferIssuance, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return req, fmt.Errorf("column %s: %w", columns[i], err)
+		}
+	}
+	// a certbot export lists the main domain in "domains" too
+	req.AltDomains = withoutName(req.AltDomains, req.Domain)
+	return req, nil
+}
+
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';'
+	})
+}
+
+func withoutName(names []string, name string) []string {
+	out := names[:0]
+	for _, n := range names {
+		if !strings.EqualFold(n, name) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
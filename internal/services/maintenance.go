@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	"time"
+)
+
+// defaultMaintenanceRetryAfter is the Retry-After hint when the admin didn't give one
+const defaultMaintenanceRetryAfter = 300
+
+// Maintenance returns the read-only state shared by all replicas, the last known one when the database
+// can't be read
+func (s *Service) Maintenance() models.MaintenanceState {
+	state, err := s.repository.GetMaintenance(repositories.WithSystemScope(s.ctx))
+	if err != nil {
+		s.log.Error("failed to read maintenance state: ", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.maintenance
+	}
+
+	s.mu.Lock()
+	s.maintenance = state
+	s.mu.Unlock()
+	return state
+}
+
+// SetMaintenance switches read-only mode, the API rejects mutations and the schedulers skip their cycles
+func (s *Service) SetMaintenance(req models.SetMaintenanceReq) (models.MaintenanceState, error) {
+	state := models.MaintenanceState{}
+	if req.Enabled {
+		now := time.Now()
+		state = models.MaintenanceState{
+			Enabled:    true,
+			Reason:     req.Reason,
+			RetryAfter: req.RetryAfter,
+			Since:      &now,
+			By:         req.UserID,
+		}
+		if state.RetryAfter <= 0 {
+			state.RetryAfter = defaultMaintenanceRetryAfter
+		}
+	}
+
+	if err := s.repository.SetMaintenance(repositories.WithSystemScope(s.ctx), state); err != nil {
+		return models.MaintenanceState{}, fmt.Errorf("save maintenance state: %w", err)
+	}
+	s.mu.Lock()
+	s.maintenance = state
+	s.mu.Unlock()
+
+	if state.Enabled {
+		s.log.Warn("Maintenance mode enabled by ", req.UserID, ": ", req.Reason)
+	} else {
+		s.log.Info("Maintenance mode disabled by ", req.UserID)
+	}
+	return state, nil
+}
+
+// pausedForMaintenance tells a scheduler to skip its cycle, the next tick checks again
+func (s *Service) pausedForMaintenance(job string) bool {
+	if !s.Maintenance().Enabled {
+		return false
+	}
+	s.log.Info("Maintenance mode, ", job, " cycle skipped")
+	return true
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultMonitorInterval = 6 * time.Hour
+	defaultMonitorTimeout  = 10 * time.Second
+)
+
+func monitorInterval(cfg utils.MonitorConfig) time.Duration {
+	if cfg.Interval > 0 {
+		return cfg.Interval
+	}
+	return defaultMonitorInterval
+}
+
+func monitorTimeout(cfg utils.MonitorConfig) time.Duration {
+	if cfg.Timeout > 0 {
+		return cfg.Timeout
+	}
+	return defaultMonitorTimeout
+}
+
+func (s *Service) StartMonitorScheduler() {
+	ticker := time.NewTicker(monitorInterval(s.config().Monitor))
+	s.mu.Lock()
+	s.monitorTicker = ticker
+	s.mu.Unlock()
+
+	go func() {
+		for range ticker.C {
+			s.runCycle("monitor", s.CheckMonitoredDomains)
+			if s.config().Monitor.CheckDrift {
+				s.runCycle("drift", s.DetectCertificateDrift)
+			}
+			if s.config().Monitor.CheckOCSP {
+				s.runCycle("ocsp", s.CheckOCSPStatus)
+			}
+		}
+	}()
+}
+
+// createMonitoredDomain stores a domain that is only watched and reads its certificate right away,
+// a failed first check is recorded on the domain and doesn't fail the creation
+func (s *Service) createMonitoredDomain(ctx context.Context, req models.CreateDomainReq, revive bool) (domainID string, err error) {
+	entity := NewEntity("domains", map[string]any{
+		"domain_name":          req.Domain,
+		"kind":                 models.DomainKindMonitored,
+		"dns_provider":         "",
+		"status":               "pending",
+		"nginx_container_name": "",
+		"created_by":           req.CreatedBy,
+		"auto_renew":           false,
+		"tags":                 normalizeTags(req.Tags),
+		"metadata":             req.Metadata,
+	})
+	if req.MonitorAddress != "" {
+		entity.StringParameters["monitor_address"] = req.MonitorAddress
+	}
+	if req.Notes != "" {
+		entity.StringParameters["notes"] = req.Notes
+	}
+
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if revive {
+			entity.NullParameters = append(entity.NullParameters, "deleted_at", "deleted_by")
+			domainID, err = s.repository.UpsertTx(ctx, tx, entity, models.UpsertOptions{
+				ConflictColumns: []string{"domain_name"},
+				OnlyDeleted:     true,
+			})
+		} else {
+			domainID, err = s.repository.InsertTx(ctx, tx, entity)
+		}
+		if err != nil {
+			return fmt.Errorf("insert domain: %w", err)
+		}
+		return s.writeEvent(ctx, tx, domainID, "created", "Monitored domain created", req.CreatedBy)
+	})
+	if err != nil {
+		s.log.Warn("CreateDomain: rolled back due to error:", err)
+		return "", err
+	}
+
+	domain := models.DomainsDTO{
+		ID:         domainID,
+		DomainName: req.Domain,
+		TenantID:   req.TenantID,
+		Details: models.DetailsDTO{
+			Kind:           models.DomainKindMonitored,
+			Status:         "pending",
+			MonitorAddress: &req.MonitorAddress,
+		},
+	}
+	if err := s.checkMonitoredDomain(domain); err != nil {
+		s.log.Error("First check of monitored domain ", req.Domain, " failed: ", err)
+	}
+	return domainID, nil
+}
+
+// CheckMonitoredDomains reads the served certificate of every monitored domain, across all tenants
+func (s *Service) CheckMonitoredDomains() {
+	ctx := repositories.WithSystemScope(s.ctx)
+
+	domains, err := s.repository.GetDomainsList(ctx, models.DomainsFilters{Kind: models.DomainKindMonitored})
+	if err != nil {
+		s.log.Error("failed fetch monitored domains:", err)
+		return
+	}
+	s.log.Debug("Monitored domains to check: ", len(domains))
+
+	for _, d := range domains {
+		if err := s.checkMonitoredDomain(d); err != nil {
+			s.log.Error("Failed to check monitored domain", d.DomainName, ":", err)
+		}
+	}
+}
+
+// checkMonitoredDomain records the served certificate, a new one is added to the certificate history
+// and becomes active. Failed checks are stored on the domain instead of returned
+func (s *Service) checkMonitoredDomain(domain models.DomainsDTO) error {
+	ctx := repositories.WithTenant(s.ctx, domain.TenantID)
+	address := monitorAddress(domain.DomainName, safeDeref(domain.Details.MonitorAddress))
+	served, fetchErr := fetchServedCertificate(address, domain.DomainName, monitorTimeout(s.config().Monitor), s.monitorPublicOnly())
+	now := time.Now()
+
+	return s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if fetchErr != nil {
+			// the full error stays in the log, users only see its kind and can't probe the network with it
+			s.log.Warn("Monitored domain ", domain.DomainName, " check failed: ", fetchErr)
+			checkErr := checkErrorSummary(fetchErr)
+			if err := s.repository.UpdateTx(ctx, tx, NewEntity("domains", map[string]any{
+				"status":           "check_failed",
+				"last_checked_at":  now,
+				"last_check_error": checkErr,
+				"updated_by":       "system-monitor",
+			}), domain.ID); err != nil {
+				return fmt.Errorf("update domain: %w", err)
+			}
+			// only the first failure is an event, the domain keeps the latest error
+			if domain.Details.Status == "check_failed" {
+				return nil
+			}
+			return s.writeEvent(ctx, tx, domain.ID, "check_failed",
+				fmt.Sprintf("Reading the certificate of '%s' from %s failed: %s", domain.DomainName, address, checkErr), "system-monitor")
+		}
+
+		status := "active"
+		if now.After(served.ValidTo) {
+			status = "expired"
+		}
+		update := NewEntity("domains", map[string]any{
+			"status":           status,
+			"last_checked_at":  now,
+			"last_check_error": nil,
+			"updated_by":       "system-monitor",
+		})
+
+		changed, err := s.isNewServedCertificate(ctx, domain, served)
+		if err != nil {
+			return err
+		}
+		if changed {
+			_, err := s.storeCertificateTx(ctx, tx, domain.ID, NewEntity("certificates", map[string]any{
+				"domain_id":     domain.ID,
+				"issuer":        served.Issuer,
+				"serial_number": served.SerialNumber,
+				"fingerprint":   served.Fingerprint,
+				"key_type":      served.KeyType,
+				"valid_from":    served.ValidFrom,
+				"valid_to":      served.ValidTo,
+				"created_by":    "system-monitor",
+			}), models.ReplaceAll)
+			if err != nil {
+				return err
+			}
+			if err := s.writeEvent(ctx, tx, domain.ID, "certificate_changed",
+				fmt.Sprintf("'%s' serves certificate %s issued by %s, valid to %s",
+					domain.DomainName, served.SerialNumber, served.Issuer, served.ValidTo.Format(time.RFC3339)), "system-monitor"); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repository.UpdateTx(ctx, tx, update, domain.ID); err != nil {
+			return fmt.Errorf("update domain: %w", err)
+		}
+		return s.alertExpiring(ctx, tx, domain, served, changed)
+	})
+}
+
+// alertExpiring warns once per certificate when it enters the warning window
+func (s *Service) alertExpiring(ctx context.Context, tx pgx.Tx, domain models.DomainsDTO, served *models.ServedCertificate, changed bool) error {
+	cfg := s.config()
+	warnBefore := cfg.Certs.RenewBefore()
+	if cfg.Monitor.WarnBeforeDays > 0 {
+		warnBefore = time.Duration(cfg.Monitor.WarnBeforeDays) * 24 * time.Hour
+	}
+
+	left := time.Until(served.ValidTo)
+	if left > warnBefore {
+		return nil
+	}
+	s.log.Warn("Monitored domain ", domain.DomainName, " certificate expires at ", served.ValidTo.Format(time.RFC3339))
+
+	// the previous check already saw this certificate inside the window and wrote the event
+	if !changed && domain.Details.CertValidTo != nil && domain.Details.LastCheckedAt != nil &&
+		domain.Details.CertValidTo.Sub(*domain.Details.LastCheckedAt) <= warnBefore {
+		return nil
+	}
+	return s.writeEvent(ctx, tx, domain.ID, "expiring",
+		fmt.Sprintf("Certificate of '%s' expires in %s (%s)", domain.DomainName, left.Round(time.Hour), served.ValidTo.Format(time.RFC3339)),
+		"system-monitor")
+}
+
+// isNewServedCertificate compares the served serial with the active certificate of the domain
+func (s *Service) isNewServedCertificate(ctx context.Context, domain models.DomainsDTO, served *models.ServedCertificate) (bool, error) {
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return false, fmt.Errorf("get certificates: %w", err)
+	}
+	for _, c := range certs {
+		if c.Primary {
+			return safeDeref(c.SerialNumber) != served.SerialNumber, nil
+		}
+	}
+	return true, nil
+}
+
+var (
+	errAddressNotAllowed = errors.New("address not allowed")
+	errNoCertificate     = errors.New("no certificate presented")
+)
+
+// fetchServedCertificate completes a TLS handshake with address and returns the leaf certificate,
+// it isn't verified since expired and self-signed certificates are reported too
+func fetchServedCertificate(address, serverName string, timeout time.Duration, publicOnly bool) (*models.ServedCertificate, error) {
+	peers, err := fetchServedChain(address, serverName, timeout, publicOnly)
+	if err != nil {
+		return nil, err
+	}
+	return servedCertificate(peers[0]), nil
+}
+
+// fetchServedChain returns the unverified certificates address presents, leaf first. With publicOnly
+// the resolved address is checked right before connecting, so a name can't be pointed inside afterwards
+func fetchServedChain(address, serverName string, timeout time.Duration, publicOnly bool) ([]*x509.Certificate, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if publicOnly {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+				return fmt.Errorf("%w: %s", errAddressNotAllowed, host)
+			}
+			return nil
+		}
+	}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	peers := conn.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return nil, errNoCertificate
+	}
+	return peers, nil
+}
+
+// monitorPublicOnly tells whether monitored domains may only be checked on public addresses,
+// monitor_address is set by users and would otherwise reach services next to Hephaestus
+func (s *Service) monitorPublicOnly() bool {
+	return !s.config().Monitor.AllowPrivateAddresses
+}
+
+// isPublicAddress rejects loopback, private, link-local and unspecified addresses
+func isPublicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// validateMonitorAddress refuses a monitor_address that is a non-public IP unless the admin allows them,
+// names are checked when they're resolved on every check
+func (s *Service) validateMonitorAddress(req models.CreateDomainReq) error {
+	if req.MonitorAddress == "" || !s.monitorPublicOnly() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(req.MonitorAddress)
+	if err != nil {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicAddress(ip) {
+		var verr models.ValidationError
+		verr.Add("monitor_address", "%s is a loopback, private or link-local address, allowed with monitor.allow_private_addresses", host)
+		return verr.Err()
+	}
+	return nil
+}
+
+// checkErrorSummary is the kind of a failed check stored on the domain, the details of dial and
+// handshake errors tell what answers on an address and are only logged
+func checkErrorSummary(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, errAddressNotAllowed):
+		return "address not allowed: loopback, private and link-local addresses are refused"
+	case errors.Is(err, errNoCertificate):
+		return errNoCertificate.Error()
+	case errors.As(err, &dnsErr):
+		return "host not found"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "connection timed out"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connection failed"
+	default:
+		return "TLS handshake failed"
+	}
+}
+
+func servedCertificate(cert *x509.Certificate) *models.ServedCertificate {
+	return &models.ServedCertificate{
+		SerialNumber: utils.CertificateSerial(cert),
+		Fingerprint:  utils.CertificateFingerprint(cert),
+		KeyType:      utils.CertificateKeyType(cert),
+		Issuer:       utils.CertificateIssuer(cert),
+		ValidFrom:    cert.NotBefore,
+		ValidTo:      cert.NotAfter,
+	}
+}
+
+// monitorAddress is the host:port a monitored domain is checked on, port 443 of the domain by default
+func monitorAddress(domain, address string) string {
+	if address == "" {
+		return net.JoinHostPort(domain, "443")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, "443")
+	}
+	return address
+}
+
+func safeDeref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"errors"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	models "hephaestus/internal/models"
+	"strings"
+)
+
+// checkNameservers fails fast when a name of the order lives in a zone the provider doesn't host,
+// the order would otherwise only time out waiting for propagation
+func (s *Service) checkNameservers(ctx context.Context, provider *dnsproviders.Provider, names ...string) error {
+	if s.config().Certs.SkipNameserverCheck {
+		return nil
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "*.")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		err := provider.CheckNameservers(ctx, name)
+		var mismatch *dnsproviders.NameserverMismatchError
+		if errors.As(err, &mismatch) {
+			return models.WithCode(models.CodeNameserverMismatch, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
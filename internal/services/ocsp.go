@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSP statuses as stored in certificates.ocsp_status
+const (
+	ocspGood    = "good"
+	ocspRevoked = "revoked"
+	ocspUnknown = "unknown"
+)
+
+// maxOCSPResponse bounds the responder's answer, real ones are a few KB
+const maxOCSPResponse = 1 << 20
+
+// errNoOCSPResponder marks certificates without an OCSP URL, Let's Encrypt stopped adding one in 2025
+var errNoOCSPResponder = errors.New("certificate names no OCSP responder")
+
+// CheckOCSPStatus asks the OCSP responder of the issuer about every active certificate, across all tenants,
+// and records the answer. A certificate turning revoked or unknown writes an ocsp_revoked or ocsp_unknown event
+func (s *Service) CheckOCSPStatus() {
+	ctx := repositories.WithSystemScope(s.ctx)
+
+	domains, err := s.repository.GetDomainsList(ctx, models.DomainsFilters{})
+	if err != nil {
+		s.log.Error("failed fetch domains:", err)
+		return
+	}
+	client, err := utils.NewHTTPClient(s.config().HTTPClient)
+	if err != nil {
+		s.log.Error("failed to create OCSP http client: ", err)
+		return
+	}
+
+	for _, d := range domains {
+		if d.Details.CertValidTo == nil || d.Details.Status == "deleted" {
+			continue
+		}
+		if err := s.checkDomainOCSP(repositories.WithTenant(s.ctx, d.TenantID), client, d); err != nil {
+			s.log.Error("Failed to check OCSP status of ", d.DomainName, ": ", err)
+		}
+	}
+}
+
+func (s *Service) checkDomainOCSP(ctx context.Context, client *http.Client, domain models.DomainsDTO) error {
+	certs, err := s.repository.GetCertificatesByDomain(ctx, models.CertificatesFilters{DomainID: domain.ID})
+	if err != nil {
+		return fmt.Errorf("get certificates: %w", err)
+	}
+
+	// monitored domains have no files, their chain is read from the host like on every check
+	var served []*x509.Certificate
+	if domain.Details.Kind == models.DomainKindMonitored {
+		address := monitorAddress(domain.DomainName, safeDeref(domain.Details.MonitorAddress))
+		if served, err = fetchServedChain(address, domain.DomainName, monitorTimeout(s.config().Monitor), s.monitorPublicOnly()); err != nil {
+			return fmt.Errorf("read served certificate: %w", err)
+		}
+	}
+
+	for _, cert := range certs {
+		if !cert.Active {
+			continue
+		}
+		chain := served
+		if domain.Details.Kind != models.DomainKindMonitored {
+			data, err := s.certStore().Read(cert.CertPath)
+			if err == nil {
+				chain, err = utils.ParseCertificateChain(data)
+			}
+			if err != nil {
+				s.log.Warn("Failed to read certificate ", safeDeref(cert.SerialNumber), " of ", domain.DomainName, ": ", err)
+				continue
+			}
+		}
+		if len(chain) == 0 || utils.CertificateSerial(chain[0]) != safeDeref(cert.SerialNumber) {
+			// the host serves another certificate, the monitor cycle records it first
+			continue
+		}
+
+		resp, err := queryOCSP(ctx, client, chain)
+		if errors.Is(err, errNoOCSPResponder) {
+			s.log.Debug("Certificate ", safeDeref(cert.SerialNumber), " of ", domain.DomainName, " names no OCSP responder")
+			continue
+		}
+		if err != nil {
+			s.log.Warn("OCSP check of certificate ", safeDeref(cert.SerialNumber), " of ", domain.DomainName, " failed: ", err)
+			continue
+		}
+		if err := s.recordOCSPStatus(ctx, domain, cert, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordOCSPStatus stores the answer, only a change to revoked or unknown is an event
+func (s *Service) recordOCSPStatus(ctx context.Context, domain models.DomainsDTO, cert models.CertsDTO, resp *ocsp.Response) error {
+	status := ocspStatus(resp)
+	update := NewEntity("certificates", map[string]any{
+		"ocsp_status":     status,
+		"ocsp_checked_at": time.Now(),
+	})
+	if status == ocspRevoked {
+		update.TimeParameters["ocsp_revoked_at"] = resp.RevokedAt
+	}
+
+	return s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.repository.UpdateTx(ctx, tx, update, cert.ID); err != nil {
+			return fmt.Errorf("update certificate: %w", err)
+		}
+		if status == ocspGood || status == safeDeref(cert.OCSPStatus) {
+			return nil
+		}
+
+		serial := safeDeref(cert.SerialNumber)
+		details := fmt.Sprintf("OCSP responder doesn't know certificate %s of '%s'", serial, domain.DomainName)
+		if status == ocspRevoked {
+			s.log.Warn("Certificate ", serial, " of ", domain.DomainName, " is revoked")
+			details = fmt.Sprintf("Certificate %s of '%s' was revoked at %s", serial, domain.DomainName, resp.RevokedAt.Format(time.RFC3339))
+		}
+		return s.writeEvent(ctx, tx, domain.ID, "ocsp_"+status, details, "system-ocsp")
+	})
+}
+
+// queryOCSP posts an OCSP request for the leaf of chain to the responder it names, the issuer
+// must follow the leaf
+func queryOCSP(ctx context.Context, client *http.Client, chain []*x509.Certificate) (*ocsp.Response, error) {
+	leaf := chain[0]
+	if len(leaf.OCSPServer) == 0 {
+		return nil, errNoOCSPResponder
+	}
+	if len(chain) < 2 {
+		return nil, errors.New("issuer certificate is missing from the chain")
+	}
+	issuer := chain[1]
+
+	body, err := ocsp.CreateRequest(leaf, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responder %s answered %s", leaf.OCSPServer[0], resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponse))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(data, leaf, issuer)
+}
+
+func ocspStatus(resp *ocsp.Response) string {
+	switch resp.Status {
+	case ocsp.Good:
+		return ocspGood
+	case ocsp.Revoked:
+		return ocspRevoked
+	}
+	return ocspUnknown
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	"slices"
+)
+
+// orderJournal keeps the orders in progress in the repository, their keys stay in the storage backend
+type orderJournal struct {
+	s *Service
+}
+
+func (j orderJournal) StartOrder(order *models.PendingOrderDTO) error {
+	id, err := j.s.repository.InsertPendingOrder(repositories.WithSystemScope(j.s.ctx), *order)
+	if err != nil {
+		return fmt.Errorf("save pending order: %w", err)
+	}
+	order.ID = id
+	return nil
+}
+
+func (j orderJournal) UpdateOrder(order *models.PendingOrderDTO) error {
+	return j.s.repository.UpdatePendingOrder(repositories.WithSystemScope(j.s.ctx), *order)
+}
+
+func (j orderJournal) FinishOrder(order *models.PendingOrderDTO) error {
+	if err := j.s.keyStore().Delete(order.KeyPath); err != nil {
+		j.s.log.Warn("Key of the pending order of ", order.DomainName, " not removed: ", err)
+	}
+	return j.s.repository.DeletePendingOrder(repositories.WithSystemScope(j.s.ctx), order.ID)
+}
+
+func (j orderJournal) PendingOrder(caURL string, names []string) (*models.PendingOrderDTO, error) {
+	orders, err := j.s.repository.GetPendingOrders(repositories.WithSystemScope(j.s.ctx))
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		if orders[i].CAURL == caURL && slices.Equal(orders[i].Names, names) {
+			return &orders[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ResumePendingOrders picks up the orders a stopped process left behind: the challenge records they
+// presented are removed and their domains are issued again, downloading a certificate the CA already
+// issued instead of ordering it twice. Orders another replica is still running are left alone
+func (s *Service) ResumePendingOrders() error {
+	ctx := repositories.WithSystemScope(s.ctx)
+	orders, err := s.repository.GetPendingOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pending orders: %w", err)
+	}
+
+	resumed := 0
+	for _, order := range orders {
+		acquired, err := s.repository.TryLock(ctx, "issue:"+order.DomainName, func() error {
+			return s.resumePendingOrder(ctx, order)
+		})
+		if err != nil {
+			s.log.Error("Pending order of ", order.DomainName, " not resumed: ", err)
+			continue
+		}
+		if acquired {
+			resumed++
+		}
+	}
+
+	if len(orders) > 0 {
+		s.log.Info("Pending orders resumed: ", resumed, " of ", len(orders))
+	}
+	return nil
+}
+
+func (s *Service) resumePendingOrder(ctx context.Context, order models.PendingOrderDTO) error {
+	s.log.Info("Resuming the interrupted order of ", order.DomainName)
+
+	if len(order.Challenges) > 0 {
+		provider, err := s.selectProvider(order.DNSProvider)
+		if err != nil {
+			s.log.Warn("Challenge records of ", order.DomainName, " not removed: ", err)
+		} else {
+			for _, c := range order.Challenges {
+				if err := provider.Challenge().CleanUp(c.Domain, c.Token, c.KeyAuth); err != nil {
+					s.log.Warn("Challenge record of ", c.Domain, " not removed: ", err)
+				}
+			}
+		}
+		order.Challenges = nil
+		if err := s.repository.UpdatePendingOrder(ctx, order); err != nil {
+			return fmt.Errorf("update pending order: %w", err)
+		}
+	}
+
+	// only stored domains are issued again, a domain being created is answered with the error of
+	// the crash and a certificate for another CA isn't looked up there
+	caURL, _ := s.rateLimitedCA()
+	domain, err := s.getDomainByName(ctx, order.DomainName)
+	if err != nil || order.CAURL != caURL || domain.Details.Kind != models.DomainKindManaged || domain.Details.Status == "deleted" {
+		s.log.Info("Interrupted order of ", order.DomainName, " dropped")
+		return orderJournal{s: s}.FinishOrder(&order)
+	}
+
+	domainCtx := repositories.WithTenant(s.ctx, domain.TenantID)
+	_ = s.safeWriteEvent(domainCtx, "system-renewal", domain.ID, "order_resumed",
+		fmt.Sprintf("Order for %v interrupted on %s resumed", order.Names, order.CreatedAt.Format("2006-01-02 15:04:05")))
+	return s.renewDomainCertificate(domainCtx, domain)
+}
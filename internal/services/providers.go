@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RegisterDNSProvider stores the provider encrypted in the database and makes it usable right away,
+// registering an existing runtime name replaces its credentials
+func (s *Service) RegisterDNSProvider(provider utils.ProviderConfig, createdBy string) error {
+	s.log.Debug("RegisterDNSProvider(): called for provider ", provider.Name)
+	cfg := s.config()
+
+	if err := provider.Validate(); err != nil {
+		return err
+	}
+	if cfg.Encryption.Key == "" {
+		return utils.ErrNoEncryptionKey
+	}
+	for _, p := range cfg.Providers {
+		if strings.EqualFold(p.Name, provider.Name) {
+			return fmt.Errorf("provider '%s' is defined in the config file", provider.Name)
+		}
+	}
+
+	// fail before storing anything the service couldn't use
+	built, err := dnsproviders.New(provider, cfg, s.log)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(provider)
+	if err != nil {
+		return fmt.Errorf("encode provider: %w", err)
+	}
+	credentials, err := utils.EncryptSecret(cfg.Encryption.Key, data, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx := repositories.WithSystemScope(s.ctx)
+	if _, err := s.repository.UpsertDNSProvider(ctx, models.DNSProviderDTO{
+		Name:        provider.Name,
+		Type:        provider.ProviderType(),
+		Credentials: credentials,
+		CreatedBy:   createdBy,
+	}); err != nil {
+		return fmt.Errorf("error saving provider: %w", err)
+	}
+
+	s.providers.PutRuntime(built)
+
+	s.log.Info("DNS provider '", provider.Name, "' registered by ", createdBy)
+	return nil
+}
+
+// DeleteDNSProvider removes a provider registered at runtime, the ones of the config file stay
+func (s *Service) DeleteDNSProvider(name, deletedBy string) error {
+	s.log.Debug("DeleteDNSProvider(): called for provider ", name)
+
+	ctx := repositories.WithSystemScope(s.ctx)
+	if err := s.repository.DeleteDNSProvider(ctx, name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WithCode(models.CodeProviderNotFound, fmt.Errorf("runtime provider '%s' doesn't exist", name))
+		}
+		return fmt.Errorf("error deleting provider: %w", err)
+	}
+
+	s.providers.RemoveRuntime(name)
+
+	s.log.Info("DNS provider '", name, "' deleted by ", deletedBy)
+	return nil
+}
+
+// LoadDNSProviders rebuilds the clients of the providers registered at runtime, providers that
+// can't be decrypted or initialized are logged and skipped
+func (s *Service) LoadDNSProviders() error {
+	cfg := s.config()
+
+	ctx := repositories.WithSystemScope(s.ctx)
+	stored, err := s.repository.GetDNSProviders(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting runtime providers: %w", err)
+	}
+
+	var list []*dnsproviders.Provider
+	for _, p := range stored {
+		data, err := utils.DecryptSecret(cfg.Encryption.Key, p.Credentials, nil)
+		if err != nil {
+			s.log.Error("Runtime provider '", p.Name, "' skipped: ", err)
+			continue
+		}
+		var provider utils.ProviderConfig
+		if err := json.Unmarshal(data, &provider); err != nil {
+			s.log.Error("Runtime provider '", p.Name, "' skipped: ", err)
+			continue
+		}
+		built, err := dnsproviders.New(provider, cfg, s.log)
+		if err != nil {
+			s.log.Error("Runtime provider '", p.Name, "' skipped: ", err)
+			continue
+		}
+		list = append(list, built)
+	}
+
+	s.providers.SetRuntime(list)
+
+	s.log.Info("Runtime DNS providers loaded: ", len(list))
+	return nil
+}
+
+// RecordDisabledProviders writes a startup event for every provider skipped because of missing
+// credentials, so it shows up in the audit log and not only in the service log
+func (s *Service) RecordDisabledProviders() error {
+	disabled := s.config().DisabledProviders()
+	if len(disabled) == 0 {
+		return nil
+	}
+
+	ctx := repositories.WithSystemScope(s.ctx)
+	return s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		for _, p := range disabled {
+			if err := s.writeEvent(
+				ctx, tx, "", "provider_disabled",
+				fmt.Sprintf("DNS provider '%s' disabled at startup: %s", p.Name, p.Disabled),
+				"system-startup",
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
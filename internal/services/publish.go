@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// publishCertificate imports the live certificate of the domain into the certs.publish target. Publishing
+// follows the storage, a failure is written as a publish_failed event and doesn't fail the issuance
+func (s *Service) publishCertificate(ctx context.Context, domainID, domainName string, tags []string, userID string) {
+	publisher := s.certPublisher()
+	if publisher == nil {
+		return
+	}
+	for _, tag := range normalizeTags(s.config().Certs.Publish.Tags) {
+		if !slices.Contains(tags, tag) {
+			return
+		}
+	}
+
+	store := s.certStore()
+	_, cert, err := store.ReadLive(domainName)
+	var key []byte
+	if err == nil {
+		key, err = store.ReadLiveKey(domainName)
+	}
+	var target string
+	if err == nil {
+		target, err = publisher.Publish(domainName, key, cert)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Publishing certificate of '%s' failed: %v", domainName, err)
+		// with several targets the others may have been written
+		if target != "" {
+			msg += fmt.Sprintf(", published to %s", target)
+		}
+		s.log.Error(msg)
+		_ = s.safeWriteEvent(ctx, userID, domainID, "publish_failed", msg)
+		return
+	}
+	s.log.Info("Certificate of ", domainName, " published to ", target)
+	_ = s.safeWriteEvent(ctx, userID, domainID, "published",
+		fmt.Sprintf("Certificate of '%s' published to %s", domainName, target))
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	models "hephaestus/internal/models"
+	repositories "hephaestus/internal/repositories"
+	utils "hephaestus/internal/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultPurgeGracePeriod = 30 * 24 * time.Hour
+	defaultPurgeInterval    = 24 * time.Hour
+)
+
+func (s *Service) StartPurgeScheduler() {
+	purgeCfg := s.config().Purge
+	if !purgeCfg.Enabled {
+		s.log.Info("Purge of deleted domains is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(purgeInterval(purgeCfg))
+	s.mu.Lock()
+	s.purgeTicker = ticker
+	s.mu.Unlock()
+
+	go func() {
+		for range ticker.C {
+			s.runCycle("purge", s.PurgeDeletedDomains)
+		}
+	}()
+}
+
+func (s *Service) PurgeDeletedDomains() {
+	ctx := repositories.WithSystemScope(s.ctx)
+	gracePeriod := s.config().Purge.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultPurgeGracePeriod
+	}
+
+	domains, err := s.repository.GetPurgeableDomains(ctx, time.Now().Add(-gracePeriod))
+	if err != nil {
+		s.log.Error("failed fetch purgeable domains:", err)
+		return
+	}
+	s.log.Debug("Domains to purge: ", len(domains))
+
+	for _, d := range domains {
+		if err := s.purgeDomain(d); err != nil {
+			s.log.Error("Failed to purge domain", d.DomainName, ":", err)
+		}
+	}
+}
+
+func (s *Service) purgeDomain(domain models.PurgeCandidateDTO) (err error) {
+	s.log.Info("Purging domain: ", domain.DomainName)
+	ctx := repositories.WithTenant(s.ctx, domain.TenantID)
+
+	err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.repository.PurgeDomainTx(ctx, tx, domain.ID); err != nil {
+			return fmt.Errorf("failed to purge domain rows: %w", err)
+		}
+
+		// the domain row is gone, so the event is kept without domain reference
+		if err := s.writeEvent(ctx, tx, "", "purged",
+			fmt.Sprintf("Domain '%s' (%s) deleted at %s purged", domain.DomainName, domain.ID, domain.DeletedAt.Format(time.RFC3339)),
+			"system-purge",
+		); err != nil {
+			return fmt.Errorf("failed to insert event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// files are normally removed on delete already, this catches the leftovers
+	if dErr := s.certStore().Delete(domain.DomainName); dErr != nil {
+		s.log.Debug("Certificate files not removed:", dErr)
+	}
+
+	return nil
+}
+
+func purgeInterval(cfg utils.PurgeConfig) time.Duration {
+	if cfg.Interval <= 0 {
+		return defaultPurgeInterval
+	}
+	return cfg.Interval
+}
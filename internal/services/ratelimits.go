@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/net/publicsuffix"
+)
+
+// rateLimit allows Count orders within Window
+type rateLimit struct {
+	Count  int
+	Window time.Duration
+}
+
+// Let's Encrypt's published limits, https://letsencrypt.org/docs/rate-limits/. Renewals of the same
+// names are exempt from the per domain and new orders limits
+var letsEncryptLimits = struct {
+	CertificatesPerDomain rateLimit
+	DuplicateCertificates rateLimit
+	FailedValidations     rateLimit
+	NewOrders             rateLimit
+}{
+	CertificatesPerDomain: rateLimit{Count: 50, Window: 7 * 24 * time.Hour},
+	DuplicateCertificates: rateLimit{Count: 5, Window: 7 * 24 * time.Hour},
+	FailedValidations:     rateLimit{Count: 5, Window: time.Hour},
+	NewOrders:             rateLimit{Count: 300, Window: 3 * time.Hour},
+}
+
+// rateLimitHistory is the longest window of the limits, older orders are dropped
+const rateLimitHistory = 7 * 24 * time.Hour
+
+// rateLimitedCA returns the directory whose orders are counted, only Let's Encrypt production has
+// its limits enforced here
+func (s *Service) rateLimitedCA() (caURL string, enforced bool) {
+	cfg := s.config()
+	caURL = cfg.Certs.DirectoryURL()
+	if cfg.Sandbox.Enabled {
+		return cfg.Sandbox.CADirURL, false
+	}
+	return caURL, caURL == utils.DefaultCADirURL && !cfg.Certs.SkipRateLimitCheck
+}
+
+// withRateLimitBudget runs the ACME order for names when it fits in the rate limits of the CA, renewal
+// marks orders renewing the certificate of a domain. The order is reserved in the transaction checking
+// the budget, concurrent orders can't both take its last slot, and finished once the CA answered. An
+// unreadable history doesn't block issuance, the CA still enforces its limits
+func (s *Service) withRateLimitBudget(ctx context.Context, provider *dnsproviders.Provider, names []string, renewal bool, order func() error) error {
+	caURL, enforced := s.rateLimitedCA()
+	// locally signed certificates never reach the CA
+	if _, selfSigned := provider.SelfSigned(); !enforced || selfSigned {
+		return order()
+	}
+
+	now := time.Now()
+	keepSince := now.Add(-rateLimitHistory)
+	attempt := newIssuanceAttempt(caURL, names, renewal)
+	attempt.Pending = true
+	var id string
+	err := s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+		history, err := s.repository.GetIssuanceAttemptsTx(ctx, tx, caURL, keepSince)
+		if err != nil {
+			return fmt.Errorf("get issuance attempts: %w", err)
+		}
+		attempt.Renewal = attempt.Renewal || issuedBefore(history, attempt.Names)
+		if err := checkRateLimits(attempt, history, now); err != nil {
+			return err
+		}
+		id, err = s.repository.InsertIssuanceAttemptTx(ctx, tx, attempt, keepSince)
+		return err
+	})
+	if models.ErrorCode(err) == models.CodeRateLimitBudget {
+		return err
+	}
+	if err != nil {
+		s.log.Warn("Rate limit budget unavailable, ordering without reservation: ", err)
+	}
+
+	err = order()
+	s.finishIssuanceAttempt(ctx, id, attempt, err, keepSince)
+	return err
+}
+
+// finishIssuanceAttempt records the outcome of the order reserved as id, an order that couldn't be
+// reserved is recorded now. Only authorizations the CA found invalid count as failed validations
+func (s *Service) finishIssuanceAttempt(ctx context.Context, id string, attempt models.IssuanceAttemptDTO, orderErr error, keepSince time.Time) {
+	code := models.ErrorCode(orderErr)
+	var err error
+	switch {
+	// an order the CA rate limited never reached validation
+	case code == models.CodeACMERateLimited:
+		if id != "" {
+			err = s.repository.DeleteIssuanceAttempt(ctx, id)
+		}
+	case id != "":
+		err = s.repository.FinishIssuanceAttempt(ctx, id, orderErr == nil, code == models.CodeACMEAuthorizationFailed)
+	default:
+		attempt.Pending = false
+		attempt.Succeeded = orderErr == nil
+		attempt.FailedValidation = code == models.CodeACMEAuthorizationFailed
+		err = s.repository.WithTx(ctx, func(tx pgx.Tx) error {
+			_, err := s.repository.InsertIssuanceAttemptTx(ctx, tx, attempt, keepSince)
+			return err
+		})
+	}
+	if err != nil {
+		s.log.Warn("Failed to record issuance attempt for ", strings.Join(attempt.Names, ", "), ": ", err)
+	}
+}
+
+func newIssuanceAttempt(caURL string, names []string, renewal bool) models.IssuanceAttemptDTO {
+	attempt := models.IssuanceAttemptDTO{CAURL: caURL, Renewal: renewal}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !slices.Contains(attempt.Names, name) {
+			attempt.Names = append(attempt.Names, name)
+		}
+		if rd := registeredDomain(name); !slices.Contains(attempt.RegisteredDomains, rd) {
+			attempt.RegisteredDomains = append(attempt.RegisteredDomains, rd)
+		}
+	}
+	slices.Sort(attempt.Names)
+	slices.Sort(attempt.RegisteredDomains)
+	return attempt
+}
+
+// registeredDomain is the public suffix + 1 of name, the unit of the per domain limit
+func registeredDomain(name string) string {
+	name = strings.TrimPrefix(name, "*.")
+	rd, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return name
+	}
+	return rd
+}
+
+// issuedBefore reports whether a certificate was issued for exactly these names, the CA treats
+// the order as a renewal then
+func issuedBefore(history []models.IssuanceAttemptDTO, names []string) bool {
+	for _, a := range history {
+		if a.Succeeded && slices.Equal(a.Names, names) {
+			return true
+		}
+	}
+	return false
+}
+
+// countsAsIssued reports whether the attempt uses up certificate budget, a reserved order may still be issued
+func countsAsIssued(a models.IssuanceAttemptDTO) bool {
+	return a.Succeeded || a.Pending
+}
+
+// checkRateLimits rejects the attempt when one of the limits is used up, history is sorted by time
+func checkRateLimits(attempt models.IssuanceAttemptDTO, history []models.IssuanceAttemptDTO, now time.Time) error {
+	limits := letsEncryptLimits
+
+	if !attempt.Renewal {
+		usage := countUsage(history, limits.NewOrders, now, func(a models.IssuanceAttemptDTO) bool { return !a.Renewal })
+		if err := exhausted(usage, "new orders of the account"); err != nil {
+			return err
+		}
+		for _, rd := range attempt.RegisteredDomains {
+			usage := countUsage(history, limits.CertificatesPerDomain, now, func(a models.IssuanceAttemptDTO) bool {
+				return countsAsIssued(a) && !a.Renewal && slices.Contains(a.RegisteredDomains, rd)
+			})
+			if err := exhausted(usage, "certificates of "+rd); err != nil {
+				return err
+			}
+		}
+	}
+
+	usage := countUsage(history, limits.DuplicateCertificates, now, func(a models.IssuanceAttemptDTO) bool {
+		return countsAsIssued(a) && slices.Equal(a.Names, attempt.Names)
+	})
+	if err := exhausted(usage, "duplicate certificates of "+strings.Join(attempt.Names, ", ")); err != nil {
+		return err
+	}
+	for _, name := range attempt.Names {
+		usage := countUsage(history, limits.FailedValidations, now, func(a models.IssuanceAttemptDTO) bool {
+			return a.FailedValidation && slices.Contains(a.Names, name)
+		})
+		if err := exhausted(usage, "failed validations of "+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countUsage counts the orders within the window of limit matching counted
+func countUsage(history []models.IssuanceAttemptDTO, limit rateLimit, now time.Time, counted func(models.IssuanceAttemptDTO) bool) models.RateLimitUsage {
+	// 168h rather than 168h0m0s
+	window := strings.TrimSuffix(strings.TrimSuffix(limit.Window.String(), "0s"), "0m")
+	usage := models.RateLimitUsage{Limit: limit.Count, Window: window}
+	since := now.Add(-limit.Window)
+	for _, a := range history {
+		if a.CreatedAt.Before(since) || !counted(a) {
+			continue
+		}
+		if usage.ResetsAt == nil {
+			resetsAt := a.CreatedAt.Add(limit.Window)
+			usage.ResetsAt = &resetsAt
+		}
+		usage.Used++
+	}
+	return usage
+}
+
+func exhausted(usage models.RateLimitUsage, what string) error {
+	if usage.Used < usage.Limit {
+		return nil
+	}
+	return models.WithCode(models.CodeRateLimitBudget, fmt.Errorf("rate limit of %d %s per %s is used up, retry after %s",
+		usage.Limit, what, usage.Window, usage.ResetsAt.Format(time.RFC3339)))
+}
+
+// GetRateLimitBudget reports the usage of the CA's rate limits, per registered domain with orders in
+// the last week. domain narrows it to the registered domain of a name
+func (s *Service) GetRateLimitBudget(domain string) (models.RateLimitBudget, error) {
+	caURL, enforced := s.rateLimitedCA()
+	budget := models.RateLimitBudget{CA: caURL, Enforced: enforced, Domains: []models.RegisteredDomainBudget{}}
+	if !enforced {
+		return budget, nil
+	}
+
+	now := time.Now()
+	history, err := s.repository.GetIssuanceAttempts(s.ctx, caURL, now.Add(-rateLimitHistory))
+	if err != nil {
+		return models.RateLimitBudget{}, fmt.Errorf("get issuance attempts: %w", err)
+	}
+	limits := letsEncryptLimits
+	budget.NewOrders = countUsage(history, limits.NewOrders, now, func(a models.IssuanceAttemptDTO) bool { return !a.Renewal })
+
+	var registered []string
+	if domain != "" {
+		registered = []string{registeredDomain(strings.ToLower(domain))}
+	} else {
+		for _, a := range history {
+			for _, rd := range a.RegisteredDomains {
+				if !slices.Contains(registered, rd) {
+					registered = append(registered, rd)
+				}
+			}
+		}
+		slices.Sort(registered)
+	}
+
+	for _, rd := range registered {
+		inDomain := func(a models.IssuanceAttemptDTO) bool { return slices.Contains(a.RegisteredDomains, rd) }
+		entry := models.RegisteredDomainBudget{
+			Domain: rd,
+			Certificates: countUsage(history, limits.CertificatesPerDomain, now, func(a models.IssuanceAttemptDTO) bool {
+				return inDomain(a) && countsAsIssued(a) && !a.Renewal
+			}),
+			FailedValidations: countUsage(history, limits.FailedValidations, now, func(a models.IssuanceAttemptDTO) bool {
+				return inDomain(a) && a.FailedValidation
+			}),
+		}
+		var seen [][]string
+		for _, a := range history {
+			if !inDomain(a) || !countsAsIssued(a) || slices.ContainsFunc(seen, func(n []string) bool { return slices.Equal(n, a.Names) }) {
+				continue
+			}
+			seen = append(seen, a.Names)
+			entry.NameSets = append(entry.NameSets, models.NameSetUsage{
+				Names: a.Names,
+				RateLimitUsage: countUsage(history, limits.DuplicateCertificates, now, func(b models.IssuanceAttemptDTO) bool {
+					return countsAsIssued(b) && slices.Equal(b.Names, a.Names)
+				}),
+			})
+		}
+		budget.Domains = append(budget.Domains, entry)
+	}
+	return budget, nil
+}
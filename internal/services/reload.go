@@ -0,0 +1,79 @@
+package services
+
+import (
+	acme "hephaestus/internal/acme"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	docker "hephaestus/internal/docker"
+	storage "hephaestus/internal/storage"
+	utils "hephaestus/internal/utils"
+)
+
+// Reload swaps the configuration, the ACME issuer and the DNS providers, issuances already running
+// keep the ones they started with. Database and server settings need a restart.
+func (s *Service) Reload(cfg *utils.Config, issuer acme.IssuerInterface, providers []*dnsproviders.Provider) {
+	s.providers.SetStatic(providers)
+	issuer.SetOrderJournal(orderJournal{s: s})
+	keys, err := storage.NewBackend(cfg, s.repository, s.log)
+	publisher, publisherErr := storage.NewPublisher(cfg, s.log)
+	dockerClient, dockerErr := docker.NewClient(cfg.Docker, s.log)
+
+	s.mu.Lock()
+	old := s.cfg
+	s.cfg = cfg
+	s.issuer = issuer
+	if err == nil {
+		s.certs = storage.NewStore(cfg, keys, s.log)
+		s.keys = keys
+	}
+	if publisherErr == nil {
+		s.publisher = publisher
+	}
+	oldDocker := s.docker
+	if dockerErr == nil {
+		s.docker = dockerClient
+	}
+	renewalTicker, purgeTicker, monitorTicker := s.renewalTicker, s.purgeTicker, s.monitorTicker
+	s.mu.Unlock()
+
+	if dockerErr == nil {
+		oldDocker.Close()
+	}
+	s.log.SetLevel(cfg.Logger.LogLevel)
+	if err != nil {
+		s.log.Error("Storage backend not reloaded: ", err)
+	}
+	if publisherErr != nil {
+		s.log.Error("Publish target not reloaded: ", publisherErr)
+	}
+	if dockerErr != nil {
+		s.log.Error("Docker client not reloaded: ", dockerErr)
+	}
+
+	if renewalTicker != nil && cfg.Certs.RenewalDuration > 0 && cfg.Certs.RenewalDuration != old.Certs.RenewalDuration {
+		renewalTicker.Reset(cfg.Certs.RenewalDuration)
+		s.log.Info("Renewal interval changed to ", cfg.Certs.RenewalDuration)
+	}
+
+	if cfg.Scheduler != old.Scheduler {
+		s.log.Warn("Scheduler timezone and renew_at are applied on restart only")
+	}
+
+	if cfg.Purge.Enabled != old.Purge.Enabled {
+		s.log.Warn("Enabling or disabling purge needs a restart")
+	}
+	if purgeTicker != nil && purgeInterval(cfg.Purge) != purgeInterval(old.Purge) {
+		purgeTicker.Reset(purgeInterval(cfg.Purge))
+		s.log.Info("Purge interval changed to ", purgeInterval(cfg.Purge))
+	}
+	if monitorTicker != nil && monitorInterval(cfg.Monitor) != monitorInterval(old.Monitor) {
+		monitorTicker.Reset(monitorInterval(cfg.Monitor))
+		s.log.Info("Monitor interval changed to ", monitorInterval(cfg.Monitor))
+	}
+
+	// runtime providers are rebuilt with the new config, e.g. a changed encryption key or storage dir
+	if err := s.LoadDNSProviders(); err != nil {
+		s.log.Error("Runtime DNS providers not reloaded: ", err)
+	}
+
+	s.log.Info("Configuration reloaded, DNS providers: ", len(providers))
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	utils "hephaestus/internal/utils"
+	"time"
+)
+
+// startDailyRenewal runs the renewal cycle every day at scheduler.renew_at in the scheduler timezone,
+// the config is validated on load so the time and zone are known to parse
+func (s *Service) startDailyRenewal(cfg utils.SchedulerConfig) {
+	loc, _ := cfg.Location()
+	hour, minute, _ := utils.ParseClock(cfg.RenewAt)
+
+	go func() {
+		for {
+			next := nextDailyRun(time.Now(), hour, minute, loc)
+			s.log.Info("Next certificate renewal cycle at ", next.Format(time.RFC3339))
+			time.Sleep(time.Until(next))
+
+			s.runCycle("renewal", s.RenewExpiringCertificates)
+		}
+	}()
+}
+
+// nextDailyRun returns the next hour:minute in loc after now, DST gaps are handled by time.Date
+func nextDailyRun(now time.Time, hour, minute int, loc *time.Location) time.Time {
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next
+}
@@ -4,54 +4,160 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	clients "hephaestus/internal/clients"
+	acme "hephaestus/internal/acme"
+	dnsproviders "hephaestus/internal/dnsproviders"
+	docker "hephaestus/internal/docker"
 	models "hephaestus/internal/models"
 	repositories "hephaestus/internal/repositories"
+	storage "hephaestus/internal/storage"
 	utils "hephaestus/internal/utils"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
 type ServiceInterface interface {
-	Validate(token string) (string, error)
+	Validate(token string) (models.Identity, error)
 	GetDomains(filters models.GetDomainsReq) (models.GetDomainsResp, error)
 	CreateDomain(req models.CreateDomainReq) (string, error)
+	ImportDomains(req models.ImportDomainsReq) (models.ImportDomainsResp, error)
+	UpdateDomain(req models.UpdateDomainReq) error
 	DeleteDomain(filters models.DeleteDomainReq) error
+	GetCertificates(req models.GetCertificatesReq) ([]models.Certificate, error)
+	ActivateCertificate(req models.ActivateCertificateReq) error
+	SwitchCertificateChain(req models.SwitchChainReq) error
+	ExportCertificate(req models.ExportCertificateReq) (models.CertificateExport, error)
+	CheckCertificateDrift(req models.CheckDriftReq) (models.CertificateDrift, error)
+	ListArchivedCertificates(req models.ArchivedCertificatesReq) ([]models.ArchivedCertificate, error)
+	RestoreCertificate(req models.RestoreCertificateReq) error
+	GetQueryStats() []models.QueryStat
+	GetConfig() map[string]any
+	GetFeatures() map[string]bool
+	IsAdmin(userID string) bool
+	RegisterDNSProvider(provider utils.ProviderConfig, createdBy string) error
+	DeleteDNSProvider(name, deletedBy string) error
+	DelegateAcmeDNS(req models.DelegateAcmeDNSReq) (models.AcmeDNSDelegation, error)
+	IssueFromCSR(req models.IssueFromCSRReq) (models.IssuedCertificate, error)
+	GetRateLimitBudget(domain string) (models.RateLimitBudget, error)
+	Maintenance() models.MaintenanceState
+	SetMaintenance(req models.SetMaintenanceReq) (models.MaintenanceState, error)
+	DeactivateAcmeAccount(req models.DeactivateAccountReq) (models.DeactivateAccountResp, error)
 }
 
 type Service struct {
-	client     []*clients.Client
-	repository *repositories.Repository
+	mu         sync.RWMutex // guards cfg, issuer, certs, keys, publisher and docker, all are swapped on config reload, and maintenance
+	issuer     acme.IssuerInterface
+	certs      storage.CertStoreInterface
+	keys       storage.Backend   // keys of the orders in progress, next to the certificates
+	publisher  storage.Publisher // nil without certs.publish
+	docker     *docker.Client    // reloads nginx in the containers of the domains
+	providers  *dnsproviders.Registry
+	repository repositories.RepositoryInterface
 	log        *utils.Logger
 	cfg        *utils.Config
 	ctx        context.Context
+
+	renewalTicker *time.Ticker
+	purgeTicker   *time.Ticker
+	monitorTicker *time.Ticker
+
+	maintenance models.MaintenanceState
 }
 
-func NewService(cfg *utils.Config, clientsList []*clients.Client, repo *repositories.Repository, log *utils.Logger) (*Service, error) {
+func NewService(cfg *utils.Config, issuer acme.IssuerInterface, providers []*dnsproviders.Provider, repo repositories.RepositoryInterface, log *utils.Logger) (*Service, error) {
 	ctx := context.Background()
 
-	return &Service{
-		client:     clientsList,
+	keys, err := storage.NewBackend(cfg, repo, log)
+	if err != nil {
+		return nil, fmt.Errorf("open storage backend: %w", err)
+	}
+	publisher, err := storage.NewPublisher(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("open publish target: %w", err)
+	}
+	dockerClient, err := docker.NewClient(cfg.Docker, log)
+	if err != nil {
+		return nil, fmt.Errorf("open docker client: %w", err)
+	}
+	s := &Service{
+		issuer:     issuer,
+		certs:      storage.NewStore(cfg, keys, log),
+		keys:       keys,
+		publisher:  publisher,
+		docker:     dockerClient,
+		providers:  dnsproviders.NewRegistry(providers),
 		repository: repo,
 		log:        log,
 		cfg:        cfg,
 		ctx:        ctx,
-	}, nil
+	}
+	s.providers.SetAcmeDNSStore(acmeDNSAccounts{s: s})
+	issuer.SetOrderJournal(orderJournal{s: s})
+	return s, nil
+}
+
+// config returns the current configuration, callers must not keep it across operations
+func (s *Service) config() *utils.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// acmeIssuer returns the issuer of the current configuration
+func (s *Service) acmeIssuer() acme.IssuerInterface {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.issuer
+}
+
+// certStore returns the storage of certificates, it follows config reloads
+func (s *Service) certStore() storage.CertStoreInterface {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.certs
+}
+
+// keyStore returns the storage backend of the keys of orders in progress
+func (s *Service) keyStore() storage.Backend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys
+}
+
+// certPublisher returns the publish target of certificates, nil when there is none
+func (s *Service) certPublisher() storage.Publisher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.publisher
+}
+
+// dockerClient returns the Docker Engine API client of the current configuration
+func (s *Service) dockerClient() *docker.Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.docker
 }
 
-func (s *Service) SelectClientByName(name string) (*clients.Client, error) {
-	if name == "no" {
-		return s.client[0], nil
+// selectProvider returns the DNS provider with the name, an empty name selects default_provider
+func (s *Service) selectProvider(name string) (*dnsproviders.Provider, error) {
+	if name == "" {
+		name = s.config().DefaultProvider
 	}
-	for _, client := range s.client {
-		// fmt.Printf("Component: %s ", name)
-		// fmt.Printf("Client: %s ", client.Name)
-		if client.Name == name {
-			return client, nil
+	if name == "" {
+		return nil, models.WithCode(models.CodeProviderNotFound, errors.New("no DNS provider given and default_provider is not set"))
+	}
+	provider, err := s.providers.Get(name)
+	if err != nil {
+		// the provider may have been registered through another replica since the last load
+		if loadErr := s.LoadDNSProviders(); loadErr != nil {
+			return nil, models.WithCode(models.CodeProviderNotFound, err)
+		}
+		if provider, err = s.providers.Get(name); err != nil {
+			return nil, models.WithCode(models.CodeProviderNotFound, err)
 		}
 	}
-	return nil, errors.New("client not found")
+	return provider, nil
 }
 
 func NewEntity(table string, params map[string]any) models.Entity {
@@ -61,6 +167,8 @@ func NewEntity(table string, params map[string]any) models.Entity {
 		IntegerParameters: map[string]int{},
 		TimeParameters:    map[string]time.Time{},
 		BoolParameters:    map[string]bool{},
+		ArrayParameters:   map[string][]string{},
+		JSONParameters:    map[string]map[string]any{},
 	}
 
 	for k, v := range params {
@@ -73,6 +181,12 @@ func NewEntity(table string, params map[string]any) models.Entity {
 			e.TimeParameters[k] = val
 		case bool:
 			e.BoolParameters[k] = val
+		case []string:
+			e.ArrayParameters[k] = val
+		case map[string]any:
+			e.JSONParameters[k] = val
+		case nil:
+			e.NullParameters = append(e.NullParameters, k)
 		default:
 			panic(fmt.Sprintf("unsupported type for key %s: %T", k, val))
 		}
@@ -109,8 +223,8 @@ func (s *Service) updateStatus(
 	return nil
 }
 
-func (s *Service) safeWriteEvent(user string, domainID string, eventType string, details string) error {
-	err := s.writeEvent(s.ctx, nil, domainID, eventType, details, user)
+func (s *Service) safeWriteEvent(ctx context.Context, user string, domainID string, eventType string, details string) error {
+	err := s.writeEvent(ctx, nil, domainID, eventType, details, user)
 	if err != nil {
 		s.log.Error("event writing failed (non-fatal):", err)
 	}
@@ -127,11 +241,14 @@ func (s *Service) writeEvent(
 ) error {
 
 	entity := NewEntity("events", map[string]any{
-		"domain_id":  domainID,
 		"event_type": eventType,
 		"message":    message,
 		"created_by": createdBy,
 	})
+	// events not bound to a domain keep domain_id NULL
+	if domainID != "" {
+		entity.StringParameters["domain_id"] = domainID
+	}
 
 	if _, err := s.repository.InsertTx(ctx, tx, entity); err != nil {
 		return fmt.Errorf("failed to write event: %w", err)
@@ -146,14 +263,13 @@ func (s *Service) insertMany(
 	entities []models.Entity,
 ) ([]string, error) {
 
-	ids := make([]string, 0, len(entities))
+	if len(entities) == 0 {
+		return nil, nil
+	}
 
-	for _, e := range entities {
-		id, err := s.repository.InsertTx(ctx, tx, e)
-		if err != nil {
-			return nil, fmt.Errorf("failed to insert into %s: %w", e.EntityName, err)
-		}
-		ids = append(ids, id)
+	ids, err := s.repository.InsertManyTx(ctx, tx, entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert into %s: %w", entities[0].EntityName, err)
 	}
 
 	return ids, nil
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"crypto/x509"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"net"
+	"slices"
+	"strings"
+)
+
+// every request is validated before it reaches SQL or the CA, all invalid fields are reported at once
+
+const (
+	// maxCertNames is the Let's Encrypt limit of names per certificate, the domain included
+	maxCertNames = 100
+
+	defaultPage     = 1
+	defaultPageSize = 10
+	maxPageSize     = 500
+
+	maxTagLength   = 128
+	maxNotesLength = 4096
+)
+
+// domainStatuses are the statuses a domain can have, used to reject typos in filters
+var domainStatuses = []string{"pending", "active", "expired", "check_failed", "update_failed", "revoked", "deleted"}
+
+// validateCreateDomain normalizes the names and checks every field of a new domain
+func validateCreateDomain(req *models.CreateDomainReq) error {
+	var verr models.ValidationError
+	collect(&verr, normalizeDomainNames(req))
+	collect(&verr, validateKind(req))
+
+	switch req.VerificationMethod {
+	case "", "dns-01":
+	default:
+		if hasWildcard(req) {
+			verr.Add("verification_method", "wildcard names can only be validated with dns-01, got %q", req.VerificationMethod)
+		} else {
+			verr.Add("verification_method", "only dns-01 is supported, got %q", req.VerificationMethod)
+		}
+	}
+	if req.KeyType != "" && !slices.Contains(utils.CertKeyTypes, req.KeyType) {
+		verr.Add("key_type", "must be one of %s, got %q", strings.Join(utils.CertKeyTypes, ", "), req.KeyType)
+	}
+	if req.Profile != "" && !utils.ValidProfile(req.Profile) {
+		verr.Add("profile", "must be a profile name like tlsserver or shortlived, got %q", req.Profile)
+	}
+	validateTags(&verr, req.Tags)
+	validateOutputFormats(&verr, req.OutputFormats)
+	if len(req.Notes) > maxNotesLength {
+		verr.Add("notes", "is %d characters long, at most %d are allowed", len(req.Notes), maxNotesLength)
+	}
+	return verr.Err()
+}
+
+// validateCAKeyType rejects a key type of a domain the configured CA doesn't sign, e.g. rsa8192 with
+// Let's Encrypt, empty follows certs.key_type which is checked on load
+func (s *Service) validateCAKeyType(keyType string) error {
+	supported := s.config().CAKeyTypes()
+	if keyType == "" || !slices.Contains(utils.CertKeyTypes, keyType) || slices.Contains(supported, keyType) {
+		return nil
+	}
+	var verr models.ValidationError
+	verr.Add("key_type", "%s isn't signed by the CA of certs.ca_dir_url, use one of %s", keyType, strings.Join(supported, ", "))
+	return verr.Err()
+}
+
+// validateGetDomains fills in the page defaults, negative values and oversized pages are rejected
+func validateGetDomains(req *models.GetDomainsReq) error {
+	var verr models.ValidationError
+	if req.Page == 0 {
+		req.Page = defaultPage
+	}
+	if req.PageSize == 0 {
+		req.PageSize = defaultPageSize
+	}
+	if req.Page < 1 {
+		verr.Add("page", "must be at least 1, got %d", req.Page)
+	}
+	if req.PageSize < 1 || req.PageSize > maxPageSize {
+		verr.Add("page_size", "must be between 1 and %d, got %d", maxPageSize, req.PageSize)
+	}
+	if req.Status != "" && !slices.Contains(domainStatuses, req.Status) {
+		verr.Add("status", "must be one of %s, got %q", strings.Join(domainStatuses, ", "), req.Status)
+	}
+	return verr.Err()
+}
+
+func validateUpdateDomain(req *models.UpdateDomainReq) error {
+	var verr models.ValidationError
+	validateDomainRef(&verr, req.DomainID, req.DomainName)
+	if req.KeyType != nil && *req.KeyType != "" && !slices.Contains(utils.CertKeyTypes, *req.KeyType) {
+		verr.Add("key_type", "must be one of %s, got %q", strings.Join(utils.CertKeyTypes, ", "), *req.KeyType)
+	}
+	if req.Profile != nil && *req.Profile != "" && !utils.ValidProfile(*req.Profile) {
+		verr.Add("profile", "must be a profile name like tlsserver or shortlived, got %q", *req.Profile)
+	}
+	validateTags(&verr, req.Tags)
+	validateOutputFormats(&verr, req.OutputFormats)
+	if req.Notes != nil && len(*req.Notes) > maxNotesLength {
+		verr.Add("notes", "is %d characters long, at most %d are allowed", len(*req.Notes), maxNotesLength)
+	}
+	return verr.Err()
+}
+
+func validateDeleteDomain(req *models.DeleteDomainReq) error {
+	var verr models.ValidationError
+	validateDomainRef(&verr, req.DomainID, req.DomainName)
+	return verr.Err()
+}
+
+func validateGetCertificates(req *models.GetCertificatesReq) error {
+	var verr models.ValidationError
+	validateDomainRef(&verr, req.DomainID, req.DomainName)
+	return verr.Err()
+}
+
+func validateCheckDrift(req *models.CheckDriftReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	return verr.Err()
+}
+
+// validateDelegateAcmeDNS normalizes the domain name, a wildcard shares the delegation of its base name
+func validateDelegateAcmeDNS(req *models.DelegateAcmeDNSReq) error {
+	var verr models.ValidationError
+	domain, err := utils.NormalizeDomainName(req.DomainName)
+	if err != nil {
+		verr.Add("domain_name", "%v", err)
+	}
+	req.DomainName = strings.TrimPrefix(domain, "*.")
+	return verr.Err()
+}
+
+func validateActivateCertificate(req *models.ActivateCertificateReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	if req.CertificateID == "" {
+		verr.Add("certificate_id", "is required")
+	} else if !isUUID(req.CertificateID) {
+		verr.Add("certificate_id", "must be a UUID, got %q", req.CertificateID)
+	}
+	return verr.Err()
+}
+
+func validateArchivedCertificates(req *models.ArchivedCertificatesReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	return verr.Err()
+}
+
+func validateRestoreCertificate(req *models.RestoreCertificateReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	if req.Version == "" {
+		verr.Add("version", "is required")
+	}
+	return verr.Err()
+}
+
+func validateSwitchChain(req *models.SwitchChainReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	if req.CertificateID == "" {
+		verr.Add("certificate_id", "is required")
+	} else if !isUUID(req.CertificateID) {
+		verr.Add("certificate_id", "must be a UUID, got %q", req.CertificateID)
+	}
+	if req.Chain == "" {
+		verr.Add("chain", "is required")
+	}
+	return verr.Err()
+}
+
+func validateExportCertificate(req *models.ExportCertificateReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	if req.CertificateID != "" && !isUUID(req.CertificateID) {
+		verr.Add("certificate_id", "must be a UUID, got %q", req.CertificateID)
+	}
+	if req.Format == "" {
+		req.Format = models.ExportFormatPEM
+	}
+	switch req.Format {
+	case models.ExportFormatPKCS12:
+		if req.Password == "" {
+			verr.Add("password", "is required for pkcs12")
+		}
+	case models.ExportFormatPEM:
+		if req.Password != "" || req.Legacy {
+			verr.Add("password", "password and legacy only apply to pkcs12")
+		}
+	default:
+		verr.Add("format", "must be %s or %s, got %q", models.ExportFormatPEM, models.ExportFormatPKCS12, req.Format)
+	}
+	return verr.Err()
+}
+
+func validateRevokeCertificate(req *models.RevokeCertificateReq) error {
+	var verr models.ValidationError
+	if req.DomainName == "" {
+		verr.Add("domain_name", "is required")
+	}
+	return verr.Err()
+}
+
+// validateDomainRef requires a domain id or name, an id that isn't a UUID would fail in SQL.
+// The services look a Unicode name up by its punycode form, see lookupName
+func validateDomainRef(verr *models.ValidationError, id, name string) {
+	if id == "" && lookupName(name) == "" {
+		verr.Add("domain_id", "domain_id or domain_name is required")
+		return
+	}
+	if id != "" && !isUUID(id) {
+		verr.Add("domain_id", "must be a UUID, got %q", id)
+	}
+}
+
+func validateTags(verr *models.ValidationError, tags []string) {
+	for i, t := range tags {
+		if len(t) > maxTagLength {
+			verr.Add(fmt.Sprintf("tags[%d]", i), "is %d characters long, at most %d are allowed", len(t), maxTagLength)
+		}
+	}
+}
+
+func validateOutputFormats(verr *models.ValidationError, formats []string) {
+	for i, f := range formats {
+		if !slices.Contains(utils.OutputFormats, f) {
+			verr.Add(fmt.Sprintf("output_formats[%d]", i), "must be one of %s, got %q", strings.Join(utils.OutputFormats, ", "), f)
+		}
+	}
+}
+
+// lookupName returns the stored (punycode) form of a domain name given in either form, names that
+// don't normalize are returned as they are and simply match nothing
+func lookupName(name string) string {
+	if normalized, err := utils.NormalizeDomainName(name); err == nil {
+		return normalized
+	}
+	return name
+}
+
+// collect adds the field errors of err to verr
+func collect(verr *models.ValidationError, err error) {
+	if e, ok := err.(*models.ValidationError); ok {
+		verr.Errors = append(verr.Errors, e.Errors...)
+	}
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalizeDomainNames converts the domain and its alternative domains to lowercase punycode,
+// lego would otherwise fail late with a less helpful error
+func normalizeDomainNames(req *models.CreateDomainReq) error {
+	var verr models.ValidationError
+
+	domain, err := utils.NormalizeDomainName(req.Domain)
+	if err != nil {
+		verr.Add("domain", "%v", err)
+	}
+	req.Domain = domain
+
+	if len(req.AltDomains)+1 > maxCertNames {
+		verr.Add("alternative_domains", "has %d names, at most %d are allowed next to the domain", len(req.AltDomains), maxCertNames-1)
+	}
+	seen := make(map[string]int, len(req.AltDomains))
+	for i, alt := range req.AltDomains {
+		field := fmt.Sprintf("alternative_domains[%d]", i)
+		name, err := utils.NormalizeDomainName(alt)
+		if err != nil {
+			verr.Add(field, "%v", err)
+			continue
+		}
+		req.AltDomains[i] = name
+
+		if name == req.Domain {
+			verr.Add(field, "duplicates domain %q", name)
+		} else if first, ok := seen[name]; ok {
+			verr.Add(field, "duplicates alternative_domains[%d] %q", first, name)
+		} else {
+			seen[name] = i
+		}
+	}
+	if verr.Err() != nil {
+		return verr.Err()
+	}
+
+	// the CA rejects names a wildcard of the same order already covers, they are dropped. The apex
+	// isn't covered by its wildcard, *.example.com and example.com both have to be listed
+	wildcards := map[string]bool{}
+	for _, name := range append([]string{req.Domain}, req.AltDomains...) {
+		if strings.HasPrefix(name, "*.") {
+			wildcards[name] = true
+		}
+	}
+	if wildcard, ok := coveringWildcard(req.Domain); ok && wildcards[wildcard] {
+		verr.Add("domain", "is covered by the alternative domain %q, make the wildcard the domain", wildcard)
+	}
+	req.AltDomains = slices.DeleteFunc(req.AltDomains, func(name string) bool {
+		wildcard, ok := coveringWildcard(name)
+		return ok && wildcards[wildcard]
+	})
+	return verr.Err()
+}
+
+// coveringWildcard is the wildcard that would cover name, *.example.com for www.example.com.
+// Wildcards only cover one label, so a wildcard itself has none
+func coveringWildcard(name string) (string, bool) {
+	if strings.HasPrefix(name, "*.") {
+		return "", false
+	}
+	_, parent, _ := strings.Cut(name, ".")
+	return "*." + parent, true
+}
+
+// hasWildcard reports whether the certificate of the request covers a wildcard name
+func hasWildcard(req *models.CreateDomainReq) bool {
+	if strings.HasPrefix(req.Domain, "*.") {
+		return true
+	}
+	return slices.ContainsFunc(req.AltDomains, func(name string) bool {
+		return strings.HasPrefix(name, "*.")
+	})
+}
+
+// validateKind checks the fields that depend on the kind of the domain, an empty kind is managed
+func validateKind(req *models.CreateDomainReq) error {
+	var verr models.ValidationError
+	switch req.Kind {
+	case "":
+		req.Kind = models.DomainKindManaged
+	case models.DomainKindManaged, models.DomainKindMonitored:
+	default:
+		verr.Add("kind", "must be %s or %s, got %q", models.DomainKindManaged, models.DomainKindMonitored, req.Kind)
+	}
+
+	if req.Kind == models.DomainKindMonitored {
+		if len(req.AltDomains) > 0 {
+			verr.Add("alternative_domains", "not used by monitored domains, the served certificate is recorded as is")
+		}
+		if strings.HasPrefix(req.Domain, "*.") {
+			verr.Add("domain", "monitored domains must be a host, not a wildcard")
+		}
+		if req.MonitorAddress != "" {
+			if _, port, err := net.SplitHostPort(req.MonitorAddress); err != nil || port == "" {
+				verr.Add("monitor_address", "must look like host:port, got %q", req.MonitorAddress)
+			}
+		}
+		if req.KeyType != "" {
+			verr.Add("key_type", "not used by monitored domains, they are never issued")
+		}
+		if req.Profile != "" {
+			verr.Add("profile", "not used by monitored domains, they are never issued")
+		}
+		if req.ReuseKey {
+			verr.Add("reuse_key", "not used by monitored domains, they are never issued")
+		}
+		if len(req.OutputFormats) > 0 {
+			verr.Add("output_formats", "not used by monitored domains, no files are written for them")
+		}
+	} else if req.MonitorAddress != "" {
+		verr.Add("monitor_address", "only used by monitored domains")
+	}
+	return verr.Err()
+}
+
+// validateIssueFromCSR parses the CSR and returns it with its normalized names
+func validateIssueFromCSR(req *models.IssueFromCSRReq) (*x509.CertificateRequest, []string, error) {
+	var verr models.ValidationError
+	if strings.TrimSpace(req.CSR) == "" {
+		verr.Add("csr", "is required")
+		return nil, nil, verr.Err()
+	}
+	csr, err := parseCSR(req.CSR)
+	if err != nil {
+		verr.Add("csr", "%v", err)
+		return nil, nil, verr.Err()
+	}
+	names, err := csrNames(csr)
+	if err != nil {
+		verr.Add("csr", "%v", err)
+	} else if len(names) > maxCertNames {
+		verr.Add("csr", "has %d names, at most %d are allowed", len(names), maxCertNames)
+	}
+	if req.Profile != "" && !utils.ValidProfile(req.Profile) {
+		verr.Add("profile", "must be a profile name like tlsserver or shortlived, got %q", req.Profile)
+	}
+	return csr, names, verr.Err()
+}
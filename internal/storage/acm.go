@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// acmKeyTypes are listed explicitly, ListCertificates only returns RSA 2048 certificates by default
+var acmKeyTypes = []string{"RSA_1024", "RSA_2048", "RSA_3072", "RSA_4096", "EC_prime256v1", "EC_secp384r1", "EC_secp521r1"}
+
+const (
+	// acmAttempts is how often a throttled or failed call is sent before its error is returned
+	acmAttempts = 3
+	acmBackoff  = 500 * time.Millisecond
+)
+
+// acmError is an error answer of the ACM API, Code is the exception without its namespace
+type acmError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *acmError) Error() string {
+	return fmt.Sprintf("%d %s %s", e.Status, e.Code, e.Message)
+}
+
+// retryable tells throttling and server errors, which go away on their own, from the rest
+func (e *acmError) retryable() bool {
+	return e.Status >= 500 || e.Code == "ThrottlingException" || e.Code == "TooManyRequestsException"
+}
+
+// acm imports the certificates into AWS Certificate Manager, through its JSON API signed with SigV4.
+// A renewal re-imports into the certificate of the domain, its ARN stays the same so load balancers
+// and CloudFront distributions using it serve the renewed certificate
+type acm struct {
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	client      *http.Client
+	log         *utils.Logger
+}
+
+type acmTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+func newACM(cfg utils.AWSPublishConfig, httpConfig utils.HTTPClientConfig, log *utils.Logger) (*acm, error) {
+	awsCfg, err := loadAWSPublishConfig(cfg, httpConfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := utils.NewHTTPClient(httpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("acm http client: %w", err)
+	}
+	return &acm{
+		endpoint:    fmt.Sprintf("https://acm.%s.amazonaws.com/", awsCfg.Region),
+		region:      awsCfg.Region,
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+		client:      client,
+		log:         log,
+	}, nil
+}
+
+func (p *acm) Publish(domain string, key, cert []byte) (string, error) {
+	leaf, rest := pem.Decode(cert)
+	if leaf == nil || leaf.Type != "CERTIFICATE" {
+		return "", errors.New("no certificate to import")
+	}
+	arn, err := p.find(domain)
+	if err != nil {
+		return "", err
+	}
+
+	// blobs are base64 in the JSON protocol, which encoding/json does for []byte
+	input := map[string]any{
+		"Certificate": pem.EncodeToMemory(leaf),
+		"PrivateKey":  key,
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		input["CertificateChain"] = rest
+	}
+	if arn != "" {
+		input["CertificateArn"] = arn
+	} else {
+		// tags can only be given on the first import
+		input["Tags"] = []acmTag{{Key: awsDomainTag, Value: domain}}
+	}
+	var imported struct {
+		CertificateArn string `json:"CertificateArn"`
+	}
+	err = p.call("ImportCertificate", input, &imported)
+	var aerr *acmError
+	if arn != "" && errors.As(err, &aerr) && aerr.Code == "ResourceNotFoundException" {
+		// deleted since it was listed, the domain gets a new certificate
+		p.log.Warn("Certificate ", arn, " of ", domain, " is gone from acm, importing a new one")
+		delete(input, "CertificateArn")
+		input["Tags"] = []acmTag{{Key: awsDomainTag, Value: domain}}
+		arn = ""
+		err = p.call("ImportCertificate", input, &imported)
+	}
+	if err != nil {
+		return "", fmt.Errorf("import certificate of %s: %w", domain, err)
+	}
+	if arn != "" {
+		p.log.Debug("Certificate of ", domain, " re-imported into acm as ", imported.CertificateArn)
+	} else {
+		p.log.Debug("Certificate of ", domain, " imported into acm as ", imported.CertificateArn)
+	}
+	return imported.CertificateArn, nil
+}
+
+// find returns the ARN of the imported certificate tagged with the domain, empty when there is none
+func (p *acm) find(domain string) (string, error) {
+	input := map[string]any{"Includes": map[string]any{"keyTypes": acmKeyTypes}, "MaxItems": 1000}
+	for {
+		var page struct {
+			CertificateSummaryList []struct {
+				CertificateArn string `json:"CertificateArn"`
+				DomainName     string `json:"DomainName"`
+				Type           string `json:"Type"`
+			} `json:"CertificateSummaryList"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := p.call("ListCertificates", input, &page); err != nil {
+			return "", fmt.Errorf("list acm certificates: %w", err)
+		}
+		for _, summary := range page.CertificateSummaryList {
+			if summary.DomainName != domain || summary.Type != "IMPORTED" {
+				continue
+			}
+			// certificates imported by hand for the same domain are left alone
+			var tags struct {
+				Tags []acmTag `json:"Tags"`
+			}
+			if err := p.call("ListTagsForCertificate", map[string]any{"CertificateArn": summary.CertificateArn}, &tags); err != nil {
+				return "", fmt.Errorf("list tags of %s: %w", summary.CertificateArn, err)
+			}
+			for _, tag := range tags.Tags {
+				if tag.Key == awsDomainTag && tag.Value == domain {
+					return summary.CertificateArn, nil
+				}
+			}
+		}
+		if page.NextToken == "" {
+			return "", nil
+		}
+		input["NextToken"] = page.NextToken
+	}
+}
+
+// call sends an action of the ACM API and decodes its response into out, throttled and failed calls are
+// sent again with a growing delay
+func (p *acm) call(action string, input, out any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err = p.send(action, body, out)
+		var aerr *acmError
+		if err == nil || attempt == acmAttempts || !errors.As(err, &aerr) || !aerr.retryable() {
+			return err
+		}
+		p.log.Debug("acm ", action, " failed, attempt ", attempt, ": ", err)
+		time.Sleep(acmBackoff << (attempt - 1))
+	}
+}
+
+// send signs and sends one request of the action
+func (p *acm) send(action string, body []byte, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), awsRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "CertificateManager."+action)
+
+	creds, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("acm credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "acm", p.region, time.Now()); err != nil {
+		return fmt.Errorf("sign acm request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var problem struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		// encoding/json matches message and Message, ACM sends either
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&problem)
+		// __type may carry the namespace, e.g. com.amazonaws.acm#ValidationException
+		code := problem.Type
+		if _, after, ok := strings.Cut(problem.Type, "#"); ok {
+			code = after
+		}
+		return &acmError{Status: resp.StatusCode, Code: code, Message: problem.Message}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// fakeACM serves the ACM actions the publisher calls, ListCertificates one certificate per page
+type fakeACM struct {
+	t *testing.T
+
+	mu    sync.Mutex
+	certs []fakeACMCert
+	calls []string
+	// failures answers the next calls of an action with these exceptions
+	failures map[string][]string
+}
+
+type fakeACMCert struct {
+	arn, domain, typ string
+	tags             []acmTag
+	imports          int
+}
+
+func (a *fakeACM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySigV4(r); err != nil {
+		a.t.Errorf("%s: %v", r.Header.Get("X-Amz-Target"), err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "CertificateManager.")
+	var input struct {
+		CertificateArn string
+		NextToken      string
+		Certificate    []byte
+		Tags           []acmTag
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &input); err != nil {
+		a.t.Errorf("%s: %v", action, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, action)
+	if failures := a.failures[action]; len(failures) > 0 {
+		a.failures[action] = failures[1:]
+		status := http.StatusBadRequest
+		if failures[0] == "InternalFailure" {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"__type":"com.amazonaws.acm#%s","Message":"%s"}`, failures[0], failures[0])
+		return
+	}
+
+	switch action {
+	case "ListCertificates":
+		n := 0
+		fmt.Sscan(input.NextToken, &n)
+		page := map[string]any{"CertificateSummaryList": []any{}}
+		if n < len(a.certs) {
+			c := a.certs[n]
+			page["CertificateSummaryList"] = []any{map[string]string{"CertificateArn": c.arn, "DomainName": c.domain, "Type": c.typ}}
+		}
+		if n+1 < len(a.certs) {
+			page["NextToken"] = fmt.Sprint(n + 1)
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	case "ListTagsForCertificate":
+		for _, c := range a.certs {
+			if c.arn == input.CertificateArn {
+				_ = json.NewEncoder(w).Encode(map[string]any{"Tags": c.tags})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type":"ResourceNotFoundException"}`)
+	case "ImportCertificate":
+		if block, _ := pem.Decode(input.Certificate); block == nil {
+			a.t.Error("ImportCertificate without a PEM certificate")
+		}
+		if input.CertificateArn == "" {
+			arn := fmt.Sprintf("arn:aws:acm:us-east-1:1:certificate/%d", len(a.certs))
+			a.certs = append(a.certs, fakeACMCert{arn: arn, typ: "IMPORTED", tags: input.Tags, imports: 1})
+			_ = json.NewEncoder(w).Encode(map[string]string{"CertificateArn": arn})
+			return
+		}
+		for i := range a.certs {
+			if a.certs[i].arn == input.CertificateArn {
+				a.certs[i].imports++
+				_ = json.NewEncoder(w).Encode(map[string]string{"CertificateArn": input.CertificateArn})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type":"com.amazonaws.acm#ResourceNotFoundException","message":"not found"}`)
+	}
+}
+
+func newTestACM(t *testing.T, certs ...fakeACMCert) (*acm, *fakeACM) {
+	t.Helper()
+	fake := &fakeACM{t: t, certs: certs, failures: map[string][]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return &acm{
+		endpoint:    server.URL + "/",
+		region:      "us-east-1",
+		credentials: credentials.NewStaticCredentialsProvider(testAWSAccessKey, testAWSSecretKey, ""),
+		signer:      v4.NewSigner(),
+		client:      server.Client(),
+		log:         utils.NewLogger("error"),
+	}, fake
+}
+
+var testACMCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("leaf")})
+
+func TestACMReimportsTheTaggedCertificate(t *testing.T) {
+	p, fake := newTestACM(t,
+		fakeACMCert{arn: "by-hand", domain: "example.com", typ: "IMPORTED"},
+		fakeACMCert{arn: "issued", domain: "example.com", typ: "AMAZON_ISSUED"},
+		fakeACMCert{arn: "other", domain: "example.org", typ: "IMPORTED", tags: []acmTag{{Key: awsDomainTag, Value: "example.org"}}},
+		fakeACMCert{arn: "ours", domain: "example.com", typ: "IMPORTED", tags: []acmTag{{Key: awsDomainTag, Value: "example.com"}}},
+	)
+	arn, err := p.Publish("example.com", []byte("key"), testACMCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arn != "ours" || fake.certs[3].imports != 1 || fake.certs[0].imports != 0 {
+		t.Errorf("imported into %s, certificates %+v", arn, fake.certs)
+	}
+}
+
+func TestACMImportsANewCertificate(t *testing.T) {
+	p, fake := newTestACM(t)
+	arn, err := p.Publish("*.example.com", []byte("key"), testACMCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.certs) != 1 || fake.certs[0].arn != arn || len(fake.certs[0].tags) != 1 || fake.certs[0].tags[0].Value != "*.example.com" {
+		t.Errorf("imported %s, certificates %+v", arn, fake.certs)
+	}
+}
+
+func TestACMImportsAgainWhenTheCertificateIsGone(t *testing.T) {
+	p, fake := newTestACM(t, fakeACMCert{arn: "ours", domain: "example.com", typ: "IMPORTED", tags: []acmTag{{Key: awsDomainTag, Value: "example.com"}}})
+	fake.failures["ImportCertificate"] = []string{"ResourceNotFoundException"}
+	arn, err := p.Publish("example.com", []byte("key"), testACMCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arn == "ours" || len(fake.certs) != 2 {
+		t.Errorf("imported into %s, certificates %+v", arn, fake.certs)
+	}
+}
+
+func TestACMRetriesThrottledCalls(t *testing.T) {
+	p, fake := newTestACM(t)
+	fake.failures["ListCertificates"] = []string{"ThrottlingException"}
+	if _, err := p.Publish("example.com", []byte("key"), testACMCert); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.calls, ","); got != "ListCertificates,ListCertificates,ImportCertificate" {
+		t.Errorf("calls %s", got)
+	}
+}
+
+func TestACMReturnsTypedErrors(t *testing.T) {
+	p, fake := newTestACM(t)
+	fake.failures["ImportCertificate"] = []string{"ValidationException"}
+	_, err := p.Publish("example.com", []byte("key"), testACMCert)
+	var aerr *acmError
+	if !errors.As(err, &aerr) || aerr.Code != "ValidationException" || aerr.Message != "ValidationException" {
+		t.Errorf("got %v, want the ACM exception", err)
+	}
+	if got := strings.Join(fake.calls, ","); got != "ListCertificates,ImportCertificate" {
+		t.Errorf("a validation error was sent again: %s", got)
+	}
+}
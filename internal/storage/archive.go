@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	archiveDir = "archive"
+	// versionTimeFormat names versions without a serial and archived versions
+	versionTimeFormat = "20060102T150405Z"
+)
+
+// withArchive moves replaced versions into <domain>/archive/<timestamp> instead of removing them, with a
+// -2, -3 and on suffix for versions archived within the same second
+func (s *CertStore) withArchive(cfg utils.CertArchiveConfig) *CertStore {
+	s.archive = cfg
+	return s
+}
+
+// Archive moves the version of certPath into the archive of the domain and returns its paths there,
+// archived versions beyond certs.archive are removed. Without certs.archive the version is retired
+func (s *CertStore) Archive(domain, certPath string) (*models.CertificatePaths, error) {
+	if s.archive.Keep == 0 {
+		return nil, s.Retire(domain, certPath)
+	}
+	domainDir := filepath.Join(s.dir, domain)
+	versionDir := filepath.Dir(certPath)
+	if filepath.Dir(versionDir) != domainDir || filepath.Base(versionDir) == archiveDir {
+		return nil, fmt.Errorf("%s is not a certificate version of %s", certPath, domain)
+	}
+	if err := s.checkNotCurrent(domainDir, filepath.Base(versionDir)); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(domainDir, archiveDir), 0755); err != nil {
+		return nil, fmt.Errorf("create archive: %w", err)
+	}
+	archived, err := archiveName(filepath.Join(domainDir, archiveDir), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.log.Debug("Archiving certificate version ", versionDir, " as ", archived)
+	if err := os.Rename(versionDir, archived); err != nil {
+		return nil, fmt.Errorf("archive certificate version: %w", err)
+	}
+	if err := s.pruneArchive(domain); err != nil {
+		s.log.Warn("Failed to prune the archive of ", domain, ": ", err)
+	}
+	return versionPaths(archived), nil
+}
+
+// Archived lists the archived versions of the domain, newest first
+func (s *CertStore) Archived(domain string) ([]models.ArchivedCertificate, error) {
+	dir := filepath.Join(s.dir, domain, archiveDir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+
+	var versions []models.ArchivedCertificate
+	seq := map[string]int{}
+	for _, e := range entries {
+		archivedAt, n, ok := parseArchiveName(e.Name())
+		if !e.IsDir() || !ok {
+			continue
+		}
+		seq[e.Name()] = n
+		versions = append(versions, models.ArchivedCertificate{
+			Version:    e.Name(),
+			ArchivedAt: archivedAt,
+			CertPath:   filepath.Join(dir, e.Name(), liveFiles[0]),
+		})
+	}
+	// newest first, versions archived within the same second by their suffix
+	slices.SortFunc(versions, func(a, b models.ArchivedCertificate) int {
+		if c := b.ArchivedAt.Compare(a.ArchivedAt); c != 0 {
+			return c
+		}
+		return seq[b.Version] - seq[a.Version]
+	})
+	return versions, nil
+}
+
+// archiveName returns a free directory name in the archive for a version archived at, the timestamp
+// with a -2, -3 and on suffix when versions were archived within the same second
+func archiveName(dir string, at time.Time) (string, error) {
+	base := at.UTC().Format(versionTimeFormat)
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		path := filepath.Join(dir, name)
+		if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		} else if err != nil {
+			return "", fmt.Errorf("archive certificate version: %w", err)
+		}
+	}
+}
+
+// parseArchiveName reads the timestamp and suffix of an archived version, 1 without a suffix
+func parseArchiveName(name string) (time.Time, int, bool) {
+	stamp, suffix, found := strings.Cut(name, "-")
+	archivedAt, err := time.Parse(versionTimeFormat, stamp)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	if !found {
+		return archivedAt, 1, true
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil || n < 2 {
+		return time.Time{}, 0, false
+	}
+	return archivedAt, n, true
+}
+
+// Restore moves an archived version back next to the current one, under its archive timestamp, and
+// returns its paths. It isn't made current
+func (s *CertStore) Restore(domain, version string) (*models.CertificatePaths, error) {
+	if _, _, ok := parseArchiveName(version); !ok {
+		return nil, fmt.Errorf("%q is not an archived version", version)
+	}
+	archived := filepath.Join(s.dir, domain, archiveDir, version)
+	if _, err := os.Stat(archived); err != nil {
+		return nil, fmt.Errorf("archived version %s of %s: %w", version, domain, err)
+	}
+	restored := filepath.Join(s.dir, domain, version)
+	if _, err := os.Lstat(restored); !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("certificate version %s of %s already exists", version, domain)
+	}
+	s.log.Debug("Restoring archived certificate version ", archived)
+	if err := os.Rename(archived, restored); err != nil {
+		return nil, fmt.Errorf("restore certificate version: %w", err)
+	}
+	return versionPaths(restored), nil
+}
+
+// pruneArchive removes the archived versions beyond certs.archive.keep and those older than max_age
+func (s *CertStore) pruneArchive(domain string) error {
+	versions, err := s.Archived(domain)
+	if err != nil {
+		return err
+	}
+	for i, v := range versions {
+		if i < s.archive.Keep && (s.archive.MaxAge == 0 || time.Since(v.ArchivedAt) <= s.archive.MaxAge) {
+			continue
+		}
+		s.log.Debug("Removing archived certificate version ", v.Version, " of ", domain)
+		if err := os.RemoveAll(filepath.Dir(v.CertPath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func versionPaths(versionDir string) *models.CertificatePaths {
+	return &models.CertificatePaths{
+		Cert:  filepath.Join(versionDir, liveFiles[0]),
+		Key:   filepath.Join(versionDir, liveFiles[1]),
+		Chain: filepath.Join(versionDir, liveFiles[2]),
+	}
+}
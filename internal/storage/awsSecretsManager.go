@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+const (
+	// awsRequestTimeout bounds a single call to Secrets Manager or ACM
+	awsRequestTimeout       = 30 * time.Second
+	defaultAWSSecretsPrefix = "hephaestus/"
+	// awsDomainTag marks the secrets and ACM certificates hephaestus manages with their domain
+	awsDomainTag = "hephaestus:domain"
+)
+
+// awsSecretsManager keeps the certificates as secrets, one per domain, every renewal puts a new
+// version which becomes AWSCURRENT, so consumers reading the secret by name rotate on their own
+type awsSecretsManager struct {
+	cfg    utils.AWSSecretsManagerConfig
+	client *secretsmanager.Client
+	log    *utils.Logger
+}
+
+// awsSecret is the value of the secrets, the certificate is the leaf followed by its chain
+type awsSecret struct {
+	Domain      string    `json:"domain"`
+	Certificate string    `json:"certificate"`
+	PrivateKey  string    `json:"private_key"`
+	Serial      string    `json:"serial"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+func newAWSSecretsManager(cfg utils.AWSPublishConfig, httpConfig utils.HTTPClientConfig, log *utils.Logger) (*awsSecretsManager, error) {
+	awsCfg, err := loadAWSPublishConfig(cfg, httpConfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SecretsManager.NamePrefix == "" {
+		cfg.SecretsManager.NamePrefix = defaultAWSSecretsPrefix
+	}
+	return &awsSecretsManager{cfg: cfg.SecretsManager, client: secretsmanager.NewFromConfig(awsCfg), log: log}, nil
+}
+
+func (p *awsSecretsManager) Publish(domain string, key, cert []byte) (string, error) {
+	leaf, err := utils.ParseLeafCertificate(cert)
+	if err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(awsSecret{
+		Domain:      domain,
+		Certificate: string(cert),
+		PrivateKey:  string(key),
+		Serial:      utils.CertificateSerial(leaf),
+		NotAfter:    leaf.NotAfter,
+	})
+	if err != nil {
+		return "", err
+	}
+	name := awsSecretName(p.cfg.NamePrefix, domain)
+
+	ctx, cancel := context.WithTimeout(context.Background(), awsRequestTimeout)
+	defer cancel()
+	put, err := p.client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(string(value)),
+	})
+	if err == nil {
+		p.log.Debug("New version of secret ", name, " put for ", domain)
+		return aws.ToString(put.ARN), nil
+	}
+	var notFound *smtypes.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return "", fmt.Errorf("put secret %s: %w", name, err)
+	}
+
+	// first issuance of the domain
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		Description:  aws.String("Certificate and private key of " + domain),
+		SecretString: aws.String(string(value)),
+		Tags:         []smtypes.Tag{{Key: aws.String(awsDomainTag), Value: aws.String(domain)}},
+	}
+	if p.cfg.KMSKeyID != "" {
+		input.KmsKeyId = aws.String(p.cfg.KMSKeyID)
+	}
+	created, err := p.client.CreateSecret(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("create secret %s: %w", name, err)
+	}
+	p.log.Debug("Secret ", name, " created for ", domain)
+	return aws.ToString(created.ARN), nil
+}
+
+// loadAWSPublishConfig returns the AWS config of certs.publish.aws, the static keys when set, the
+// default credential chain otherwise
+func loadAWSPublishConfig(cfg utils.AWSPublishConfig, httpConfig utils.HTTPClientConfig) (aws.Config, error) {
+	client, err := utils.NewHTTPClient(httpConfig)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("aws http client: %w", err)
+	}
+	opts := []func(*awsconfig.LoadOptions) error{awsconfig.WithHTTPClient(client)}
+	if cfg.AccessKey != "" && cfg.SecretKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+		))
+	}
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("load aws config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return aws.Config{}, errors.New("aws region is not set, set certs.publish.aws.region or AWS_REGION")
+	}
+	return awsCfg, nil
+}
+
+// awsSecretName maps the domain to a secret name below the prefix, *.example.com becomes
+// wildcard.example.com as secret names don't allow the asterisk
+func awsSecretName(prefix, domain string) string {
+	if rest, ok := strings.CutPrefix(domain, "*."); ok {
+		domain = "wildcard." + rest
+	}
+	return prefix + domain
+}
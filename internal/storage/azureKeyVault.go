@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+const (
+	azureKeyVaultAPIVersion   = "7.4"
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com"
+	// azureIMDSTokenURL is the managed identity endpoint of Azure VMs and AKS nodes
+	azureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// azureNameLimit is the longest name of a Key Vault object
+	azureNameLimit = 127
+)
+
+// azureKeyVault imports the certificates as certificate objects of a Key Vault, one object per domain,
+// every import adds a version of it. Consumers like Application Gateway reference the versionless secret
+// of the object and pick up renewals themselves
+type azureKeyVault struct {
+	cfg      utils.AzureKeyVaultConfig
+	resource string // audience of the tokens, e.g. https://vault.azure.net
+	client   *http.Client
+	log      *utils.Logger
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newAzureKeyVault(cfg utils.AzureKeyVaultConfig, httpConfig utils.HTTPClientConfig, log *utils.Logger) (*azureKeyVault, error) {
+	u, err := url.Parse(cfg.VaultURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid key vault url %q", cfg.VaultURL)
+	}
+	cfg.VaultURL = strings.TrimRight(cfg.VaultURL, "/")
+	if cfg.AuthorityHost == "" {
+		cfg.AuthorityHost = defaultAzureAuthorityHost
+	}
+	cfg.AuthorityHost = strings.TrimRight(cfg.AuthorityHost, "/")
+
+	client, err := utils.NewHTTPClient(httpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("key vault http client: %w", err)
+	}
+	// my-vault.vault.azure.net is of https://vault.azure.net, sovereign clouds have their own domain
+	_, domain, _ := strings.Cut(u.Hostname(), ".")
+	return &azureKeyVault{cfg: cfg, resource: "https://" + domain, client: client, log: log}, nil
+}
+
+func (p *azureKeyVault) Publish(domain string, key, cert []byte) (string, error) {
+	key, keyProps, err := azureKey(key)
+	if err != nil {
+		return "", err
+	}
+	name := azureCertificateName(p.cfg.NamePrefix, domain)
+	token, err := p.accessToken()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"value": string(key) + string(cert),
+		"policy": map[string]any{
+			"key_props":    keyProps,
+			"secret_props": map[string]any{"contentType": "application/x-pem-file"},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	endpoint := p.cfg.VaultURL + "/certificates/" + name + "/import?api-version=" + azureKeyVaultAPIVersion
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var imported struct {
+		ID string `json:"id"`
+	}
+	if err := p.send(req, &imported); err != nil {
+		return "", fmt.Errorf("import certificate %s: %w", name, err)
+	}
+	p.log.Debug("Certificate of ", domain, " imported into key vault as ", imported.ID)
+	return imported.ID, nil
+}
+
+// accessToken returns a token for the vault, from the client secret or the managed identity
+func (p *azureKeyVault) accessToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.expires) {
+		return p.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if p.cfg.ClientSecret != "" {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {p.cfg.ClientID},
+			"client_secret": {p.cfg.ClientSecret},
+			"scope":         {p.resource + "/.default"},
+		}
+		req, err = http.NewRequest(http.MethodPost, p.cfg.AuthorityHost+"/"+url.PathEscape(p.cfg.TenantID)+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		query := url.Values{"api-version": {"2018-02-01"}, "resource": {p.resource}}
+		if p.cfg.ClientID != "" {
+			query.Set("client_id", p.cfg.ClientID)
+		}
+		req, err = http.NewRequest(http.MethodGet, azureIMDSTokenURL+"?"+query.Encode(), nil)
+		if err == nil {
+			req.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var body struct {
+		AccessToken string          `json:"access_token"`
+		ExpiresIn   json.RawMessage `json:"expires_in"` // a number from Entra ID, a string from the managed identity endpoint
+	}
+	if err := p.send(req, &body); err != nil {
+		return "", fmt.Errorf("azure token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("azure token endpoint returned no token")
+	}
+	seconds, _ := strconv.Atoi(strings.Trim(string(body.ExpiresIn), `"`))
+	p.token = body.AccessToken
+	p.expires = time.Now().Add(time.Duration(seconds)*time.Second - tokenRenewMargin)
+	p.log.Debug("Got an Azure token for ", p.resource, ", valid for ", seconds, "s")
+	return p.token, nil
+}
+
+func (p *azureKeyVault) send(req *http.Request, out any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		// Key Vault nests the error, Entra ID doesn't
+		var problem struct {
+			Error            json.RawMessage `json:"error"`
+			ErrorDescription string          `json:"error_description"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&problem)
+		var nested struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(problem.Error, &nested) == nil {
+			return fmt.Errorf("%s %s %s", resp.Status, nested.Code, nested.Message)
+		}
+		return fmt.Errorf("%s %s %s", resp.Status, strings.Trim(string(problem.Error), `"`), problem.ErrorDescription)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// azureCertificateName maps the domain to a Key Vault object name, which allows letters, digits and
+// dashes only: *.example.com becomes wildcard-example-com-<hash>. The hash of the domain keeps a-b.com and
+// a.b.com, or long names cut at the limit, from overwriting each other
+func azureCertificateName(prefix, domain string) string {
+	sum := sha256.Sum256([]byte(domain))
+	suffix := "-" + hex.EncodeToString(sum[:4])
+	if rest, ok := strings.CutPrefix(domain, "*."); ok {
+		domain = "wildcard." + rest
+	}
+	name := prefix + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, domain)
+	if len(name) > azureNameLimit-len(suffix) {
+		name = name[:azureNameLimit-len(suffix)]
+	}
+	return name + suffix
+}
+
+// azureKey re-encodes the key as PKCS#8, the only PEM key format Key Vault imports, and returns the key
+// properties of the import policy, which default to RSA. The key stays exportable for the consumers
+func azureKey(key []byte) ([]byte, map[string]any, error) {
+	parsed, err := certcrypto.ParsePEMPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse certificate key: %w", err)
+	}
+	props := map[string]any{"exportable": true, "kty": "RSA"}
+	if k, ok := parsed.(*ecdsa.PrivateKey); ok {
+		props["kty"] = "EC"
+		props["crv"] = map[int]string{256: "P-256", 384: "P-384", 521: "P-521"}[k.Curve.Params().BitSize]
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(parsed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode certificate key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), props, nil
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAzure serves the Entra ID token endpoint of the tenant and the certificate import of Key Vault
+type fakeAzure struct {
+	t   *testing.T
+	url string
+
+	mu      sync.Mutex
+	tokens  int
+	imports map[string][]azureImport
+}
+
+// azureImport is the body of a certificate import
+type azureImport struct {
+	Value  string `json:"value"`
+	Policy struct {
+		KeyProps    map[string]any    `json:"key_props"`
+		SecretProps map[string]string `json:"secret_props"`
+	} `json:"policy"`
+}
+
+var azureImportPath = regexp.MustCompile(`^/certificates/([A-Za-z0-9-]+)/import$`)
+
+func (a *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+		if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+			a.t.Errorf("token request %s: %v", r.Method, err)
+		}
+		want := map[string]string{
+			"grant_type":    "client_credentials",
+			"client_id":     "app",
+			"client_secret": "app-secret",
+			"scope":         "https://vault.azure.net/.default",
+		}
+		for field, value := range want {
+			if got := r.PostForm.Get(field); got != value {
+				a.t.Errorf("token request %s = %q, want %q", field, got, value)
+			}
+		}
+		a.tokens++
+		_ = json.NewEncoder(w).Encode(map[string]any{"token_type": "Bearer", "access_token": fmt.Sprint("token-", a.tokens), "expires_in": 3600})
+		return
+	}
+
+	match := azureImportPath.FindStringSubmatch(r.URL.Path)
+	if match == nil || r.Method != http.MethodPost || r.URL.Query().Get("api-version") != azureKeyVaultAPIVersion {
+		a.t.Errorf("unexpected call %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if got := r.Header.Get("Authorization"); got != fmt.Sprint("Bearer token-", a.tokens) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"code":"Unauthorized","message":"AKV10000: Request is missing a Bearer or PoP token."}}`)
+		return
+	}
+	var body azureImport
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		a.t.Errorf("import body: %v", err)
+	}
+	name := match[1]
+	a.imports[name] = append(a.imports[name], body)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": fmt.Sprintf("%s/certificates/%s/%d", a.url, name, len(a.imports[name]))})
+}
+
+func newTestAzureKeyVault(t *testing.T) (*azureKeyVault, *fakeAzure) {
+	t.Helper()
+	fake := &fakeAzure{t: t, imports: map[string][]azureImport{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+	return &azureKeyVault{
+		cfg: utils.AzureKeyVaultConfig{
+			VaultURL:      server.URL,
+			TenantID:      "tenant",
+			ClientID:      "app",
+			ClientSecret:  "app-secret",
+			AuthorityHost: server.URL,
+		},
+		resource: "https://vault.azure.net",
+		client:   server.Client(),
+		log:      utils.NewLogger("error"),
+	}, fake
+}
+
+func TestAzureKeyVaultImportsTheCertificate(t *testing.T) {
+	p, fake := newTestAzureKeyVault(t)
+	_, ecKey := testKeys(t)
+	version := testVersion(t, 1, ecKey)
+	// the SEC 1 key is imported as PKCS#8
+	sec1, err := x509.MarshalECPrivateKey(ecKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})
+
+	for i := 1; i <= 2; i++ {
+		id, err := p.Publish("*.example.com", key, version.Cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := azureCertificateName("", "*.example.com")
+		if want := fmt.Sprintf("%s/certificates/%s/%d", fake.url, name, i); id != want {
+			t.Errorf("got id %s, want %s", id, want)
+		}
+	}
+	if fake.tokens != 1 {
+		t.Errorf("requested %d tokens, want one for both imports", fake.tokens)
+	}
+
+	imports := fake.imports[azureCertificateName("", "*.example.com")]
+	if len(imports) != 2 {
+		t.Fatalf("got imports %v, want two versions of the certificate", fake.imports)
+	}
+	body := imports[0]
+	block, rest := pem.Decode([]byte(body.Value))
+	if block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("the value doesn't start with a PKCS#8 key:\n%s", body.Value)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		t.Errorf("parse imported key: %v", err)
+	}
+	if string(rest) != string(version.Cert) {
+		t.Errorf("the key is followed by\n%s\nwant the certificate", rest)
+	}
+	if props := body.Policy.KeyProps; props["kty"] != "EC" || props["crv"] != "P-256" || props["exportable"] != true {
+		t.Errorf("got key_props %v", props)
+	}
+	if got := body.Policy.SecretProps["contentType"]; got != "application/x-pem-file" {
+		t.Errorf("got secret contentType %q", got)
+	}
+}
+
+func TestAzureKeyVaultImportsRSAKeys(t *testing.T) {
+	p, fake := newTestAzureKeyVault(t)
+	rsaKey, _ := testKeys(t)
+	version := testVersion(t, 1, rsaKey)
+	if _, err := p.Publish("example.com", version.Key, version.Cert); err != nil {
+		t.Fatal(err)
+	}
+	imports := fake.imports[azureCertificateName("", "example.com")]
+	if len(imports) != 1 || imports[0].Policy.KeyProps["kty"] != "RSA" || imports[0].Policy.KeyProps["crv"] != nil {
+		t.Errorf("got imports %+v, want an RSA key", imports)
+	}
+}
+
+func TestAzureKeyVaultErrors(t *testing.T) {
+	p, _ := newTestAzureKeyVault(t)
+	p.token, p.expires = "revoked", time.Now().Add(time.Hour)
+	rsaKey, _ := testKeys(t)
+	version := testVersion(t, 1, rsaKey)
+	_, err := p.Publish("example.com", version.Key, version.Cert)
+	if err == nil || !strings.Contains(err.Error(), "Unauthorized AKV10000") {
+		t.Errorf("got %v, want the nested Key Vault error", err)
+	}
+}
+
+func TestAzureCertificateName(t *testing.T) {
+	suffix := func(domain string) string {
+		sum := sha256.Sum256([]byte(domain))
+		return "-" + hex.EncodeToString(sum[:4])
+	}
+	tests := []struct {
+		prefix, domain, want string
+	}{
+		{"", "example.com", "example-com" + suffix("example.com")},
+		{"hephaestus-", "*.example.com", "hephaestus-wildcard-example-com" + suffix("*.example.com")},
+		{"", "xn--bcher-kva.example", "xn--bcher-kva-example" + suffix("xn--bcher-kva.example")},
+	}
+	for _, tt := range tests {
+		if got := azureCertificateName(tt.prefix, tt.domain); got != tt.want {
+			t.Errorf("azureCertificateName(%q, %q) = %q, want %q", tt.prefix, tt.domain, got, tt.want)
+		}
+	}
+
+	// names that map to the same characters are told apart by the hash of the domain
+	if azureCertificateName("", "a-b.example.com") == azureCertificateName("", "a.b.example.com") {
+		t.Error("a-b.example.com and a.b.example.com share a name")
+	}
+	long := strings.Repeat("a", 60) + "." + strings.Repeat("b", 60) + "." + strings.Repeat("c", 60) + ".com"
+	name := azureCertificateName("", long)
+	if len(name) != azureNameLimit || !strings.HasSuffix(name, suffix(long)) {
+		t.Errorf("got %q (%d characters), want %d characters ending in the hash", name, len(name), azureNameLimit)
+	}
+	if other := azureCertificateName("", long[:len(long)-4]+".org"); other == name {
+		t.Error("long names cut at the limit share a name")
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"os"
+	"path/filepath"
+)
+
+// Backend keeps named files outside of the certificate versions of CertStore: the private keys of
+// the issuer and, with a backend other than file, the certificates. Names are slash separated,
+// e.g. pending/example.com-1700000000.key
+type Backend interface {
+	// Read returns an error wrapping os.ErrNotExist when there is no file of the name
+	Read(name string) ([]byte, error)
+	Write(name string, data []byte) error
+	// Delete removes the file of the name and every file below it, a missing name isn't an error
+	Delete(name string) error
+	// Location is where the file of the name is kept, as shown to users and stored in the database
+	Location(name string) string
+}
+
+// NewBackend returns the backend of certs.storage, the files of the file backend are in storage_dir and
+// the ones of the postgres backend in files
+func NewBackend(cfg *utils.Config, files FileTable, log *utils.Logger) (Backend, error) {
+	switch cfg.Certs.Storage.BackendName() {
+	case utils.StorageFile:
+		if sealer := newSealer(cfg.Certs.Encryption); sealer != nil {
+			return &sealedBackend{Backend: &fileBackend{dir: cfg.Certs.StorageDir}, sealer: sealer}, nil
+		}
+		return &fileBackend{dir: cfg.Certs.StorageDir}, nil
+	case utils.StorageVault:
+		return newVaultBackend(cfg.Certs.Storage.Vault, cfg.HTTPClient, log)
+	case utils.StorageS3:
+		return newS3Backend(cfg.Certs.Storage.S3, cfg.HTTPClient, log)
+	case utils.StoragePostgres:
+		backend, err := newPostgresBackend(files)
+		if err != nil {
+			return nil, err
+		}
+		sealer := newSealer(cfg.Certs.Encryption)
+		if sealer == nil {
+			return nil, errors.New("postgres storage backend needs certs.encryption.key")
+		}
+		return &sealedBackend{Backend: backend, sealer: sealer}, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Certs.Storage.Backend)
+}
+
+// NewStore returns the certificate store of certs.storage: version directories in storage_dir for the
+// file backend, encrypted with certs.encryption and written as certs.files sets, files of the backend otherwise
+func NewStore(cfg *utils.Config, backend Backend, log *utils.Logger) CertStoreInterface {
+	if cfg.Certs.Storage.BackendName() == utils.StorageFile {
+		return NewCertStore(cfg.Certs.StorageDir, log).withFiles(cfg.Certs.Files).withArchive(cfg.Certs.Archive).withEncryption(cfg.Certs.Encryption)
+	}
+	return NewObjectStore(backend, log)
+}
+
+// fileBackend keeps the files readable by the owner only, they hold private keys
+type fileBackend struct {
+	dir string
+}
+
+func (b *fileBackend) Read(name string) ([]byte, error) {
+	return os.ReadFile(b.Location(name))
+}
+
+func (b *fileBackend) Write(name string, data []byte) error {
+	path := b.Location(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (b *fileBackend) Delete(name string) error {
+	if err := os.RemoveAll(b.Location(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *fileBackend) Location(name string) string {
+	return filepath.Join(b.dir, filepath.FromSlash(name))
+}
@@ -0,0 +1,643 @@
+package storage
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CertStoreInterface saves and removes the certificate files of a domain, used by services.Service
+type CertStoreInterface interface {
+	Save(domain string, certData *models.CertificateData) (*models.CertificatePaths, error)
+	Stage(domain string, certData *models.CertificateData) (*models.CertificatePaths, error)
+	Promote(domain, certPath string, replacePrimary bool) (previous string, err error)
+	Retire(domain, certPath string) error
+	Archive(domain, certPath string) (*models.CertificatePaths, error)
+	Archived(domain string) ([]models.ArchivedCertificate, error)
+	Restore(domain, version string) (*models.CertificatePaths, error)
+	ReadLive(domain string) (path string, certPEM []byte, err error)
+	ReadLiveKey(domain string) (keyPEM []byte, err error)
+	ReadCurrent(domain, keyType string) (path string, certPEM []byte, err error)
+	Read(certPath string) (certPEM []byte, err error)
+	SwitchChain(domain, certPath, chain string) error
+	Delete(domain string) error
+}
+
+var _ CertStoreInterface = (*CertStore)(nil)
+
+const (
+	currentLink = "current"
+	legacyDir   = "legacy"
+)
+
+// keyTypeLink is the link to the current version of a key type, e.g. current-ec256
+func keyTypeLink(keyType string) string {
+	return currentLink + "-" + keyType
+}
+
+// isCurrentLink tells whether a name of a domain directory is the primary link or one of a key type
+func isCurrentLink(name string) bool {
+	return name == currentLink || strings.HasPrefix(name, currentLink+"-") && !strings.HasSuffix(name, ".tmp")
+}
+
+// liveFiles are the paths deployment targets read, symlinks into the current version
+var liveFiles = []string{"cert.pem", "privkey.pem", "chain.pem"}
+
+// CertStore keeps the certificate files of every domain under <storage_dir>/<domain>,
+// independent of the DNS provider the certificate was issued with. Every certificate gets its own
+// version directory, <domain>/current-<key type> points at the one in use for its key type and <domain>/current
+// at the primary one. <domain>/cert.pem, privkey.pem, chain.pem and fullchain.pem are symlinks through
+// current, so a rotation swaps all of them at once.
+// With certs.encryption the files are encrypted and the live files are decrypted into liveDir
+type CertStore struct {
+	dir     string
+	sealer  *sealer
+	liveDir string
+	files   filePerms
+	archive utils.CertArchiveConfig
+	log     *utils.Logger
+}
+
+func NewCertStore(dir string, log *utils.Logger) *CertStore {
+	return &CertStore{dir: dir, files: newFilePerms(utils.CertFilesConfig{}), log: log}
+}
+
+// withFiles writes the files from now on with the mode and owner of certs.files
+func (s *CertStore) withFiles(cfg utils.CertFilesConfig) *CertStore {
+	s.files = newFilePerms(cfg)
+	return s
+}
+
+// withEncryption encrypts the files written from now on and writes the decrypted live files of every
+// domain to live_dir, which may be a tmpfs emptied by a reboot
+func (s *CertStore) withEncryption(cfg utils.CertEncryptionConfig) *CertStore {
+	s.sealer = newSealer(cfg)
+	if s.sealer == nil {
+		return s
+	}
+	if err := s.sealExisting(); err != nil {
+		s.log.Warn("Files in ", s.dir, " written before encryption stay as they are: ", err)
+	}
+	if cfg.LiveDir == "" {
+		return s
+	}
+	s.liveDir = cfg.LiveDir
+	entries, err := os.ReadDir(s.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.log.Warn("Live files not written to ", s.liveDir, ": ", err)
+	}
+	for _, e := range entries {
+		if _, err := os.Readlink(filepath.Join(s.dir, e.Name(), currentLink)); err != nil {
+			continue
+		}
+		if err := s.writeLive(e.Name()); err != nil {
+			s.log.Warn("Live files of ", e.Name(), " not written to ", s.liveDir, ": ", err)
+		}
+	}
+	return s
+}
+
+// sealExisting encrypts the files written before certs.encryption was turned on, so enabling it
+// doesn't leave the keys of every issued certificate plaintext until their renewal, and seals the files of
+// older releases again under a derived key. Symlinks are skipped, they point at the sealed versions
+func (s *CertStore) sealExisting() error {
+	sealed := 0
+	err := filepath.WalkDir(s.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		private := privateFile(path)
+		if !private && !(s.sealer.certs && slices.Contains([]string{".pem", ".der"}, filepath.Ext(path))) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isSealed(data) && !isLegacy(data) {
+			return nil
+		}
+		// files sealed under the SHA-256 of the key are sealed again under a derived one
+		if data, err = s.sealer.open(s.storageName(path), data); err != nil {
+			return fmt.Errorf("decrypt %s: %w", path, err)
+		}
+		if err := s.writeFile(path, data, private); err != nil {
+			return fmt.Errorf("encrypt %s: %w", path, err)
+		}
+		sealed++
+		return nil
+	})
+	if sealed > 0 {
+		s.log.Info("Encrypted ", sealed, " files written before certs.encryption was turned on or by an older release")
+	}
+	return err
+}
+
+// Save stages the certificate and makes it the current one right away
+func (s *CertStore) Save(domain string, certData *models.CertificateData) (*models.CertificatePaths, error) {
+	paths, err := s.Stage(domain, certData)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Promote(domain, paths.Cert, false); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Stage writes the certificate into its own version directory next to the current one,
+// deployment targets keep reading the current files until Promote
+func (s *CertStore) Stage(domain string, certData *models.CertificateData) (*models.CertificatePaths, error) {
+	s.log.Debug("CertStore.Stage(): called for domain: ", domain)
+	version := certData.SerialNumber
+	if version == "" {
+		version = time.Now().UTC().Format(versionTimeFormat)
+	}
+	baseDir := filepath.Join(s.dir, domain, version)
+	s.log.Debug("Ensuring version directory: ", baseDir)
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create version dir: %w", err)
+	}
+
+	certPath := filepath.Join(baseDir, "cert.pem")
+	keyPath := filepath.Join(baseDir, "privkey.pem")
+	chainPath := filepath.Join(baseDir, "chain.pem")
+
+	s.log.Debug("Writing cert file: ", certPath)
+	if err := s.writeFile(certPath, certData.Cert, false); err != nil {
+		return nil, fmt.Errorf("write cert: %w", err)
+	}
+	s.log.Debug("Writing key file: ", keyPath)
+	if err := s.writeFile(keyPath, certData.Key, true); err != nil {
+		return nil, fmt.Errorf("write key: %w", err)
+	}
+	s.log.Debug("Writing chain file: ", chainPath)
+	// an empty chain file keeps configs referencing it valid, the cert may already bundle the chain
+	if err := s.writeFile(chainPath, certData.Chain, false); err != nil {
+		return nil, fmt.Errorf("write chain: %w", err)
+	}
+
+	// alternate chains are kept next to the default one, which is copied so it can be switched back to
+	if len(certData.AltChains) > 0 {
+		for n, chain := range append([][]byte{certData.Chain}, certData.AltChains...) {
+			path := chainFile(baseDir, models.ChainName(n))
+			s.log.Debug("Writing chain file: ", path)
+			if err := s.writeFile(path, chain, false); err != nil {
+				return nil, fmt.Errorf("write chain %s: %w", models.ChainName(n), err)
+			}
+		}
+	}
+
+	files, err := formatFiles(certData)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		path := filepath.Join(baseDir, f.name)
+		s.log.Debug("Writing file: ", path)
+		if err := s.writeFile(path, f.data, f.private); err != nil {
+			return nil, fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+
+	s.log.Debug("Certificate files staged successfully")
+	return versionPaths(baseDir), nil
+}
+
+// Promote points the link of the key type of certPath, e.g. <domain>/current-ec256, at its version and
+// returns the cert path of the version of that key type that was current before, empty when there was none.
+// The live files follow when the primary version, <domain>/current, has the same key type or there is none,
+// like the primary certificate of the domain, so the certificate of another key type is served next to it.
+// replacePrimary makes the version primary also over another key type, e.g. a renewal after the domain's key
+// type changed, the link of the old key type is removed and the old primary version returned
+func (s *CertStore) Promote(domain, certPath string, replacePrimary bool) (string, error) {
+	domainDir := filepath.Join(s.dir, domain)
+	version := filepath.Base(filepath.Dir(certPath))
+	if filepath.Dir(filepath.Dir(certPath)) != domainDir || isCurrentLink(version) || version == archiveDir {
+		return "", fmt.Errorf("%s is not a certificate version of %s", certPath, domain)
+	}
+	if _, err := os.Stat(filepath.Join(domainDir, version)); err != nil {
+		return "", fmt.Errorf("certificate version %s: %w", version, err)
+	}
+	keyType, err := s.versionKeyType(filepath.Join(domainDir, version))
+	if err != nil {
+		return "", err
+	}
+
+	primary, err := s.current(domainDir)
+	if err != nil {
+		return "", err
+	}
+	previous, err := s.currentOf(domainDir, keyType, primary)
+	if err != nil {
+		return "", err
+	}
+	if err := s.ensureFullchain(filepath.Join(domainDir, version)); err != nil {
+		return "", err
+	}
+
+	// the primary version has another key type when it isn't the previous one of this key type
+	var primaryType string
+	if replacePrimary && primary != "" && primary != previous {
+		if primaryType, err = s.versionKeyType(filepath.Join(domainDir, primary)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.link(domainDir, keyTypeLink(keyType), version); err != nil {
+		return "", err
+	}
+	if primary == "" || primary == previous || primaryType != "" {
+		if err := s.link(domainDir, currentLink, version); err != nil {
+			return "", err
+		}
+		if err := s.linkLiveFiles(domainDir); err != nil {
+			return "", err
+		}
+		if err := s.writeLive(domain); err != nil {
+			return "", err
+		}
+	}
+	if primaryType != "" {
+		if err := os.Remove(filepath.Join(domainDir, keyTypeLink(primaryType))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("remove %s link: %w", primaryType, err)
+		}
+		s.log.Info("Primary certificate of ", domain, " switched from ", primaryType, " to ", keyType)
+		previous = primary
+	}
+
+	s.log.Debug("Certificate version ", version, " of ", domain, " is current for ", keyType)
+	if previous == "" || previous == version {
+		return "", nil
+	}
+	return filepath.Join(domainDir, previous, "cert.pem"), nil
+}
+
+// link points the link of the domain at version, the swap is a rename over the link, readers see
+// either the old or the new version
+func (s *CertStore) link(domainDir, name, version string) error {
+	tmp := filepath.Join(domainDir, name+".tmp")
+	_ = os.Remove(tmp)
+	if err := os.Symlink(version, tmp); err != nil {
+		return fmt.Errorf("link version: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(domainDir, name)); err != nil {
+		return fmt.Errorf("switch version: %w", err)
+	}
+	return nil
+}
+
+// Retire removes the version directory of certPath, versions current for a key type are kept
+func (s *CertStore) Retire(domain, certPath string) error {
+	domainDir := filepath.Join(s.dir, domain)
+	versionDir := filepath.Dir(certPath)
+	if filepath.Dir(versionDir) != domainDir {
+		return fmt.Errorf("%s is not a certificate version of %s", certPath, domain)
+	}
+	if err := s.checkNotCurrent(domainDir, filepath.Base(versionDir)); err != nil {
+		return err
+	}
+
+	s.log.Debug("Retiring certificate version: ", versionDir)
+	if err := os.RemoveAll(versionDir); err != nil {
+		return fmt.Errorf("remove certificate version: %w", err)
+	}
+	return nil
+}
+
+// ReadLive reads the certificate deployment targets are given, <domain>/cert.pem through the current version
+func (s *CertStore) ReadLive(domain string) (string, []byte, error) {
+	path := filepath.Join(s.dir, domain, liveFiles[0])
+	data, err := s.readFile(path)
+	if err != nil {
+		return path, nil, fmt.Errorf("read live certificate: %w", err)
+	}
+	return path, data, nil
+}
+
+// ReadLiveKey reads the private key of the current version, <domain>/privkey.pem
+func (s *CertStore) ReadLiveKey(domain string) ([]byte, error) {
+	data, err := s.readFile(filepath.Join(s.dir, domain, liveFiles[1]))
+	if err != nil {
+		return nil, fmt.Errorf("read live key: %w", err)
+	}
+	return data, nil
+}
+
+// ReadCurrent reads the certificate of the current version of the key type, through current-<key type>
+func (s *CertStore) ReadCurrent(domain, keyType string) (string, []byte, error) {
+	domainDir := filepath.Join(s.dir, domain)
+	primary, err := s.current(domainDir)
+	if err != nil {
+		return "", nil, err
+	}
+	version, err := s.currentOf(domainDir, keyType, primary)
+	if err != nil {
+		return "", nil, err
+	}
+	if version == "" {
+		return "", nil, fmt.Errorf("no %s certificate of %s: %w", keyType, domain, os.ErrNotExist)
+	}
+	path := filepath.Join(domainDir, version, liveFiles[0])
+	data, err := s.readFile(path)
+	if err != nil {
+		return path, nil, fmt.Errorf("read current %s certificate: %w", keyType, err)
+	}
+	return path, data, nil
+}
+
+// Read reads a stored certificate version by the cert path Stage returned
+func (s *CertStore) Read(certPath string) ([]byte, error) {
+	data, err := s.readFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("read certificate: %w", err)
+	}
+	return data, nil
+}
+
+// SwitchChain makes chain, e.g. alt1, the chain of the certificate version of certPath: chain.pem is
+// replaced and cert.pem bundles the leaf with it. Each file is swapped by a rename
+func (s *CertStore) SwitchChain(domain, certPath, chain string) error {
+	versionDir := filepath.Dir(certPath)
+	if filepath.Dir(versionDir) != filepath.Join(s.dir, domain) {
+		return fmt.Errorf("%s is not a certificate version of %s", certPath, domain)
+	}
+	chainPEM, err := s.readFile(chainFile(versionDir, chain))
+	if err != nil {
+		return fmt.Errorf("read chain %s: %w", chain, err)
+	}
+	certPEM, err := s.readFile(certPath)
+	if err != nil {
+		return fmt.Errorf("read certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("no certificate in %s", certPath)
+	}
+
+	s.log.Debug("Switching chain of ", versionDir, " to ", chain)
+	bundle := append(pem.EncodeToMemory(block), chainPEM...)
+	if err := s.writeFile(filepath.Join(versionDir, liveFiles[2]), chainPEM, false); err != nil {
+		return fmt.Errorf("write chain: %w", err)
+	}
+	if err := s.writeFile(certPath, bundle, false); err != nil {
+		return fmt.Errorf("write cert: %w", err)
+	}
+	// fullchain.pem is the leaf with the chain as well
+	if err := s.writeFile(filepath.Join(versionDir, fullchainFile), bundle, false); err != nil {
+		return fmt.Errorf("write fullchain: %w", err)
+	}
+	if current, err := s.current(filepath.Dir(versionDir)); err != nil || current != filepath.Base(versionDir) {
+		return err
+	}
+	return s.writeLive(domain)
+}
+
+// chainFile is the path of a stored chain in a version directory, e.g. chain-alt1.pem
+func chainFile(versionDir, chain string) string {
+	return filepath.Join(versionDir, "chain-"+chain+".pem")
+}
+
+// writeFile writes a file of a version, encrypted with certs.encryption
+func (s *CertStore) writeFile(path string, data []byte, private bool) error {
+	data, err := s.sealer.seal(s.storageName(path), data, private)
+	if err != nil {
+		return err
+	}
+	return s.replaceFile(path, data, private)
+}
+
+// readFile reads a file of a version, decrypted
+func (s *CertStore) readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = s.sealer.open(s.storageName(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// storageName is the name of the file in storage_dir, the one the backend would give it
+func (s *CertStore) storageName(path string) string {
+	name, err := filepath.Rel(s.dir, path)
+	if err != nil {
+		return path
+	}
+	return name
+}
+
+// writeLive writes the decrypted current files of the domain to <live_dir>/<domain>, the keys first and
+// the certificates last. Nothing is written without live_dir
+func (s *CertStore) writeLive(domain string) error {
+	if s.liveDir == "" {
+		return nil
+	}
+	dir := filepath.Join(s.liveDir, domain)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create live dir: %w", err)
+	}
+	for _, name := range []string{liveFiles[1], derFiles[1], liveFiles[2], fullchainFile, derFiles[0], liveFiles[0]} {
+		data, err := s.readFile(filepath.Join(s.dir, domain, currentLink, name))
+		if errors.Is(err, os.ErrNotExist) && slices.Contains(derFiles, name) {
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		if err := s.replaceFile(filepath.Join(dir, name), data, privateFile(name)); err != nil {
+			return fmt.Errorf("write live %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ensureFullchain writes fullchain.pem into a version written before every version had it
+func (s *CertStore) ensureFullchain(versionDir string) error {
+	path := filepath.Join(versionDir, fullchainFile)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	certPEM, err := s.readFile(filepath.Join(versionDir, liveFiles[0]))
+	if err != nil {
+		return fmt.Errorf("read certificate: %w", err)
+	}
+	chainPEM, err := s.readFile(filepath.Join(versionDir, liveFiles[2]))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read chain: %w", err)
+	}
+	fullchain, err := fullchainPEM(certPEM, chainPEM)
+	if err != nil {
+		return fmt.Errorf("%s: %w", versionDir, err)
+	}
+	s.log.Debug("Writing fullchain file: ", path)
+	return s.writeFile(path, fullchain, false)
+}
+
+// replaceFile writes data next to path and renames it over path, readers see the old or the new content.
+// The mode and owner are set before the rename, so the file is never readable by others in between
+func (s *CertStore) replaceFile(path string, data []byte, private bool) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	err := s.files.apply(tmp, private)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// current returns the current version of the domain, files written before versions existed
+// are moved into the legacy version first
+func (s *CertStore) current(domainDir string) (string, error) {
+	target, err := os.Readlink(filepath.Join(domainDir, currentLink))
+	if err == nil {
+		return target, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("read current version: %w", err)
+	}
+
+	info, err := os.Lstat(filepath.Join(domainDir, liveFiles[0]))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "", nil
+	}
+
+	s.log.Info("Moving certificate files of ", filepath.Base(domainDir), " into version ", legacyDir)
+	if err := os.MkdirAll(filepath.Join(domainDir, legacyDir), 0755); err != nil {
+		return "", fmt.Errorf("create legacy version: %w", err)
+	}
+	for _, name := range liveFiles {
+		err := os.Rename(filepath.Join(domainDir, name), filepath.Join(domainDir, legacyDir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("move %s into legacy version: %w", name, err)
+		}
+	}
+	return legacyDir, nil
+}
+
+// currentOf returns the current version of the key type, versions promoted before every key type had
+// its own link only have the primary one
+func (s *CertStore) currentOf(domainDir, keyType, primary string) (string, error) {
+	target, err := os.Readlink(filepath.Join(domainDir, keyTypeLink(keyType)))
+	if err == nil {
+		return target, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("read current %s version: %w", keyType, err)
+	}
+	if primary == "" {
+		return "", nil
+	}
+	primaryType, err := s.versionKeyType(filepath.Join(domainDir, primary))
+	if err != nil {
+		return "", err
+	}
+	if primaryType != keyType {
+		return "", nil
+	}
+	return primary, nil
+}
+
+// checkNotCurrent fails when a link of the domain, the primary one or one of a key type, points at version
+func (s *CertStore) checkNotCurrent(domainDir, version string) error {
+	if _, err := s.current(domainDir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(domainDir)
+	if err != nil {
+		return fmt.Errorf("read certificate versions: %w", err)
+	}
+	for _, e := range entries {
+		if !isCurrentLink(e.Name()) {
+			continue
+		}
+		if target, err := os.Readlink(filepath.Join(domainDir, e.Name())); err == nil && target == version {
+			return fmt.Errorf("certificate version %s of %s is %s", version, filepath.Base(domainDir), e.Name())
+		}
+	}
+	return nil
+}
+
+// versionKeyType names the key of the certificate of a version like certificates.key_type
+func (s *CertStore) versionKeyType(versionDir string) (string, error) {
+	data, err := s.readFile(filepath.Join(versionDir, liveFiles[0]))
+	if err != nil {
+		return "", fmt.Errorf("read certificate: %w", err)
+	}
+	cert, err := utils.ParseLeafCertificate(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", versionDir, err)
+	}
+	return utils.CertificateKeyType(cert), nil
+}
+
+// linkLiveFiles creates the relative symlinks deployment targets read, they never change afterwards.
+// The DER files are only linked while the current version has them
+func (s *CertStore) linkLiveFiles(domainDir string) error {
+	for _, name := range slices.Concat(liveFiles, []string{fullchainFile}, derFiles) {
+		path := filepath.Join(domainDir, name)
+		target := currentLink + string(filepath.Separator) + name
+		if slices.Contains(derFiles, name) {
+			if _, err := os.Stat(filepath.Join(domainDir, target)); err != nil {
+				_ = os.Remove(path)
+				continue
+			}
+		}
+		if existing, err := os.Readlink(path); err == nil && existing == target {
+			continue
+		}
+		_ = os.Remove(path)
+		if err := os.Symlink(target, path); err != nil {
+			return fmt.Errorf("link %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *CertStore) Delete(domain string) error {
+	s.log.Info("Deleting certificate files for domain: ", domain)
+	dir := filepath.Join(s.dir, domain)
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("certificate not found for domain: %s", domain)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove certificate directory: %w", err)
+	}
+	if s.liveDir != "" {
+		if err := os.RemoveAll(filepath.Join(s.liveDir, domain)); err != nil {
+			return fmt.Errorf("failed to remove live files: %w", err)
+		}
+	}
+
+	return nil
+}
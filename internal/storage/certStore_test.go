@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testVersion returns the files of a self-signed certificate for a key of the type, named by serial
+func testVersion(t *testing.T, serial int64, key crypto.Signer) *models.CertificateData {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.CertificateData{
+		SerialNumber: fmt.Sprintf("%X", serial),
+		Cert:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:          pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func testKeys(t *testing.T) (rsaKey, ecKey crypto.Signer) {
+	t.Helper()
+	r, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, e
+}
+
+// stores runs a test against the file store and the object store on the file backend
+func stores(t *testing.T, test func(t *testing.T, store CertStoreInterface, link func(name string) string)) {
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		test(t, NewCertStore(dir, utils.NewLogger("error")), func(name string) string {
+			target, _ := os.Readlink(filepath.Join(dir, "example.com", name))
+			return target
+		})
+	})
+	t.Run("object", func(t *testing.T) {
+		backend := &fileBackend{dir: t.TempDir()}
+		test(t, NewObjectStore(backend, utils.NewLogger("error")), func(name string) string {
+			data, _ := backend.Read("example.com/" + name)
+			return string(data)
+		})
+	})
+}
+
+func TestPromoteKeepsACurrentVersionPerKeyType(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	stores(t, func(t *testing.T, store CertStoreInterface, link func(string) string) {
+		rsaPaths, err := store.Save("example.com", testVersion(t, 1, rsaKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a certificate of another key type is served next to the primary one
+		ecPaths, err := store.Stage("example.com", testVersion(t, 2, ecKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, err := store.Promote("example.com", ecPaths.Cert, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous != "" {
+			t.Errorf("the first ec256 version replaced %s", previous)
+		}
+		if link("current") != "1" || link("current-rsa2048") != "1" || link("current-ec256") != "2" {
+			t.Errorf("links current %q, rsa2048 %q, ec256 %q", link("current"), link("current-rsa2048"), link("current-ec256"))
+		}
+		if err := store.Retire("example.com", ecPaths.Cert); err == nil {
+			t.Error("retired the current ec256 version")
+		}
+
+		// the renewal of one key type replaces only the version of that key type
+		renewed, err := store.Stage("example.com", testVersion(t, 3, ecKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, err = store.Promote("example.com", renewed.Cert, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous != ecPaths.Cert {
+			t.Errorf("the ec256 renewal replaced %q, want %q", previous, ecPaths.Cert)
+		}
+		if err := store.Retire("example.com", previous); err != nil {
+			t.Errorf("retire the replaced ec256 version: %v", err)
+		}
+		if err := store.Retire("example.com", rsaPaths.Cert); err == nil {
+			t.Error("retired the primary rsa2048 version")
+		}
+
+		// a renewal of the primary key type moves the live files
+		rsaRenewed, err := store.Stage("example.com", testVersion(t, 4, rsaKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous, err = store.Promote("example.com", rsaRenewed.Cert, false); err != nil || previous != rsaPaths.Cert {
+			t.Errorf("the rsa2048 renewal replaced %q (%v), want %q", previous, err, rsaPaths.Cert)
+		}
+		if link("current") != "4" || link("current-ec256") != "3" {
+			t.Errorf("links current %q, ec256 %q", link("current"), link("current-ec256"))
+		}
+	})
+}
+
+func TestPromoteVersionsWithoutKeyTypeLinks(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	stores(t, func(t *testing.T, store CertStoreInterface, link func(string) string) {
+		first, err := store.Save("example.com", testVersion(t, 1, rsaKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// as promoted by a release with a single current link
+		switch s := store.(type) {
+		case *CertStore:
+			_ = os.Remove(filepath.Join(s.dir, "example.com", "current-rsa2048"))
+		case *ObjectStore:
+			_ = s.backend.Delete("example.com/current-rsa2048")
+		}
+
+		ec, err := store.Stage("example.com", testVersion(t, 2, ecKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous, err := store.Promote("example.com", ec.Cert, false); err != nil || previous != "" {
+			t.Errorf("the first ec256 version replaced %q (%v)", previous, err)
+		}
+		renewed, err := store.Stage("example.com", testVersion(t, 3, rsaKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous, err := store.Promote("example.com", renewed.Cert, false); err != nil || previous != first.Cert {
+			t.Errorf("the rsa2048 renewal replaced %q (%v), want %q", previous, err, first.Cert)
+		}
+		if link("current") != "3" {
+			t.Errorf("current is %q", link("current"))
+		}
+	})
+}
+
+func TestPromoteSwitchesThePrimaryKeyType(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	stores(t, func(t *testing.T, store CertStoreInterface, link func(string) string) {
+		rsa, err := store.Save("example.com", testVersion(t, 1, rsaKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the renewal after key_type changed to ec256 replaces the rsa2048 primary
+		ec, err := store.Stage("example.com", testVersion(t, 2, ecKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, err := store.Promote("example.com", ec.Cert, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous != rsa.Cert {
+			t.Errorf("the switch replaced %q, want the rsa2048 primary %q", previous, rsa.Cert)
+		}
+		if link("current") != "2" || link("current-ec256") != "2" || link("current-rsa2048") != "" {
+			t.Errorf("links current %q, ec256 %q, rsa2048 %q", link("current"), link("current-ec256"), link("current-rsa2048"))
+		}
+		_, data, err := store.ReadLive("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert, err := utils.ParseLeafCertificate(data); err != nil || utils.CertificateKeyType(cert) != "ec256" {
+			t.Errorf("live certificate isn't the ec256 one (%v)", err)
+		}
+
+		// a failed deployment switches back the same way, the staged version can be removed then
+		if previous, err = store.Promote("example.com", previous, true); err != nil || previous != ec.Cert {
+			t.Errorf("the rollback replaced %q (%v), want %q", previous, err, ec.Cert)
+		}
+		if link("current") != "1" || link("current-rsa2048") != "1" || link("current-ec256") != "" {
+			t.Errorf("links current %q, rsa2048 %q, ec256 %q", link("current"), link("current-rsa2048"), link("current-ec256"))
+		}
+		if err := store.Retire("example.com", ec.Cert); err != nil {
+			t.Errorf("retire the rolled back ec256 version: %v", err)
+		}
+	})
+}
+
+func TestReadCurrentOfKeyType(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	stores(t, func(t *testing.T, store CertStoreInterface, _ func(string) string) {
+		if _, err := store.Save("example.com", testVersion(t, 1, rsaKey)); err != nil {
+			t.Fatal(err)
+		}
+		ec, err := store.Stage("example.com", testVersion(t, 2, ecKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Promote("example.com", ec.Cert, false); err != nil {
+			t.Fatal(err)
+		}
+
+		for keyType, serial := range map[string]string{"rsa2048": "1", "ec256": "2"} {
+			_, data, err := store.ReadCurrent("example.com", keyType)
+			if err != nil {
+				t.Fatalf("%s: %v", keyType, err)
+			}
+			cert, err := utils.ParseLeafCertificate(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%X", cert.SerialNumber); got != serial {
+				t.Errorf("current %s is serial %s, want %s", keyType, got, serial)
+			}
+		}
+		if _, _, err := store.ReadCurrent("example.com", "ec384"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("got %v for a key type without a version", err)
+		}
+	})
+}
+
+func TestArchiveWithinTheSameSecond(t *testing.T) {
+	rsaKey, _ := testKeys(t)
+	store := NewCertStore(t.TempDir(), utils.NewLogger("error")).withArchive(utils.CertArchiveConfig{Keep: 5})
+	for serial := int64(1); serial <= 3; serial++ {
+		paths, err := store.Stage("example.com", testVersion(t, serial, rsaKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, err := store.Promote("example.com", paths.Cert, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous != "" {
+			if _, err := store.Archive("example.com", previous); err != nil {
+				t.Fatalf("archive %s: %v", previous, err)
+			}
+		}
+	}
+
+	versions, err := store.Archived("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("archived %d versions, want 2", len(versions))
+	}
+	// newest first, each under its own name
+	for i, v := range versions {
+		data, err := store.Read(v.CertPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := utils.ParseLeafCertificate(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if serial := fmt.Sprintf("%X", cert.SerialNumber); serial != fmt.Sprint(2-i) {
+			t.Errorf("archived version %d (%s) holds serial %s", i, v.Version, serial)
+		}
+	}
+
+	if _, err := store.Restore("example.com", versions[1].Version); err != nil {
+		t.Errorf("restore %s: %v", versions[1].Version, err)
+	}
+	if _, err := store.Restore("example.com", "20060102T150405Z-1"); err == nil {
+		t.Error("restored a version with an invalid suffix")
+	}
+}
+
+func TestArchiveNameSuffix(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for _, want := range []string{"20261015T120000Z", "20261015T120000Z-2", "20261015T120000Z-3"} {
+		path, err := archiveName(dir, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(path) != want {
+			t.Errorf("got %s, want %s", filepath.Base(path), want)
+		}
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if archivedAt, _, ok := parseArchiveName(want); !ok || !archivedAt.Equal(at) {
+			t.Errorf("%s parsed as %v, %v", want, archivedAt, ok)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// sealedBlock is the PEM type of encrypted files, they stay text like the files they replace
+	sealedBlock = "HEPHAESTUS ENCRYPTED FILE"
+	// sealedVersion is the Version header of files sealed under a derived key with their name as additional
+	// data, files without it were sealed under the SHA-256 of the key and are still read
+	sealedVersion = "2"
+)
+
+// sealer encrypts files at rest with certs.encryption.key through utils.EncryptSecret. A nil sealer
+// leaves files plaintext
+type sealer struct {
+	key   string
+	certs bool
+}
+
+func newSealer(cfg utils.CertEncryptionConfig) *sealer {
+	if cfg.Key == "" {
+		return nil
+	}
+	return &sealer{key: cfg.Key, certs: cfg.Certs}
+}
+
+// seal encrypts private keys, and with certs.encryption.certs every other file too. The file can only be
+// opened under the same name
+func (s *sealer) seal(name string, data []byte, private bool) ([]byte, error) {
+	if s == nil || (!private && !s.certs) {
+		return data, nil
+	}
+	sealed, err := utils.EncryptSecret(s.key, data, []byte(sealName(name)))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: sealedBlock, Headers: map[string]string{"Version": sealedVersion}, Bytes: sealed}), nil
+}
+
+// isSealed tells whether data was encrypted by seal
+func isSealed(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == sealedBlock
+}
+
+// isLegacy tells whether data was sealed before files were versioned, under the SHA-256 of the key
+func isLegacy(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == sealedBlock && block.Headers["Version"] == ""
+}
+
+// open decrypts sealed files, files written before encryption was turned on are returned as they are
+func (s *sealer) open(name string, data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+	block, _ := pem.Decode(data)
+	if s == nil {
+		return nil, errors.New("file is encrypted but certs.encryption.key is not set")
+	}
+	switch block.Headers["Version"] {
+	case "":
+		return utils.DecryptLegacySecret(s.key, block.Bytes)
+	case sealedVersion:
+		return utils.DecryptSecret(s.key, block.Bytes, []byte(sealName(name)))
+	}
+	return nil, fmt.Errorf("unknown encrypted file version %q", block.Headers["Version"])
+}
+
+// sealName is the name a file is sealed under: its first directory, the domain, and its file name.
+// Version directories are left out, archive and restore rename them
+func sealName(name string) string {
+	parts := strings.Split(path.Clean(filepath.ToSlash(name)), "/")
+	if len(parts) <= 2 {
+		return strings.Join(parts, "/")
+	}
+	return parts[0] + "/" + parts[len(parts)-1]
+}
+
+// sealedBackend encrypts the files of the wrapped backend like CertStore does: private keys always,
+// certificates and chains with certs.encryption.certs
+type sealedBackend struct {
+	Backend
+	sealer *sealer
+}
+
+func (b *sealedBackend) Read(name string) ([]byte, error) {
+	data, err := b.Backend.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err = b.sealer.open(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Location(name), err)
+	}
+	return data, nil
+}
+
+func (b *sealedBackend) Write(name string, data []byte) error {
+	sealed, err := b.sealer.seal(name, data, privateFile(name))
+	if err != nil {
+		return err
+	}
+	return b.Backend.Write(name, sealed)
+}
+
+// privateFile tells private keys from the other files by their name: privkey.pem, privkey.der, *.key and
+// archived keys like acme_user.key.deactivated-<timestamp>
+func privateFile(name string) bool {
+	base := path.Base(name)
+	return base == liveFiles[1] || base == derFiles[1] || strings.Contains(base, ".key")
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	utils "hephaestus/internal/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSealKey = "0123456789abcdef0123456789abcdef"
+
+// legacySeal seals data like releases before the key derivation: AES-256-GCM under the SHA-256 of the key
+func legacySeal(t *testing.T, data []byte) []byte {
+	t.Helper()
+	key := sha256.Sum256([]byte(testSealKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: sealedBlock, Bytes: gcm.Seal(nonce, nonce, data, nil)})
+}
+
+func TestSealedFilesFollowArchiveAndRestore(t *testing.T) {
+	rsaKey, _ := testKeys(t)
+	dir := t.TempDir()
+	store := NewCertStore(dir, utils.NewLogger("error")).
+		withArchive(utils.CertArchiveConfig{Keep: 5}).
+		withEncryption(utils.CertEncryptionConfig{Key: testSealKey, Certs: true})
+	first, err := store.Save("example.com", testVersion(t, 1, rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.Save("example.com", testVersion(t, 2, rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(second.Key); !isSealed(data) || isLegacy(data) {
+		t.Fatalf("the key was written as\n%s", data)
+	}
+	archived, err := store.Archive("example.com", first.Cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// archive and restore rename the version directory, its files still open
+	if _, err := store.Read(archived.Cert); err != nil {
+		t.Errorf("read archived version: %v", err)
+	}
+	versions, err := store.Archived("example.com")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("got %v, %v, want the archived version", versions, err)
+	}
+	restored, err := store.Restore("example.com", versions[0].Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Read(restored.Cert); err != nil {
+		t.Errorf("read restored version: %v", err)
+	}
+}
+
+func TestSealedFileUnderAnotherName(t *testing.T) {
+	backend := &sealedBackend{Backend: &fileBackend{dir: t.TempDir()}, sealer: newSealer(utils.CertEncryptionConfig{Key: testSealKey})}
+	if err := backend.Write("a.example.com/1/privkey.pem", []byte("key of a")); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := backend.Backend.Read("a.example.com/1/privkey.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Backend.Write("b.example.com/1/privkey.pem", sealed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.Read("b.example.com/1/privkey.pem"); err == nil {
+		t.Error("the key of a.example.com opened as the one of b.example.com")
+	}
+}
+
+func TestLegacySealedFilesAreSealedAgain(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acme_user.key"), legacySeal(t, []byte("account key")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	backend := &sealedBackend{Backend: &fileBackend{dir: dir}, sealer: newSealer(utils.CertEncryptionConfig{Key: testSealKey})}
+	if data, err := backend.Read("acme_user.key"); err != nil || string(data) != "account key" {
+		t.Fatalf("got %q, %v, want the legacy file opened", data, err)
+	}
+
+	NewCertStore(dir, utils.NewLogger("error")).withEncryption(utils.CertEncryptionConfig{Key: testSealKey})
+	data, err := os.ReadFile(filepath.Join(dir, "acme_user.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isLegacy(data) || bytes.Contains(data, []byte("account key")) {
+		t.Fatalf("the file wasn't sealed again:\n%s", data)
+	}
+	if data, err := backend.Read("acme_user.key"); err != nil || string(data) != "account key" {
+		t.Errorf("got %q, %v, want the file sealed again opened", data, err)
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"os"
+)
+
+// filePerms are the mode and owner CertStore gives its files, -1 keeps the owner of the process
+type filePerms struct {
+	cert, key os.FileMode
+	uid, gid  int
+}
+
+func newFilePerms(cfg utils.CertFilesConfig) filePerms {
+	p := filePerms{uid: -1, gid: -1}
+	p.cert, p.key = cfg.Modes()
+	if cfg.UID > 0 {
+		p.uid = cfg.UID
+	}
+	if cfg.GID > 0 {
+		p.gid = cfg.GID
+	}
+	return p
+}
+
+// apply sets the mode of a certificate or private key file, independent of the umask, and its owner
+func (p filePerms) apply(path string, private bool) error {
+	mode := p.cert
+	if private {
+		mode = p.key
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	if p.uid == -1 && p.gid == -1 {
+		return nil
+	}
+	if err := os.Chown(path, p.uid, p.gid); err != nil {
+		return fmt.Errorf("set owner %d:%d: %w", p.uid, p.gid, err)
+	}
+	return nil
+}
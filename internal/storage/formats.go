@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"slices"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// fullchainFile is the leaf followed by its chain, the name nginx, HAProxy and certbot setups expect.
+// Every version has it, versions written by older releases get it when they become current
+const fullchainFile = "fullchain.pem"
+
+// derFiles are the leaf and the PKCS#8 private key in DER, written with the der output format
+var derFiles = []string{"cert.der", "privkey.der"}
+
+// formatFile is a file of a version written next to liveFiles
+type formatFile struct {
+	name    string
+	data    []byte
+	private bool
+}
+
+// formatFiles returns fullchain.pem and the files of the output formats of the certificate
+func formatFiles(certData *models.CertificateData) ([]formatFile, error) {
+	fullchain, err := fullchainPEM(certData.Cert, certData.Chain)
+	if err != nil {
+		return nil, err
+	}
+	files := []formatFile{{name: fullchainFile, data: fullchain}}
+	if !slices.Contains(certData.OutputFormats, utils.OutputFormatDER) {
+		return files, nil
+	}
+
+	block, _ := pem.Decode(certData.Cert)
+	key, err := certcrypto.ParsePEMPrivateKey(certData.Key)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encode private key: %w", err)
+	}
+	return append(files,
+		formatFile{name: derFiles[0], data: block.Bytes},
+		formatFile{name: derFiles[1], data: keyDER, private: true},
+	), nil
+}
+
+// fullchainPEM bundles the leaf of certPEM, which may already carry the issuer, with chainPEM. Without
+// a chain certPEM is taken as it is
+func fullchainPEM(certPEM, chainPEM []byte) ([]byte, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no certificate to bundle")
+	}
+	if len(bytes.TrimSpace(chainPEM)) == 0 {
+		return certPEM, nil
+	}
+	return append(pem.EncodeToMemory(block), chainPEM...), nil
+}
@@ -0,0 +1,398 @@
+package storage
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	models "hephaestus/internal/models"
+	utils "hephaestus/internal/utils"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+var _ CertStoreInterface = (*ObjectStore)(nil)
+
+// ObjectStore keeps the certificate versions of CertStore as files of a Backend, <domain>/<version>/cert.pem
+// and on, the current version of every key type in <domain>/current-<key type> and the primary one in
+// <domain>/current. <domain>/cert.pem, privkey.pem, chain.pem and fullchain.pem are copies of the primary
+// version for consumers of the backend. Nothing is written to the local disk, deployment targets read the
+// certificates from the backend themselves. Paths are the backend's locations
+type ObjectStore struct {
+	backend Backend
+	log     *utils.Logger
+}
+
+func NewObjectStore(backend Backend, log *utils.Logger) *ObjectStore {
+	return &ObjectStore{backend: backend, log: log}
+}
+
+func (s *ObjectStore) Save(domain string, certData *models.CertificateData) (*models.CertificatePaths, error) {
+	paths, err := s.Stage(domain, certData)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Promote(domain, paths.Cert, false); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Stage writes the files of a new version, the key first so a version never lacks it
+func (s *ObjectStore) Stage(domain string, certData *models.CertificateData) (*models.CertificatePaths, error) {
+	s.log.Debug("ObjectStore.Stage(): called for domain: ", domain)
+	version := certData.SerialNumber
+	if version == "" {
+		version = time.Now().UTC().Format(versionTimeFormat)
+	}
+	dir := path.Join(domain, version)
+
+	files := []formatFile{
+		{name: liveFiles[1], data: certData.Key},
+		{name: liveFiles[2], data: certData.Chain},
+		{name: liveFiles[0], data: certData.Cert},
+	}
+	if len(certData.AltChains) > 0 {
+		for n, chain := range append([][]byte{certData.Chain}, certData.AltChains...) {
+			files = append(files, formatFile{name: "chain-" + models.ChainName(n) + ".pem", data: chain})
+		}
+	}
+	formats, err := formatFiles(certData)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, formats...)
+	for _, f := range files {
+		if err := s.backend.Write(path.Join(dir, f.name), f.data); err != nil {
+			return nil, fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+
+	s.log.Debug("Certificate files staged in ", s.backend.Location(dir))
+	return &models.CertificatePaths{
+		Cert:  s.backend.Location(path.Join(dir, liveFiles[0])),
+		Key:   s.backend.Location(path.Join(dir, liveFiles[1])),
+		Chain: s.backend.Location(path.Join(dir, liveFiles[2])),
+	}, nil
+}
+
+// Promote writes the version into <domain>/current-<key type> and, when the primary version in
+// <domain>/current has the same key type or there is none, publishes it like CertStore.Promote.
+// replacePrimary publishes it over another key type and removes the link of the old one
+func (s *ObjectStore) Promote(domain, certPath string, replacePrimary bool) (string, error) {
+	version, err := s.version(domain, certPath)
+	if err != nil {
+		return "", err
+	}
+	keyType, err := s.versionKeyType(domain, version)
+	if err != nil {
+		return "", fmt.Errorf("certificate version %s: %w", version, err)
+	}
+	primary, err := s.current(domain)
+	if err != nil {
+		return "", err
+	}
+	previous, err := s.currentOf(domain, keyType, primary)
+	if err != nil {
+		return "", err
+	}
+	// the primary version has another key type when it isn't the previous one of this key type
+	var primaryType string
+	if replacePrimary && primary != "" && primary != previous {
+		if primaryType, err = s.versionKeyType(domain, primary); err != nil {
+			return "", fmt.Errorf("certificate version %s: %w", primary, err)
+		}
+	}
+
+	if err := s.backend.Write(path.Join(domain, keyTypeLink(keyType)), []byte(version)); err != nil {
+		return "", fmt.Errorf("switch version: %w", err)
+	}
+	if primary == "" || primary == previous || primaryType != "" {
+		if err := s.publish(domain, version); err != nil {
+			return "", err
+		}
+		if err := s.backend.Write(path.Join(domain, currentLink), []byte(version)); err != nil {
+			return "", fmt.Errorf("switch version: %w", err)
+		}
+	}
+	if primaryType != "" {
+		if err := s.backend.Delete(path.Join(domain, keyTypeLink(primaryType))); err != nil {
+			return "", fmt.Errorf("remove %s link: %w", primaryType, err)
+		}
+		s.log.Info("Primary certificate of ", domain, " switched from ", primaryType, " to ", keyType)
+		previous = primary
+	}
+
+	s.log.Debug("Certificate version ", version, " of ", domain, " is current for ", keyType)
+	if previous == "" || previous == version {
+		return "", nil
+	}
+	return s.backend.Location(path.Join(domain, previous, liveFiles[0])), nil
+}
+
+// Retire removes the version, versions current for a key type are kept
+func (s *ObjectStore) Retire(domain, certPath string) error {
+	version, err := s.version(domain, certPath)
+	if err != nil {
+		return err
+	}
+	current, err := s.current(domain)
+	if err != nil {
+		return err
+	}
+	if version == current {
+		return fmt.Errorf("certificate version %s of %s is current", current, domain)
+	}
+	// a version without a readable certificate can't be current for its key type
+	if keyType, err := s.versionKeyType(domain, version); err == nil {
+		currentOfType, err := s.readLink(domain, keyTypeLink(keyType))
+		if err != nil {
+			return err
+		}
+		if version == currentOfType {
+			return fmt.Errorf("certificate version %s of %s is %s", version, domain, keyTypeLink(keyType))
+		}
+	}
+	s.log.Debug("Retiring certificate version ", version, " of ", domain)
+	if err := s.backend.Delete(path.Join(domain, version)); err != nil {
+		return fmt.Errorf("remove certificate version: %w", err)
+	}
+	return nil
+}
+
+// Archive retires the version, backends have no archive
+func (s *ObjectStore) Archive(domain, certPath string) (*models.CertificatePaths, error) {
+	return nil, s.Retire(domain, certPath)
+}
+
+func (s *ObjectStore) Archived(domain string) ([]models.ArchivedCertificate, error) {
+	return nil, nil
+}
+
+func (s *ObjectStore) Restore(domain, version string) (*models.CertificatePaths, error) {
+	return nil, errors.New("archived versions are only kept by the file storage backend")
+}
+
+func (s *ObjectStore) ReadLive(domain string) (string, []byte, error) {
+	return s.readCurrent(domain, liveFiles[0])
+}
+
+func (s *ObjectStore) ReadLiveKey(domain string) ([]byte, error) {
+	_, data, err := s.readCurrent(domain, liveFiles[1])
+	if err != nil {
+		return nil, fmt.Errorf("read live key: %w", err)
+	}
+	return data, nil
+}
+
+func (s *ObjectStore) ReadCurrent(domain, keyType string) (string, []byte, error) {
+	primary, err := s.current(domain)
+	if err != nil {
+		return "", nil, err
+	}
+	version, err := s.currentOf(domain, keyType, primary)
+	if err != nil {
+		return "", nil, err
+	}
+	if version == "" {
+		return "", nil, fmt.Errorf("no %s certificate of %s: %w", keyType, domain, os.ErrNotExist)
+	}
+	name := path.Join(domain, version, liveFiles[0])
+	data, err := s.backend.Read(name)
+	if err != nil {
+		return s.backend.Location(name), nil, fmt.Errorf("read current %s certificate: %w", keyType, err)
+	}
+	return s.backend.Location(name), data, nil
+}
+
+func (s *ObjectStore) Read(certPath string) ([]byte, error) {
+	name, ok := s.name(certPath)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the storage backend", certPath)
+	}
+	data, err := s.backend.Read(name)
+	if err != nil {
+		return nil, fmt.Errorf("read certificate: %w", err)
+	}
+	return data, nil
+}
+
+func (s *ObjectStore) SwitchChain(domain, certPath, chain string) error {
+	version, err := s.version(domain, certPath)
+	if err != nil {
+		return err
+	}
+	dir := path.Join(domain, version)
+	chainPEM, err := s.backend.Read(path.Join(dir, "chain-"+chain+".pem"))
+	if err != nil {
+		return fmt.Errorf("read chain %s: %w", chain, err)
+	}
+	certPEM, err := s.backend.Read(path.Join(dir, liveFiles[0]))
+	if err != nil {
+		return fmt.Errorf("read certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("no certificate in %s", certPath)
+	}
+
+	s.log.Debug("Switching chain of ", s.backend.Location(dir), " to ", chain)
+	if err := s.backend.Write(path.Join(dir, liveFiles[2]), chainPEM); err != nil {
+		return fmt.Errorf("write chain: %w", err)
+	}
+	bundle := append(pem.EncodeToMemory(block), chainPEM...)
+	if err := s.backend.Write(path.Join(dir, liveFiles[0]), bundle); err != nil {
+		return fmt.Errorf("write cert: %w", err)
+	}
+	if err := s.backend.Write(path.Join(dir, fullchainFile), bundle); err != nil {
+		return fmt.Errorf("write fullchain: %w", err)
+	}
+	if current, err := s.current(domain); err != nil || current != version {
+		return err
+	}
+	return s.publish(domain, version)
+}
+
+func (s *ObjectStore) Delete(domain string) error {
+	s.log.Info("Deleting certificate files for domain: ", domain)
+	current, err := s.current(domain)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return fmt.Errorf("certificate not found for domain: %s", domain)
+	}
+	if err := s.backend.Delete(domain); err != nil {
+		return fmt.Errorf("failed to remove certificate files: %w", err)
+	}
+	return nil
+}
+
+// publish copies the files of the version to <domain>/, the keys first and the certificates last. The
+// DER files of a version without them are removed, fullchain.pem is bundled for versions of older releases
+func (s *ObjectStore) publish(domain, version string) error {
+	for _, file := range []string{liveFiles[1], derFiles[1], liveFiles[2], fullchainFile, derFiles[0], liveFiles[0]} {
+		data, err := s.backend.Read(path.Join(domain, version, file))
+		if errors.Is(err, os.ErrNotExist) && slices.Contains(derFiles, file) {
+			if err := s.backend.Delete(path.Join(domain, file)); err != nil {
+				return fmt.Errorf("remove %s: %w", file, err)
+			}
+			continue
+		}
+		if errors.Is(err, os.ErrNotExist) && file == fullchainFile {
+			data, err = s.fullchain(domain, version)
+		}
+		if err != nil {
+			return fmt.Errorf("read %s of version %s: %w", file, version, err)
+		}
+		if err := s.backend.Write(path.Join(domain, file), data); err != nil {
+			return fmt.Errorf("publish %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// fullchain bundles fullchain.pem of a version from its cert.pem and chain.pem
+func (s *ObjectStore) fullchain(domain, version string) ([]byte, error) {
+	certPEM, err := s.backend.Read(path.Join(domain, version, liveFiles[0]))
+	if err != nil {
+		return nil, err
+	}
+	chainPEM, err := s.backend.Read(path.Join(domain, version, liveFiles[2]))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	data, err := fullchainPEM(certPEM, chainPEM)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.backend.Write(path.Join(domain, version, fullchainFile), data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// name returns the backend name of a location, ok is false for locations of other backends
+func (s *ObjectStore) name(location string) (string, bool) {
+	name, ok := strings.CutPrefix(location, s.backend.Location(""))
+	return strings.TrimPrefix(name, "/"), ok
+}
+
+// version returns the version of a cert path Stage returned for the domain
+func (s *ObjectStore) version(domain, certPath string) (string, error) {
+	name, ok := s.name(certPath)
+	dir, file := path.Split(name)
+	if !ok || file != liveFiles[0] || path.Dir(path.Clean(dir)) != domain {
+		return "", fmt.Errorf("%s is not a certificate version of %s", certPath, domain)
+	}
+	version := path.Base(dir)
+	if isCurrentLink(version) {
+		return "", fmt.Errorf("%s is not a certificate version of %s", certPath, domain)
+	}
+	return version, nil
+}
+
+// current returns the primary version of the domain, empty when it has none
+func (s *ObjectStore) current(domain string) (string, error) {
+	return s.readLink(domain, currentLink)
+}
+
+// currentOf returns the current version of the key type, versions promoted before every key type had
+// its own link only have the primary one
+func (s *ObjectStore) currentOf(domain, keyType, primary string) (string, error) {
+	version, err := s.readLink(domain, keyTypeLink(keyType))
+	if err != nil || version != "" || primary == "" {
+		return version, err
+	}
+	primaryType, err := s.versionKeyType(domain, primary)
+	if err != nil {
+		return "", fmt.Errorf("certificate version %s: %w", primary, err)
+	}
+	if primaryType != keyType {
+		return "", nil
+	}
+	return primary, nil
+}
+
+// readLink returns the version a link file like current names, empty when there is none
+func (s *ObjectStore) readLink(domain, name string) (string, error) {
+	data, err := s.backend.Read(path.Join(domain, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s version: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// versionKeyType names the key of the certificate of a version like certificates.key_type
+func (s *ObjectStore) versionKeyType(domain, version string) (string, error) {
+	data, err := s.backend.Read(path.Join(domain, version, liveFiles[0]))
+	if err != nil {
+		return "", err
+	}
+	cert, err := utils.ParseLeafCertificate(data)
+	if err != nil {
+		return "", err
+	}
+	return utils.CertificateKeyType(cert), nil
+}
+
+func (s *ObjectStore) readCurrent(domain, file string) (string, []byte, error) {
+	current, err := s.current(domain)
+	if err != nil {
+		return "", nil, err
+	}
+	if current == "" {
+		return "", nil, fmt.Errorf("no certificate of %s: %w", domain, os.ErrNotExist)
+	}
+	name := path.Join(domain, current, file)
+	data, err := s.backend.Read(name)
+	if err != nil {
+		return s.backend.Location(name), nil, fmt.Errorf("read live certificate: %w", err)
+	}
+	return s.backend.Location(name), data, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FileTable keeps the files of the postgres backend, repositories.Repository implements it. The files
+// have a table of their own instead of columns of certificates: the ACME account key and the keys of
+// orders in progress belong to no certificate, and a certificate has several files (key, chains, DER)
+type FileTable interface {
+	// GetCertificateFile returns pgx.ErrNoRows when there is no file of the name
+	GetCertificateFile(ctx context.Context, name string) ([]byte, error)
+	SaveCertificateFile(ctx context.Context, name string, content []byte) error
+	DeleteCertificateFiles(ctx context.Context, name string) error
+}
+
+// postgresBackend keeps every file as a row of certificate_files, so replicas share the certificates
+// without a shared disk. It's wrapped in a sealedBackend, the rows are encrypted
+type postgresBackend struct {
+	files FileTable
+}
+
+func newPostgresBackend(files FileTable) (*postgresBackend, error) {
+	if files == nil {
+		return nil, errors.New("postgres storage backend needs the database, it isn't connected")
+	}
+	return &postgresBackend{files: files}, nil
+}
+
+func (b *postgresBackend) Read(name string) ([]byte, error) {
+	data, err := b.files.GetCertificateFile(context.Background(), name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", b.Location(name), os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", b.Location(name), err)
+	}
+	return data, nil
+}
+
+func (b *postgresBackend) Write(name string, data []byte) error {
+	if err := b.files.SaveCertificateFile(context.Background(), name, data); err != nil {
+		return fmt.Errorf("write %s: %w", b.Location(name), err)
+	}
+	return nil
+}
+
+func (b *postgresBackend) Delete(name string) error {
+	if err := b.files.DeleteCertificateFiles(context.Background(), name); err != nil {
+		return fmt.Errorf("delete %s: %w", b.Location(name), err)
+	}
+	return nil
+}
+
+func (b *postgresBackend) Location(name string) string {
+	return "postgres:" + name
+}
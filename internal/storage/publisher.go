@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	utils "hephaestus/internal/utils"
+	"strings"
+)
+
+// Publisher imports the live certificate of a domain into a service consuming it, e.g. a cloud load
+// balancer reading certificates from a key store. The storage stays the source of the certificates
+type Publisher interface {
+	// Publish imports the PEM private key and certificate with its chain, it returns what was written to
+	Publish(domain string, key, cert []byte) (string, error)
+}
+
+// NewPublisher returns the publisher of certs.publish, nil when no target is set
+func NewPublisher(cfg *utils.Config, log *utils.Logger) (Publisher, error) {
+	publish := cfg.Certs.Publish
+	var publishers multiPublisher
+	if publish.AzureKeyVault.VaultURL != "" {
+		vault, err := newAzureKeyVault(publish.AzureKeyVault, cfg.HTTPClient, log)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, vault)
+	}
+	if publish.AWS.SecretsManager.Enabled {
+		secrets, err := newAWSSecretsManager(publish.AWS, cfg.HTTPClient, log)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, secrets)
+	}
+	if publish.AWS.ACM.Enabled {
+		certs, err := newACM(publish.AWS, cfg.HTTPClient, log)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, certs)
+	}
+
+	switch len(publishers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return publishers[0], nil
+	}
+	return publishers, nil
+}
+
+// multiPublisher publishes to every target, a failing target doesn't keep the others from being
+// updated. The targets written to are returned along with the errors of the others
+type multiPublisher []Publisher
+
+func (m multiPublisher) Publish(domain string, key, cert []byte) (string, error) {
+	var targets, failures []string
+	for _, p := range m {
+		target, err := p.Publish(domain, key, cert)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		targets = append(targets, target)
+	}
+	if len(failures) > 0 {
+		return strings.Join(targets, ", "), errors.New(strings.Join(failures, "; "))
+	}
+	return strings.Join(targets, ", "), nil
+}
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// s3RequestTimeout bounds a single call to the object storage
+const s3RequestTimeout = 30 * time.Second
+
+// s3Backend keeps every file as an object of the bucket, <prefix>/<name>, through the S3 REST API
+// signed with SigV4, so S3-compatible services work the same
+type s3Backend struct {
+	cfg         utils.S3Config
+	endpoint    *url.URL
+	region      string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	client      *http.Client
+	log         *utils.Logger
+}
+
+func newS3Backend(cfg utils.S3Config, httpConfig utils.HTTPClientConfig, log *utils.Logger) (*s3Backend, error) {
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.AccessKey != "" && cfg.SecretKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+		))
+	}
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, errors.New("s3 region is not set, set certs.storage.s3.region or AWS_REGION")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", awsCfg.Region)
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	client, err := utils.NewHTTPClient(httpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("s3 http client: %w", err)
+	}
+	return &s3Backend{
+		cfg:         cfg,
+		endpoint:    u,
+		region:      awsCfg.Region,
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+		client:      client,
+		log:         log,
+	}, nil
+}
+
+func (b *s3Backend) Read(name string) ([]byte, error) {
+	resp, err := b.do(http.MethodGet, b.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", b.Location(name), os.ErrNotExist)
+	}
+	if err := s3Error(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (b *s3Backend) Write(name string, data []byte) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType(name))
+	if b.cfg.SSE != "" {
+		header.Set("X-Amz-Server-Side-Encryption", b.cfg.SSE)
+	}
+	if b.cfg.KMSKeyID != "" {
+		header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", b.cfg.KMSKeyID)
+	}
+	resp, err := b.do(http.MethodPut, b.key(name), header, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp)
+}
+
+// Delete removes the object of the name and the objects below <name>/
+func (b *s3Backend) Delete(name string) error {
+	keys, err := b.list(b.key(name) + "/")
+	if err != nil {
+		return err
+	}
+	for _, key := range append(keys, b.key(name)) {
+		resp, err := b.do(http.MethodDelete, key, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if err := s3Error(resp); err != nil && resp.StatusCode != http.StatusNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *s3Backend) Location(name string) string {
+	return "s3://" + b.cfg.Bucket + "/" + b.key(name)
+}
+
+func (b *s3Backend) key(name string) string {
+	return strings.TrimPrefix(path.Join(b.cfg.Prefix, name), "/")
+}
+
+// list returns the keys of the objects with the prefix, following the pages of ListObjectsV2
+func (b *s3Backend) list(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := b.doQuery(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = s3Error(resp)
+		if err == nil {
+			err = xml.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", prefix, err)
+		}
+		for _, c := range page.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (b *s3Backend) do(method, key string, header http.Header, body []byte) (*http.Response, error) {
+	return b.doQuery(method, key, nil, header, body)
+}
+
+// doQuery signs and sends a request for the object of key, the bucket itself when key is empty
+func (b *s3Backend) doQuery(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *b.endpoint
+	if b.cfg.PathStyle {
+		u.Path = u.Path + "/" + b.cfg.Bucket + "/" + key
+	} else {
+		u.Host = b.cfg.Bucket + "." + u.Host
+		u.Path = u.Path + "/" + key
+	}
+	// the signature covers the path as S3 encodes it, which differs from url.PathEscape e.g. for + and *
+	u.RawPath = s3Escape(u.Path, true)
+	u.RawQuery = s3Query(query)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	creds, err := b.credentials.Retrieve(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("s3 credentials: %w", err)
+	}
+	err = b.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", b.region, time.Now(), func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("sign s3 request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("s3 %s %s: %w", method, key, err)
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// s3Escape percent-encodes everything but the unreserved characters of RFC 3986, and slashes of paths,
+// like S3 does for the canonical request
+func s3Escape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~", c) >= 0 || path && c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// s3Query encodes the query sorted by key like url.Values.Encode, with spaces as %20 instead of +
+func s3Query(query url.Values) string {
+	var parts []string
+	for _, k := range slices.Sorted(maps.Keys(query)) {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Error returns the error of an answer that isn't a success, with the code S3 gave
+func s3Error(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var problem struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&problem)
+	return fmt.Errorf("s3 %s %s: %s %s %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, problem.Code, problem.Message)
+}
+
+// contentType lets consumers of the bucket tell PEM files from the rest
+func contentType(name string) string {
+	if strings.HasSuffix(name, ".pem") || strings.HasSuffix(name, ".key") {
+		return "application/x-pem-file"
+	}
+	return "application/octet-stream"
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testS3Bucket     = "certs"
+	testAWSAccessKey = "AKIDEXAMPLE"
+	testAWSSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// fakeS3 keeps objects in memory and rejects requests whose SigV4 signature doesn't verify. The signature
+// is computed here from the request S3 receives, independent of the signer of the backend
+type fakeS3 struct {
+	t         *testing.T
+	pathStyle bool
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+	// failWith answers every request with this status and an S3 error document
+	failWith int
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySigV4(r); err != nil {
+		s.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		s3Problem(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+	if s.failWith != 0 {
+		s3Problem(w, s.failWith, "InternalError", "We encountered an internal error")
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if s.pathStyle {
+		bucket, rest, _ := strings.Cut(key, "/")
+		if bucket != testS3Bucket {
+			s3Problem(w, http.StatusNotFound, "NoSuchBucket", bucket)
+			return
+		}
+		key = rest
+	} else if r.Host != testS3Bucket+"."+hostOf(r) {
+		s3Problem(w, http.StatusNotFound, "NoSuchBucket", r.Host)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		s.list(w, r.URL.Query())
+	case r.Method == http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			s3Problem(w, http.StatusNotFound, "NoSuchKey", key)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+		s.headers[key] = r.Header.Clone()
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list answers ListObjectsV2 one key per page, so the continuation is followed
+func (s *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, query.Get("prefix")) && k > query.Get("continuation-token") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	type contents struct {
+		Key string `xml:"Key"`
+	}
+	page := struct {
+		XMLName               xml.Name   `xml:"ListBucketResult"`
+		Contents              []contents `xml:"Contents"`
+		IsTruncated           bool       `xml:"IsTruncated"`
+		NextContinuationToken string     `xml:"NextContinuationToken,omitempty"`
+	}{}
+	if len(keys) > 0 {
+		page.Contents = []contents{{Key: keys[0]}}
+		page.IsTruncated = len(keys) > 1
+		if page.IsTruncated {
+			page.NextContinuationToken = keys[0]
+		}
+	}
+	_ = xml.NewEncoder(w).Encode(page)
+}
+
+func s3Problem(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+func hostOf(r *http.Request) string {
+	_, host, _ := strings.Cut(r.Host, ".")
+	return host
+}
+
+// verifySigV4 checks the Authorization header like AWS does. The canonical URI encodes every segment of
+// the decoded path once like S3, e.g. * as %2A, the other services are only called on /
+func verifySigV4(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	scope := strings.SplitN(fields["Credential"], "/", 2)
+	if len(scope) != 2 || scope[0] != testAWSAccessKey {
+		return fmt.Errorf("unexpected credential %q", fields["Credential"])
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	// S3 requires the hash as a header, the other services only sign it
+	if h := r.Header.Get("X-Amz-Content-Sha256"); h != "" && h != payloadHash {
+		return errors.New("payload hash doesn't match the body")
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		segments = append(segments, awsURIEncode(segment))
+	}
+	var query []string
+	for k, values := range r.URL.Query() {
+		for _, v := range values {
+			query = append(query, awsURIEncode(k)+"="+awsURIEncode(v))
+		}
+	}
+	sort.Strings(query)
+	var headers strings.Builder
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(value))
+	}
+	canonical := strings.Join([]string{
+		r.Method,
+		strings.Join(segments, "/"),
+		strings.Join(query, "&"),
+		headers.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	canonicalSum := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", r.Header.Get("X-Amz-Date"), scope[1], hex.EncodeToString(canonicalSum[:])}, "\n")
+
+	key := []byte("AWS4" + testAWSSecretKey)
+	for _, part := range strings.Split(scope[1], "/") {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); want != fields["Signature"] {
+		return fmt.Errorf("signature doesn't match the canonical request:\n%s", canonical)
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything but the unreserved characters of RFC 3986
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func newTestS3(t *testing.T, pathStyle bool, cfg utils.S3Config) (*s3Backend, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, pathStyle: pathStyle, objects: map[string][]byte{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg.Bucket = testS3Bucket
+	cfg.Region = "eu-west-1"
+	cfg.Endpoint = server.URL
+	cfg.PathStyle = pathStyle
+	cfg.AccessKey, cfg.SecretKey = testAWSAccessKey, testAWSSecretKey
+	b, err := newS3Backend(cfg, utils.HTTPClientConfig{}, utils.NewLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pathStyle {
+		// certs.127.0.0.1 doesn't resolve, every host is the test server
+		addr := server.Listener.Addr().String()
+		b.client.Transport = &http.Transport{DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}}
+	}
+	return b, fake
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		t.Run(fmt.Sprintf("path style %v", pathStyle), func(t *testing.T) {
+			b, fake := newTestS3(t, pathStyle, utils.S3Config{Prefix: "/hephaestus/"})
+			for _, name := range []string{"example.com/current/cert.pem", "*.example.com/current/privkey.pem", "xn--mnchen-3ya.de/a b+c.pem"} {
+				if err := b.Write(name, []byte("data of "+name)); err != nil {
+					t.Fatalf("write %s: %v", name, err)
+				}
+				got, err := b.Read(name)
+				if err != nil {
+					t.Fatalf("read %s: %v", name, err)
+				}
+				if string(got) != "data of "+name {
+					t.Errorf("read %q from %s", got, name)
+				}
+				if _, ok := fake.objects["hephaestus/"+name]; !ok {
+					t.Errorf("%s isn't stored under its key, have %v", name, fake.objects)
+				}
+			}
+			if loc := b.Location("*.example.com/cert.pem"); loc != "s3://certs/hephaestus/*.example.com/cert.pem" {
+				t.Errorf("location %s", loc)
+			}
+		})
+	}
+}
+
+func TestS3ReadMissing(t *testing.T) {
+	b, _ := newTestS3(t, true, utils.S3Config{})
+	if _, err := b.Read("*.example.com/cert.pem"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestS3DeleteFollowsListPages(t *testing.T) {
+	b, fake := newTestS3(t, true, utils.S3Config{})
+	for _, name := range []string{"*.example.com/1/cert.pem", "*.example.com/1/privkey.pem", "*.example.com/2 b+c/cert.pem", "example.com/1/cert.pem"} {
+		if err := b.Write(name, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Delete("*.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for k := range fake.objects {
+		left = append(left, k)
+	}
+	if !slices.Equal(left, []string{"example.com/1/cert.pem"}) {
+		t.Errorf("left %v", left)
+	}
+	if err := b.Delete("missing.example.com"); err != nil {
+		t.Errorf("deleting a missing name: %v", err)
+	}
+}
+
+func TestS3WriteSetsEncryption(t *testing.T) {
+	b, fake := newTestS3(t, true, utils.S3Config{SSE: "aws:kms", KMSKeyID: "alias/certs"})
+	if err := b.Write("example.com/privkey.pem", []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	h := fake.headers["example.com/privkey.pem"]
+	if h.Get("X-Amz-Server-Side-Encryption") != "aws:kms" || h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "alias/certs" {
+		t.Errorf("encryption headers %v", h)
+	}
+	if h.Get("Content-Type") != "application/x-pem-file" {
+		t.Errorf("content type %s", h.Get("Content-Type"))
+	}
+}
+
+func TestS3Errors(t *testing.T) {
+	b, fake := newTestS3(t, true, utils.S3Config{})
+	fake.failWith = http.StatusInternalServerError
+	if err := b.Write("example.com/cert.pem", []byte("x")); err == nil || !strings.Contains(err.Error(), "InternalError") {
+		t.Errorf("write: got %v, want the S3 error code", err)
+	}
+	if _, err := b.Read("example.com/cert.pem"); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("read: got %v, want a failure that isn't a missing file", err)
+	}
+	if err := b.Delete("example.com"); err == nil {
+		t.Error("delete: a failed listing must fail")
+	}
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultVaultMount     = "secret"
+	defaultVaultPath      = "hephaestus"
+	defaultVaultAuthMount = "approle"
+
+	// tokenRenewMargin logs in again this long before an AppRole token expires
+	tokenRenewMargin = 30 * time.Second
+)
+
+// vaultBackend keeps every file as a secret of a KV v2 engine, <mount>/<path>/<name>, the content is
+// in the content field, or base64 encoded in content_base64 when it isn't text
+type vaultBackend struct {
+	cfg    utils.VaultConfig
+	client *http.Client
+	log    *utils.Logger
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time // zero for a configured token, it's used until Vault rejects it
+}
+
+func newVaultBackend(cfg utils.VaultConfig, httpConfig utils.HTTPClientConfig, log *utils.Logger) (*vaultBackend, error) {
+	if cfg.Mount == "" {
+		cfg.Mount = defaultVaultMount
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultVaultPath
+	}
+	if cfg.AuthMount == "" {
+		cfg.AuthMount = defaultVaultAuthMount
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	cfg.Mount, cfg.Path = strings.Trim(cfg.Mount, "/"), strings.Trim(cfg.Path, "/")
+
+	client, err := utils.NewHTTPClient(httpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("vault http client: %w", err)
+	}
+	return &vaultBackend{cfg: cfg, client: client, log: log, token: cfg.Token}, nil
+}
+
+func (b *vaultBackend) Read(name string) ([]byte, error) {
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	status, err := b.do(http.MethodGet, "data/"+b.secret(name), nil, &body)
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", b.Location(name), os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if encoded, ok := body.Data.Data["content_base64"]; ok {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	content, ok := body.Data.Data["content"]
+	if !ok {
+		return nil, fmt.Errorf("%s has no content", b.Location(name))
+	}
+	return []byte(content), nil
+}
+
+func (b *vaultBackend) Write(name string, data []byte) error {
+	fields := map[string]string{"content": string(data)}
+	if !utf8.Valid(data) {
+		fields = map[string]string{"content_base64": base64.StdEncoding.EncodeToString(data)}
+	}
+	_, err := b.do(http.MethodPost, "data/"+b.secret(name), map[string]any{"data": fields}, nil)
+	return err
+}
+
+// Delete removes every version of the secret and of the secrets below it
+func (b *vaultBackend) Delete(name string) error {
+	var body struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	status, err := b.do("LIST", "metadata/"+b.secret(name), nil, &body)
+	if err != nil && status != http.StatusNotFound {
+		return err
+	}
+	for _, key := range body.Data.Keys {
+		if err := b.Delete(path.Join(name, key)); err != nil {
+			return err
+		}
+	}
+	status, err = b.do(http.MethodDelete, "metadata/"+b.secret(name), nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return err
+	}
+	return nil
+}
+
+func (b *vaultBackend) Location(name string) string {
+	return "vault:" + b.cfg.Mount + "/" + b.secret(name)
+}
+
+func (b *vaultBackend) secret(name string) string {
+	return path.Join(b.cfg.Path, name)
+}
+
+// do calls the KV engine, a token rejected by Vault is replaced by a new AppRole login once
+func (b *vaultBackend) do(method, endpoint string, in, out any) (int, error) {
+	token, err := b.currentToken(false)
+	if err != nil {
+		return 0, err
+	}
+	status, err := b.request(method, b.cfg.Mount+"/"+endpoint, token, in, out)
+	if status == http.StatusForbidden && b.cfg.RoleID != "" {
+		if token, err = b.currentToken(true); err != nil {
+			return 0, err
+		}
+		status, err = b.request(method, b.cfg.Mount+"/"+endpoint, token, in, out)
+	}
+	return status, err
+}
+
+func (b *vaultBackend) currentToken(renew bool) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.RoleID == "" {
+		return b.token, nil
+	}
+	if !renew && b.token != "" && (b.expires.IsZero() || time.Now().Before(b.expires)) {
+		return b.token, nil
+	}
+
+	var body struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	login := map[string]any{"role_id": b.cfg.RoleID, "secret_id": b.cfg.SecretID}
+	if _, err := b.request(http.MethodPost, "auth/"+b.cfg.AuthMount+"/login", "", login, &body); err != nil {
+		return "", fmt.Errorf("vault approle login: %w", err)
+	}
+	if body.Auth.ClientToken == "" {
+		return "", errors.New("vault approle login returned no token")
+	}
+	b.token = body.Auth.ClientToken
+	b.expires = time.Time{}
+	if body.Auth.LeaseDuration > 0 {
+		b.expires = time.Now().Add(time.Duration(body.Auth.LeaseDuration)*time.Second - tokenRenewMargin)
+	}
+	b.log.Debug("Logged in to Vault with AppRole, token valid for ", body.Auth.LeaseDuration, "s")
+	return b.token, nil
+}
+
+func (b *vaultBackend) request(method, endpoint, token string, in, out any) (int, error) {
+	var reqBody io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, b.cfg.Address+"/v1/"+endpoint, reqBody)
+	if err != nil {
+		return 0, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if b.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.cfg.Namespace)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("vault %s %s: %w", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var problem struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&problem)
+		return resp.StatusCode, fmt.Errorf("vault %s %s: %s %s", method, endpoint, resp.Status, strings.Join(problem.Errors, "; "))
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decode vault response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
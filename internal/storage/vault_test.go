@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	utils "hephaestus/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault serves a KV v2 engine mounted at secret/ and the AppRole login, only tokens it issued are
+// accepted until they are revoked
+type fakeVault struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	secrets map[string]map[string]string // by path below the mount
+	tokens  map[string]bool
+	logins  int
+	calls   []string
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	endpoint := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if endpoint == "auth/approle/login" {
+		var login struct {
+			RoleID   string `json:"role_id"`
+			SecretID string `json:"secret_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login.RoleID != "role" || login.SecretID != "role-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["invalid role or secret ID"]}`)
+			return
+		}
+		v.logins++
+		token := fmt.Sprint("token-", v.logins)
+		v.tokens[token] = true
+		_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{"client_token": token, "lease_duration": 3600}})
+		return
+	}
+	if !v.tokens[r.Header.Get("X-Vault-Token")] {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors":["permission denied"]}`)
+		return
+	}
+	v.calls = append(v.calls, r.Method+" "+endpoint)
+
+	if name, ok := strings.CutPrefix(endpoint, "secret/data/"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			fields, ok := v.secrets[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":[]}`)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": fields}})
+		case http.MethodPost:
+			var body struct {
+				Data map[string]string `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				v.t.Errorf("write %s: %v", name, err)
+			}
+			v.secrets[name] = body.Data
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"version": 1}})
+		}
+		return
+	}
+	if name, ok := strings.CutPrefix(endpoint, "secret/metadata/"); ok {
+		switch r.Method {
+		case "LIST":
+			// like Vault, the keys one level below the path, folders with a trailing slash
+			var keys []string
+			for secret := range v.secrets {
+				rest, ok := strings.CutPrefix(secret, name+"/")
+				if !ok {
+					continue
+				}
+				if folder, _, deeper := strings.Cut(rest, "/"); deeper {
+					rest = folder + "/"
+				}
+				if !slices.Contains(keys, rest) {
+					keys = append(keys, rest)
+				}
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":[]}`)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": keys}})
+		case http.MethodDelete:
+			delete(v.secrets, name)
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+	v.t.Errorf("unexpected call %s %s", r.Method, r.URL)
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// revoke makes Vault reject every token issued so far
+func (v *fakeVault) revoke() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.tokens = map[string]bool{}
+}
+
+func newTestVault(t *testing.T, cfg utils.VaultConfig) (*vaultBackend, *fakeVault) {
+	t.Helper()
+	fake := &fakeVault{t: t, secrets: map[string]map[string]string{}, tokens: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	cfg.Address = server.URL
+	backend, err := newVaultBackend(cfg, utils.HTTPClientConfig{}, utils.NewLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, fake
+}
+
+func TestVaultLogsInAgainWhenTheTokenIsRejected(t *testing.T) {
+	b, fake := newTestVault(t, utils.VaultConfig{RoleID: "role", SecretID: "role-secret"})
+	if err := b.Write("example.com/1/privkey.pem", []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	// a token revoked before its lease ends is only noticed by the 403
+	fake.revoke()
+	data, err := b.Read("example.com/1/privkey.pem")
+	if err != nil || string(data) != "key" {
+		t.Fatalf("got %q, %v after the token was revoked", data, err)
+	}
+	if fake.logins != 2 {
+		t.Errorf("logged in %d times, want 2", fake.logins)
+	}
+	if got := strings.Join(fake.calls, ","); got != "POST secret/data/hephaestus/example.com/1/privkey.pem,GET secret/data/hephaestus/example.com/1/privkey.pem" {
+		t.Errorf("calls %s", got)
+	}
+}
+
+func TestVaultConfiguredTokenIsNotReplaced(t *testing.T) {
+	b, fake := newTestVault(t, utils.VaultConfig{Token: "configured"})
+	err := b.Write("example.com/1/privkey.pem", []byte("key"))
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("got %v, want the rejected token", err)
+	}
+	if fake.logins != 0 {
+		t.Errorf("logged in %d times without an AppRole", fake.logins)
+	}
+}
+
+func TestVaultDeleteIsRecursive(t *testing.T) {
+	b, fake := newTestVault(t, utils.VaultConfig{RoleID: "role", SecretID: "role-secret"})
+	for _, name := range []string{
+		"example.com/current",
+		"example.com/1/cert.pem",
+		"example.com/1/privkey.pem",
+		"example.com/2/cert.pem",
+		"example.com.au/current",
+		"example.org/1/cert.pem",
+	} {
+		if err := b.Write(name, []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Delete("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for secret := range fake.secrets {
+		left = append(left, secret)
+	}
+	slices.Sort(left)
+	if want := []string{"hephaestus/example.com.au/current", "hephaestus/example.org/1/cert.pem"}; !slices.Equal(left, want) {
+		t.Errorf("left %v, want %v", left, want)
+	}
+	if _, err := b.Read("example.com/1/cert.pem"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want os.ErrNotExist", err)
+	}
+	// deleting what is gone is no error
+	if err := b.Delete("example.com"); err != nil {
+		t.Errorf("delete again: %v", err)
+	}
+}
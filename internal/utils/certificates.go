@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseLeafCertificate returns the first certificate of a PEM bundle, the leaf when the chain is bundled
+func ParseLeafCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no certificate in PEM data")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// ParseCertificateChain returns every certificate of a PEM bundle in order, leaf first
+func ParseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate in PEM data")
+	}
+	return chain, nil
+}
+
+// CertificateFingerprint is the SHA-256 of the DER certificate in the format of
+// `openssl x509 -fingerprint -sha256`, e.g. 3F:A2:...
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// CertificateSerial is the serial number as stored in certificates.serial_number, upper case hex
+func CertificateSerial(cert *x509.Certificate) string {
+	return fmt.Sprintf("%X", cert.SerialNumber)
+}
+
+// CertificateKeyType names the public key of a certificate like certificates.key_type, e.g. ec256
+func CertificateKeyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("rsa%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ec%d", key.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		return "ed25519"
+	}
+	return strings.ToLower(cert.PublicKeyAlgorithm.String())
+}
+
+// CertificateIssuer names the CA of a certificate by its organization, e.g. "Let's Encrypt"
+func CertificateIssuer(cert *x509.Certificate) string {
+	if len(cert.Issuer.Organization) > 0 {
+		return cert.Issuer.Organization[0]
+	}
+	return cert.Issuer.CommonName
+}
+
+// ChainRoot names the root a PEM chain leads to by the common name of the last certificate's issuer,
+// e.g. "ISRG Root X1", empty when the chain can't be parsed
+func ChainRoot(chain []byte) string {
+	certs, err := ParseCertificateChain(chain)
+	if err != nil {
+		return ""
+	}
+	last := certs[len(certs)-1]
+	if last.Issuer.CommonName != "" {
+		return last.Issuer.CommonName
+	}
+	return CertificateIssuer(last)
+}
@@ -3,7 +3,11 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,65 +15,420 @@ import (
 )
 
 type Config struct {
-	AppName    string         `yaml:"app_name" env:"APP_NAME"`
-	Version    string         `yaml:"version"  env:"APP_VERSION"`
-	APIS       []API          `yaml:"apis"`
-	Components Components     `yaml:"components"`
-	AwsConfig  AWSConfig      `yaml:"aws_config"`
-	Database   DatabaseConfig `yaml:"database"`
-	Auth       AuthConfig     `yaml:"auth"`
-	Certs      CertsConfig    `yaml:"certs"`
-	Server     ServerConfig   `yaml:"server"`
-	Logger     LoggerConfig   `yaml:"logger"`
+	AppName         string           `yaml:"app_name" json:"app_name" toml:"app_name" env:"HEPHAESTUS_APP_NAME,APP_NAME"`
+	Version         string           `yaml:"version" json:"version" toml:"version" env:"HEPHAESTUS_APP_VERSION,APP_VERSION"`
+	Providers       []ProviderConfig `yaml:"providers" json:"providers" toml:"providers"`
+	DefaultProvider string           `yaml:"default_provider" json:"default_provider" toml:"default_provider" env:"HEPHAESTUS_DEFAULT_PROVIDER,DEFAULT_PROVIDER"` // used when a domain doesn't name its dns_provider
+	APIS            []API            `yaml:"apis" json:"apis" toml:"apis"`                                                                                        // deprecated, converted to providers on load
+	Components      Components       `yaml:"components" json:"components" toml:"components"`
+	AwsConfig       AWSConfig        `yaml:"aws_config" json:"aws_config" toml:"aws_config"`
+	Database        DatabaseConfig   `yaml:"database" json:"database" toml:"database"`
+	Auth            AuthConfig       `yaml:"auth" json:"auth" toml:"auth"`
+	Encryption      EncryptionConfig `yaml:"encryption" json:"encryption" toml:"encryption"`
+	Certs           CertsConfig      `yaml:"certs" json:"certs" toml:"certs"`
+	HTTPClient      HTTPClientConfig `yaml:"http_client" json:"http_client" toml:"http_client"`
+	Docker          DockerConfig     `yaml:"docker" json:"docker" toml:"docker"`
+	Purge           PurgeConfig      `yaml:"purge" json:"purge" toml:"purge"`
+	Monitor         MonitorConfig    `yaml:"monitor" json:"monitor" toml:"monitor"`
+	Sandbox         SandboxConfig    `yaml:"sandbox" json:"sandbox" toml:"sandbox"`
+	Scheduler       SchedulerConfig  `yaml:"scheduler" json:"scheduler" toml:"scheduler"`
+	Server          ServerConfig     `yaml:"server" json:"server" toml:"server"`
+	Logger          LoggerConfig     `yaml:"logger" json:"logger" toml:"logger"`
+	Features        map[string]bool  `yaml:"features" json:"features" toml:"features" env:"HEPHAESTUS_FEATURES,FEATURES"` // e.g. webhooks: true, env FEATURES="webhooks:true,discovery:false"
 }
 
+// API is the legacy provider entry, the key comes from API_KEY_<NAME>. Use ProviderConfig instead
 type API struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
-	Key  string `yaml:"-"`
+	Name string `yaml:"name" json:"name" toml:"name"`
+	Type string `yaml:"type" json:"type" toml:"type"` // optional, e.g. cloudflare for a cloudflare-staging entry
+	URL  string `yaml:"url" json:"url" toml:"url"`    // unused
 }
 
 type Components struct {
-	HetznerCli      string `yaml:"hetzner_cli"`
-	CloudflareCli   string `yaml:"cloudflare_cli"`
-	Route53Cli      string `yaml:"route_53_cli"`
-	DigitalOceanCli string `yaml:"digitalocean_cli"`
-	AuthCli         string `yaml:"auth_cli"`
+	HetznerCli      string `yaml:"hetzner_cli" json:"hetzner_cli" toml:"hetzner_cli"`
+	CloudflareCli   string `yaml:"cloudflare_cli" json:"cloudflare_cli" toml:"cloudflare_cli"`
+	Route53Cli      string `yaml:"route_53_cli" json:"route_53_cli" toml:"route_53_cli"`
+	DigitalOceanCli string `yaml:"digitalocean_cli" json:"digitalocean_cli" toml:"digitalocean_cli"`
+	AuthCli         string `yaml:"auth_cli" json:"auth_cli" toml:"auth_cli"`
 }
 
 type AWSConfig struct {
-	AccessKey string `yaml:"access_key" env:"AWS_ACCESS_KEY"`
-	SecretKey string `yaml:"secret_key" env:"AWS_SECRET_KEY"`
-	Region    string `yaml:"region"`
+	AccessKey string `yaml:"access_key" json:"access_key" toml:"access_key" env:"HEPHAESTUS_AWS_ACCESS_KEY,AWS_ACCESS_KEY" secret:"true"`
+	SecretKey string `yaml:"secret_key" json:"secret_key" toml:"secret_key" env:"HEPHAESTUS_AWS_SECRET_KEY,AWS_SECRET_KEY" secret:"true"`
+	Region    string `yaml:"region" json:"region" toml:"region"`
 }
 
 type DatabaseConfig struct {
-	Name          string `yaml:"name"`
-	Host          string `yaml:"host" env:"DB_HOST"`
-	Port          int    `yaml:"port" env:"DB_PORT"`
-	User          string `yaml:"user" env:"DB_USER"`
-	Password      string `yaml:"password" env:"DB_PASSWORD"`
-	Database      string `yaml:"database"`
-	MigrationPath string `yaml:"migration_path"`
+	Name          string     `yaml:"name" json:"name" toml:"name"`
+	Host          string     `yaml:"host" json:"host" toml:"host" env:"HEPHAESTUS_DB_HOST,DB_HOST"`
+	Port          int        `yaml:"port" json:"port" toml:"port" env:"HEPHAESTUS_DB_PORT,DB_PORT"`
+	User          string     `yaml:"user" json:"user" toml:"user" env:"HEPHAESTUS_DB_USER,DB_USER"`
+	Password      string     `yaml:"password" json:"password" toml:"password" env:"HEPHAESTUS_DB_PASSWORD,DB_PASSWORD" secret:"true"`
+	Database      string     `yaml:"database" json:"database" toml:"database"`
+	MigrationPath string     `yaml:"migration_path" json:"migration_path" toml:"migration_path"`
+	Pool          PoolConfig `yaml:"pool" json:"pool" toml:"pool"`
+	ReadDSN       string     `yaml:"read_dsn" json:"read_dsn" toml:"read_dsn" env:"HEPHAESTUS_DB_READ_DSN,DB_READ_DSN" secret:"true"` // optional read-only replica for list and report queries
+
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" json:"slow_query_threshold" toml:"slow_query_threshold" env:"HEPHAESTUS_DB_SLOW_QUERY_THRESHOLD,DB_SLOW_QUERY_THRESHOLD"` // 0 disables slow query logging
+	QueryTimeout       time.Duration `yaml:"query_timeout" json:"query_timeout" toml:"query_timeout" env:"HEPHAESTUS_DB_QUERY_TIMEOUT,DB_QUERY_TIMEOUT"`                                    // deadline of a single repository call, 0 keeps the default
+	StatementTimeout   time.Duration `yaml:"statement_timeout" json:"statement_timeout" toml:"statement_timeout" env:"HEPHAESTUS_DB_STATEMENT_TIMEOUT,DB_STATEMENT_TIMEOUT"`                // server side statement_timeout, 0 keeps the server setting
+}
+
+// PoolConfig tunes the pgx connection pool, zero values keep the defaults
+type PoolConfig struct {
+	MaxConns               int32         `yaml:"max_conns" json:"max_conns" toml:"max_conns" env:"HEPHAESTUS_DB_POOL_MAX_CONNS,DB_POOL_MAX_CONNS"`
+	MinConns               int32         `yaml:"min_conns" json:"min_conns" toml:"min_conns" env:"HEPHAESTUS_DB_POOL_MIN_CONNS,DB_POOL_MIN_CONNS"`
+	MaxConnLifetime        time.Duration `yaml:"max_conn_lifetime" json:"max_conn_lifetime" toml:"max_conn_lifetime" env:"HEPHAESTUS_DB_POOL_MAX_CONN_LIFETIME,DB_POOL_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime        time.Duration `yaml:"max_conn_idle_time" json:"max_conn_idle_time" toml:"max_conn_idle_time" env:"HEPHAESTUS_DB_POOL_MAX_CONN_IDLE_TIME,DB_POOL_MAX_CONN_IDLE_TIME"`
+	HealthCheckPeriod      time.Duration `yaml:"health_check_period" json:"health_check_period" toml:"health_check_period" env:"HEPHAESTUS_DB_POOL_HEALTH_CHECK_PERIOD,DB_POOL_HEALTH_CHECK_PERIOD"`
+	ConnectTimeout         time.Duration `yaml:"connect_timeout" json:"connect_timeout" toml:"connect_timeout" env:"HEPHAESTUS_DB_POOL_CONNECT_TIMEOUT,DB_POOL_CONNECT_TIMEOUT"`
+	StatementCacheCapacity int           `yaml:"statement_cache_capacity" json:"statement_cache_capacity" toml:"statement_cache_capacity" env:"HEPHAESTUS_DB_POOL_STATEMENT_CACHE_CAPACITY,DB_POOL_STATEMENT_CACHE_CAPACITY"`
+	QueryExecMode          string        `yaml:"query_exec_mode" json:"query_exec_mode" toml:"query_exec_mode" env:"HEPHAESTUS_DB_POOL_QUERY_EXEC_MODE,DB_POOL_QUERY_EXEC_MODE"` // cache_statement | cache_describe | describe_exec | exec | simple_protocol
 }
 
 type AuthConfig struct {
-	AccessSecKey  string `yaml:"access_sec_key"  env:"AUTH_ACCESS_KEY"`
-	RefreshSecKey string `yaml:"refresh_sec_key" env:"AUTH_REFRESH_KEY"`
+	AccessSecKey  string   `yaml:"access_sec_key" json:"access_sec_key" toml:"access_sec_key" env:"HEPHAESTUS_AUTH_ACCESS_KEY,AUTH_ACCESS_KEY" secret:"true"`
+	RefreshSecKey string   `yaml:"refresh_sec_key" json:"refresh_sec_key" toml:"refresh_sec_key" env:"HEPHAESTUS_AUTH_REFRESH_KEY,AUTH_REFRESH_KEY" secret:"true"`
+	TenantClaim   string   `yaml:"tenant_claim" json:"tenant_claim" toml:"tenant_claim" env:"HEPHAESTUS_AUTH_TENANT_CLAIM,AUTH_TENANT_CLAIM"`           // JWT claim holding the tenant, "tenant_id" by default
+	RequireTenant bool     `yaml:"require_tenant" json:"require_tenant" toml:"require_tenant" env:"HEPHAESTUS_AUTH_REQUIRE_TENANT,AUTH_REQUIRE_TENANT"` // reject tokens without the tenant claim
+	AdminIDs      []string `yaml:"admin_ids" json:"admin_ids" toml:"admin_ids" env:"HEPHAESTUS_AUTH_ADMIN_IDS,AUTH_ADMIN_IDS"`                          // token subjects allowed to call /admin endpoints
+}
+
+// EncryptionConfig protects secrets stored in the database, e.g. providers registered through the API
+type EncryptionConfig struct {
+	Key string `yaml:"key" json:"key" toml:"key" env:"HEPHAESTUS_ENCRYPTION_KEY,ENCRYPTION_KEY" secret:"true"` // passphrase, changing it makes stored secrets unreadable
 }
 
 type CertsConfig struct {
-	StorageDir      string        `yaml:"storage_dir"`
-	Email           string        `yaml:"email"`
-	RenewalDuration time.Duration `yaml:"renewal_duration" env:"CERT_RENEWAL_DURATION"`
+	StorageDir      string        `yaml:"storage_dir" json:"storage_dir" toml:"storage_dir"`
+	Email           string        `yaml:"email" json:"email" toml:"email"`
+	RenewalDuration time.Duration `yaml:"renewal_duration" json:"renewal_duration" toml:"renewal_duration" env:"HEPHAESTUS_CERT_RENEWAL_DURATION,CERT_RENEWAL_DURATION"`
+	RenewalTags     []string      `yaml:"renewal_tags" json:"renewal_tags" toml:"renewal_tags" env:"HEPHAESTUS_CERT_RENEWAL_TAGS,CERT_RENEWAL_TAGS"` // only domains with all of these tags are renewed
+
+	RenewBeforeDays    int           `yaml:"renew_before_days" json:"renew_before_days" toml:"renew_before_days" env:"HEPHAESTUS_CERT_RENEW_BEFORE_DAYS,CERT_RENEW_BEFORE_DAYS"`           // renew this many days before expiry, 30 when 0
+	PropagationTimeout time.Duration `yaml:"propagation_timeout" json:"propagation_timeout" toml:"propagation_timeout" env:"HEPHAESTUS_CERT_PROPAGATION_TIMEOUT,CERT_PROPAGATION_TIMEOUT"` // how long to wait for the TXT record, 0 keeps the provider default
+	DNSPollInterval    time.Duration `yaml:"dns_poll_interval" json:"dns_poll_interval" toml:"dns_poll_interval" env:"HEPHAESTUS_CERT_DNS_POLL_INTERVAL,CERT_DNS_POLL_INTERVAL"`           // how often the TXT record is checked, 0 keeps the provider default
+	ObtainTimeout      time.Duration `yaml:"obtain_timeout" json:"obtain_timeout" toml:"obtain_timeout" env:"HEPHAESTUS_CERT_OBTAIN_TIMEOUT,CERT_OBTAIN_TIMEOUT"`                          // how long to wait for the CA to issue an order, 0 keeps the lego default
+	VerifyDeployment   bool          `yaml:"verify_deployment" json:"verify_deployment" toml:"verify_deployment" env:"HEPHAESTUS_CERT_VERIFY_DEPLOYMENT,CERT_VERIFY_DEPLOYMENT"`           // check that the domain serves a renewed certificate before the previous one is retired
+
+	Resolvers           []string `yaml:"resolvers" json:"resolvers" toml:"resolvers" env:"HEPHAESTUS_CERT_RESOLVERS,CERT_RESOLVERS"`                                                             // recursive resolvers the TXT record must show up on, e.g. 1.1.1.1, 8.8.8.8:53
+	SkipNameserverCheck bool     `yaml:"skip_nameserver_check" json:"skip_nameserver_check" toml:"skip_nameserver_check" env:"HEPHAESTUS_CERT_SKIP_NAMESERVER_CHECK,CERT_SKIP_NAMESERVER_CHECK"` // don't compare the zone's nameservers with the provider's before ordering
+	SkipRateLimitCheck  bool     `yaml:"skip_rate_limit_check" json:"skip_rate_limit_check" toml:"skip_rate_limit_check" env:"HEPHAESTUS_CERT_SKIP_RATE_LIMIT_CHECK,CERT_SKIP_RATE_LIMIT_CHECK"` // don't hold back orders exceeding Let's Encrypt's rate limits
+	KeyType             string   `yaml:"key_type" json:"key_type" toml:"key_type" env:"HEPHAESTUS_CERT_KEY_TYPE,CERT_KEY_TYPE"`                                                                  // key of issued certificates, rsa2048 when empty
+	Profile             string   `yaml:"profile" json:"profile" toml:"profile" env:"HEPHAESTUS_CERT_PROFILE,CERT_PROFILE"`                                                                       // ACME profile of orders, e.g. tlsserver or shortlived, the CA's default when empty
+	OutputFormats       []string `yaml:"output_formats" json:"output_formats" toml:"output_formats" env:"HEPHAESTUS_CERT_OUTPUT_FORMATS,CERT_OUTPUT_FORMATS"`                                    // encodings written next to the PEM files, der adds cert.der and privkey.der
+	CADirURL            string   `yaml:"ca_dir_url" json:"ca_dir_url" toml:"ca_dir_url" env:"HEPHAESTUS_CERT_CA_DIR_URL,CERT_CA_DIR_URL"`                                                        // ACME directory of the CA, Let's Encrypt production when empty
+	CARootBundle        string   `yaml:"ca_root_bundle" json:"ca_root_bundle" toml:"ca_root_bundle" env:"HEPHAESTUS_CERT_CA_ROOT_BUNDLE,CERT_CA_ROOT_BUNDLE"`                                    // PEM file with the roots of a private CA like step-ca, trusted next to the system roots for ACME calls
+	EABKeyID            string   `yaml:"eab_key_id" json:"eab_key_id" toml:"eab_key_id" env:"HEPHAESTUS_CERT_EAB_KEY_ID,CERT_EAB_KEY_ID"`                                                        // external account binding, required by ZeroSSL and Google Trust Services
+	EABHMACKey          string   `yaml:"eab_hmac_key" json:"eab_hmac_key" toml:"eab_hmac_key" env:"HEPHAESTUS_CERT_EAB_HMAC_KEY,CERT_EAB_HMAC_KEY" secret:"true"`                                // base64url HMAC key given with the key id
+
+	Storage    StorageConfig        `yaml:"storage" json:"storage" toml:"storage"`
+	Encryption CertEncryptionConfig `yaml:"encryption" json:"encryption" toml:"encryption"`
+	Files      CertFilesConfig      `yaml:"files" json:"files" toml:"files"`
+	Archive    CertArchiveConfig    `yaml:"archive" json:"archive" toml:"archive"`
+	Publish    PublishConfig        `yaml:"publish" json:"publish" toml:"publish"`
+}
+
+// CertEncryptionConfig encrypts the private keys in storage_dir, and with Certs the certificates too,
+// with AES-256-GCM under a key derived from Key. Deployment targets read decrypted copies from LiveDir
+type CertEncryptionConfig struct {
+	Key     string `yaml:"key" json:"key" toml:"key" env:"HEPHAESTUS_CERT_ENCRYPTION_KEY,CERT_ENCRYPTION_KEY" secret:"true"` // master secret, e.g. a vault:// reference, files stay plaintext when empty
+	Certs   bool   `yaml:"certs" json:"certs" toml:"certs" env:"HEPHAESTUS_CERT_ENCRYPT_CERTS,CERT_ENCRYPT_CERTS"`           // encrypt certificates and chains as well as private keys
+	LiveDir string `yaml:"live_dir" json:"live_dir" toml:"live_dir" env:"HEPHAESTUS_CERT_LIVE_DIR,CERT_LIVE_DIR"`            // decrypted current files of every domain, e.g. on a tmpfs, none when empty
+}
+
+// CertFilesConfig sets the mode and owner of the certificate, chain and key files in storage_dir and
+// live_dir, e.g. so the nginx user of another container can read the mounted keys
+type CertFilesConfig struct {
+	CertMode string `yaml:"cert_mode" json:"cert_mode" toml:"cert_mode" env:"HEPHAESTUS_CERT_FILE_MODE,CERT_FILE_MODE"`      // octal mode of certificates and chains, 0644 when empty
+	KeyMode  string `yaml:"key_mode" json:"key_mode" toml:"key_mode" env:"HEPHAESTUS_CERT_KEY_FILE_MODE,CERT_KEY_FILE_MODE"` // octal mode of private keys, 0600 when empty
+	UID      int    `yaml:"uid" json:"uid" toml:"uid" env:"HEPHAESTUS_CERT_FILE_UID,CERT_FILE_UID"`                          // owner of the files, the user of the process when 0
+	GID      int    `yaml:"gid" json:"gid" toml:"gid" env:"HEPHAESTUS_CERT_FILE_GID,CERT_FILE_GID"`                          // group of the files, the group of the process when 0
+}
+
+// CertArchiveConfig keeps replaced certificate versions in <storage_dir>/<domain>/archive instead of
+// removing them, so they can be restored
+type CertArchiveConfig struct {
+	Keep   int           `yaml:"keep" json:"keep" toml:"keep" env:"HEPHAESTUS_CERT_ARCHIVE_KEEP,CERT_ARCHIVE_KEEP"`                // archived versions kept per domain, none when 0
+	MaxAge time.Duration `yaml:"max_age" json:"max_age" toml:"max_age" env:"HEPHAESTUS_CERT_ARCHIVE_MAX_AGE,CERT_ARCHIVE_MAX_AGE"` // archived versions older than this are removed, no limit when 0
+}
+
+// PublishConfig selects services the live certificate and key are imported into whenever they change, on
+// issuance, renewal, activation, restore and chain switch, next to the storage. Nothing is published when no target is set
+type PublishConfig struct {
+	Tags          []string            `yaml:"tags" json:"tags" toml:"tags" env:"HEPHAESTUS_CERT_PUBLISH_TAGS,CERT_PUBLISH_TAGS"` // only domains with all of these tags are published, all when empty
+	AzureKeyVault AzureKeyVaultConfig `yaml:"azure_key_vault" json:"azure_key_vault" toml:"azure_key_vault"`
+	AWS           AWSPublishConfig    `yaml:"aws" json:"aws" toml:"aws"`
+}
+
+// AWSPublishConfig puts the certificates into AWS Secrets Manager, one secret per domain, and imports
+// them into ACM for load balancers and CloudFront. Renewals add a secret version and re-import into the
+// same ACM certificate, so consumers keep their references. Without keys the default credential chain is used
+type AWSPublishConfig struct {
+	Region         string                  `yaml:"region" json:"region" toml:"region" env:"HEPHAESTUS_AWS_PUBLISH_REGION,AWS_PUBLISH_REGION"` // the default AWS region when empty, CloudFront needs us-east-1
+	AccessKey      string                  `yaml:"access_key" json:"access_key" toml:"access_key" env:"HEPHAESTUS_AWS_PUBLISH_ACCESS_KEY,AWS_PUBLISH_ACCESS_KEY" secret:"true"`
+	SecretKey      string                  `yaml:"secret_key" json:"secret_key" toml:"secret_key" env:"HEPHAESTUS_AWS_PUBLISH_SECRET_KEY,AWS_PUBLISH_SECRET_KEY" secret:"true"`
+	SecretsManager AWSSecretsManagerConfig `yaml:"secrets_manager" json:"secrets_manager" toml:"secrets_manager"`
+	ACM            ACMConfig               `yaml:"acm" json:"acm" toml:"acm"`
+}
+
+type AWSSecretsManagerConfig struct {
+	Enabled    bool   `yaml:"enabled" json:"enabled" toml:"enabled" env:"HEPHAESTUS_AWS_SECRETS_MANAGER_PUBLISH,AWS_SECRETS_MANAGER_PUBLISH"`
+	NamePrefix string `yaml:"name_prefix" json:"name_prefix" toml:"name_prefix"` // prepended to the secret names, hephaestus/ when empty
+	KMSKeyID   string `yaml:"kms_key_id" json:"kms_key_id" toml:"kms_key_id"`    // key new secrets are encrypted with, the AWS managed key when empty
+}
+
+type ACMConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled" toml:"enabled" env:"HEPHAESTUS_ACM_PUBLISH,ACM_PUBLISH"`
+}
+
+// Enabled tells whether any AWS target is set
+func (c AWSPublishConfig) Enabled() bool {
+	return c.SecretsManager.Enabled || c.ACM.Enabled
+}
+
+// AzureKeyVaultConfig imports the certificates as certificate objects of a Key Vault, e.g. for
+// Application Gateway and Front Door. It authenticates with ClientSecret or, without it, the managed identity
+type AzureKeyVaultConfig struct {
+	VaultURL      string `yaml:"vault_url" json:"vault_url" toml:"vault_url" env:"HEPHAESTUS_AZURE_KEY_VAULT_URL,AZURE_KEY_VAULT_URL"` // e.g. https://my-vault.vault.azure.net, disabled when empty
+	TenantID      string `yaml:"tenant_id" json:"tenant_id" toml:"tenant_id" env:"HEPHAESTUS_AZURE_TENANT_ID,AZURE_TENANT_ID"`
+	ClientID      string `yaml:"client_id" json:"client_id" toml:"client_id" env:"HEPHAESTUS_AZURE_CLIENT_ID,AZURE_CLIENT_ID"` // app registration, or the user-assigned managed identity
+	ClientSecret  string `yaml:"client_secret" json:"client_secret" toml:"client_secret" env:"HEPHAESTUS_AZURE_CLIENT_SECRET,AZURE_CLIENT_SECRET" secret:"true"`
+	AuthorityHost string `yaml:"authority_host" json:"authority_host" toml:"authority_host" env:"HEPHAESTUS_AZURE_AUTHORITY_HOST,AZURE_AUTHORITY_HOST"` // https://login.microsoftonline.com when empty
+	NamePrefix    string `yaml:"name_prefix" json:"name_prefix" toml:"name_prefix"`                                                                     // prepended to the certificate names, e.g. hephaestus-
+}
+
+// Storage backends of certificates and private keys
+const (
+	StorageFile     = "file"
+	StorageVault    = "vault"
+	StorageS3       = "s3"
+	StoragePostgres = "postgres"
+)
+
+// StorageBackends are the values of certs.storage.backend
+var StorageBackends = []string{StorageFile, StorageVault, StorageS3, StoragePostgres}
+
+// StorageConfig selects where certificates and private keys are kept, storage_dir by default.
+// With another backend only acme_account.json stays in storage_dir, it holds no secret
+type StorageConfig struct {
+	Backend string      `yaml:"backend" json:"backend" toml:"backend" env:"HEPHAESTUS_CERT_STORAGE_BACKEND,CERT_STORAGE_BACKEND"` // file, vault, s3 or postgres, file when empty
+	Vault   VaultConfig `yaml:"vault" json:"vault" toml:"vault"`
+	S3      S3Config    `yaml:"s3" json:"s3" toml:"s3"`
+}
+
+// S3Config is a bucket of S3 or an S3-compatible object storage like MinIO, one object per file.
+// Credentials and region fall back to the default AWS chain, e.g. AWS_REGION and an instance role
+type S3Config struct {
+	Bucket    string `yaml:"bucket" json:"bucket" toml:"bucket" env:"HEPHAESTUS_S3_BUCKET,S3_BUCKET"`
+	Prefix    string `yaml:"prefix" json:"prefix" toml:"prefix" env:"HEPHAESTUS_S3_PREFIX,S3_PREFIX"`                     // key prefix of the files, the bucket root when empty
+	Region    string `yaml:"region" json:"region" toml:"region" env:"HEPHAESTUS_S3_REGION,S3_REGION"`                     // the default AWS region when empty
+	Endpoint  string `yaml:"endpoint" json:"endpoint" toml:"endpoint" env:"HEPHAESTUS_S3_ENDPOINT,S3_ENDPOINT"`           // S3-compatible service, e.g. https://minio:9000, AWS when empty
+	PathStyle bool   `yaml:"path_style" json:"path_style" toml:"path_style" env:"HEPHAESTUS_S3_PATH_STYLE,S3_PATH_STYLE"` // bucket in the path instead of the host name, needed by most S3-compatible services
+	AccessKey string `yaml:"access_key" json:"access_key" toml:"access_key" env:"HEPHAESTUS_S3_ACCESS_KEY,S3_ACCESS_KEY" secret:"true"`
+	SecretKey string `yaml:"secret_key" json:"secret_key" toml:"secret_key" env:"HEPHAESTUS_S3_SECRET_KEY,S3_SECRET_KEY" secret:"true"`
+	SSE       string `yaml:"sse" json:"sse" toml:"sse" env:"HEPHAESTUS_S3_SSE,S3_SSE"`                                    // server-side encryption, AES256 or aws:kms, the bucket default when empty
+	KMSKeyID  string `yaml:"kms_key_id" json:"kms_key_id" toml:"kms_key_id" env:"HEPHAESTUS_S3_KMS_KEY_ID,S3_KMS_KEY_ID"` // KMS key of aws:kms, the AWS managed key when empty
+}
+
+// S3Encryptions are the values of certs.storage.s3.sse
+var S3Encryptions = []string{"AES256", "aws:kms"}
+
+// BackendName returns the storage backend, file when none is set
+func (c StorageConfig) BackendName() string {
+	if c.Backend == "" {
+		return StorageFile
+	}
+	return c.Backend
+}
+
+// VaultConfig is a KV v2 secrets engine certificates and keys are written to, one secret per file.
+// It authenticates with Token or, when RoleID is set, with AppRole
+type VaultConfig struct {
+	Address   string `yaml:"address" json:"address" toml:"address" env:"HEPHAESTUS_VAULT_ADDR,VAULT_ADDR"`
+	Namespace string `yaml:"namespace" json:"namespace" toml:"namespace" env:"HEPHAESTUS_VAULT_NAMESPACE,VAULT_NAMESPACE"`
+	Mount     string `yaml:"mount" json:"mount" toml:"mount"` // KV v2 mount, secret when empty
+	Path      string `yaml:"path" json:"path" toml:"path"`    // prefix of the secrets in the mount, hephaestus when empty
+	Token     string `yaml:"token" json:"token" toml:"token" env:"HEPHAESTUS_VAULT_TOKEN,VAULT_TOKEN" secret:"true"`
+	RoleID    string `yaml:"role_id" json:"role_id" toml:"role_id" env:"HEPHAESTUS_VAULT_ROLE_ID,VAULT_ROLE_ID"`
+	SecretID  string `yaml:"secret_id" json:"secret_id" toml:"secret_id" env:"HEPHAESTUS_VAULT_SECRET_ID,VAULT_SECRET_ID" secret:"true"`
+	AuthMount string `yaml:"auth_mount" json:"auth_mount" toml:"auth_mount"` // AppRole auth mount, approle when empty
+}
+
+// CertKeyType returns the key type of issued certificates
+func (c CertsConfig) CertKeyType() string {
+	if c.KeyType == "" {
+		return DefaultKeyType
+	}
+	return c.KeyType
+}
+
+// DirectoryURL returns the ACME directory to issue from
+func (c CertsConfig) DirectoryURL() string {
+	if c.CADirURL == "" {
+		return DefaultCADirURL
+	}
+	return c.CADirURL
+}
+
+const defaultRenewBeforeDays = 30
+
+// profilePattern is the form of ACME profile names, like Let's Encrypt's classic, tlsserver and shortlived
+var awsSecretPrefix = regexp.MustCompile(`^[a-zA-Z0-9/_+=.@-]*$`)
+
+var profilePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// azureNamePrefix keeps prefixed certificate names valid Key Vault object names
+var azureNamePrefix = regexp.MustCompile(`^[0-9A-Za-z-]*$`)
+
+// ValidProfile reports whether name can be an ACME profile, the CA decides whether it offers it
+func ValidProfile(name string) bool {
+	return profilePattern.MatchString(name)
+}
+
+// default modes of the certificate files, keys are readable by their owner only
+const (
+	defaultCertFileMode = 0644
+	defaultKeyFileMode  = 0600
+)
+
+// Modes returns the modes of certificates and private keys, the defaults for empty or invalid modes
+func (c CertFilesConfig) Modes() (cert, key os.FileMode) {
+	return parseFileMode(c.CertMode, defaultCertFileMode), parseFileMode(c.KeyMode, defaultKeyFileMode)
+}
+
+func parseFileMode(value string, fallback os.FileMode) os.FileMode {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return fallback
+	}
+	return os.FileMode(mode)
+}
+
+// DefaultKeyType is the key type of certificates when certs.key_type is empty
+const DefaultKeyType = "rsa2048"
+
+// CertKeyTypes are the key types certificates can be issued with, as stored in certificates.key_type
+var CertKeyTypes = []string{"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096", "rsa8192"}
+
+// OutputFormatDER writes the leaf and the private key in DER next to the PEM files, e.g. for Java and IIS
+const OutputFormatDER = "der"
+
+// OutputFormats are the encodings certificate files can be written in, pem is always written
+var OutputFormats = []string{"pem", OutputFormatDER}
+
+// DefaultCADirURL is the Let's Encrypt production directory
+const DefaultCADirURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+// caKeyTypes are the key types of the public CAs that don't sign all of CertKeyTypes, by the host
+// of their directory. They reject RSA keys above 4096 bits, other CAs are assumed to sign every type
+var caKeyTypes = map[string][]string{
+	"acme-v02.api.letsencrypt.org":         {"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096"},
+	"acme-staging-v02.api.letsencrypt.org": {"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096"},
+	"dv.acme-v02.api.pki.goog":             {"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096"},
+	"dv.acme-v02.test-api.pki.goog":        {"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096"},
+	"api.buypass.com":                      {"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096"},
+	"api.test4.buypass.no":                 {"ec256", "ec384", "rsa2048", "rsa3072", "rsa4096"},
+}
+
+// CAKeyTypes returns the key types the CA certificates are ordered from signs, in sandbox mode
+// Pebble signs all of them
+func (c *Config) CAKeyTypes() []string {
+	if c.Sandbox.Enabled {
+		return CertKeyTypes
+	}
+	u, err := url.Parse(c.Certs.DirectoryURL())
+	if err != nil {
+		return CertKeyTypes
+	}
+	if types, ok := caKeyTypes[strings.ToLower(u.Hostname())]; ok {
+		return types
+	}
+	return CertKeyTypes
+}
+
+// RenewBefore is how long before expiry a certificate is renewed
+func (c CertsConfig) RenewBefore() time.Duration {
+	days := c.RenewBeforeDays
+	if days <= 0 {
+		days = defaultRenewBeforeDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// HTTPClientConfig tunes outbound HTTP calls to the ACME CA and the DNS provider APIs
+type HTTPClientConfig struct {
+	Timeout       time.Duration `yaml:"timeout" json:"timeout" toml:"timeout" env:"HEPHAESTUS_HTTP_CLIENT_TIMEOUT,HTTP_CLIENT_TIMEOUT"`                                         // whole request, 30s when 0
+	Retries       int           `yaml:"retries" json:"retries" toml:"retries" env:"HEPHAESTUS_HTTP_CLIENT_RETRIES,HTTP_CLIENT_RETRIES"`                                         // retries of idempotent requests on network errors, 429 and 5xx
+	RetryWait     time.Duration `yaml:"retry_wait" json:"retry_wait" toml:"retry_wait" env:"HEPHAESTUS_HTTP_CLIENT_RETRY_WAIT,HTTP_CLIENT_RETRY_WAIT"`                          // first backoff, doubled on every retry, 1s when 0
+	TLSMinVersion string        `yaml:"tls_min_version" json:"tls_min_version" toml:"tls_min_version" env:"HEPHAESTUS_HTTP_CLIENT_TLS_MIN_VERSION,HTTP_CLIENT_TLS_MIN_VERSION"` // "1.2" (default) or "1.3"
+	Proxy         string        `yaml:"proxy" json:"proxy" toml:"proxy" env:"HEPHAESTUS_HTTP_CLIENT_PROXY,HTTP_CLIENT_PROXY" secret:"true"`                                     // proxy URL, HTTPS_PROXY and NO_PROXY are used when empty
+
+	InsecureSkipVerify bool   `yaml:"-" json:"-" toml:"-"` // set in sandbox mode only, Pebble serves a self-signed certificate
+	RootCAs            []byte `yaml:"-" json:"-" toml:"-"` // PEM roots trusted next to the system ones, set by the issuer from certs.ca_root_bundle
+}
+
+// DockerConfig is the Docker Engine API nginx is reloaded through, the containers are named by the
+// nginx_container_name of the domains
+type DockerConfig struct {
+	Socket      string        `yaml:"socket" json:"socket" toml:"socket" env:"HEPHAESTUS_DOCKER_SOCKET,DOCKER_SOCKET"`                               // unix socket path or unix:// URL, /var/run/docker.sock when empty
+	Timeout     time.Duration `yaml:"timeout" json:"timeout" toml:"timeout" env:"HEPHAESTUS_DOCKER_TIMEOUT,DOCKER_TIMEOUT"`                          // a single API call, 10s when 0
+	ExecTimeout time.Duration `yaml:"exec_timeout" json:"exec_timeout" toml:"exec_timeout" env:"HEPHAESTUS_DOCKER_EXEC_TIMEOUT,DOCKER_EXEC_TIMEOUT"` // the reload command inside the container, 30s when 0
+}
+
+// SocketPath returns the path of the Docker socket
+func (c DockerConfig) SocketPath() string {
+	if c.Socket == "" {
+		return "/var/run/docker.sock"
+	}
+	return strings.TrimPrefix(c.Socket, "unix://")
+}
+
+type PurgeConfig struct {
+	Enabled     bool          `yaml:"enabled" json:"enabled" toml:"enabled" env:"HEPHAESTUS_PURGE_ENABLED,PURGE_ENABLED"`
+	GracePeriod time.Duration `yaml:"grace_period" json:"grace_period" toml:"grace_period" env:"HEPHAESTUS_PURGE_GRACE_PERIOD,PURGE_GRACE_PERIOD"` // how long soft-deleted domains are kept
+	Interval    time.Duration `yaml:"interval" json:"interval" toml:"interval" env:"HEPHAESTUS_PURGE_INTERVAL,PURGE_INTERVAL"`
+}
+
+// MonitorConfig sets how often the certificates served by monitored domains are checked
+type MonitorConfig struct {
+	Interval              time.Duration `yaml:"interval" json:"interval" toml:"interval" env:"HEPHAESTUS_MONITOR_INTERVAL,MONITOR_INTERVAL"`
+	Timeout               time.Duration `yaml:"timeout" json:"timeout" toml:"timeout" env:"HEPHAESTUS_MONITOR_TIMEOUT,MONITOR_TIMEOUT"`                                                                                 // TLS handshake with the monitored host
+	WarnBeforeDays        int           `yaml:"warn_before_days" json:"warn_before_days" toml:"warn_before_days" env:"HEPHAESTUS_MONITOR_WARN_BEFORE_DAYS,MONITOR_WARN_BEFORE_DAYS"`                                    // certs.renew_before_days when 0
+	CheckDrift            bool          `yaml:"check_drift" json:"check_drift" toml:"check_drift" env:"HEPHAESTUS_MONITOR_CHECK_DRIFT,MONITOR_CHECK_DRIFT"`                                                             // compare managed domains' files and served certificates with the database
+	CheckOCSP             bool          `yaml:"check_ocsp" json:"check_ocsp" toml:"check_ocsp" env:"HEPHAESTUS_MONITOR_CHECK_OCSP,MONITOR_CHECK_OCSP"`                                                                  // ask the OCSP responder of the issuer whether active certificates were revoked
+	AllowPrivateAddresses bool          `yaml:"allow_private_addresses" json:"allow_private_addresses" toml:"allow_private_addresses" env:"HEPHAESTUS_MONITOR_ALLOW_PRIVATE_ADDRESSES,MONITOR_ALLOW_PRIVATE_ADDRESSES"` // check monitored domains on loopback, private and link-local addresses too
+}
+
+// SchedulerConfig sets when background jobs run, times are local to Timezone
+type SchedulerConfig struct {
+	Timezone string `yaml:"timezone" json:"timezone" toml:"timezone" env:"HEPHAESTUS_SCHEDULER_TIMEZONE,SCHEDULER_TIMEZONE"` // IANA name, e.g. Europe/Berlin, UTC when empty
+	RenewAt  string `yaml:"renew_at" json:"renew_at" toml:"renew_at" env:"HEPHAESTUS_SCHEDULER_RENEW_AT,SCHEDULER_RENEW_AT"` // daily renewal time "15:04", replaces certs.renewal_duration when set
+}
+
+// Location returns the scheduler timezone
+func (c SchedulerConfig) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// ParseClock parses a "15:04" time of day
+func ParseClock(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour(), t.Minute(), nil
 }
 
 type ServerConfig struct {
-	Port string `yaml:"port" env:"SERVER_PORT"`
+	Port string `yaml:"port" json:"port" toml:"port" env:"HEPHAESTUS_SERVER_PORT,SERVER_PORT"`
 }
 
 type LoggerConfig struct {
-	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"`
+	LogLevel string `yaml:"log_level" json:"log_level" toml:"log_level" env:"HEPHAESTUS_LOG_LEVEL,LOG_LEVEL"`
 }
 
 func LoadConfig(confPath string) (*Config, error) {
@@ -83,27 +442,71 @@ func LoadConfig(confPath string) (*Config, error) {
 
 	var cfg Config
 
-	// Load YAML file
-	if err := cleanenv.ReadConfig(confPath, &cfg); err != nil {
+	// Load the config file, the format is detected by extension
+	if err := readConfigFile(confPath, &cfg); err != nil {
 		return nil, err
 	}
 
-	// Load Api.Key values
-	for i := range cfg.APIS {
-		api := &cfg.APIS[i]
-
-		envName := "API_KEY_" + strings.ToUpper(api.Name)
-
-		api.Key = os.Getenv(envName)
-		if api.Key == "" {
-			return nil, fmt.Errorf("missing environment variable %s for API '%s'", envName, api.Name)
+	// Environment overlay, e.g. config.prod.yaml for CONFIG_ENV=prod, only the keys it sets change
+	if env := Getenv("CONFIG_ENV"); env != "" {
+		overlay := overlayPath(confPath, env)
+		if _, err := os.Stat(overlay); err != nil {
+			return nil, fmt.Errorf("config overlay for CONFIG_ENV=%s does not exist: %s", env, overlay)
+		}
+		if err := readConfigFile(overlay, &cfg); err != nil {
+			return nil, err
 		}
 	}
 
+	// problems are collected and returned together
+	var errs ConfigErrors
+
+	// Provider credentials, API_KEY_<NAME> fills the ones missing in the file
+	cfg.fromLegacyAPIs()
+	cfg.loadProviderKeys(&errs)
+
 	// Override with environment variables
 	if err := cleanenv.ReadEnv(&cfg); err != nil {
 		return nil, err
 	}
 
+	// Secrets mounted as files, <ENV>_FILE or file:/path values
+	resolveSecrets(&cfg, &errs)
+
+	// sandbox mode needs no DNS credentials, every provider answers from memory
+	cfg.applySandbox()
+
+	cfg.validate(&errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
 	return &cfg, nil
 }
+
+// overlayPath returns the overlay next to the base config, config.yaml -> config.<env>.yaml
+func overlayPath(confPath, env string) string {
+	ext := filepath.Ext(confPath)
+	return strings.TrimSuffix(confPath, ext) + "." + env + ext
+}
+
+// readConfigFile reads YAML, JSON or TOML, JSON goes through the YAML decoder (JSON is valid YAML)
+// so durations like "30s" are accepted in every format
+func readConfigFile(confPath string, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(confPath)) {
+	case ".yaml", ".yml", ".toml":
+		return cleanenv.ReadConfig(confPath, cfg)
+	case ".json":
+		f, err := os.Open(confPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := cleanenv.ParseYAML(f, cfg); err != nil {
+			return fmt.Errorf("config file %s: %w", confPath, err)
+		}
+		return cleanenv.ReadEnv(cfg)
+	default:
+		return fmt.Errorf("unsupported config format %q, use .yaml, .json or .toml", filepath.Ext(confPath))
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"reflect"
+	"time"
+)
+
+const redactedValue = "******"
+
+// RedactedConfig returns the effective configuration keyed like the config file, after env overrides
+// and defaults, with secret values masked and durations written as "30s"
+func RedactedConfig(cfg *Config) map[string]any {
+	return redactValue(reflect.ValueOf(cfg).Elem()).(map[string]any)
+}
+
+func redactValue(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fv := v.Field(i)
+			if field.Tag.Get("secret") == "true" && fv.Kind() == reflect.String {
+				// an empty secret stays visible, "not set" is what is usually debugged
+				if fv.String() != "" {
+					out[configKey(field)] = redactedValue
+				} else {
+					out[configKey(field)] = ""
+				}
+				continue
+			}
+			out[configKey(field)] = redactValue(fv)
+		}
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem())
+	case reflect.Slice:
+		out := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, redactValue(v.Index(i)))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,385 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minRenewalDuration is the shortest interval of the renewal cycle, a cycle reads every domain
+const minRenewalDuration = time.Minute
+
+// shortLivedRenewalWindow is how long before expiry the 6-day certificates of the shortlived profile are
+// renewed, a third of their lifetime
+const shortLivedRenewalWindow = 2 * 24 * time.Hour
+
+// ConfigError is a single problem of the config, Path is the YAML path of the value
+type ConfigError struct {
+	Path    string
+	Message string
+}
+
+// ConfigErrors lists every problem found while loading the config, so they can be fixed in one go
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid config:")
+	for _, ce := range e {
+		fmt.Fprintf(&b, "\n  - %s: %s", ce.Path, ce.Message)
+	}
+	return b.String()
+}
+
+func (e *ConfigErrors) add(path, format string, args ...any) {
+	*e = append(*e, ConfigError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	logLevels      = []string{"trace", "debug", "info", "warn", "error", "fatal", "success"}
+	queryExecModes = []string{"cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol"}
+)
+
+// validate checks the loaded config and appends every problem to errs
+func (c *Config) validate(errs *ConfigErrors) {
+	c.validateProviders(errs)
+
+	db := c.Database
+	if db.Host == "" {
+		errs.add("database.host", "is required (env DB_HOST)")
+	}
+	if db.Port <= 0 || db.Port > 65535 {
+		errs.add("database.port", "must be between 1 and 65535, got %d (env DB_PORT)", db.Port)
+	}
+	if db.User == "" {
+		errs.add("database.user", "is required (env DB_USER)")
+	}
+	if db.Name == "" {
+		errs.add("database.name", "is required")
+	}
+	if db.Database == "" {
+		errs.add("database.database", "is required")
+	}
+	if db.Pool.MaxConns < 0 {
+		errs.add("database.pool.max_conns", "must not be negative")
+	}
+	if db.Pool.MinConns < 0 {
+		errs.add("database.pool.min_conns", "must not be negative")
+	}
+	if db.Pool.MaxConns > 0 && db.Pool.MinConns > db.Pool.MaxConns {
+		errs.add("database.pool.min_conns", "exceeds max_conns (%d > %d)", db.Pool.MinConns, db.Pool.MaxConns)
+	}
+	if db.Pool.QueryExecMode != "" && !slices.Contains(queryExecModes, db.Pool.QueryExecMode) {
+		errs.add("database.pool.query_exec_mode", "must be one of %s, got %q", strings.Join(queryExecModes, ", "), db.Pool.QueryExecMode)
+	}
+	if db.QueryTimeout < 0 {
+		errs.add("database.query_timeout", "must not be negative")
+	}
+	if db.StatementTimeout < 0 {
+		errs.add("database.statement_timeout", "must not be negative")
+	}
+
+	if c.Auth.AccessSecKey == "" {
+		errs.add("auth.access_sec_key", "is required (env AUTH_ACCESS_KEY)")
+	}
+
+	if c.Encryption.Key != "" && len(c.Encryption.Key) < 16 {
+		errs.add("encryption.key", "must be at least 16 characters (env ENCRYPTION_KEY)")
+	}
+
+	if c.Certs.Email == "" {
+		errs.add("certs.email", "is required, it is the ACME account contact")
+	} else if _, err := mail.ParseAddress(c.Certs.Email); err != nil {
+		errs.add("certs.email", "invalid email %q", c.Certs.Email)
+	}
+	if c.Certs.StorageDir == "" {
+		errs.add("certs.storage_dir", "is required")
+	}
+	// the renewal ticker panics on a negative interval and would spin on a tiny one
+	switch {
+	case c.Certs.RenewalDuration < 0:
+		errs.add("certs.renewal_duration", "must not be negative, got %s", c.Certs.RenewalDuration)
+	case c.Certs.RenewalDuration == 0 && c.Scheduler.RenewAt == "":
+		errs.add("certs.renewal_duration", "must be greater than 0, e.g. \"24h\" (env CERT_RENEWAL_DURATION)")
+	case c.Certs.RenewalDuration > 0 && c.Certs.RenewalDuration < minRenewalDuration:
+		errs.add("certs.renewal_duration", "must be at least %s, got %s", minRenewalDuration, c.Certs.RenewalDuration)
+	}
+
+	if c.Certs.RenewBeforeDays < 0 || c.Certs.RenewBeforeDays >= 90 {
+		errs.add("certs.renew_before_days", "must be between 0 and 89, certificates are valid for 90 days, got %d", c.Certs.RenewBeforeDays)
+	}
+	if c.Certs.PropagationTimeout < 0 {
+		errs.add("certs.propagation_timeout", "must not be negative")
+	}
+	if c.Certs.DNSPollInterval < 0 {
+		errs.add("certs.dns_poll_interval", "must not be negative")
+	}
+	if c.Certs.PropagationTimeout > 0 && c.Certs.DNSPollInterval > c.Certs.PropagationTimeout {
+		errs.add("certs.dns_poll_interval", "must not be longer than certs.propagation_timeout")
+	}
+	if c.Certs.ObtainTimeout < 0 {
+		errs.add("certs.obtain_timeout", "must not be negative")
+	}
+	for _, r := range c.Certs.Resolvers {
+		if !validResolver(r) {
+			errs.add("certs.resolvers", "%q must be host or host:port", r)
+		}
+	}
+	if c.Certs.KeyType != "" && !slices.Contains(CertKeyTypes, c.Certs.KeyType) {
+		errs.add("certs.key_type", "must be one of %s, got %q", strings.Join(CertKeyTypes, ", "), c.Certs.KeyType)
+	} else if supported := c.CAKeyTypes(); !slices.Contains(supported, c.Certs.CertKeyType()) {
+		errs.add("certs.key_type", "%s isn't signed by the CA of certs.ca_dir_url, use one of %s", c.Certs.CertKeyType(), strings.Join(supported, ", "))
+	}
+	if c.Certs.Profile != "" && !ValidProfile(c.Certs.Profile) {
+		errs.add("certs.profile", "must be a profile name like tlsserver or shortlived, got %q", c.Certs.Profile)
+	}
+	if c.Certs.Profile == "shortlived" && c.Scheduler.RenewAt == "" && c.Certs.RenewalDuration >= shortLivedRenewalWindow {
+		errs.add("certs.renewal_duration", "must be shorter than %s with the shortlived profile, its certificates are renewed 2 days before expiry, e.g. \"4h\", got %s",
+			shortLivedRenewalWindow, c.Certs.RenewalDuration)
+	}
+	for _, f := range c.Certs.OutputFormats {
+		if !slices.Contains(OutputFormats, f) {
+			errs.add("certs.output_formats", "must be one of %s, got %q", strings.Join(OutputFormats, ", "), f)
+		}
+	}
+	if c.Certs.CADirURL != "" {
+		if u, err := url.Parse(c.Certs.CADirURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs.add("certs.ca_dir_url", "must be an https URL like https://acme.zerossl.com/v2/DV90")
+		}
+	}
+	if c.Certs.CARootBundle != "" {
+		if bundle, err := os.ReadFile(c.Certs.CARootBundle); err != nil {
+			errs.add("certs.ca_root_bundle", "can't be read: %v", err)
+		} else if _, err := RootPool(bundle); err != nil {
+			errs.add("certs.ca_root_bundle", "%v", err)
+		}
+	}
+	if (c.Certs.EABKeyID == "") != (c.Certs.EABHMACKey == "") {
+		errs.add("certs.eab_hmac_key", "eab_key_id and eab_hmac_key must be set together")
+	}
+
+	c.Certs.Storage.validate(errs)
+	c.Certs.Encryption.validate(errs, c.Certs.Storage.BackendName())
+	c.Certs.Files.validate(errs, c.Certs.Storage.BackendName())
+	c.Certs.Archive.validate(errs, c.Certs.Storage.BackendName())
+	c.Certs.Publish.AzureKeyVault.validate(errs)
+	c.Certs.Publish.AWS.validate(errs)
+
+	if c.HTTPClient.Timeout < 0 {
+		errs.add("http_client.timeout", "must not be negative")
+	}
+	if c.HTTPClient.Retries < 0 {
+		errs.add("http_client.retries", "must not be negative")
+	}
+	if c.HTTPClient.RetryWait < 0 {
+		errs.add("http_client.retry_wait", "must not be negative")
+	}
+	if _, ok := tlsVersions[c.HTTPClient.TLSMinVersion]; !ok {
+		errs.add("http_client.tls_min_version", "must be 1.2 or 1.3, got %q", c.HTTPClient.TLSMinVersion)
+	}
+	if c.HTTPClient.Proxy != "" {
+		if u, err := url.Parse(c.HTTPClient.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			errs.add("http_client.proxy", "must be a URL like http://proxy:3128")
+		}
+	}
+
+	if !filepath.IsAbs(c.Docker.SocketPath()) {
+		errs.add("docker.socket", "must be an absolute path or a unix:// URL, got %q", c.Docker.Socket)
+	}
+	if c.Docker.Timeout < 0 {
+		errs.add("docker.timeout", "must not be negative")
+	}
+	if c.Docker.ExecTimeout < 0 {
+		errs.add("docker.exec_timeout", "must not be negative")
+	}
+
+	if _, err := c.Scheduler.Location(); err != nil {
+		errs.add("scheduler.timezone", "unknown timezone %q", c.Scheduler.Timezone)
+	}
+	if c.Scheduler.RenewAt != "" {
+		if _, _, err := ParseClock(c.Scheduler.RenewAt); err != nil {
+			errs.add("scheduler.renew_at", "%v", err)
+		}
+	}
+
+	if c.Purge.GracePeriod < 0 {
+		errs.add("purge.grace_period", "must not be negative")
+	}
+	if c.Purge.Interval < 0 {
+		errs.add("purge.interval", "must not be negative")
+	}
+
+	if c.Monitor.Interval < 0 {
+		errs.add("monitor.interval", "must not be negative")
+	}
+	if c.Monitor.Timeout < 0 {
+		errs.add("monitor.timeout", "must not be negative")
+	}
+	if c.Monitor.WarnBeforeDays < 0 {
+		errs.add("monitor.warn_before_days", "must not be negative")
+	}
+
+	if c.Server.Port == "" {
+		errs.add("server.port", "is required (env SERVER_PORT)")
+	} else if _, port, err := net.SplitHostPort(c.Server.Port); err != nil {
+		errs.add("server.port", "must look like host:port or :port, got %q", c.Server.Port)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		errs.add("server.port", "port must be numeric and at most 65535, got %q", port)
+	}
+
+	c.validateFeatures(errs)
+
+	if c.Logger.LogLevel != "" && !slices.Contains(logLevels, strings.ToLower(c.Logger.LogLevel)) {
+		errs.add("logger.log_level", "must be one of %s, got %q", strings.Join(logLevels, ", "), c.Logger.LogLevel)
+	}
+}
+
+func (c StorageConfig) validate(errs *ConfigErrors) {
+	if !slices.Contains(StorageBackends, c.BackendName()) {
+		errs.add("certs.storage.backend", "must be one of %s, got %q", strings.Join(StorageBackends, ", "), c.Backend)
+		return
+	}
+	if c.BackendName() == StorageS3 {
+		c.S3.validate(errs)
+		return
+	}
+	if c.BackendName() != StorageVault {
+		return
+	}
+	if u, err := url.Parse(c.Vault.Address); err != nil || u.Scheme == "" || u.Host == "" {
+		errs.add("certs.storage.vault.address", "must be a URL like https://vault:8200 (env VAULT_ADDR)")
+	}
+	switch {
+	case c.Vault.RoleID != "" && c.Vault.SecretID == "":
+		errs.add("certs.storage.vault.secret_id", "is required with role_id (env VAULT_SECRET_ID)")
+	case c.Vault.RoleID == "" && c.Vault.Token == "":
+		errs.add("certs.storage.vault.token", "token or role_id and secret_id are required (env VAULT_TOKEN)")
+	}
+}
+
+func (c CertEncryptionConfig) validate(errs *ConfigErrors, backend string) {
+	if c.Key == "" {
+		switch {
+		case backend == StoragePostgres:
+			errs.add("certs.encryption.key", "is required with the postgres storage backend (env CERT_ENCRYPTION_KEY)")
+		case c.Certs || c.LiveDir != "":
+			errs.add("certs.encryption.key", "is required with certs and live_dir (env CERT_ENCRYPTION_KEY)")
+		}
+		return
+	}
+	if len(c.Key) < 16 {
+		errs.add("certs.encryption.key", "must be at least 16 characters (env CERT_ENCRYPTION_KEY)")
+	}
+	// vault and s3 have their own encryption at rest, their files are read by consumers directly
+	if backend != StorageFile && backend != StoragePostgres {
+		errs.add("certs.encryption.key", "only applies to the file and postgres storage backends, got %q", backend)
+	}
+	if c.LiveDir != "" && backend != StorageFile {
+		errs.add("certs.encryption.live_dir", "only applies to the file storage backend, got %q", backend)
+	}
+	// the files in storage_dir are encrypted, nginx and the reloaded containers can only read live_dir
+	if c.LiveDir == "" && backend == StorageFile {
+		errs.add("certs.encryption.live_dir", "is required with key and the file storage backend, deployment targets can't read the encrypted files (env CERT_LIVE_DIR)")
+	}
+}
+
+func (c CertFilesConfig) validate(errs *ConfigErrors, backend string) {
+	if c == (CertFilesConfig{}) {
+		return
+	}
+	// the other backends don't write files, their consumers read them with their own access rules
+	if backend != StorageFile {
+		errs.add("certs.files", "only applies to the file storage backend, got %q", backend)
+	}
+	for _, f := range []struct{ name, mode string }{{"cert_mode", c.CertMode}, {"key_mode", c.KeyMode}} {
+		if f.mode == "" {
+			continue
+		}
+		if m, err := strconv.ParseUint(f.mode, 8, 32); err != nil || m > 0777 {
+			errs.add("certs.files."+f.name, "must be an octal mode like 0640, got %q", f.mode)
+		}
+	}
+	if _, key := c.Modes(); key&0007 != 0 {
+		errs.add("certs.files.key_mode", "must not grant access to other users, give the reader's group access with gid and 0640")
+	}
+	if c.UID < 0 {
+		errs.add("certs.files.uid", "must not be negative, got %d", c.UID)
+	}
+	if c.GID < 0 {
+		errs.add("certs.files.gid", "must not be negative, got %d", c.GID)
+	}
+}
+
+func (c CertArchiveConfig) validate(errs *ConfigErrors, backend string) {
+	if c.Keep < 0 {
+		errs.add("certs.archive.keep", "must not be negative, got %d", c.Keep)
+	}
+	if c.MaxAge < 0 {
+		errs.add("certs.archive.max_age", "must not be negative")
+	}
+	if c.MaxAge > 0 && c.Keep == 0 {
+		errs.add("certs.archive.max_age", "needs certs.archive.keep, nothing is archived without it")
+	}
+	if c.Keep > 0 && backend != StorageFile {
+		errs.add("certs.archive.keep", "only applies to the file storage backend, got %q", backend)
+	}
+}
+
+func (c AWSPublishConfig) validate(errs *ConfigErrors) {
+	if !c.Enabled() {
+		return
+	}
+	if (c.AccessKey == "") != (c.SecretKey == "") {
+		errs.add("certs.publish.aws.access_key", "access_key and secret_key are set together, or neither for the default credential chain")
+	}
+	if !awsSecretPrefix.MatchString(c.SecretsManager.NamePrefix) {
+		errs.add("certs.publish.aws.secrets_manager.name_prefix", "may only contain letters, digits and /_+=.@-, got %q", c.SecretsManager.NamePrefix)
+	}
+}
+
+func (c AzureKeyVaultConfig) validate(errs *ConfigErrors) {
+	if c.VaultURL == "" {
+		return
+	}
+	if u, err := url.Parse(c.VaultURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		errs.add("certs.publish.azure_key_vault.vault_url", "must be a URL like https://my-vault.vault.azure.net")
+	}
+	if c.ClientSecret != "" && (c.TenantID == "" || c.ClientID == "") {
+		errs.add("certs.publish.azure_key_vault.client_secret", "tenant_id and client_id are required with client_secret")
+	}
+	if c.AuthorityHost != "" {
+		if u, err := url.Parse(c.AuthorityHost); err != nil || u.Scheme == "" || u.Host == "" {
+			errs.add("certs.publish.azure_key_vault.authority_host", "must be a URL like https://login.microsoftonline.com")
+		}
+	}
+	if !azureNamePrefix.MatchString(c.NamePrefix) {
+		errs.add("certs.publish.azure_key_vault.name_prefix", "may only contain letters, digits and dashes, got %q", c.NamePrefix)
+	}
+}
+
+func (c S3Config) validate(errs *ConfigErrors) {
+	if c.Bucket == "" {
+		errs.add("certs.storage.s3.bucket", "is required (env S3_BUCKET)")
+	}
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs.add("certs.storage.s3.endpoint", "must be a URL like https://minio:9000")
+		}
+	}
+	if (c.AccessKey == "") != (c.SecretKey == "") {
+		errs.add("certs.storage.s3.secret_key", "access_key and secret_key must be set together")
+	}
+	if c.SSE != "" && !slices.Contains(S3Encryptions, c.SSE) {
+		errs.add("certs.storage.s3.sse", "must be one of %s, got %q", strings.Join(S3Encryptions, ", "), c.SSE)
+	}
+	if c.KMSKeyID != "" && c.SSE != "aws:kms" {
+		errs.add("certs.storage.s3.kms_key_id", "is only used with sse: aws:kms")
+	}
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	maxDomainLength = 253
+	maxLabelLength  = 63
+)
+
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(true),
+	idna.BidiRule(),
+)
+
+// NormalizeDomainName lowercases a domain, converts Unicode labels to punycode and checks the
+// DNS syntax rules, a leading "*." wildcard label is allowed since certificates are issued over DNS-01
+func NormalizeDomainName(name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "", errors.New("is required")
+	}
+
+	wildcard := false
+	if rest, ok := strings.CutPrefix(name, "*."); ok {
+		wildcard, name = true, rest
+	}
+	if strings.Contains(name, "*") {
+		return "", errors.New("wildcard is only allowed as the whole leftmost label, e.g. *.example.com")
+	}
+
+	ascii, err := idnaProfile.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain name: %w", err)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", errors.New("must have at least two labels, e.g. example.com")
+	}
+	for _, label := range labels {
+		if err := checkLabel(label); err != nil {
+			return "", err
+		}
+	}
+	if isNumeric(labels[len(labels)-1]) {
+		return "", errors.New("top-level domain can't be all numeric")
+	}
+
+	if wildcard {
+		ascii = "*." + ascii
+	}
+	if len(ascii) > maxDomainLength {
+		return "", fmt.Errorf("is %d characters long, at most %d are allowed", len(ascii), maxDomainLength)
+	}
+	return ascii, nil
+}
+
+// UnicodeDomainName returns the Unicode form of a normalized domain, e.g. münchen.de for
+// xn--mnchen-3ya.de, ASCII names are returned as they are
+func UnicodeDomainName(ascii string) string {
+	wildcard, name := "", ascii
+	if rest, ok := strings.CutPrefix(ascii, "*."); ok {
+		wildcard, name = "*.", rest
+	}
+	unicode, err := idnaProfile.ToUnicode(name)
+	if err != nil {
+		return ascii
+	}
+	return wildcard + unicode
+}
+
+// PunycodeTerm converts the Unicode labels of a search term to punycode, ok is false when the term
+// has none or can't be converted
+func PunycodeTerm(term string) (string, bool) {
+	term = strings.ToLower(term)
+	ascii, err := idna.Punycode.ToASCII(term)
+	if err != nil || ascii == term {
+		return "", false
+	}
+	return ascii, true
+}
+
+// checkLabel applies the letters, digits and hyphen rule to one label
+func checkLabel(label string) error {
+	if label == "" {
+		return errors.New("has an empty label")
+	}
+	if len(label) > maxLabelLength {
+		return fmt.Errorf("label %q is %d characters long, at most %d are allowed", label, len(label), maxLabelLength)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("label %q can't start or end with a hyphen", label)
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Errorf("label %q has invalid character %q", label, r)
+		}
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+const (
+	// sealedVersion leads data sealed under a key derived with HKDF-SHA256, data sealed before starts
+	// with its nonce
+	sealedVersion = 2
+	saltSize      = 16
+	// keyInfo binds the derived keys to sealing, the secret may be used for other things too
+	keyInfo = "hephaestus sealed data"
+)
+
+// ErrNoEncryptionKey is returned when data has to be encrypted but encryption.key is not configured
+var ErrNoEncryptionKey = errors.New("encryption.key is not set (env ENCRYPTION_KEY)")
+
+// EncryptSecret seals data with AES-256-GCM under a key derived from the secret with HKDF-SHA256 and a
+// random salt. additionalData is authenticated but not stored, e.g. the name of a file so it can't be
+// swapped for another. The result is the version byte, the salt, the nonce and the ciphertext
+func EncryptSecret(secret string, plaintext, additionalData []byte) ([]byte, error) {
+	if secret == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	gcm, err := derivedGCM(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := append(append([]byte{sealedVersion}, salt...), nonce...)
+	return gcm.Seal(sealed, nonce, plaintext, additionalData), nil
+}
+
+// DecryptSecret opens data sealed by EncryptSecret with the same additional data, and data sealed before
+// the key derivation, which has no additional data
+func DecryptSecret(secret string, sealed, additionalData []byte) ([]byte, error) {
+	if secret == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	if len(sealed) == 0 || sealed[0] != sealedVersion {
+		return DecryptLegacySecret(secret, sealed)
+	}
+	plaintext, err := openDerived(secret, sealed[1:], additionalData)
+	if err != nil {
+		// one legacy nonce in 256 starts with the version byte
+		if legacy, legacyErr := DecryptLegacySecret(secret, sealed); legacyErr == nil {
+			return legacy, nil
+		}
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// DecryptLegacySecret opens data sealed under the SHA-256 of the secret, before the key derivation
+func DecryptLegacySecret(secret string, sealed []byte) ([]byte, error) {
+	if secret == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	key := sha256.Sum256([]byte(secret))
+	gcm, err := newGCM(key[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func openDerived(secret string, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < saltSize {
+		return nil, errors.New("encrypted data is too short")
+	}
+	gcm, err := derivedGCM(secret, sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[saltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// derivedGCM returns the cipher of the key derived from the secret and the salt
+func derivedGCM(secret string, salt []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, []byte(secret), salt, keyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	return newGCM(key)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestDecryptSecretChecksTheAdditionalData(t *testing.T) {
+	sealed, err := EncryptSecret("0123456789abcdef", []byte("secret"), []byte("example.com/privkey.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := DecryptSecret("0123456789abcdef", sealed, []byte("example.com/privkey.pem")); err != nil || string(plaintext) != "secret" {
+		t.Errorf("got %q, %v", plaintext, err)
+	}
+	if _, err := DecryptSecret("0123456789abcdef", sealed, []byte("example.org/privkey.pem")); err == nil {
+		t.Error("opened with other additional data")
+	}
+}
+
+func TestDecryptSecretOpensLegacyData(t *testing.T) {
+	key := sha256.Sum256([]byte("0123456789abcdef"))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a legacy nonce starting with the version byte isn't taken for the current format
+	for _, first := range []byte{0, sealedVersion} {
+		nonce := make([]byte, gcm.NonceSize())
+		nonce[0] = first
+		legacy := gcm.Seal(nonce, nonce, []byte("secret"), nil)
+		if plaintext, err := DecryptSecret("0123456789abcdef", legacy, nil); err != nil || string(plaintext) != "secret" {
+			t.Errorf("nonce starting with %d: got %q, %v", first, plaintext, err)
+		}
+	}
+}
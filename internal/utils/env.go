@@ -0,0 +1,15 @@
+package utils
+
+import "os"
+
+// EnvPrefix namespaces the environment variables of the service, so they don't collide with other
+// services sharing the environment. The unprefixed names still work as a fallback
+const EnvPrefix = "HEPHAESTUS_"
+
+// Getenv reads HEPHAESTUS_<name>, falling back to <name>
+func Getenv(name string) string {
+	if value, ok := os.LookupEnv(EnvPrefix + name); ok {
+		return value
+	}
+	return os.Getenv(name)
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Feature flags gate subsystems that are rolled out per installation, all are off by default
+const (
+	FeatureWebhooks  = "webhooks"
+	FeatureDiscovery = "discovery"
+	FeatureOperator  = "operator"
+)
+
+var knownFeatures = []string{FeatureWebhooks, FeatureDiscovery, FeatureOperator}
+
+// FeatureEnabled reports whether the feature is switched on in the features section
+func (c *Config) FeatureEnabled(name string) bool {
+	return c.Features[strings.ToLower(name)]
+}
+
+// FeatureFlags returns the state of every known feature
+func (c *Config) FeatureFlags() map[string]bool {
+	flags := make(map[string]bool, len(knownFeatures))
+	for _, name := range knownFeatures {
+		flags[name] = c.FeatureEnabled(name)
+	}
+	return flags
+}
+
+// validateFeatures rejects unknown names, a typo would otherwise leave a feature silently off
+func (c *Config) validateFeatures(errs *ConfigErrors) {
+	for _, name := range slices.Sorted(maps.Keys(c.Features)) {
+		if !slices.Contains(knownFeatures, name) {
+			errs.add("features."+name, "unknown feature, expected one of %s", strings.Join(knownFeatures, ", "))
+		}
+	}
+}
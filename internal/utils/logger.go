@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync/atomic"
 )
 
 type LogLevel int
@@ -43,15 +44,21 @@ var levelColors = map[LogLevel]string{
 const resetColor = "\033[0m"
 
 type Logger struct {
-	level LogLevel
+	level atomic.Int32 // LogLevel, changed on config reload
 	*log.Logger
 }
 
 func NewLogger(level string) *Logger {
-	return &Logger{
-		level:  parseLevel(level),
+	l := &Logger{
 		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
 	}
+	l.SetLevel(level)
+	return l
+}
+
+// SetLevel changes the level of a running logger, unknown levels fall back to info
+func (l *Logger) SetLevel(level string) {
+	l.level.Store(int32(parseLevel(level)))
 }
 
 func parseLevel(s string) LogLevel {
@@ -76,7 +83,7 @@ func parseLevel(s string) LogLevel {
 }
 
 func (l *Logger) canLog(level LogLevel) bool {
-	return level >= l.level
+	return int32(level) >= l.level.Load()
 }
 
 func formatArgs(v ...interface{}) (string, []interface{}) {
@@ -0,0 +1,382 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"unicode/utf16"
+)
+
+// pkcs12Iterations is the iteration count of the key derivations, the one of OpenSSL
+const pkcs12Iterations = 2048
+
+var (
+	oidData                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidShroudedKeyBag      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBEWithSHA3KeyTDES  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBES2               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256      = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC           = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidSHA1                = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256              = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	asn1NULL               = asn1.RawValue{Tag: asn1.TagNull}
+	errEmptyPKCS12Password = errors.New("a PKCS#12 file needs a password")
+)
+
+type pfx struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional,omitempty"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Data      []byte
+}
+
+type macData struct {
+	Mac        digestInfo
+	Salt       []byte
+	Iterations int
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	Kdf              pkix.AlgorithmIdentifier
+	EncryptionScheme pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int
+	Prf        pkix.AlgorithmIdentifier
+}
+
+// EncodePKCS12 bundles the key, its certificate and the chain into a password protected PKCS#12 file, the
+// key and the certificate carry name as friendly name. The key and the certificates are encrypted with
+// AES-256-CBC and PBKDF2 and the file is authenticated with HMAC-SHA256, like OpenSSL 3 does. legacy uses
+// 3DES and HMAC-SHA1 instead, for Windows Server 2016 and Java before 8u301
+func EncodePKCS12(key crypto.PrivateKey, cert *x509.Certificate, chain []*x509.Certificate, name, password string, legacy bool) ([]byte, error) {
+	if password == "" {
+		return nil, errEmptyPKCS12Password
+	}
+	keyID := sha1.Sum(cert.Raw)
+	attributes, err := bagAttributes(name, keyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var certBags []safeBag
+	for n, c := range append([]*x509.Certificate{cert}, chain...) {
+		bag, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: c.Raw})
+		if err != nil {
+			return nil, err
+		}
+		sb := safeBag{ID: oidCertBag, Value: asn1.RawValue{FullBytes: explicit0(bag)}}
+		if n == 0 {
+			sb.Attributes = attributes
+		}
+		certBags = append(certBags, sb)
+	}
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, err
+	}
+	certAlgorithm, encryptedCerts, err := pbEncrypt(certContents, password, legacy)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt certificates: %w", err)
+	}
+	certsInfo, err := asn1.Marshal(encryptedData{EncryptedContentInfo: encryptedContentInfo{
+		ContentType:                oidData,
+		ContentEncryptionAlgorithm: certAlgorithm,
+		EncryptedContent:           encryptedCerts,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encode key: %w", err)
+	}
+	keyAlgorithm, encryptedKey, err := pbEncrypt(pkcs8, password, legacy)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+	shrouded, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: keyAlgorithm, Data: encryptedKey})
+	if err != nil {
+		return nil, err
+	}
+	keyContents, err := asn1.Marshal([]safeBag{{
+		ID:         oidShroudedKeyBag,
+		Value:      asn1.RawValue{FullBytes: explicit0(shrouded)},
+		Attributes: attributes,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := asn1.Marshal(keyContents)
+	if err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal([]contentInfo{
+		{ContentType: oidEncryptedData, Content: asn1.RawValue{FullBytes: explicit0(certsInfo)}},
+		{ContentType: oidData, Content: asn1.RawValue{FullBytes: explicit0(keyData)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	authSafeData, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := pkcs12MAC(authSafe, password, legacy)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pfx{
+		Version:  3,
+		AuthSafe: contentInfo{ContentType: oidData, Content: asn1.RawValue{FullBytes: explicit0(authSafeData)}},
+		MacData:  mac,
+	})
+}
+
+// bagAttributes are the friendly name and the local key id, which pairs the key with its certificate
+func bagAttributes(name string, keyID []byte) ([]pkcs12Attribute, error) {
+	id, err := asn1.Marshal(keyID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pkcs12Attribute{{ID: oidLocalKeyID, Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: id}}}
+	if name != "" {
+		friendly, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Class: asn1.ClassUniversal, Bytes: bmpString(name, false)})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append([]pkcs12Attribute{{ID: oidFriendlyName, Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: friendly}}}, attributes...)
+	}
+	return attributes, nil
+}
+
+// pbEncrypt encrypts data with a key derived from the password, returning the algorithm and its parameters
+func pbEncrypt(data []byte, password string, legacy bool) (pkix.AlgorithmIdentifier, []byte, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	if legacy {
+		params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: pkcs12Iterations})
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, err
+		}
+		pass := bmpString(password, true)
+		key := pkcs12KDF(sha1.New, 1, pass, salt, pkcs12Iterations, 24)
+		iv := pkcs12KDF(sha1.New, 2, pass, salt, pkcs12Iterations, des.BlockSize)
+		block, err := des.NewTripleDESCipher(key)
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, err
+		}
+		return pkix.AlgorithmIdentifier{Algorithm: oidPBEWithSHA3KeyTDES, Parameters: asn1.RawValue{FullBytes: params}},
+			cbcEncrypt(block, iv, data), nil
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, pkcs12Iterations, 32)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:       salt,
+		Iterations: pkcs12Iterations,
+		KeyLength:  32,
+		Prf:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1NULL},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		Kdf:              pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		cbcEncrypt(block, iv, data), nil
+}
+
+// pkcs12MAC authenticates the content with an HMAC keyed by the PKCS#12 key derivation
+func pkcs12MAC(content []byte, password string, legacy bool) (macData, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return macData{}, err
+	}
+	h, oid := sha256.New, oidSHA256
+	if legacy {
+		h, oid = sha1.New, oidSHA1
+	}
+	key := pkcs12KDF(h, 3, bmpString(password, true), salt, pkcs12Iterations, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(content)
+	return macData{
+		Mac:        digestInfo{Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1NULL}, Digest: mac.Sum(nil)},
+		Salt:       salt,
+		Iterations: pkcs12Iterations,
+	}, nil
+}
+
+// pkcs12KDF derives size bytes of key material, id 1 for keys, 2 for IVs and 3 for MAC keys (RFC 7292, B.2)
+func pkcs12KDF(h func() hash.Hash, id byte, password, salt []byte, iterations, size int) []byte {
+	v := h().BlockSize()
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	i := append(fill(salt), fill(password)...)
+
+	var out []byte
+	one := big.NewInt(1)
+	for len(out) < size {
+		hh := h()
+		hh.Write(d)
+		hh.Write(i)
+		a := hh.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			hh = h()
+			hh.Write(a)
+			a = hh.Sum(nil)
+		}
+		out = append(out, a...)
+
+		// every v-byte block of I becomes I_j + B + 1 mod 2^(8v), B being A repeated to v bytes
+		b := new(big.Int).SetBytes(fill(a)[:v])
+		b.Add(b, one)
+		for j := 0; j < len(i); j += v {
+			sum := new(big.Int).SetBytes(i[j : j+v])
+			sum.Add(sum, b)
+			sb := sum.Bytes()
+			if len(sb) > v {
+				sb = sb[len(sb)-v:]
+			}
+			block := i[j : j+v]
+			clear(block)
+			copy(block[v-len(sb):], sb)
+		}
+	}
+	return out[:size]
+}
+
+// bmpString encodes s as UTF-16 big endian, passwords end with two zero bytes
+func bmpString(s string, terminate bool) []byte {
+	var out []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(r>>8), byte(r))
+	}
+	if terminate {
+		out = append(out, 0, 0)
+	}
+	return out
+}
+
+// cbcEncrypt pads data with PKCS#7 and encrypts it
+func cbcEncrypt(block cipher.Block, iv, data []byte) []byte {
+	pad := block.BlockSize() - len(data)%block.BlockSize()
+	padded := append(append([]byte{}, data...), make([]byte, pad)...)
+	for i := len(data); i < len(padded); i++ {
+		padded[i] = byte(pad)
+	}
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+	return out
+}
+
+// explicit0 wraps DER in the [0] EXPLICIT tag of ContentInfo and SafeBag values
+func explicit0(der []byte) []byte {
+	out, _ := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der})
+	return out
+}
@@ -0,0 +1,324 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// the encoder is checked against decoders it doesn't share code with: golang.org/x/crypto/pkcs12 for the
+// legacy format, a PBES2 decoder on the standard library for the default one, and openssl when installed
+
+const testPKCS12Password = "correct horse"
+
+type pkcs12Case struct {
+	name  string
+	key   crypto.Signer
+	cert  *x509.Certificate
+	chain []*x509.Certificate
+}
+
+func pkcs12Cases(t *testing.T) []pkcs12Case {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := testCertificate(t, "Test Root", caKey, nil, caKey)
+
+	var cases []pkcs12Case
+	for _, k := range []struct {
+		name string
+		key  crypto.Signer
+	}{{"rsa", rsaKey}, {"ec", ecKey}} {
+		leaf := testCertificate(t, "example.com", k.key, ca, caKey)
+		cases = append(cases,
+			pkcs12Case{name: k.name, key: k.key, cert: leaf},
+			pkcs12Case{name: k.name + " with chain", key: k.key, cert: leaf, chain: []*x509.Certificate{ca}},
+		)
+	}
+	return cases
+}
+
+// testCertificate issues a certificate for key, self-signed without a parent
+func testCertificate(t *testing.T, name string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestEncodePKCS12Legacy(t *testing.T) {
+	for _, tc := range pkcs12Cases(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := EncodePKCS12(tc.key, tc.cert, tc.chain, "example.com", testPKCS12Password, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pkcs12.ToPEM(data, "wrong"); err == nil {
+				t.Error("decoded with a wrong password")
+			}
+			blocks, err := pkcs12.ToPEM(data, testPKCS12Password)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var certs [][]byte
+			var key any
+			for _, b := range blocks {
+				switch b.Type {
+				case "CERTIFICATE":
+					certs = append(certs, b.Bytes)
+				case "PRIVATE KEY":
+					key, err = x509.ParsePKCS1PrivateKey(b.Bytes)
+					if err != nil {
+						key, err = x509.ParseECPrivateKey(b.Bytes)
+					}
+					if err != nil {
+						t.Fatalf("parse key: %v", err)
+					}
+				}
+				// the key and the leaf are paired by name and key id, the chain carries none
+				leaf := b.Type == "CERTIFICATE" && len(certs) == 1
+				if (leaf || b.Type == "PRIVATE KEY") && b.Headers["friendlyName"] != "example.com" {
+					t.Errorf("%s has friendly name %q", b.Type, b.Headers["friendlyName"])
+				}
+			}
+			checkPKCS12Contents(t, tc, key, certs)
+		})
+	}
+}
+
+func TestEncodePKCS12(t *testing.T) {
+	for _, tc := range pkcs12Cases(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := EncodePKCS12(tc.key, tc.cert, tc.chain, "example.com", testPKCS12Password, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := decodePBES2PKCS12(data, "wrong"); err == nil {
+				t.Error("decoded with a wrong password")
+			}
+			key, certs, err := decodePBES2PKCS12(data, testPKCS12Password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPKCS12Contents(t, tc, key, certs)
+		})
+	}
+}
+
+func TestEncodePKCS12OpenSSL(t *testing.T) {
+	if _, err := exec.LookPath("openssl"); err != nil {
+		t.Skip("openssl is not installed")
+	}
+	for _, tc := range pkcs12Cases(t) {
+		for _, legacy := range []bool{false, true} {
+			data, err := EncodePKCS12(tc.key, tc.cert, tc.chain, "example.com", testPKCS12Password, legacy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "bundle.p12")
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			out, err := exec.Command("openssl", "pkcs12", "-in", path, "-passin", "pass:"+testPKCS12Password, "-nodes").CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s, legacy %v: %v: %s", tc.name, legacy, err, out)
+			}
+			if n := strings.Count(string(out), "BEGIN CERTIFICATE"); n != 1+len(tc.chain) {
+				t.Errorf("%s, legacy %v: openssl read %d certificates", tc.name, legacy, n)
+			}
+			if !strings.Contains(string(out), "BEGIN PRIVATE KEY") {
+				t.Errorf("%s, legacy %v: openssl read no key", tc.name, legacy)
+			}
+		}
+	}
+}
+
+func TestEncodePKCS12NeedsAPassword(t *testing.T) {
+	tc := pkcs12Cases(t)[0]
+	if _, err := EncodePKCS12(tc.key, tc.cert, nil, "", "", false); err == nil {
+		t.Error("encoded without a password")
+	}
+}
+
+// checkPKCS12Contents compares the decoded key and certificates, the leaf first, with the encoded ones
+func checkPKCS12Contents(t *testing.T, tc pkcs12Case, key any, certs [][]byte) {
+	t.Helper()
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		t.Fatalf("decoded key %T isn't a private key", key)
+	}
+	if !signer.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(tc.key.Public()) {
+		t.Error("decoded key differs from the encoded one")
+	}
+	want := [][]byte{tc.cert.Raw}
+	for _, c := range tc.chain {
+		want = append(want, c.Raw)
+	}
+	if len(certs) != len(want) {
+		t.Fatalf("decoded %d certificates, want %d", len(certs), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(certs[i], want[i]) {
+			t.Errorf("certificate %d differs from the encoded one", i)
+		}
+	}
+}
+
+// decodePBES2PKCS12 reads a file with certificates and a key encrypted by PBES2 with AES-256-CBC and a
+// SHA-256 MAC, the layout EncodePKCS12 writes by default
+func decodePBES2PKCS12(data []byte, password string) (any, [][]byte, error) {
+	var p pfx
+	if err := unmarshalAll(data, &p); err != nil {
+		return nil, nil, err
+	}
+	var authSafe []byte
+	if err := unmarshalAll(p.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, err
+	}
+	macKey := pkcs12KDF(sha256.New, 3, bmpString(password, true), p.MacData.Salt, p.MacData.Iterations, sha256.Size)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(authSafe)
+	if !hmac.Equal(mac.Sum(nil), p.MacData.Mac.Digest) {
+		return nil, nil, pkcs12.ErrIncorrectPassword
+	}
+
+	var infos []contentInfo
+	if err := unmarshalAll(authSafe, &infos); err != nil {
+		return nil, nil, err
+	}
+	var key any
+	var certs [][]byte
+	for _, info := range infos {
+		var contents []byte
+		switch {
+		case info.ContentType.Equal(oidEncryptedData):
+			var ed encryptedData
+			if err := unmarshalAll(info.Content.Bytes, &ed); err != nil {
+				return nil, nil, err
+			}
+			var err error
+			contents, err = pbes2Decrypt(ed.EncryptedContentInfo.ContentEncryptionAlgorithm, ed.EncryptedContentInfo.EncryptedContent, password)
+			if err != nil {
+				return nil, nil, err
+			}
+		case info.ContentType.Equal(oidData):
+			if err := unmarshalAll(info.Content.Bytes, &contents); err != nil {
+				return nil, nil, err
+			}
+		}
+		var bags []safeBag
+		if err := unmarshalAll(contents, &bags); err != nil {
+			return nil, nil, err
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if err := unmarshalAll(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, err
+				}
+				certs = append(certs, cb.Data)
+			case bag.ID.Equal(oidShroudedKeyBag):
+				var epki encryptedPrivateKeyInfo
+				if err := unmarshalAll(bag.Value.Bytes, &epki); err != nil {
+					return nil, nil, err
+				}
+				pkcs8, err := pbes2Decrypt(epki.Algorithm, epki.Data, password)
+				if err != nil {
+					return nil, nil, err
+				}
+				if key, err = x509.ParsePKCS8PrivateKey(pkcs8); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	return key, certs, nil
+}
+
+func pbes2Decrypt(algorithm pkix.AlgorithmIdentifier, data []byte, password string) ([]byte, error) {
+	var params pbes2Params
+	if err := unmarshalAll(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	var kdf pbkdf2Params
+	if err := unmarshalAll(params.Kdf.Parameters.FullBytes, &kdf); err != nil {
+		return nil, err
+	}
+	var iv []byte
+	if err := unmarshalAll(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, kdf.Salt, kdf.Iterations, kdf.KeyLength)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, pkcs12.ErrIncorrectPassword
+	}
+	return out[:len(out)-pad], nil
+}
+
+func unmarshalAll(der []byte, out any) error {
+	rest, err := asn1.Unmarshal(der, out)
+	if err == nil && len(rest) > 0 {
+		err = asn1.SyntaxError{Msg: "trailing data"}
+	}
+	return err
+}
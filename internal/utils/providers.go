@@ -0,0 +1,782 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNS provider types
+const (
+	ProviderCloudflare   = "cloudflare"
+	ProviderHetzner      = "hetzner"
+	ProviderDigitalOcean = "digitalocean"
+	ProviderRoute53      = "route53"
+	ProviderDNSimple     = "dnsimple"
+	ProviderNamecheap    = "namecheap"
+	ProviderGandi        = "gandi"
+	ProviderOVH          = "ovh"
+	ProviderPorkbun      = "porkbun"
+	ProviderPowerDNS     = "pdns"
+	ProviderRFC2136      = "rfc2136"
+	ProviderLinode       = "linode"
+	ProviderVultr        = "vultr"
+	ProviderDeSEC        = "desec"
+	ProviderGoDaddy      = "godaddy"
+	ProviderScaleway     = "scaleway"
+	ProviderNS1          = "ns1"
+	ProviderInfoblox     = "infoblox"
+	ProviderOracleCloud  = "oraclecloud"
+	ProviderAliDNS       = "alidns"
+	ProviderIONOS        = "ionos"
+	ProviderAcmeDNS      = "acmedns"
+	ProviderHTTPReq      = "httpreq"
+	ProviderWebhook      = "webhook" // alias of httpreq
+	ProviderNetlify      = "netlify"
+	ProviderSelfSigned   = "selfsigned"
+)
+
+var providerTypes = []string{ProviderCloudflare, ProviderHetzner, ProviderDigitalOcean, ProviderRoute53, ProviderDNSimple, ProviderNamecheap, ProviderGandi, ProviderOVH, ProviderPorkbun, ProviderPowerDNS, ProviderRFC2136, ProviderLinode, ProviderVultr, ProviderDeSEC, ProviderGoDaddy, ProviderScaleway, ProviderNS1, ProviderInfoblox, ProviderOracleCloud, ProviderAliDNS, ProviderIONOS, ProviderAcmeDNS, ProviderHTTPReq, ProviderNetlify, ProviderSelfSigned}
+
+// ProviderConfig is one DNS provider account, Name is what domains reference in dns_provider.
+// Only the block matching Type is used
+type ProviderConfig struct {
+	Name string `yaml:"name" json:"name" toml:"name"`
+	Type string `yaml:"type" json:"type" toml:"type"` // one of the provider types, the name when empty
+
+	Cloudflare   *CloudflareConfig   `yaml:"cloudflare" json:"cloudflare" toml:"cloudflare"`
+	Hetzner      *HetznerConfig      `yaml:"hetzner" json:"hetzner" toml:"hetzner"`
+	DigitalOcean *DigitalOceanConfig `yaml:"digitalocean" json:"digitalocean" toml:"digitalocean"`
+	Route53      *Route53Config      `yaml:"route53" json:"route53" toml:"route53"`
+	DNSimple     *DNSimpleConfig     `yaml:"dnsimple" json:"dnsimple" toml:"dnsimple"`
+	Namecheap    *NamecheapConfig    `yaml:"namecheap" json:"namecheap" toml:"namecheap"`
+	Gandi        *GandiConfig        `yaml:"gandi" json:"gandi" toml:"gandi"`
+	OVH          *OVHConfig          `yaml:"ovh" json:"ovh" toml:"ovh"`
+	Porkbun      *PorkbunConfig      `yaml:"porkbun" json:"porkbun" toml:"porkbun"`
+	PowerDNS     *PowerDNSConfig     `yaml:"pdns" json:"pdns" toml:"pdns"`
+	RFC2136      *RFC2136Config      `yaml:"rfc2136" json:"rfc2136" toml:"rfc2136"`
+	Linode       *LinodeConfig       `yaml:"linode" json:"linode" toml:"linode"`
+	Vultr        *VultrConfig        `yaml:"vultr" json:"vultr" toml:"vultr"`
+	DeSEC        *DeSECConfig        `yaml:"desec" json:"desec" toml:"desec"`
+	GoDaddy      *GoDaddyConfig      `yaml:"godaddy" json:"godaddy" toml:"godaddy"`
+	Scaleway     *ScalewayConfig     `yaml:"scaleway" json:"scaleway" toml:"scaleway"`
+	NS1          *NS1Config          `yaml:"ns1" json:"ns1" toml:"ns1"`
+	Infoblox     *InfobloxConfig     `yaml:"infoblox" json:"infoblox" toml:"infoblox"`
+	OracleCloud  *OracleCloudConfig  `yaml:"oraclecloud" json:"oraclecloud" toml:"oraclecloud"`
+	AliDNS       *AliDNSConfig       `yaml:"alidns" json:"alidns" toml:"alidns"`
+	IONOS        *IONOSConfig        `yaml:"ionos" json:"ionos" toml:"ionos"`
+	AcmeDNS      *AcmeDNSConfig      `yaml:"acmedns" json:"acmedns" toml:"acmedns"`
+	HTTPReq      *HTTPReqConfig      `yaml:"httpreq" json:"httpreq" toml:"httpreq"`
+	Netlify      *NetlifyConfig      `yaml:"netlify" json:"netlify" toml:"netlify"`
+	SelfSigned   *SelfSignedConfig   `yaml:"selfsigned" json:"selfsigned" toml:"selfsigned"`
+
+	// override certs.propagation_timeout and certs.dns_poll_interval, e.g. for a provider with slow updates
+	PropagationTimeout time.Duration `yaml:"propagation_timeout" json:"propagation_timeout" toml:"propagation_timeout"`
+	PollingInterval    time.Duration `yaml:"polling_interval" json:"polling_interval" toml:"polling_interval"`
+	// the record must also show up on these recursive resolvers (host or host:port), overrides certs.resolvers
+	Resolvers []string `yaml:"resolvers" json:"resolvers" toml:"resolvers"`
+	// patterns like *.ns.cloudflare.com of the nameservers hosting the provider's zones, the known ones of the type when empty
+	Nameservers []string `yaml:"nameservers" json:"nameservers" toml:"nameservers"`
+	// only the resolvers are checked, e.g. when the authoritative nameservers can't be reached from here
+	SkipAuthoritativeCheck bool `yaml:"skip_authoritative_check" json:"skip_authoritative_check" toml:"skip_authoritative_check"`
+	// _acme-challenge CNAMEs pointing into these zones get their record from another provider
+	Delegations []DelegationConfig `yaml:"delegations" json:"delegations" toml:"delegations"`
+
+	Disabled string `yaml:"-" json:"-" toml:"-"` // why the provider is skipped, set on load when its credentials are missing
+}
+
+// DelegationConfig names the provider hosting Zone, the zone _acme-challenge records are CNAMEd to
+type DelegationConfig struct {
+	Zone     string `yaml:"zone" json:"zone" toml:"zone"`
+	Provider string `yaml:"provider" json:"provider" toml:"provider"`
+}
+
+type CloudflareConfig struct {
+	Token     string `yaml:"token" json:"token" toml:"token" secret:"true"`                // API token with Zone:DNS:Edit
+	ZoneToken string `yaml:"zone_token" json:"zone_token" toml:"zone_token" secret:"true"` // optional separate Zone:Read token
+}
+
+type HetznerConfig struct {
+	APIKey   string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`       // DNS console (dns.hetzner.com) key
+	APIToken string `yaml:"api_token" json:"api_token" toml:"api_token" secret:"true"` // Hetzner Cloud API token, used instead of api_key when set
+}
+
+type DigitalOceanConfig struct {
+	Token string `yaml:"token" json:"token" toml:"token" secret:"true"`
+}
+
+// Route53Config falls back to the default AWS credential chain (instance role, AWS_* env) when the keys are empty
+type Route53Config struct {
+	AccessKey    string `yaml:"access_key" json:"access_key" toml:"access_key" secret:"true"`
+	SecretKey    string `yaml:"secret_key" json:"secret_key" toml:"secret_key" secret:"true"`
+	Region       string `yaml:"region" json:"region" toml:"region"`
+	HostedZoneID string `yaml:"hosted_zone_id" json:"hosted_zone_id" toml:"hosted_zone_id"` // optional, skips the zone lookup
+}
+
+// DNSimpleConfig takes an account token, a user token needs the account_id
+type DNSimpleConfig struct {
+	Token     string `yaml:"token" json:"token" toml:"token" secret:"true"`
+	AccountID string `yaml:"account_id" json:"account_id" toml:"account_id"` // optional, read from the account token
+	BaseURL   string `yaml:"base_url" json:"base_url" toml:"base_url"`       // e.g. https://api.sandbox.dnsimple.com/v2
+}
+
+// NamecheapConfig needs API access enabled for the account and client_ip whitelisted,
+// the public IP is looked up when client_ip is empty
+type NamecheapConfig struct {
+	APIUser  string `yaml:"api_user" json:"api_user" toml:"api_user"`
+	APIKey   string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`
+	ClientIP string `yaml:"client_ip" json:"client_ip" toml:"client_ip"`
+	Sandbox  bool   `yaml:"sandbox" json:"sandbox" toml:"sandbox"` // api.sandbox.namecheap.com
+}
+
+// GandiConfig is a LiveDNS account, the personal access token needs the "Manage domain name technical configurations" permission
+type GandiConfig struct {
+	PersonalAccessToken string `yaml:"personal_access_token" json:"personal_access_token" toml:"personal_access_token" secret:"true"`
+	APIKey              string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"` // deprecated by Gandi, used when no token is set
+}
+
+// OVHConfig is an application created at <endpoint>/createToken with GET/POST/DELETE on /domain/zone/*
+type OVHConfig struct {
+	Endpoint          string `yaml:"endpoint" json:"endpoint" toml:"endpoint"` // ovh-eu (default), ovh-ca, ovh-us, kimsufi-*, soyoustart-* or a URL
+	ApplicationKey    string `yaml:"application_key" json:"application_key" toml:"application_key"`
+	ApplicationSecret string `yaml:"application_secret" json:"application_secret" toml:"application_secret" secret:"true"`
+	ConsumerKey       string `yaml:"consumer_key" json:"consumer_key" toml:"consumer_key" secret:"true"`
+}
+
+// PorkbunConfig needs API access enabled on every domain in the Porkbun dashboard
+type PorkbunConfig struct {
+	APIKey    string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`
+	SecretKey string `yaml:"secret_key" json:"secret_key" toml:"secret_key" secret:"true"`
+}
+
+// PowerDNSConfig talks to the built-in HTTP API of the authoritative server (api=yes, api-key in pdns.conf)
+type PowerDNSConfig struct {
+	APIURL     string `yaml:"api_url" json:"api_url" toml:"api_url"` // e.g. http://pdns.internal:8081
+	APIKey     string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`
+	ServerName string `yaml:"server_name" json:"server_name" toml:"server_name"` // localhost when empty
+	APIVersion int    `yaml:"api_version" json:"api_version" toml:"api_version"` // detected when 0
+}
+
+// RFC2136Config sends dynamic updates to a nameserver like BIND or Knot, updates are unsigned without a TSIG key
+type RFC2136Config struct {
+	Nameserver    string `yaml:"nameserver" json:"nameserver" toml:"nameserver"` // host or host:port of the primary, port 53 by default
+	TSIGKey       string `yaml:"tsig_key" json:"tsig_key" toml:"tsig_key"`       // key name as defined on the server
+	TSIGSecret    string `yaml:"tsig_secret" json:"tsig_secret" toml:"tsig_secret" secret:"true"`
+	TSIGAlgorithm string `yaml:"tsig_algorithm" json:"tsig_algorithm" toml:"tsig_algorithm"` // e.g. hmac-sha256, hmac-sha1 when empty
+	TSIGFile      string `yaml:"tsig_file" json:"tsig_file" toml:"tsig_file"`                // key file written by tsig-keygen, replaces the tsig_* values
+}
+
+// LinodeConfig takes a personal access token with Domains read/write
+type LinodeConfig struct {
+	Token string `yaml:"token" json:"token" toml:"token" secret:"true"`
+}
+
+// VultrConfig takes a personal API key, its access control must allow the Hephaestus host
+type VultrConfig struct {
+	APIKey string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`
+}
+
+// DeSECConfig takes an API token of the deSEC account
+type DeSECConfig struct {
+	Token string `yaml:"token" json:"token" toml:"token" secret:"true"`
+}
+
+// GoDaddyConfig takes a production key of developer.godaddy.com, the API is only open to larger accounts
+type GoDaddyConfig struct {
+	APIKey    string `yaml:"api_key" json:"api_key" toml:"api_key"`
+	APISecret string `yaml:"api_secret" json:"api_secret" toml:"api_secret" secret:"true"`
+}
+
+// ScalewayConfig takes the secret key of an API key with DomainsDNSFullAccess and the project of the zones
+type ScalewayConfig struct {
+	SecretKey string `yaml:"secret_key" json:"secret_key" toml:"secret_key" secret:"true"`
+	ProjectID string `yaml:"project_id" json:"project_id" toml:"project_id"`
+}
+
+// NS1Config takes an API key allowed to manage the records of the zones
+type NS1Config struct {
+	APIKey string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`
+}
+
+// InfobloxConfig reaches the WAPI of a NIOS grid master, the user needs write access to the TXT records of the zones
+type InfobloxConfig struct {
+	Host        string `yaml:"host" json:"host" toml:"host"`                         // grid master, https:// is added when no scheme is given
+	WAPIVersion string `yaml:"wapi_version" json:"wapi_version" toml:"wapi_version"` // 2.11 when empty
+	Username    string `yaml:"username" json:"username" toml:"username"`
+	Password    string `yaml:"password" json:"password" toml:"password" secret:"true"`
+	DNSView     string `yaml:"dns_view" json:"dns_view" toml:"dns_view"`       // the grid's default view when empty
+	SSLVerify   *bool  `yaml:"ssl_verify" json:"ssl_verify" toml:"ssl_verify"` // true when unset
+}
+
+// OracleCloudConfig holds the values of an OCI config file profile, the user needs to manage dns in the compartment
+type OracleCloudConfig struct {
+	TenancyOCID          string `yaml:"tenancy_ocid" json:"tenancy_ocid" toml:"tenancy_ocid"`
+	UserOCID             string `yaml:"user_ocid" json:"user_ocid" toml:"user_ocid"`
+	Fingerprint          string `yaml:"fingerprint" json:"fingerprint" toml:"fingerprint"`
+	PrivateKey           string `yaml:"private_key" json:"private_key" toml:"private_key" secret:"true"` // PEM, or
+	PrivateKeyFile       string `yaml:"private_key_file" json:"private_key_file" toml:"private_key_file"`
+	PrivateKeyPassphrase string `yaml:"private_key_passphrase" json:"private_key_passphrase" toml:"private_key_passphrase" secret:"true"`
+	Region               string `yaml:"region" json:"region" toml:"region"`
+	CompartmentID        string `yaml:"compartment_id" json:"compartment_id" toml:"compartment_id"` // optional, when zone names aren't unique in the tenancy
+}
+
+// AliDNSConfig takes a RAM user access key with AliyunDNSFullAccess
+type AliDNSConfig struct {
+	AccessKey string `yaml:"access_key" json:"access_key" toml:"access_key"`
+	SecretKey string `yaml:"secret_key" json:"secret_key" toml:"secret_key" secret:"true"`
+	RegionID  string `yaml:"region_id" json:"region_id" toml:"region_id"` // optional, e.g. cn-hangzhou, the global endpoint when empty
+}
+
+// IONOSConfig takes a hosting API key, "<public prefix>.<secret>" as shown by the IONOS developer portal
+type IONOSConfig struct {
+	APIKey string `yaml:"api_key" json:"api_key" toml:"api_key" secret:"true"`
+}
+
+// AcmeDNSConfig points to an acme-dns server, the accounts of the domains are registered on it as needed
+type AcmeDNSConfig struct {
+	ServerURL string   `yaml:"server_url" json:"server_url" toml:"server_url"`
+	AllowFrom []string `yaml:"allow_from" json:"allow_from" toml:"allow_from"` // optional, CIDRs allowed to update the registered accounts
+}
+
+// HTTPReqConfig points to an endpoint of in-house DNS automation, Mode RAW sends the challenge instead of the record
+type HTTPReqConfig struct {
+	Endpoint string       `yaml:"endpoint" json:"endpoint" toml:"endpoint"`
+	Mode     string       `yaml:"mode" json:"mode" toml:"mode"`
+	Username string       `yaml:"username" json:"username" toml:"username"` // optional basic auth
+	Password string       `yaml:"password" json:"password" toml:"password" secret:"true"`
+	Headers  []HTTPHeader `yaml:"headers" json:"headers" toml:"headers"`
+}
+
+// HTTPHeader is sent with every request, e.g. an Authorization or API key header
+type HTTPHeader struct {
+	Name  string `yaml:"name" json:"name" toml:"name"`
+	Value string `yaml:"value" json:"value" toml:"value" secret:"true"`
+}
+
+// NetlifyConfig takes a personal access token of a member of the team owning the DNS zones
+type NetlifyConfig struct {
+	Token string `yaml:"token" json:"token" toml:"token" secret:"true"`
+}
+
+// SelfSignedConfig signs certificates with a local development CA instead of ordering them, no ACME CA
+// or DNS zone is involved
+type SelfSignedConfig struct {
+	Validity time.Duration `yaml:"validity" json:"validity" toml:"validity"` // lifetime of the certificates, 90 days when 0
+}
+
+// ProviderType returns the lowercased type, the name when no type is set, webhook is reported as httpreq
+func (p ProviderConfig) ProviderType() string {
+	t := strings.ToLower(p.Type)
+	if t == "" {
+		t = strings.ToLower(p.Name)
+	}
+	if t == ProviderWebhook {
+		return ProviderHTTPReq
+	}
+	return t
+}
+
+// providerKeyEnv is the variable holding the main credential of a provider, e.g. API_KEY_CLOUDFLARE_PROD
+func providerKeyEnv(name string) string {
+	return "API_KEY_" + strings.ToUpper(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name))
+}
+
+// fromLegacyAPIs turns the deprecated apis list into providers, entries already defined in providers win.
+// Route53 credentials come from aws_config as they used to
+func (c *Config) fromLegacyAPIs() {
+	for _, api := range c.APIS {
+		if api.Name == "" || c.provider(api.Name) != nil {
+			continue
+		}
+		p := ProviderConfig{Name: api.Name, Type: api.Type}
+		if p.Type == "" {
+			p.Type = legacyProviderType(api.Name)
+		}
+		if p.ProviderType() == ProviderRoute53 {
+			p.Route53 = &Route53Config{
+				AccessKey: c.AwsConfig.AccessKey,
+				SecretKey: c.AwsConfig.SecretKey,
+				Region:    c.AwsConfig.Region,
+			}
+		}
+		c.Providers = append(c.Providers, p)
+	}
+}
+
+// legacyProviderType reads the type from names like cloudflare-prod or hetzner_staging, the apis
+// list had no type so several accounts of a type could only be told apart by their name
+func legacyProviderType(name string) string {
+	name = strings.ToLower(name)
+	best := ""
+	for _, t := range providerTypes {
+		if len(t) > len(best) && (name == t || strings.HasPrefix(name, t+"-") || strings.HasPrefix(name, t+"_")) {
+			best = t
+		}
+	}
+	return best
+}
+
+func (c *Config) provider(name string) *ProviderConfig {
+	for i := range c.Providers {
+		if strings.EqualFold(c.Providers[i].Name, name) {
+			return &c.Providers[i]
+		}
+	}
+	return nil
+}
+
+// loadProviderKeys fills the main credential of every provider from API_KEY_<NAME> (or its _FILE)
+// when the config doesn't set it, providers without it are disabled instead of failing the load
+func (c *Config) loadProviderKeys(errs *ConfigErrors) {
+	for i := range c.Providers {
+		p := &c.Providers[i]
+		path := fmt.Sprintf("providers[%d]", i)
+		envName := providerKeyEnv(p.Name)
+
+		var key *string
+		switch p.ProviderType() {
+		case ProviderCloudflare:
+			if p.Cloudflare == nil {
+				p.Cloudflare = &CloudflareConfig{}
+			}
+			key, path = &p.Cloudflare.Token, path+".cloudflare.token"
+		case ProviderHetzner:
+			if p.Hetzner == nil {
+				p.Hetzner = &HetznerConfig{}
+			}
+			if p.Hetzner.APIToken != "" {
+				continue
+			}
+			key, path = &p.Hetzner.APIKey, path+".hetzner.api_key"
+		case ProviderDigitalOcean:
+			if p.DigitalOcean == nil {
+				p.DigitalOcean = &DigitalOceanConfig{}
+			}
+			key, path = &p.DigitalOcean.Token, path+".digitalocean.token"
+		case ProviderDNSimple:
+			if p.DNSimple == nil {
+				p.DNSimple = &DNSimpleConfig{}
+			}
+			key, path = &p.DNSimple.Token, path+".dnsimple.token"
+		case ProviderNamecheap:
+			if p.Namecheap == nil {
+				p.Namecheap = &NamecheapConfig{}
+			}
+			if p.Namecheap.APIUser == "" {
+				errs.add(path+".namecheap.api_user", "is required")
+				continue
+			}
+			key, path = &p.Namecheap.APIKey, path+".namecheap.api_key"
+		case ProviderGandi:
+			if p.Gandi == nil {
+				p.Gandi = &GandiConfig{}
+			}
+			if p.Gandi.APIKey != "" {
+				continue
+			}
+			key, path = &p.Gandi.PersonalAccessToken, path+".gandi.personal_access_token"
+		case ProviderOVH:
+			if p.OVH == nil {
+				p.OVH = &OVHConfig{}
+			}
+			if p.OVH.ApplicationKey == "" || p.OVH.ConsumerKey == "" {
+				errs.add(path+".ovh", "application_key and consumer_key are required")
+				continue
+			}
+			key, path = &p.OVH.ApplicationSecret, path+".ovh.application_secret"
+		case ProviderPorkbun:
+			if p.Porkbun == nil {
+				p.Porkbun = &PorkbunConfig{}
+			}
+			if p.Porkbun.SecretKey == "" {
+				errs.add(path+".porkbun.secret_key", "is required")
+				continue
+			}
+			key, path = &p.Porkbun.APIKey, path+".porkbun.api_key"
+		case ProviderPowerDNS:
+			if p.PowerDNS == nil {
+				p.PowerDNS = &PowerDNSConfig{}
+			}
+			if p.PowerDNS.APIURL == "" {
+				errs.add(path+".pdns.api_url", "is required")
+				continue
+			}
+			key, path = &p.PowerDNS.APIKey, path+".pdns.api_key"
+		case ProviderRFC2136:
+			if p.RFC2136 == nil {
+				p.RFC2136 = &RFC2136Config{}
+			}
+			if p.RFC2136.Nameserver == "" {
+				errs.add(path+".rfc2136.nameserver", "is required")
+				continue
+			}
+			// unsigned updates need no secret
+			if p.RFC2136.TSIGKey == "" || p.RFC2136.TSIGFile != "" {
+				continue
+			}
+			key, path = &p.RFC2136.TSIGSecret, path+".rfc2136.tsig_secret"
+		case ProviderLinode:
+			if p.Linode == nil {
+				p.Linode = &LinodeConfig{}
+			}
+			key, path = &p.Linode.Token, path+".linode.token"
+		case ProviderVultr:
+			if p.Vultr == nil {
+				p.Vultr = &VultrConfig{}
+			}
+			key, path = &p.Vultr.APIKey, path+".vultr.api_key"
+		case ProviderDeSEC:
+			if p.DeSEC == nil {
+				p.DeSEC = &DeSECConfig{}
+			}
+			key, path = &p.DeSEC.Token, path+".desec.token"
+		case ProviderGoDaddy:
+			if p.GoDaddy == nil {
+				p.GoDaddy = &GoDaddyConfig{}
+			}
+			if p.GoDaddy.APIKey == "" {
+				errs.add(path+".godaddy.api_key", "is required")
+				continue
+			}
+			key, path = &p.GoDaddy.APISecret, path+".godaddy.api_secret"
+		case ProviderScaleway:
+			if p.Scaleway == nil {
+				p.Scaleway = &ScalewayConfig{}
+			}
+			if p.Scaleway.ProjectID == "" {
+				errs.add(path+".scaleway.project_id", "is required")
+				continue
+			}
+			key, path = &p.Scaleway.SecretKey, path+".scaleway.secret_key"
+		case ProviderNS1:
+			if p.NS1 == nil {
+				p.NS1 = &NS1Config{}
+			}
+			key, path = &p.NS1.APIKey, path+".ns1.api_key"
+		case ProviderInfoblox:
+			if p.Infoblox == nil {
+				p.Infoblox = &InfobloxConfig{}
+			}
+			if p.Infoblox.Host == "" || p.Infoblox.Username == "" {
+				errs.add(path+".infoblox", "host and username are required")
+				continue
+			}
+			key, path = &p.Infoblox.Password, path+".infoblox.password"
+		case ProviderOracleCloud:
+			if p.OracleCloud == nil {
+				p.OracleCloud = &OracleCloudConfig{}
+			}
+			if c := p.OracleCloud; c.TenancyOCID == "" || c.UserOCID == "" || c.Fingerprint == "" || c.Region == "" {
+				errs.add(path+".oraclecloud", "tenancy_ocid, user_ocid, fingerprint and region are required")
+				continue
+			}
+			if p.OracleCloud.PrivateKeyFile != "" {
+				continue
+			}
+			key, path = &p.OracleCloud.PrivateKey, path+".oraclecloud.private_key"
+		case ProviderAliDNS:
+			if p.AliDNS == nil {
+				p.AliDNS = &AliDNSConfig{}
+			}
+			if p.AliDNS.AccessKey == "" {
+				errs.add(path+".alidns.access_key", "is required")
+				continue
+			}
+			key, path = &p.AliDNS.SecretKey, path+".alidns.secret_key"
+		case ProviderIONOS:
+			if p.IONOS == nil {
+				p.IONOS = &IONOSConfig{}
+			}
+			key, path = &p.IONOS.APIKey, path+".ionos.api_key"
+		case ProviderAcmeDNS:
+			// no credential, the accounts are registered per domain and kept in the database
+			if p.AcmeDNS == nil || p.AcmeDNS.ServerURL == "" {
+				errs.add(path+".acmedns.server_url", "is required")
+			}
+			continue
+		case ProviderHTTPReq:
+			if p.HTTPReq == nil || p.HTTPReq.Endpoint == "" {
+				errs.add(path+".httpreq.endpoint", "is required")
+				continue
+			}
+			// headers may carry the credentials instead
+			if p.HTTPReq.Username == "" {
+				continue
+			}
+			key, path = &p.HTTPReq.Password, path+".httpreq.password"
+		case ProviderNetlify:
+			if p.Netlify == nil {
+				p.Netlify = &NetlifyConfig{}
+			}
+			key, path = &p.Netlify.Token, path+".netlify.token"
+		case ProviderSelfSigned:
+			// no credential, certificates are signed locally
+			continue
+		case ProviderRoute53:
+			// optional keys, the AWS credential chain is used otherwise
+			if p.Route53 == nil {
+				p.Route53 = &Route53Config{}
+			}
+			continue
+		default:
+			// reported by validate
+			continue
+		}
+		if *key != "" {
+			continue
+		}
+
+		value, err := lookupSecretEnv(envName)
+		if err != nil {
+			errs.add(path, "%v", err)
+			continue
+		}
+		if value == "" {
+			p.Disabled = fmt.Sprintf("%s is not set in the config nor in %s (or %s_FILE)", path, envName, envName)
+			continue
+		}
+		*key = value
+	}
+}
+
+// EnabledProviders returns the providers that have their credentials
+func (c *Config) EnabledProviders() []ProviderConfig {
+	var out []ProviderConfig
+	for _, p := range c.Providers {
+		if p.Disabled == "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// DisabledProviders returns the providers skipped because of missing credentials
+func (c *Config) DisabledProviders() []ProviderConfig {
+	var out []ProviderConfig
+	for _, p := range c.Providers {
+		if p.Disabled != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// validateProviders checks names and types, credentials are checked by loadProviderKeys
+func (c *Config) validateProviders(errs *ConfigErrors) {
+	if len(c.Providers) == 0 {
+		errs.add("providers", "at least one DNS provider is required")
+	} else if len(c.EnabledProviders()) == 0 {
+		for _, p := range c.Providers {
+			errs.add("providers", "no DNS provider has credentials, %s", p.Disabled)
+		}
+	}
+	seen := make(map[string]bool, len(c.Providers))
+	for i, p := range c.Providers {
+		path := fmt.Sprintf("providers[%d]", i)
+		if p.Name == "" {
+			errs.add(path+".name", "is required")
+		} else if seen[strings.ToLower(p.Name)] {
+			errs.add(path+".name", "duplicated provider %q", p.Name)
+		}
+		seen[strings.ToLower(p.Name)] = true
+
+		if p.ProviderType() == ProviderSandbox && c.Sandbox.Enabled {
+			continue
+		}
+		if p.SelfSigned != nil && p.SelfSigned.Validity < 0 {
+			errs.add(path+".selfsigned.validity", "must not be negative")
+		}
+		if !slices.Contains(providerTypes, p.ProviderType()) {
+			errs.add(path+".type", "unknown provider type %q, expected one of %s", p.ProviderType(), strings.Join(providerTypes, ", "))
+		}
+		for j, d := range p.Delegations {
+			if err := d.validate(p.Name); err != nil {
+				errs.add(fmt.Sprintf("%s.delegations[%d]", path, j), "%s", err)
+			} else if c.provider(d.Provider) == nil {
+				errs.add(fmt.Sprintf("%s.delegations[%d].provider", path, j), "provider %q is not defined in providers", d.Provider)
+			}
+		}
+	}
+
+	if c.DefaultProvider != "" {
+		if p := c.provider(c.DefaultProvider); p == nil {
+			errs.add("default_provider", "provider %q is not defined in providers", c.DefaultProvider)
+		} else if p.Disabled != "" {
+			errs.add("default_provider", "provider %q is disabled, %s", c.DefaultProvider, p.Disabled)
+		}
+	}
+}
+
+// Validate checks a provider registered at runtime, it has no API_KEY_<NAME> fallback
+func (p ProviderConfig) Validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	var missing string
+	switch p.ProviderType() {
+	case ProviderCloudflare:
+		if p.Cloudflare == nil || p.Cloudflare.Token == "" {
+			missing = "cloudflare.token"
+		}
+	case ProviderHetzner:
+		if p.Hetzner == nil || p.Hetzner.APIKey == "" && p.Hetzner.APIToken == "" {
+			missing = "hetzner.api_key or hetzner.api_token"
+		}
+	case ProviderDigitalOcean:
+		if p.DigitalOcean == nil || p.DigitalOcean.Token == "" {
+			missing = "digitalocean.token"
+		}
+	case ProviderDNSimple:
+		if p.DNSimple == nil || p.DNSimple.Token == "" {
+			missing = "dnsimple.token"
+		}
+	case ProviderNamecheap:
+		if p.Namecheap == nil || p.Namecheap.APIUser == "" || p.Namecheap.APIKey == "" {
+			missing = "namecheap.api_user and namecheap.api_key"
+		}
+	case ProviderGandi:
+		if p.Gandi == nil || p.Gandi.PersonalAccessToken == "" && p.Gandi.APIKey == "" {
+			missing = "gandi.personal_access_token or gandi.api_key"
+		}
+	case ProviderOVH:
+		if p.OVH == nil || p.OVH.ApplicationKey == "" || p.OVH.ApplicationSecret == "" || p.OVH.ConsumerKey == "" {
+			missing = "ovh.application_key, ovh.application_secret and ovh.consumer_key"
+		}
+	case ProviderPorkbun:
+		if p.Porkbun == nil || p.Porkbun.APIKey == "" || p.Porkbun.SecretKey == "" {
+			missing = "porkbun.api_key and porkbun.secret_key"
+		}
+	case ProviderPowerDNS:
+		if p.PowerDNS == nil || p.PowerDNS.APIURL == "" || p.PowerDNS.APIKey == "" {
+			missing = "pdns.api_url and pdns.api_key"
+		}
+	case ProviderRFC2136:
+		if p.RFC2136 == nil || p.RFC2136.Nameserver == "" {
+			missing = "rfc2136.nameserver"
+		} else if p.RFC2136.TSIGKey != "" && p.RFC2136.TSIGSecret == "" {
+			missing = "rfc2136.tsig_secret"
+		}
+	case ProviderLinode:
+		if p.Linode == nil || p.Linode.Token == "" {
+			missing = "linode.token"
+		}
+	case ProviderVultr:
+		if p.Vultr == nil || p.Vultr.APIKey == "" {
+			missing = "vultr.api_key"
+		}
+	case ProviderDeSEC:
+		if p.DeSEC == nil || p.DeSEC.Token == "" {
+			missing = "desec.token"
+		}
+	case ProviderGoDaddy:
+		if p.GoDaddy == nil || p.GoDaddy.APIKey == "" || p.GoDaddy.APISecret == "" {
+			missing = "godaddy.api_key and godaddy.api_secret"
+		}
+	case ProviderScaleway:
+		if p.Scaleway == nil || p.Scaleway.SecretKey == "" || p.Scaleway.ProjectID == "" {
+			missing = "scaleway.secret_key and scaleway.project_id"
+		}
+	case ProviderNS1:
+		if p.NS1 == nil || p.NS1.APIKey == "" {
+			missing = "ns1.api_key"
+		}
+	case ProviderInfoblox:
+		if p.Infoblox == nil || p.Infoblox.Host == "" || p.Infoblox.Username == "" || p.Infoblox.Password == "" {
+			missing = "infoblox.host, infoblox.username and infoblox.password"
+		}
+	case ProviderOracleCloud:
+		if c := p.OracleCloud; c == nil || c.TenancyOCID == "" || c.UserOCID == "" || c.Fingerprint == "" || c.Region == "" {
+			missing = "oraclecloud.tenancy_ocid, oraclecloud.user_ocid, oraclecloud.fingerprint and oraclecloud.region"
+		} else if c.PrivateKey == "" && c.PrivateKeyFile == "" {
+			missing = "oraclecloud.private_key or oraclecloud.private_key_file"
+		}
+	case ProviderAliDNS:
+		if p.AliDNS == nil || p.AliDNS.AccessKey == "" || p.AliDNS.SecretKey == "" {
+			missing = "alidns.access_key and alidns.secret_key"
+		}
+	case ProviderIONOS:
+		if p.IONOS == nil || p.IONOS.APIKey == "" {
+			missing = "ionos.api_key"
+		}
+	case ProviderAcmeDNS:
+		if p.AcmeDNS == nil || p.AcmeDNS.ServerURL == "" {
+			missing = "acmedns.server_url"
+		}
+	case ProviderHTTPReq:
+		if p.HTTPReq == nil || p.HTTPReq.Endpoint == "" {
+			missing = "httpreq.endpoint"
+		}
+	case ProviderNetlify:
+		if p.Netlify == nil || p.Netlify.Token == "" {
+			missing = "netlify.token"
+		}
+	case ProviderSelfSigned:
+		if p.SelfSigned != nil && p.SelfSigned.Validity < 0 {
+			return errors.New("selfsigned.validity must not be negative")
+		}
+	case ProviderRoute53:
+		if p.Route53 != nil && (p.Route53.AccessKey == "") != (p.Route53.SecretKey == "") {
+			return errors.New("route53.access_key and route53.secret_key must be set together")
+		}
+	default:
+		return fmt.Errorf("unknown provider type %q, expected one of %s", p.ProviderType(), strings.Join(providerTypes, ", "))
+	}
+	if missing != "" {
+		return fmt.Errorf("%s is required for provider '%s'", missing, p.Name)
+	}
+	for _, r := range p.Resolvers {
+		if !validResolver(r) {
+			return fmt.Errorf("resolver %q of provider '%s' must be host or host:port", r, p.Name)
+		}
+	}
+	if p.SkipAuthoritativeCheck && len(p.Resolvers) == 0 {
+		return fmt.Errorf("skip_authoritative_check of provider '%s' needs resolvers", p.Name)
+	}
+	for _, ns := range p.Nameservers {
+		if _, err := path.Match(ns, ""); err != nil || ns == "" {
+			return fmt.Errorf("nameserver pattern %q of provider '%s' is invalid", ns, p.Name)
+		}
+	}
+	for _, d := range p.Delegations {
+		if err := d.validate(p.Name); err != nil {
+			return fmt.Errorf("delegation of provider '%s': %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the delegation of the provider named owner, the target must be another provider
+func (d DelegationConfig) validate(owner string) error {
+	if _, err := NormalizeDomainName(strings.TrimSuffix(d.Zone, ".")); err != nil {
+		return fmt.Errorf("zone %q is invalid: %w", d.Zone, err)
+	}
+	if d.Provider == "" {
+		return errors.New("provider is required")
+	}
+	if strings.EqualFold(d.Provider, owner) {
+		return errors.New("provider can't delegate to itself")
+	}
+	return nil
+}
+
+// validResolver accepts a resolver address as host or host:port, port 53 is added when missing
+func validResolver(address string) bool {
+	if address == "" || strings.ContainsAny(address, "/ ") {
+		return false
+	}
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		_, portErr := strconv.Atoi(port)
+		return host != "" && portErr == nil
+	}
+	return !strings.Contains(address, ":") || net.ParseIP(address) != nil
+}
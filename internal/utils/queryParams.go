@@ -3,6 +3,7 @@ package utils
 import (
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 func GetDefaultQueryValue(queryParams url.Values, key, defaultValue string) string {
@@ -28,3 +29,30 @@ func GetDefaultIntegerQueryValue(queryParams url.Values, key string, defaultValu
 
 	return value
 }
+
+func GetDefaultBoolQueryValue(queryParams url.Values, key string, defaultValue bool) bool {
+	valueStr := queryParams.Get(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetListQueryValue collects comma separated and repeated values of a query parameter
+func GetListQueryValue(queryParams url.Values, key string) []string {
+	var values []string
+	for _, raw := range queryParams[key] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,41 @@
+package utils
+
+// ProviderSandbox is the in-memory DNS provider of sandbox mode, it isn't accepted otherwise
+const ProviderSandbox = "sandbox"
+
+const (
+	defaultSandboxCADirURL   = "https://localhost:14000/dir"
+	defaultSandboxDNSAddress = ":8053"
+)
+
+// SandboxConfig runs issuance against a local Pebble CA, the DNS-01 records are served from memory
+// by Hephaestus itself on DNSAddress, Pebble has to use it as its resolver (pebble -dnsserver)
+type SandboxConfig struct {
+	Enabled    bool   `yaml:"enabled" json:"enabled" toml:"enabled" env:"HEPHAESTUS_SANDBOX_ENABLED,SANDBOX_ENABLED"`
+	CADirURL   string `yaml:"ca_dir_url" json:"ca_dir_url" toml:"ca_dir_url" env:"HEPHAESTUS_SANDBOX_CA_DIR_URL,SANDBOX_CA_DIR_URL"`      // Pebble directory, https://localhost:14000/dir when empty
+	DNSAddress string `yaml:"dns_address" json:"dns_address" toml:"dns_address" env:"HEPHAESTUS_SANDBOX_DNS_ADDRESS,SANDBOX_DNS_ADDRESS"` // where the challenge records are served, :8053 when empty
+}
+
+// applySandbox makes every provider usable without credentials and adds the sandbox provider,
+// which becomes the default one when none is set
+func (c *Config) applySandbox() {
+	if !c.Sandbox.Enabled {
+		return
+	}
+	if c.Sandbox.CADirURL == "" {
+		c.Sandbox.CADirURL = defaultSandboxCADirURL
+	}
+	if c.Sandbox.DNSAddress == "" {
+		c.Sandbox.DNSAddress = defaultSandboxDNSAddress
+	}
+
+	for i := range c.Providers {
+		c.Providers[i].Disabled = ""
+	}
+	if c.provider(ProviderSandbox) == nil {
+		c.Providers = append(c.Providers, ProviderConfig{Name: ProviderSandbox, Type: ProviderSandbox})
+	}
+	if c.DefaultProvider == "" {
+		c.DefaultProvider = ProviderSandbox
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+const secretResolveTimeout = 10 * time.Second
+
+// SecretResolver fetches the secret a config value refers to, e.g. vault://secret/hephaestus#cf_token
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+// secretResolvers by URI scheme, RegisterSecretResolver adds more before LoadConfig is called
+var secretResolvers = map[string]SecretResolver{
+	"vault": &vaultResolver{client: &http.Client{Timeout: secretResolveTimeout}},
+	"awssm": &awsSecretsManagerResolver{},
+}
+
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolvers[scheme] = resolver
+}
+
+// resolveSecretRef returns ok=false when the value is not a reference of a registered scheme
+func resolveSecretRef(value string) (secret string, ok bool, err error) {
+	scheme, _, found := strings.Cut(value, "://")
+	if !found {
+		return "", false, nil
+	}
+	resolver, registered := secretResolvers[scheme]
+	if !registered {
+		return "", false, nil
+	}
+
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", true, fmt.Errorf("invalid secret reference: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+	secret, err = resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", true, fmt.Errorf("resolve %s://%s%s: %w", ref.Scheme, ref.Host, ref.Path, err)
+	}
+	return secret, true, nil
+}
+
+// vaultResolver reads KV v2 secrets: vault://<mount>/<path>#<key>, the server and token
+// come from VAULT_ADDR and VAULT_TOKEN (or VAULT_TOKEN_FILE), VAULT_NAMESPACE is optional
+type vaultResolver struct {
+	client *http.Client
+}
+
+func (r *vaultResolver) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	mount, path, key := ref.Host, strings.Trim(ref.Path, "/"), ref.Fragment
+	if mount == "" || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference must look like vault://<mount>/<path>#<key>")
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token, err := lookupSecretEnv("VAULT_TOKEN")
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(addr, "/"), mount, path), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	value, ok := body.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found", key)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// awsSecretsManagerResolver reads awssm://<name>[?region=<region>][#<json key>] with the default
+// AWS credential chain, without a key the whole secret string is used
+type awsSecretsManagerResolver struct{}
+
+func (r *awsSecretsManagerResolver) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	name := ref.Host + ref.Path
+	if name == "" {
+		return "", fmt.Errorf("aws secrets manager reference must look like awssm://<name>#<key>")
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if region := ref.Query().Get("region"); region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("load aws config: %w", err)
+	}
+
+	out, err := secretsmanager.NewFromConfig(awsCfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	secret := aws.ToString(out.SecretString)
+
+	if ref.Fragment == "" {
+		return secret, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, can't select key %q", ref.Fragment)
+	}
+	value, ok := fields[ref.Fragment]
+	if !ok {
+		return "", fmt.Errorf("key %q not found", ref.Fragment)
+	}
+	return fmt.Sprint(value), nil
+}
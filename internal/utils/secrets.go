@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// secretFilePrefix marks a config value that is the path of a file holding the secret
+const secretFilePrefix = "file:"
+
+// resolveSecrets fills the fields tagged secret:"true" from <ENV>_FILE variables, file: values
+// and references like vault://... handled by a SecretResolver, so secrets don't have to be
+// visible in the environment
+func resolveSecrets(v any, errs *ConfigErrors) {
+	walkSecrets(reflect.ValueOf(v).Elem(), "", errs)
+}
+
+func walkSecrets(v reflect.Value, path string, errs *ConfigErrors) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := joinConfigPath(path, field)
+			fv := v.Field(i)
+
+			if field.Tag.Get("secret") == "true" && fv.Kind() == reflect.String {
+				if err := resolveSecretField(fv, field); err != nil {
+					errs.add(fieldPath, "%v", err)
+				}
+				continue
+			}
+			walkSecrets(fv, fieldPath, errs)
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			walkSecrets(v.Elem(), path, errs)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkSecrets(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func resolveSecretField(v reflect.Value, field reflect.StructField) error {
+	// names are in priority order, a set variable was already applied by cleanenv
+	for _, envName := range strings.Split(field.Tag.Get("env"), ",") {
+		if envName == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(envName); ok {
+			break
+		}
+		if file := os.Getenv(envName + "_FILE"); file != "" {
+			secret, err := readSecretFile(file)
+			if err != nil {
+				return fmt.Errorf("from %s_FILE: %w", envName, err)
+			}
+			v.SetString(secret)
+			return nil
+		}
+	}
+
+	if file, ok := strings.CutPrefix(v.String(), secretFilePrefix); ok {
+		secret, err := readSecretFile(file)
+		if err != nil {
+			return err
+		}
+		v.SetString(secret)
+		return nil
+	}
+
+	secret, ok, err := resolveSecretRef(v.String())
+	if err != nil {
+		return err
+	}
+	if ok {
+		v.SetString(secret)
+	}
+	return nil
+}
+
+// lookupSecretEnv reads the variable itself or, when it is unset, the file named by <name>_FILE,
+// the HEPHAESTUS_ prefixed pair is tried first
+func lookupSecretEnv(name string) (string, error) {
+	for _, envName := range []string{EnvPrefix + name, name} {
+		if value := os.Getenv(envName); value != "" {
+			return value, nil
+		}
+		if file := os.Getenv(envName + "_FILE"); file != "" {
+			secret, err := readSecretFile(file)
+			if err != nil {
+				return "", fmt.Errorf("%s_FILE: %w", envName, err)
+			}
+			return secret, nil
+		}
+	}
+	return "", nil
+}
+
+// readSecretFile drops the trailing newline editors and `echo` leave in secret files
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// joinConfigPath builds the YAML path of a field, e.g. database.password
+func joinConfigPath(parent string, field reflect.StructField) string {
+	name := configKey(field)
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// configKey is the YAML key of a field, fields not read from the file use their lowercased name
+func configKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" || name == "-" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
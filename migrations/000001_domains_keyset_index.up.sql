@@ -0,0 +1,2 @@
+-- supports keyset pagination on (created_at, id) in GetDomainsList
+CREATE INDEX IF NOT EXISTS idx_domains_created_at_id ON domains(created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_domains_tags;
+
+ALTER TABLE domains DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,5 @@
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN domains.tags IS 'Free-form labels used for filtering, e.g. env=prod, team=payments.';
+
+CREATE INDEX IF NOT EXISTS idx_domains_tags ON domains USING GIN (tags);
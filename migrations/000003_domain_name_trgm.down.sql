@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_alternative_domains_domain_name_trgm;
+DROP INDEX IF EXISTS idx_domains_domain_name_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
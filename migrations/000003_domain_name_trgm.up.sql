@@ -0,0 +1,7 @@
+-- trigram indexes keep substring (ILIKE '%...%') and similarity searches on domain names fast
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_domains_domain_name_trgm
+    ON domains USING GIN (domain_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_alternative_domains_domain_name_trgm
+    ON alternative_domains USING GIN (domain_name gin_trgm_ops);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_events_tenant_id;
+DROP INDEX IF EXISTS idx_certificates_tenant_id;
+DROP INDEX IF EXISTS idx_alternative_domains_tenant_id;
+DROP INDEX IF EXISTS idx_domains_tenant_id;
+
+ALTER TABLE events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE certificates DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE alternative_domains DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE domains DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,12 @@
+-- every row belongs to a tenant, '' is the default tenant of single-tenant installations
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE alternative_domains ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN domains.tenant_id IS 'Owning tenant, every repository query is scoped by it.';
+
+CREATE INDEX IF NOT EXISTS idx_domains_tenant_id ON domains(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_alternative_domains_tenant_id ON alternative_domains(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_certificates_tenant_id ON certificates(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_events_tenant_id ON events(tenant_id);
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS trg_update_alternative_domains_timestamp ON alternative_domains;
+
+DROP INDEX IF EXISTS idx_certificates_domain_id;
+DROP INDEX IF EXISTS idx_alternative_domains_domain_id;
+
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_domain_id_fkey;
+ALTER TABLE events ADD CONSTRAINT events_domain_id_fkey
+    FOREIGN KEY (domain_id) REFERENCES domains(id) ON DELETE CASCADE;
+
+ALTER TABLE certificates ALTER COLUMN domain_id DROP NOT NULL;
+ALTER TABLE alternative_domains ALTER COLUMN domain_id DROP NOT NULL;
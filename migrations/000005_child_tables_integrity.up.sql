@@ -0,0 +1,19 @@
+-- child rows must always belong to a domain
+DELETE FROM alternative_domains WHERE domain_id IS NULL;
+DELETE FROM certificates WHERE domain_id IS NULL;
+
+ALTER TABLE alternative_domains ALTER COLUMN domain_id SET NOT NULL;
+ALTER TABLE certificates ALTER COLUMN domain_id SET NOT NULL;
+
+-- purging a domain keeps its audit trail, events lose only the reference
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_domain_id_fkey;
+ALTER TABLE events ADD CONSTRAINT events_domain_id_fkey
+    FOREIGN KEY (domain_id) REFERENCES domains(id) ON DELETE SET NULL;
+
+-- cascades and per-domain lookups scan these
+CREATE INDEX IF NOT EXISTS idx_alternative_domains_domain_id ON alternative_domains(domain_id);
+CREATE INDEX IF NOT EXISTS idx_certificates_domain_id ON certificates(domain_id);
+
+CREATE TRIGGER trg_update_alternative_domains_timestamp
+BEFORE UPDATE ON alternative_domains
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_certificates_domain_id_created_at;
+
+ALTER TABLE domains DROP COLUMN IF EXISTS active_certificate_id;
+ALTER TABLE certificates DROP COLUMN IF EXISTS serial_number;
@@ -0,0 +1,18 @@
+-- every issuance is a new certificates row, the domain points at the one in use
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS serial_number TEXT;
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS active_certificate_id UUID
+    REFERENCES certificates(id) ON DELETE SET NULL;
+
+-- until now a domain had a single certificate row, the newest one becomes active
+UPDATE domains d
+SET active_certificate_id = c.id
+FROM (
+    SELECT DISTINCT ON (domain_id) id, domain_id
+    FROM certificates
+    WHERE deleted_at IS NULL
+    ORDER BY domain_id, created_at DESC
+) c
+WHERE c.domain_id = d.id;
+
+-- history is listed newest first
+CREATE INDEX IF NOT EXISTS idx_certificates_domain_id_created_at ON certificates(domain_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- free-form data teams attach to a domain: ticket ids, owners, runbook links
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::JSONB;
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS notes TEXT;
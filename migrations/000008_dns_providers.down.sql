@@ -0,0 +1 @@
+DROP TABLE IF EXISTS dns_providers;
@@ -0,0 +1,20 @@
+-- DNS providers registered through the API, shared by all tenants
+CREATE TABLE IF NOT EXISTS dns_providers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) UNIQUE NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    credentials BYTEA NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    created_by TEXT NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_by TEXT
+);
+
+COMMENT ON TABLE dns_providers IS
+    'DNS provider accounts added at runtime, in addition to the ones of the config file.';
+COMMENT ON COLUMN dns_providers.name IS 'Name domains use as dns_provider.';
+COMMENT ON COLUMN dns_providers.credentials IS 'Provider config as JSON, AES-GCM encrypted with encryption.key.';
+
+CREATE TRIGGER trg_update_dns_providers_timestamp
+BEFORE UPDATE ON dns_providers
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_domains_kind;
+
+UPDATE certificates SET cert_path = '' WHERE cert_path IS NULL;
+UPDATE certificates SET key_path = '' WHERE key_path IS NULL;
+ALTER TABLE certificates ALTER COLUMN cert_path SET NOT NULL;
+ALTER TABLE certificates ALTER COLUMN key_path SET NOT NULL;
+
+ALTER TABLE domains DROP COLUMN IF EXISTS last_check_error;
+ALTER TABLE domains DROP COLUMN IF EXISTS last_checked_at;
+ALTER TABLE domains DROP COLUMN IF EXISTS monitor_address;
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_kind_check;
+ALTER TABLE domains DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,15 @@
+-- monitored domains are only watched, their certificates are issued outside of Hephaestus
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'managed';
+ALTER TABLE domains ADD CONSTRAINT domains_kind_check CHECK (kind IN ('managed', 'monitored'));
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS monitor_address TEXT;
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMPTZ;
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS last_check_error TEXT;
+
+COMMENT ON COLUMN domains.kind IS 'managed: issued and renewed by Hephaestus, monitored: only the served certificate is checked.';
+COMMENT ON COLUMN domains.monitor_address IS 'host:port the served certificate of a monitored domain is read from.';
+
+-- certificates seen on monitored domains have no files
+ALTER TABLE certificates ALTER COLUMN cert_path DROP NOT NULL;
+ALTER TABLE certificates ALTER COLUMN key_path DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_domains_kind ON domains(kind) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_certificates_active_key_type;
+ALTER TABLE certificates DROP CONSTRAINT IF EXISTS certificates_state_check;
+ALTER TABLE certificates DROP COLUMN IF EXISTS state;
+ALTER TABLE certificates DROP COLUMN IF EXISTS key_type;
@@ -0,0 +1,17 @@
+-- a domain can hold several current certificates, one active per key type (e.g. RSA and ECDSA
+-- served side by side), a new certificate is staged until it replaces the active one
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS key_type VARCHAR(20) NOT NULL DEFAULT 'rsa2048';
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'retired';
+ALTER TABLE certificates ADD CONSTRAINT certificates_state_check CHECK (state IN ('staged', 'active', 'retired'));
+
+COMMENT ON COLUMN certificates.key_type IS 'Key algorithm and size, e.g. rsa2048 or ec256.';
+COMMENT ON COLUMN certificates.state IS 'staged: issued, not deployed yet; active: in use for its key type; retired: replaced.';
+COMMENT ON COLUMN domains.active_certificate_id IS 'Primary active certificate, used by deployment targets that take a single certificate.';
+
+UPDATE certificates c
+SET state = 'active'
+FROM domains d
+WHERE d.active_certificate_id = c.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_certificates_active_key_type
+    ON certificates(domain_id, key_type) WHERE state = 'active' AND deleted_at IS NULL;
@@ -0,0 +1,15 @@
+-- Read-only maintenance mode, one row shared by every replica
+CREATE TABLE IF NOT EXISTS maintenance (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT,
+    retry_after INTEGER NOT NULL DEFAULT 0,
+    since TIMESTAMPTZ,
+    set_by TEXT
+);
+
+INSERT INTO maintenance (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE maintenance IS
+    'Read-only mode of the installation, a single row so every replica sees the same state.';
+COMMENT ON COLUMN maintenance.retry_after IS 'Retry-After hint in seconds for rejected mutations.';
@@ -0,0 +1,3 @@
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS fingerprint VARCHAR(95);
+
+COMMENT ON COLUMN certificates.fingerprint IS 'SHA-256 of the DER certificate, colon separated hex like openssl -fingerprint -sha256. NULL for certificates stored before it was recorded.';
@@ -0,0 +1,14 @@
+-- acme-dns accounts of the domains, shared by all tenants like dns_providers
+CREATE TABLE IF NOT EXISTS acmedns_accounts (
+    server_url TEXT NOT NULL,
+    domain_name VARCHAR(255) NOT NULL,
+    full_domain VARCHAR(255) NOT NULL,
+    credentials BYTEA NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (server_url, domain_name)
+);
+
+COMMENT ON TABLE acmedns_accounts IS
+    'Accounts registered on acme-dns servers, one per domain and server.';
+COMMENT ON COLUMN acmedns_accounts.full_domain IS 'Target of the _acme-challenge CNAME of the domain.';
+COMMENT ON COLUMN acmedns_accounts.credentials IS 'Account as returned by /register, AES-GCM encrypted with encryption.key.';
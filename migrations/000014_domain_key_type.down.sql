@@ -0,0 +1,2 @@
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_key_type_check;
+ALTER TABLE domains DROP COLUMN IF EXISTS key_type;
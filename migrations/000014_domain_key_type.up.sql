@@ -0,0 +1,5 @@
+-- the key type renewals of the domain are issued with, NULL follows certs.key_type
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS key_type VARCHAR(20);
+ALTER TABLE domains ADD CONSTRAINT domains_key_type_check CHECK (key_type IN ('ec256', 'ec384', 'rsa2048', 'rsa3072', 'rsa4096', 'rsa8192'));
+
+COMMENT ON COLUMN domains.key_type IS 'Key type of the certificates issued for the domain, like certificates.key_type. NULL uses certs.key_type of the config.';
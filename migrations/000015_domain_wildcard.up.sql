@@ -0,0 +1,9 @@
+-- set when the domain or one of its alternative domains is a wildcard, those are only issued over DNS-01
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS wildcard BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE domains d SET wildcard = true
+WHERE d.domain_name LIKE '*.%' OR EXISTS (
+    SELECT 1 FROM alternative_domains ad
+    WHERE ad.domain_id = d.id AND ad.deleted_at IS NULL AND ad.domain_name LIKE '*.%');
+
+COMMENT ON COLUMN domains.wildcard IS 'The certificate covers a wildcard name, the domain itself or one of its alternative domains.';
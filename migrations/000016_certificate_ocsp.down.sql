@@ -0,0 +1,4 @@
+ALTER TABLE certificates DROP COLUMN IF EXISTS ocsp_revoked_at;
+ALTER TABLE certificates DROP COLUMN IF EXISTS ocsp_checked_at;
+ALTER TABLE certificates DROP CONSTRAINT IF EXISTS certificates_ocsp_status_check;
+ALTER TABLE certificates DROP COLUMN IF EXISTS ocsp_status;
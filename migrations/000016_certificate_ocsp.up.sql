@@ -0,0 +1,7 @@
+-- latest OCSP answer for active certificates, NULL until checked or when the certificate names no responder
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS ocsp_status VARCHAR(20);
+ALTER TABLE certificates ADD CONSTRAINT certificates_ocsp_status_check CHECK (ocsp_status IN ('good', 'revoked', 'unknown'));
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS ocsp_checked_at TIMESTAMPTZ;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS ocsp_revoked_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN certificates.ocsp_status IS 'good, revoked or unknown as answered by the OCSP responder of the issuer on ocsp_checked_at.';
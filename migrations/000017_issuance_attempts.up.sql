@@ -0,0 +1,18 @@
+-- ACME orders sent to CAs with published rate limits, shared by all tenants like the ACME account
+CREATE TABLE IF NOT EXISTS issuance_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ca_url TEXT NOT NULL,
+    names TEXT[] NOT NULL,
+    registered_domains TEXT[] NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    renewal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_issuance_attempts_ca_created ON issuance_attempts (ca_url, created_at);
+
+COMMENT ON TABLE issuance_attempts IS
+    'ACME orders of the account per CA directory, rows older than the longest rate limit window are removed.';
+COMMENT ON COLUMN issuance_attempts.names IS 'Sorted names of the order.';
+COMMENT ON COLUMN issuance_attempts.registered_domains IS 'Registered domains (public suffix + 1) of the names.';
+COMMENT ON COLUMN issuance_attempts.renewal IS 'The order renewed a certificate for the same names, exempt from the per domain and new orders limits.';
@@ -0,0 +1,4 @@
+-- the ACME profile renewals of the domain are ordered with, NULL follows certs.profile
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS profile VARCHAR(64);
+
+COMMENT ON COLUMN domains.profile IS 'ACME profile of the orders for the domain, e.g. shortlived. NULL uses certs.profile of the config.';
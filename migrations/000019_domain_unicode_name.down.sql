@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_alternative_domains_unicode_name_trgm;
+DROP INDEX IF EXISTS idx_domains_unicode_name_trgm;
+ALTER TABLE alternative_domains DROP COLUMN IF EXISTS unicode_name;
+ALTER TABLE domains DROP COLUMN IF EXISTS unicode_name;
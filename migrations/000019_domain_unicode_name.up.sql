@@ -0,0 +1,15 @@
+-- Unicode form of the names next to the punycode domain_name, equal to it for ASCII names, searched as well
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS unicode_name VARCHAR(255);
+ALTER TABLE alternative_domains ADD COLUMN IF NOT EXISTS unicode_name VARCHAR(255);
+
+-- punycode can't be decoded here, existing internationalized names stay NULL and are found by their punycode form
+UPDATE domains SET unicode_name = domain_name WHERE unicode_name IS NULL AND domain_name NOT LIKE '%xn--%';
+UPDATE alternative_domains SET unicode_name = domain_name WHERE unicode_name IS NULL AND domain_name NOT LIKE '%xn--%';
+
+CREATE INDEX IF NOT EXISTS idx_domains_unicode_name_trgm
+    ON domains USING GIN (unicode_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_alternative_domains_unicode_name_trgm
+    ON alternative_domains USING GIN (unicode_name gin_trgm_ops);
+
+COMMENT ON COLUMN domains.unicode_name IS 'Unicode form of domain_name, e.g. münchen.de for xn--mnchen-3ya.de.';
+COMMENT ON COLUMN alternative_domains.unicode_name IS 'Unicode form of domain_name.';
@@ -0,0 +1,4 @@
+-- renewals of the domain keep the private key of its current certificate
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS reuse_key BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN domains.reuse_key IS 'Renewals order the new certificate for the private key of the current one, e.g. for DANE TLSA records or pinning.';
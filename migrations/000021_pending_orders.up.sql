@@ -0,0 +1,25 @@
+-- ACME orders in progress, a restart after a crash resumes or cleans up the ones left behind.
+-- Shared by all tenants like the ACME account
+CREATE TABLE IF NOT EXISTS pending_orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    domain_name VARCHAR(255) NOT NULL,
+    names TEXT[] NOT NULL,
+    dns_provider VARCHAR(255) NOT NULL,
+    ca_url TEXT NOT NULL,
+    key_type VARCHAR(16) NOT NULL,
+    key_path TEXT NOT NULL,
+    order_url TEXT,
+    certificate_url TEXT,
+    challenges JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_orders_ca_url ON pending_orders (ca_url);
+
+COMMENT ON TABLE pending_orders IS
+    'ACME orders that were started and have not finished, rows are removed once the order succeeds or fails.';
+COMMENT ON COLUMN pending_orders.names IS 'Sorted names of the order.';
+COMMENT ON COLUMN pending_orders.key_path IS 'Private key the order is for, in certs.storage_dir/pending.';
+COMMENT ON COLUMN pending_orders.certificate_url IS 'Set once the CA issued the certificate, it is downloaded instead of ordered again.';
+COMMENT ON COLUMN pending_orders.challenges IS 'DNS-01 records presented and not cleaned up yet, as domain, token and key_auth.';
@@ -0,0 +1,2 @@
+ALTER TABLE certificates DROP COLUMN IF EXISTS active_chain;
+ALTER TABLE certificates DROP COLUMN IF EXISTS chains;
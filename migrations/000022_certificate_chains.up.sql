@@ -0,0 +1,6 @@
+-- alternate chains the CA offered are stored next to the default one, e.g. chain-alt1.pem, any of them can be active
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS chains TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS active_chain VARCHAR(20) NOT NULL DEFAULT 'default';
+
+COMMENT ON COLUMN certificates.chains IS 'Root each stored chain leads to, default first and alt1, alt2 after it. Empty when the CA offered no alternate chain.';
+COMMENT ON COLUMN certificates.active_chain IS 'Chain bundled in cert.pem and chain.pem: default, alt1, alt2 and on.';
@@ -0,0 +1,12 @@
+-- files of the postgres storage backend: certificate versions, their private keys, the ACME account key
+-- and the keys of orders in progress, keyed by the same names as in storage_dir. A table of its own rather
+-- than columns of certificates: the account key and the order keys belong to no certificate row, and a
+-- certificate has several files (key, chains, DER)
+CREATE TABLE IF NOT EXISTS certificate_files (
+    name       TEXT PRIMARY KEY,
+    content    BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE certificate_files IS 'Certificates and keys of certs.storage.backend: postgres, encrypted with certs.encryption.key.';
+COMMENT ON COLUMN certificate_files.name IS 'Slash separated name, e.g. example.com/03A1F2/privkey.pem or pending/example.com-1700000000.key.';
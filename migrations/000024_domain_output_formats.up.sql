@@ -0,0 +1,4 @@
+-- encodings the certificate files of the domain are written in besides PEM, NULL follows certs.output_formats
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS output_formats TEXT[];
+
+COMMENT ON COLUMN domains.output_formats IS 'Encodings of the certificate files besides PEM, e.g. {der}. NULL uses certs.output_formats of the config.';
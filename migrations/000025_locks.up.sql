@@ -0,0 +1,8 @@
+-- Leases coordinating replicas, a row is held by one replica until it is released or expires
+CREATE TABLE IF NOT EXISTS locks (
+    name       TEXT PRIMARY KEY,
+    holder     TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+COMMENT ON TABLE locks IS 'Leases of scheduler cycles and issuances, renewed while the holder works and taken over once expired.';
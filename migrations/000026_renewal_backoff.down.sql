@@ -0,0 +1,2 @@
+ALTER TABLE domains DROP COLUMN IF EXISTS next_renewal_at;
+ALTER TABLE domains DROP COLUMN IF EXISTS renewal_attempts;
@@ -0,0 +1,6 @@
+-- failed renewals of a domain back off instead of being retried on every cycle, a successful one resets both
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS renewal_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS next_renewal_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN domains.renewal_attempts IS 'Failed issuance or renewal attempts since the last certificate of the domain.';
+COMMENT ON COLUMN domains.next_renewal_at IS 'The renewal cycle skips the domain until this time after a failed attempt. NULL when the last attempt succeeded.';
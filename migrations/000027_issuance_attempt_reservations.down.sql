@@ -0,0 +1,2 @@
+ALTER TABLE issuance_attempts DROP COLUMN IF EXISTS failed_validation;
+ALTER TABLE issuance_attempts DROP COLUMN IF EXISTS pending;
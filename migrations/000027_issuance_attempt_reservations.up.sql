@@ -0,0 +1,9 @@
+-- an order is reserved in the transaction checking the budget and finished once the CA answered, concurrent checks
+-- see the reservation. Only authorizations the CA found invalid count against the failed validations limit
+ALTER TABLE issuance_attempts ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE issuance_attempts ADD COLUMN IF NOT EXISTS failed_validation BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE issuance_attempts SET failed_validation = NOT succeeded;
+
+COMMENT ON COLUMN issuance_attempts.pending IS 'The order was reserved and the CA hasn''t answered yet, counted as issued.';
+COMMENT ON COLUMN issuance_attempts.failed_validation IS 'The CA found an authorization of the order invalid.';